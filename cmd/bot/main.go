@@ -2,18 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/access"
 	"github.com/hray3182/LifeLine/internal/ai"
+	"github.com/hray3182/LifeLine/internal/api"
 	"github.com/hray3182/LifeLine/internal/bot"
 	"github.com/hray3182/LifeLine/internal/config"
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/metrics"
+	"github.com/hray3182/LifeLine/internal/quota"
 	"github.com/hray3182/LifeLine/internal/repository"
 	"github.com/hray3182/LifeLine/internal/scheduler"
+	"github.com/hray3182/LifeLine/internal/webhook"
 )
 
 func main() {
@@ -30,6 +38,13 @@ func main() {
 	if cfg.TelegramToken == "" {
 		log.Fatal("TELEGRAM_TOKEN is required")
 	}
+	accessMode, err := access.ParseMode(cfg.AccessMode)
+	if err != nil {
+		log.Fatalf("Invalid ACCESS_MODE: %v", err)
+	}
+	if accessMode != access.ModeOpen && len(cfg.AdminIDs) == 0 {
+		log.Printf("Warning: ACCESS_MODE=%s without ADMIN_IDS, nobody can approve users", accessMode)
+	}
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -49,11 +64,24 @@ func main() {
 	}
 	log.Println("Database migrations completed")
 
+	// Access policy, also used to exempt admins from AI quotas
+	accessPolicy := access.NewPolicy(accessMode, cfg.AdminIDs, cfg.AllowedUserIDs)
+
+	// Track AI usage per user and enforce quotas
+	quotaTracker := quota.New(repository.NewAIUsageRepository(db), repository.NewUserSettingsRepository(db), quota.Limits{
+		DailyTokens:   cfg.AIDailyTokenLimit,
+		MonthlyTokens: cfg.AIMonthlyTokenLimit,
+	})
+	quotaTracker.SetExempt(accessPolicy.IsAdmin)
+
 	// Initialize AI client (optional)
 	var aiClient *ai.Client
 	if cfg.AIAPIKey != "" {
 		aiClient = ai.New(cfg.AIAPIKey, cfg.AIBaseURL, cfg.AIModel)
-		log.Printf("AI client initialized (model: %s)", cfg.AIModel)
+		aiClient.SetUsageTracker(quotaTracker)
+		aiClient.SetPricing(cfg.AIPromptPrice, cfg.AICompletionPrice)
+		log.Printf("AI client initialized (model: %s, daily limit: %d, monthly limit: %d tokens)",
+			cfg.AIModel, cfg.AIDailyTokenLimit, cfg.AIMonthlyTokenLimit)
 	} else {
 		log.Println("AI client not configured, natural language features disabled")
 	}
@@ -64,39 +92,106 @@ func main() {
 		log.Fatalf("Failed to create Telegram API: %v", err)
 	}
 
+	// Background services, waited for on shutdown so their in-flight work
+	// finishes before the database pool is closed
+	var services sync.WaitGroup
+
+	// Start health and metrics server (optional)
+	if cfg.MetricsAddr != "" {
+		metricsServer := metrics.NewServer(cfg.MetricsAddr,
+			metrics.Check{Name: "database", Fn: db.Ping},
+			metrics.Check{Name: "telegram", Fn: func(ctx context.Context) error {
+				_, err := tgAPI.GetMe()
+				return err
+			}},
+		)
+		services.Go(func() {
+			if err := metricsServer.Start(ctx); err != nil {
+				log.Printf("Metrics server error: %v", err)
+			}
+		})
+	}
+
 	// Create repositories for scheduler
 	reminderRepo := repository.NewReminderRepository(db)
 	eventRepo := repository.NewEventRepository(db)
 	todoRepo := repository.NewTodoRepository(db)
 	userSettingsRepo := repository.NewUserSettingsRepository(db)
 
+	// Create and start webhook dispatcher
+	hooks := webhook.New(db)
+	services.Go(func() { hooks.Start(ctx) })
+
 	// Create and start scheduler
 	sched := scheduler.New(tgAPI, reminderRepo, eventRepo, todoRepo, userSettingsRepo)
-	go sched.Start(ctx)
+	sched.SetWebhooks(hooks)
+	sched.SetProjects(repository.NewProjectRepository(db))
+	services.Go(func() { sched.Start(ctx) })
 
 	// Create and start bot
 	if cfg.DevMode {
 		log.Println("[DEV] Development mode enabled")
 	}
-	b, err := bot.New(cfg.TelegramToken, db, aiClient, cfg.DevMode)
+	b, err := bot.New(cfg.TelegramToken, db, aiClient, bot.Options{
+		DevMode:            cfg.DevMode,
+		RateLimitPerMinute: cfg.RateLimitPerMinute,
+		Workers:            cfg.UpdateWorkers,
+		ShutdownTimeout:    cfg.ShutdownTimeout,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
 
 	// Connect scheduler notification to bot handlers
 	b.SetSchedulerNotify(sched.Notify)
+	b.SetWebhooks(hooks)
+	b.SetAccess(accessPolicy)
+	b.SetQuota(quotaTracker)
+	log.Printf("Access mode: %s (%d admins)", accessMode, len(cfg.AdminIDs))
 
-	// Handle graceful shutdown
+	// Start HTTP API (optional)
+	if cfg.APIAddr != "" {
+		apiServer := api.New(cfg.APIAddr, b.Handlers(), sched.Notify)
+		services.Go(func() {
+			if err := apiServer.Start(ctx); err != nil {
+				log.Printf("API server error: %v", err)
+			}
+		})
+	}
+
+	// Handle graceful shutdown: stop polling, then drain in-flight updates and
+	// scheduler sends within ShutdownTimeout
+	stopping := make(chan time.Time, 1)
 	go func() {
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 		log.Println("Shutting down...")
+		stopping <- time.Now()
 		cancel()
 	}()
 
 	log.Println("Starting bot...")
-	if err := b.Start(ctx); err != nil && err != context.Canceled {
+	if err := b.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		log.Fatalf("Bot error: %v", err)
 	}
+
+	deadline := time.Now().Add(cfg.ShutdownTimeout)
+	select {
+	case stoppedAt := <-stopping:
+		deadline = stoppedAt.Add(cfg.ShutdownTimeout)
+	default:
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		services.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Println("Shutdown complete")
+	case <-time.After(time.Until(deadline)):
+		log.Println("Shutdown timed out, closing with work still in progress")
+	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/export"
+)
+
+func (a *app) dump(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	output := fs.String("o", "", "output file (default stdout)")
+	userID, err := parseUserIDWithFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	archive, err := export.New(a.db).Collect(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Dumped %d rows of user %d to %s\n", archive.Count(), userID, *output)
+	}
+	return nil
+}
+
+func (a *app) restore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "delete the existing user and all of their data first")
+
+	// Accept the file before or after the flags
+	var path string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		path, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		return errors.New("dump file is required")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	archive, err := export.ReadArchive(f)
+	if err != nil {
+		return err
+	}
+
+	if err := export.New(a.db).Restore(ctx, archive, *replace); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d rows of user %d (exported %s)\n",
+		archive.Count(), archive.User.UserID, archive.ExportedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,150 @@
+// Command lifelinectl is the operations CLI for LifeLine.
+// It talks to the same database as the bot and uses DATABASE_URI and
+// TELEGRAM_TOKEN from the environment (or .env).
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/config"
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/hray3182/LifeLine/internal/scheduler"
+)
+
+const usage = `Usage: lifelinectl <command> [arguments]
+
+Migrations:
+  migrate                         Apply pending migrations
+  migrate status                  Show applied and pending migrations
+
+Users:
+  users                           List users and their settings
+  user <user_id>                  Show a user's settings and data counts
+  schedule <user_id> [-days N]    Print a user's daily summary and upcoming schedule
+  summary <user_id>               Send the daily summary to a user now
+
+Reminders:
+  reminders <user_id>             List a user's reminders
+  reminders resend <reminder_id>  Send a reminder again now
+  reminders cancel <reminder_id>  Disable a reminder
+
+Maintenance:
+  sessions purge                  Delete revoked and expired API tokens
+
+Data:
+  dump <user_id> [-o file]        Write a user's data as JSON (default stdout)
+  restore <file> [-replace]       Restore a user's data from a dump
+`
+
+// app holds the shared dependencies of all commands
+type app struct {
+	cfg *config.Config
+	db  *database.DB
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "lifelinectl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.DatabaseURI == "" {
+		return fmt.Errorf("DATABASE_URI is required")
+	}
+
+	db, err := database.New(ctx, cfg.DatabaseURI)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	a := &app{cfg: cfg, db: db}
+
+	switch command {
+	case "migrate":
+		return a.migrate(ctx, args)
+	case "users":
+		return a.listUsers(ctx)
+	case "user":
+		return a.showUser(ctx, args)
+	case "schedule":
+		return a.schedule(ctx, args)
+	case "summary":
+		return a.summary(ctx, args)
+	case "reminders":
+		return a.reminders(ctx, args)
+	case "sessions":
+		return a.sessions(ctx, args)
+	case "dump":
+		return a.dump(ctx, args)
+	case "restore":
+		return a.restore(ctx, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// scheduler builds a scheduler that can send Telegram messages on behalf of the bot
+func (a *app) scheduler() (*scheduler.Scheduler, error) {
+	if a.cfg.TelegramToken == "" {
+		return nil, fmt.Errorf("TELEGRAM_TOKEN is required for this command")
+	}
+	api, err := tgbotapi.NewBotAPI(a.cfg.TelegramToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Telegram API: %w", err)
+	}
+	return a.newScheduler(api), nil
+}
+
+// offlineScheduler builds a scheduler without a Telegram connection, for rendering only
+func (a *app) offlineScheduler() *scheduler.Scheduler {
+	return a.newScheduler(nil)
+}
+
+func (a *app) newScheduler(api *tgbotapi.BotAPI) *scheduler.Scheduler {
+	s := scheduler.New(api,
+		repository.NewReminderRepository(a.db),
+		repository.NewEventRepository(a.db),
+		repository.NewTodoRepository(a.db),
+		repository.NewUserSettingsRepository(a.db),
+	)
+	s.SetProjects(repository.NewProjectRepository(a.db))
+	return s
+}
+
+// formatTime formats an optional timestamp for table output
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
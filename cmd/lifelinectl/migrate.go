@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func (a *app) migrate(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "status" {
+		return a.migrationStatus(ctx)
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unknown migrate subcommand %q", args[0])
+	}
+
+	if err := a.db.Migrate(ctx); err != nil {
+		return err
+	}
+	fmt.Println("Database migrations completed")
+	return nil
+}
+
+func (a *app) migrationStatus(ctx context.Context) error {
+	statuses, err := a.db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT")
+	for _, st := range statuses {
+		status := "pending"
+		appliedAt := "-"
+		if st.Applied {
+			status = "applied"
+			if st.AppliedAt != nil {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+		}
+		if !st.Embedded {
+			status += " (unknown to this binary)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", st.Version, status, appliedAt)
+	}
+	return w.Flush()
+}
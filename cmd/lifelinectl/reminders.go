@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/hray3182/LifeLine/internal/repository"
+)
+
+func (a *app) reminders(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: reminders <user_id> | reminders resend <reminder_id> | reminders cancel <reminder_id>")
+	}
+
+	switch args[0] {
+	case "resend", "cancel":
+		if len(args) < 2 {
+			return fmt.Errorf("reminder_id is required")
+		}
+		reminderID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid reminder_id %q", args[1])
+		}
+		if args[0] == "resend" {
+			return a.resendReminder(ctx, reminderID)
+		}
+		return a.cancelReminder(ctx, reminderID)
+	default:
+		return a.listReminders(ctx, args)
+	}
+}
+
+func (a *app) listReminders(ctx context.Context, args []string) error {
+	userID, err := parseUserID(args)
+	if err != nil {
+		return err
+	}
+
+	reminders, err := repository.NewReminderRepository(a.db).GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tENABLED\tREMIND AT\tNOTIFIED\tACKNOWLEDGED\tRRULE\tMESSAGE")
+	for _, r := range reminders {
+		fmt.Fprintf(w, "%d\t%t\t%s\t%s\t%s\t%s\t%s\n", r.ReminderID, r.Enabled,
+			formatTime(r.RemindAt), formatTime(r.NotifiedAt), formatTime(r.AcknowledgedAt),
+			orDash(r.RecurrenceRule), r.Messages)
+	}
+	return w.Flush()
+}
+
+func (a *app) resendReminder(ctx context.Context, reminderID int) error {
+	sched, err := a.scheduler()
+	if err != nil {
+		return err
+	}
+	if err := sched.ResendReminder(ctx, reminderID); err != nil {
+		return err
+	}
+	fmt.Printf("Resent reminder %d\n", reminderID)
+	return nil
+}
+
+func (a *app) cancelReminder(ctx context.Context, reminderID int) error {
+	repo := repository.NewReminderRepository(a.db)
+	reminder, err := repo.GetByIDOnly(ctx, reminderID)
+	if err != nil {
+		return fmt.Errorf("failed to get reminder: %w", err)
+	}
+	if err := repo.SetEnabled(ctx, reminderID, reminder.UserID, false); err != nil {
+		return err
+	}
+	fmt.Printf("Cancelled reminder %d of user %d\n", reminderID, reminder.UserID)
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/repository"
+)
+
+// sessions manages persisted sessions. AI conversation sessions live in the bot's
+// memory and expire on their own, so only API tokens are stored in the database.
+func (a *app) sessions(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		return errors.New("usage: sessions purge")
+	}
+
+	n, err := repository.NewAPITokenRepository(a.db).PurgeInactive(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d revoked or expired API tokens\n", n)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/export"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+func (a *app) listUsers(ctx context.Context) error {
+	users, err := repository.NewUserRepository(a.db).GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	settingsRepo := repository.NewUserSettingsRepository(a.db)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USER ID\tUSERNAME\tTIMEZONE\tTODO REMINDERS\tDAILY SUMMARY\tQUIET HOURS")
+	for _, u := range users {
+		settings, err := settingsRepo.GetByUserID(ctx, u.UserID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			fmt.Fprintf(w, "%d\t%s\t-\t-\t-\t-\n", u.UserID, u.UserName)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get settings for %d: %w", u.UserID, err)
+		}
+		summary := "off"
+		if settings.DailySummaryEnabled {
+			summary = settings.DailySummaryTime
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%s\t%s-%s\n", u.UserID, u.UserName, settings.Timezone,
+			settings.TodoRemindersEnabled, summary, settings.QuietStart, settings.QuietEnd)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d users\n", len(users))
+	return nil
+}
+
+func (a *app) showUser(ctx context.Context, args []string) error {
+	userID, err := parseUserID(args)
+	if err != nil {
+		return err
+	}
+
+	archive, err := export.New(a.db).Collect(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("User:          %d (%s)\n", archive.User.UserID, archive.User.UserName)
+	if s := archive.Settings; s != nil {
+		fmt.Printf("Timezone:      %s\n", s.Timezone)
+		fmt.Printf("Quiet hours:   %s - %s\n", s.QuietStart, s.QuietEnd)
+		fmt.Printf("Todo reminders: %t (max %d/day, intervals %+v)\n", s.TodoRemindersEnabled, s.MaxDailyReminders, s.ReminderIntervals)
+		fmt.Printf("Daily summary: %t at %s", s.DailySummaryEnabled, s.DailySummaryTime)
+		if s.LastDailySummaryDate != nil {
+			fmt.Printf(" (last sent %s)", s.LastDailySummaryDate.Format("2006-01-02"))
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("Settings:      (defaults, not stored yet)")
+	}
+	fmt.Println()
+	fmt.Printf("Memos: %d, todos: %d, reminders: %d, events: %d, transactions: %d, categories: %d\n",
+		len(archive.Memos), len(archive.Todos), len(archive.Reminders), len(archive.Events),
+		len(archive.Transactions), len(archive.Categories))
+	return nil
+}
+
+// schedule prints what the user would see in the daily summary, followed by upcoming events and reminders
+func (a *app) schedule(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("schedule", flag.ContinueOnError)
+	days := fs.Int("days", 7, "number of days of upcoming events to list")
+	userID, err := parseUserIDWithFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// The summary text is rendered without a Telegram connection
+	sched := a.offlineScheduler()
+
+	now := time.Now()
+	text, err := sched.BuildDailySummary(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	fmt.Println(format.ParseMarkdown(text).Text)
+
+	events, err := calendar.New(repository.NewEventRepository(a.db)).Occurrences(ctx, userID, now, now.AddDate(0, 0, *days))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n--- Events in the next %d days ---\n", *days)
+	if len(events) == 0 {
+		fmt.Println("(none)")
+	}
+	for _, ev := range events {
+		fmt.Printf("#%d  %s  %s", ev.EventID, ev.NextOccurrence.Format("2006-01-02 15:04"), ev.Title)
+		if ev.IsRecurring() {
+			fmt.Printf("  [%s]", ev.RecurrenceRule)
+		}
+		fmt.Println()
+	}
+
+	reminders, err := repository.NewReminderRepository(a.db).GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n--- Pending reminders ---")
+	pending := 0
+	for _, r := range reminders {
+		if !r.Enabled || r.RemindAt == nil || r.AcknowledgedAt != nil {
+			continue
+		}
+		pending++
+		fmt.Printf("#%d  %s  %s\n", r.ReminderID, r.RemindAt.Format("2006-01-02 15:04"), r.Messages)
+	}
+	if pending == 0 {
+		fmt.Println("(none)")
+	}
+	return nil
+}
+
+func (a *app) summary(ctx context.Context, args []string) error {
+	userID, err := parseUserID(args)
+	if err != nil {
+		return err
+	}
+
+	sched, err := a.scheduler()
+	if err != nil {
+		return err
+	}
+	if err := sched.SendDailySummary(ctx, userID); err != nil {
+		return err
+	}
+	fmt.Printf("Sent daily summary to user %d\n", userID)
+	return nil
+}
+
+func parseUserID(args []string) (int64, error) {
+	if len(args) < 1 {
+		return 0, errors.New("user_id is required")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user_id %q", args[0])
+	}
+	return userID, nil
+}
+
+// parseUserIDWithFlags accepts the user ID before or after the flags
+func parseUserIDWithFlags(fs *flag.FlagSet, args []string) (int64, error) {
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		userID, err := parseUserID(args)
+		if err != nil {
+			return 0, err
+		}
+		return userID, fs.Parse(args[1:])
+	}
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	return parseUserID(fs.Args())
+}
@@ -0,0 +1,70 @@
+// Package access decides who may use the bot.
+package access
+
+import "fmt"
+
+// Mode selects who may use the bot besides admins
+type Mode string
+
+const (
+	// ModeOpen lets everyone in
+	ModeOpen Mode = "open"
+	// ModeAllowlist admits users listed in config or approved by an admin
+	ModeAllowlist Mode = "allowlist"
+	// ModeInvite admits users who redeemed an invite code or were approved by an admin
+	ModeInvite Mode = "invite"
+)
+
+// ParseMode parses ACCESS_MODE; empty means open
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOpen:
+		return ModeOpen, nil
+	case ModeAllowlist, ModeInvite:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown access mode %q (want open, allowlist or invite)", s)
+}
+
+// Policy is the access configuration. Admins are always allowed and cannot be banned.
+type Policy struct {
+	Mode    Mode
+	admins  map[int64]bool
+	allowed map[int64]bool
+}
+
+// NewPolicy builds a policy from the configured admin and allowlisted user IDs
+func NewPolicy(mode Mode, admins, allowed []int64) *Policy {
+	p := &Policy{
+		Mode:    mode,
+		admins:  make(map[int64]bool, len(admins)),
+		allowed: make(map[int64]bool, len(allowed)),
+	}
+	for _, id := range admins {
+		p.admins[id] = true
+	}
+	for _, id := range allowed {
+		p.allowed[id] = true
+	}
+	return p
+}
+
+// IsAdmin reports whether the user has the admin role
+func (p *Policy) IsAdmin(userID int64) bool {
+	return p != nil && p.admins[userID]
+}
+
+// Allows reports whether a user may use the bot given their stored state.
+// A nil policy is open.
+func (p *Policy) Allows(userID int64, approved, banned bool) bool {
+	if p.IsAdmin(userID) {
+		return true
+	}
+	if banned {
+		return false
+	}
+	if p == nil || p.Mode == ModeOpen {
+		return true
+	}
+	return approved || p.allowed[userID]
+}
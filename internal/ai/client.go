@@ -6,12 +6,18 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hray3182/LifeLine/internal/metrics"
 	"github.com/sashabaranov/go-openai"
 )
 
 type Client struct {
-	client *openai.Client
-	model  string
+	client  *openai.Client
+	model   string
+	tracker UsageTracker
+
+	// Prices in USD per million tokens, used to estimate the cost of a call
+	promptPrice     float64
+	completionPrice float64
 }
 
 func New(apiKey, baseURL, model string) *Client {
@@ -28,6 +34,58 @@ func (c *Client) SetModel(model string) {
 	c.model = model
 }
 
+// SetUsageTracker sets the tracker that enforces quotas and records token usage
+func (c *Client) SetUsageTracker(t UsageTracker) {
+	c.tracker = t
+}
+
+// SetPricing sets the prices in USD per million prompt and completion tokens
+func (c *Client) SetPricing(promptPerMillion, completionPerMillion float64) {
+	c.promptPrice = promptPerMillion
+	c.completionPrice = completionPerMillion
+}
+
+// createChatCompletion calls the AI API and records latency, failures and
+// token usage under the given call name. Calls for a user over quota fail
+// with an error wrapping ErrQuotaExceeded without reaching the API.
+func (c *Client) createChatCompletion(ctx context.Context, call string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	userID, _ := UserIDFromContext(ctx)
+	if c.tracker != nil && userID != 0 {
+		if err := c.tracker.CheckQuota(ctx, userID); err != nil {
+			metrics.AIRequestFailures.WithLabelValues(call, "quota").Inc()
+			return openai.ChatCompletionResponse{}, err
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.client.CreateChatCompletion(ctx, req)
+	metrics.AIRequestDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		metrics.AIRequestFailures.WithLabelValues(call, "api").Inc()
+		return resp, err
+	}
+
+	metrics.AITokens.WithLabelValues(call, "prompt").Add(float64(resp.Usage.PromptTokens))
+	metrics.AITokens.WithLabelValues(call, "completion").Add(float64(resp.Usage.CompletionTokens))
+	if c.tracker != nil {
+		c.tracker.RecordUsage(ctx, Usage{
+			UserID:           userID,
+			Call:             call,
+			Model:            req.Model,
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			Cost: (float64(resp.Usage.PromptTokens)*c.promptPrice +
+				float64(resp.Usage.CompletionTokens)*c.completionPrice) / 1e6,
+		})
+	}
+
+	if len(resp.Choices) == 0 {
+		metrics.AIRequestFailures.WithLabelValues(call, "empty").Inc()
+	}
+	return resp, nil
+}
+
 // ActionItem represents a single action in multi-action requests
 type ActionItem struct {
 	Action     string            `json:"action"`
@@ -80,30 +138,61 @@ const systemPromptTemplate = `你是 LifeLine，一個專業的個人生活助
 - create_memo: 建立備忘錄
 - list_memo: 列出備忘錄 (可帶 keyword 搜尋)
 - delete_memo: 刪除備忘錄
-- create_todo: 建立待辦事項
-- list_todo: 列出待辦事項 (可帶 keyword 搜尋)
+- create_todo: 建立待辦事項 (可帶 project 放入專案，或 parent_id 建立為子任務)
+- list_todo: 列出待辦事項 (可帶 keyword 搜尋，或 project 只列出某專案)
 - complete_todo: 完成待辦事項
 - delete_todo: 刪除待辦事項
-- update_todo: 更新待辦事項
+- update_todo: 更新待辦事項 (可帶 blocked_by 設定前置工作，或 status 移動看板狀態)
+- assign_todo: 將待辦事項指派給其他人（「把 #12 交給 @amy」）
+  * id: 待辦事項編號
+  * assignee: 指派對象的 Telegram 使用者名稱，例如 "@amy"
 - create_reminder: 建立提醒
 - list_reminder: 列出提醒 (可帶 keyword 搜尋)
-- delete_reminder: 刪除提醒
+- delete_reminder: 刪除提醒 (重複提醒可帶 scope、occurrence)
+- update_reminder: 更新提醒 (可帶 message、dtstart、rrule；重複提醒可帶 scope、occurrence)
 - create_expense: 記錄支出
 - create_income: 記錄收入
 - list_transaction: 列出交易記錄 (可帶 keyword 搜尋)
 - delete_transaction: 刪除交易記錄
 - get_balance: 查看收支統計
+- split_expense: 記錄多人分攤的支出（「我付了晚餐 1200，4 個人平分」）
+  * participants: 參與者，逗號分隔，例如 "@amy,@bob"；「我」代表用戶本人
+  * 百分比分攤: "@amy:60%%,@bob:40%%"；指定金額: "@amy:300,@bob:200"
+  * 平分時付款人自動包含在內；百分比或指定金額未分配的部分由付款人負擔
+  * paid_by: 付款人（預設為用戶本人）
+- list_debts: 查看分帳餘額（誰欠誰）與建議還款方式
+- settle_debt: 記錄還款
+  * to: 收款人，例如 "@amy"
+  * from: 付款人（預設為用戶本人）
+  * amount: 金額（省略時依建議還款方式結清）
 - create_event: 建立事件
+  * 全天或跨日事件（假日、生日、旅行、截止日）：all_day="true"，dtstart 只給日期 YYYY-MM-DD，跨日時 end_date 為最後一天 (YYYY-MM-DD，含當天)
+  * busy: 全天事件預設不佔用時間（假日、生日、截止日）；旅行、出差等整天都沒空的事件設 busy="true"
+  * 用戶說「12/24 到 12/26 去東京玩」→ create_event, title="東京旅行", all_day="true", dtstart="2025-12-24", end_date="2025-12-26", busy="true"
+  * 用戶說「1/1 元旦放假」→ create_event, title="元旦", all_day="true", dtstart="2026-01-01"
 - list_event: 列出事件 (可帶 keyword 搜尋，或用 date/start_date/end_date 篩選日期)
-- delete_event: 刪除事件
-- update_event: 更新事件
+- delete_event: 刪除事件 (重複事件可帶 scope、occurrence)
+- update_event: 更新事件 (重複事件可帶 scope、occurrence；all_day="false" 改回有時間的事件)
+  * 建立事件或更改事件時間時，系統會檢查是否與其他行程重疊，並讓用戶選擇保留兩者、改到建議時段或取消，不需要 confirmation
 - query_schedule: 查詢行程（用於「明天要幹嘛」「這週有什麼事」等問題，會搜尋事件、待辦、提醒）
   * 重要：必須提供 date 參數，格式為 YYYY-MM-DD
   * 「明天」→ date 必須設為當前日期 +1 天的具體日期
   * 「後天」→ date 必須設為當前日期 +2 天的具體日期
   * 「這週」→ 使用 start_date 和 end_date 指定範圍
 - find_free_time: 尋找空閒時間（用於「明天什麼時候有空」「下週有空的時間」等問題）
-  * 重要：必須提供 date 參數，格式為 YYYY-MM-DD
+  * 單日：提供 date 參數，格式為 YYYY-MM-DD
+  * 多日：使用 start_date 和 end_date 指定範圍（最多 31 天）
+  * min_minutes: 需要的最短時長（分鐘），例如「找個兩小時的空檔」→ 120
+  * title: 要安排的事件標題（例如「看牙醫」），用戶點選建議時段後會直接建立此事件
+  * count: 建議時段數量（預設 5）
+  * 會依用戶的工作時段、會議間緩衝與勿擾時段計算，並包含重複事件
+- plan_schedule: 把未完成的待辦排進空閒時間（「幫我排今天的行程」「規劃這週要做的事」）
+  * range: "day" 或 "week"（從今天起 7 天）
+  * date: 只排某一天時的日期，格式為 YYYY-MM-DD
+  * 系統會提出排程建議，用戶按下接受後才會建立時間區塊事件，不需要 confirmation
+- search: 同時搜尋備忘錄、待辦、提醒、事件與交易記錄（「找一下跟牙醫有關的東西」「之前記過 wifi 密碼嗎」）
+  * keyword: 搜尋關鍵字
+  * 不確定要查哪一類時使用 search；明確指定類別時使用對應的 list_ action
 - unknown: 無法識別
 
 Event、Todo、Reminder 的區分（重要）：
@@ -153,6 +242,8 @@ Event、Todo、Reminder 的區分（重要）：
 根據 action 類型，parameters 可能包含：
 - id: 項目編號 (用於刪除、更新、完成操作)
 - keyword: 搜尋關鍵字 (用於 list_* 操作，搜尋標題、內容、描述、標籤)
+- tag: 標籤名稱 (用於 list_memo、list_todo、list_reminder、list_transaction、list_event，只列出有此標籤的項目，可與 keyword 一起使用)
+  * 用戶說「列出工作標籤的待辦」「#旅遊 的花費」時使用 tag，不要放在 keyword
 - date: 指定日期篩選 (用於 list_event，格式: YYYY-MM-DD)
 - start_date: 日期範圍起始 (用於 list_event，格式: YYYY-MM-DD)
 - end_date: 日期範圍結束 (用於 list_event，格式: YYYY-MM-DD)
@@ -175,9 +266,35 @@ Event、Todo、Reminder 的區分（重要）：
   * 表示「事情在這個時間發生/開始」
   * 範例：會議在下午 3 點「開始」
 - rrule: RFC 5545 重複規則 (用於 reminder 和 event 的重複設定)
+- alerts: 事件開始前幾分鐘提醒，多個以逗號分隔 (用於 create_event、update_event)
+  * 用戶說「提前一天和半小時提醒我」→ alerts="1440,30"
+  * 用戶說「開始前 10 分鐘和 1 小時提醒」→ alerts="60,10"；「準時提醒」→ alerts="0"；「不用提醒」→ alerts="none"
+  * 用戶沒提到提醒時省略，會使用用戶設定的預設提醒；最多 5 個，改提醒時會套用到整個重複事件
 - amount: 金額
 - category: 分類
-- tags: 標籤
+- tags: 標籤，多個以逗號分隔 (建立或更新時使用)
+- project: 專案名稱 (用於 create_todo、list_todo)
+  * 用戶說「在日本旅行專案加上買票」→ create_todo, title="買票", project="日本旅行"
+  * 專案不存在時會自動建立
+- parent_id: 上層待辦事項編號 (用於 create_todo 建立子任務)
+  * 用戶說「在 #12 底下加上訂飯店」→ create_todo, title="訂飯店", parent_id="12"
+- blocked_by: 必須先完成的待辦事項編號，多個以逗號分隔 (用於 create_todo、update_todo)
+  * 用戶說「#12 要等 #10 做完才能開始」→ update_todo, id="12", blocked_by="10"
+  * 被阻擋的待辦不會提醒，前置工作全部完成後會通知用戶
+- estimated_minutes: 待辦預估需要的分鐘數 (用於 create_todo、update_todo，排程時使用)
+  * 用戶說「寫報告大概要兩小時」→ estimated_minutes="120"
+- status: 待辦的看板狀態 (用於 update_todo)，可用值: inbox, next, doing, waiting, done, cancelled
+  * 用戶說「#12 開始做了」→ update_todo, id="12", status="doing"
+  * 用戶說「#8 在等廠商回覆」→ update_todo, id="8", status="waiting"（等待中的待辦不會提醒）
+  * 用戶說「#5 不做了」→ update_todo, id="5", status="cancelled"
+- scope: 修改或刪除重複事件/提醒的範圍 (用於 update_event、delete_event、update_reminder、delete_reminder)
+  * this: 只有指定的那一次；following: 指定的那次及之後；all: 整個系列
+  * 只有用戶明確說出範圍時才設定（「只有這週」「從下次開始都」「全部」），否則省略，系統會詢問用戶
+- occurrence: 要修改的是哪一次，格式為 YYYY-MM-DD 或 YYYY-MM-DD HH:MM（省略時為下一次）
+  * 用戶說「這週五的晨會取消」→ delete_event, id="3", scope="this", occurrence="2025-12-19"
+  * 用戶說「下週一的晨會改到 10 點」→ update_event, id="3", scope="this", occurrence="2025-12-22", dtstart="2025-12-22 10:00"
+  * 用戶說「從下個月開始晨會改到 9:30」→ update_event, id="3", scope="following", occurrence="2026-01-05", dtstart="2026-01-05 09:30"
+  * scope="this" 時 dtstart 是那一次的新時間，title/message 與 duration 只改那一次
 
 重要規則：
 1. 時間處理（極重要）：
@@ -304,16 +421,14 @@ Event、Todo、Reminder 的區分（重要）：
 
 9. 尋找空閒時間並安排事件：
    - 當用戶說「明天什麼時候有空，我要去看牙醫」這類請求時：
-     1. 先用 find_free_time 查詢指定日期的空閒時段
-     2. 設定 return_result_to_ai=true，讓結果返回給你
-     3. 收到空閒時段後，向用戶提供選項或建議時間
-     4. 用戶選擇後，建立事件
+     1. 用 find_free_time 查詢，並在 title 填入要安排的事件、min_minutes 填入所需時長
+     2. 系統會列出建議時段按鈕，用戶點選後直接建立事件，不需要再 create_event
    - 範例流程：
      * 用戶：「明天什麼時候有空，我要去看牙醫」
-     * AI：action=find_free_time, parameters={date: "2025-12-18"}, return_result_to_ai=true
-     * 系統返回：「空閒時段: 09:00-10:30, 14:00-17:00」
-     * AI：提供 confirmation_options 讓用戶選擇時間，並記住要建立「看牙醫」事件
-     * 用戶選擇後：action=create_event, parameters={title: "看牙醫", dtstart: "選擇的時間"}
+     * AI：action=find_free_time, parameters={date: "2025-12-18", title: "看牙醫", min_minutes: "60"}
+     * 用戶：「下週找個兩小時的空檔寫報告」
+     * AI：action=find_free_time, parameters={start_date: "下週一", end_date: "下週日", title: "寫報告", min_minutes: "120"}（日期需換算為 YYYY-MM-DD）
+   - 只有在用戶要你根據空閒時間回答其他問題時，才設定 return_result_to_ai=true 讓結果返回給你
 
 10. 複合語句拆解（Event + Todo）：
    - 用戶的一句話可能同時包含 Event 和 Todo，必須拆解並使用 multi_action
@@ -357,7 +472,7 @@ var intentSchema = json.RawMessage(`{
 	"properties": {
 		"action": {
 			"type": "string",
-			"enum": ["create_memo", "list_memo", "delete_memo", "create_todo", "list_todo", "complete_todo", "delete_todo", "update_todo", "create_reminder", "list_reminder", "delete_reminder", "create_expense", "create_income", "list_transaction", "delete_transaction", "get_balance", "create_event", "list_event", "delete_event", "update_event", "query_schedule", "find_free_time", "multi_action", "unknown"],
+			"enum": ["create_memo", "list_memo", "delete_memo", "create_todo", "list_todo", "complete_todo", "delete_todo", "update_todo", "assign_todo", "create_reminder", "list_reminder", "delete_reminder", "update_reminder", "create_expense", "create_income", "list_transaction", "delete_transaction", "get_balance", "split_expense", "list_debts", "settle_debt", "create_event", "list_event", "delete_event", "update_event", "query_schedule", "find_free_time", "plan_schedule", "search", "multi_action", "unknown"],
 			"description": "The action to perform. Use multi_action when multiple operations are needed. Use query_schedule when user asks about their schedule."
 		},
 		"entity": {
@@ -455,7 +570,7 @@ var intentSchema = json.RawMessage(`{
 }`)
 
 func (c *Client) ParseIntent(ctx context.Context, userMessage string) (*Intent, error) {
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	resp, err := c.createChatCompletion(ctx, "parse_intent", openai.ChatCompletionRequest{
 		Model: c.model,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -489,6 +604,7 @@ func (c *Client) ParseIntent(ctx context.Context, userMessage string) (*Intent,
 	intent := &Intent{RawResponse: content}
 
 	if err := json.Unmarshal([]byte(content), intent); err != nil {
+		metrics.AIRequestFailures.WithLabelValues("parse_intent", "parse").Inc()
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
@@ -496,7 +612,11 @@ func (c *Client) ParseIntent(ctx context.Context, userMessage string) (*Intent,
 }
 
 func (c *Client) GenerateResponse(ctx context.Context, systemMsg, userMsg string) (string, error) {
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	return c.generateResponse(ctx, "generate_response", systemMsg, userMsg)
+}
+
+func (c *Client) generateResponse(ctx context.Context, call, systemMsg, userMsg string) (string, error) {
+	resp, err := c.createChatCompletion(ctx, call, openai.ChatCompletionRequest{
 		Model: c.model,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -537,11 +657,15 @@ const formatQueryResultPrompt = `你是 LifeLine，專業的個人生活助理
 func (c *Client) FormatQueryResult(ctx context.Context, queryType, dateRange, rawData string) (string, error) {
 	prompt := fmt.Sprintf("查詢類型: %s\n日期範圍: %s\n\n查詢結果:\n%s", queryType, dateRange, rawData)
 
-	return c.GenerateResponse(ctx, formatQueryResultPrompt, prompt)
+	return c.generateResponse(ctx, "format_query_result", formatQueryResultPrompt, prompt)
 }
 
 // ParseIntentWithHistory parses intent using conversation history for multi-turn conversations
 func (c *Client) ParseIntentWithHistory(ctx context.Context, history []Message) (*Intent, error) {
+	return c.parseIntentWithHistory(ctx, "parse_intent_history", history)
+}
+
+func (c *Client) parseIntentWithHistory(ctx context.Context, call string, history []Message) (*Intent, error) {
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
@@ -556,7 +680,7 @@ func (c *Client) ParseIntentWithHistory(ctx context.Context, history []Message)
 		})
 	}
 
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+	resp, err := c.createChatCompletion(ctx, call, openai.ChatCompletionRequest{
 		Model:    c.model,
 		Messages: messages,
 		ResponseFormat: &openai.ChatCompletionResponseFormat{
@@ -581,6 +705,7 @@ func (c *Client) ParseIntentWithHistory(ctx context.Context, history []Message)
 	intent := &Intent{RawResponse: content}
 
 	if err := json.Unmarshal([]byte(content), intent); err != nil {
+		metrics.AIRequestFailures.WithLabelValues(call, "parse").Inc()
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
@@ -595,5 +720,5 @@ func (c *Client) ContinueWithToolResult(ctx context.Context, history []Message,
 		Content: fmt.Sprintf("[工具執行結果]\n%s", toolResult),
 	})
 
-	return c.ParseIntentWithHistory(ctx, history)
+	return c.parseIntentWithHistory(ctx, "tool_continuation", history)
 }
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrQuotaExceeded is wrapped by UsageTracker errors when a user is out of AI quota
+var ErrQuotaExceeded = errors.New("AI quota exceeded")
+
+// Usage is the token usage and estimated cost of one AI call
+type Usage struct {
+	UserID           int64 // 0 when the call was not made on behalf of a user
+	Call             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // USD, estimated from the configured prices
+}
+
+// UsageTracker enforces quotas before and records usage after every AI call
+type UsageTracker interface {
+	// CheckQuota returns an error wrapping ErrQuotaExceeded when the user may not call the AI
+	CheckQuota(ctx context.Context, userID int64) error
+	RecordUsage(ctx context.Context, usage Usage)
+}
+
+type userIDKey struct{}
+
+// WithUserID attributes AI calls made with the context to a user
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the user set by WithUserID
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	return userID, ok
+}
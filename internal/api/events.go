@@ -0,0 +1,243 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type eventRequest struct {
+	Title               *string    `json:"title"`
+	Description         *string    `json:"description"`
+	Dtstart             *time.Time `json:"dtstart"`
+	Duration            *int       `json:"duration"`
+	Alerts              *[]int     `json:"alerts"`               // minutes before each occurrence to notify
+	NotificationMinutes *int       `json:"notification_minutes"` // a single alert, older form of alerts
+	RecurrenceRule      *string    `json:"recurrence_rule"`
+	Tags                *string    `json:"tags"`
+	AllDay              *bool      `json:"all_day"`     // duration then counts whole days, one by default
+	Transparent         *bool      `json:"transparent"` // leaves its time free
+}
+
+// alerts returns the alerts of the request, nil when it has none
+func (req *eventRequest) alerts() ([]int, error) {
+	var alerts []int
+	switch {
+	case req.Alerts != nil:
+		alerts = *req.Alerts
+	case req.NotificationMinutes != nil:
+		alerts = []int{*req.NotificationMinutes}
+	default:
+		return nil, nil
+	}
+	for _, minutes := range alerts {
+		if minutes < 0 || minutes > models.MaxAlertMinutes {
+			return nil, fmt.Errorf("alerts must be between 0 and %d minutes", models.MaxAlertMinutes)
+		}
+	}
+	alerts = models.NormalizeAlerts(alerts)
+	if len(alerts) > models.MaxAlerts {
+		return nil, fmt.Errorf("at most %d alerts are allowed", models.MaxAlerts)
+	}
+	if alerts == nil {
+		alerts = []int{}
+	}
+	return alerts, nil
+}
+
+// listEvents supports ?q=keyword or ?start=YYYY-MM-DD&end=YYYY-MM-DD
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	start, err := parseDateParam(r, "start")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseDateParam(r, "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var events []*models.Event
+	switch {
+	case start != nil || end != nil:
+		from := time.Now()
+		if start != nil {
+			from = *start
+		}
+		to := from.AddDate(1, 0, 0)
+		if end != nil {
+			to = end.Add(24*time.Hour - time.Second)
+		}
+		events, err = s.calendar.Occurrences(r.Context(), userID(r), from, to)
+	case r.URL.Query().Get("q") != "":
+		events, err = s.repos.Event.Search(r.Context(), userID(r), r.URL.Query().Get("q"))
+	default:
+		events, err = s.repos.Event.GetByUserID(r.Context(), userID(r))
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (s *Server) createEvent(w http.ResponseWriter, r *http.Request) {
+	var req eventRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Title == nil || *req.Title == "" {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	alerts, err := req.alerts()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var description, rruleStr, tags string
+	var duration int
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.RecurrenceRule != nil {
+		rruleStr = *req.RecurrenceRule
+	}
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+	if req.Duration != nil {
+		duration = *req.Duration
+	}
+
+	var event *models.Event
+	if req.AllDay != nil && *req.AllDay {
+		if req.Dtstart == nil {
+			writeError(w, http.StatusBadRequest, "dtstart is required for all-day events")
+			return
+		}
+		// CreateAllDayEvent notifies the scheduler itself
+		event, err = s.handlers.CreateAllDayEvent(r.Context(), userID(r), userID(r), *req.Title, description, *localTime(req.Dtstart),
+			duration/models.MinutesPerDay, req.Transparent != nil && *req.Transparent, alerts, rruleStr, tags)
+	} else {
+		// CreateEvent notifies the scheduler itself
+		event, err = s.handlers.CreateEvent(r.Context(), userID(r), userID(r), *req.Title, description, localTime(req.Dtstart),
+			duration, alerts, rruleStr, tags)
+		if err == nil && req.Transparent != nil && *req.Transparent {
+			event.Transparent = true
+			err = s.repos.Event.Update(r.Context(), event)
+		}
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, event)
+}
+
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	event, err := s.repos.Event.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req eventRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	alerts, err := req.alerts()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	event, err := s.repos.Event.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Title != nil {
+		event.Title = *req.Title
+	}
+	if req.Description != nil {
+		event.Description = *req.Description
+	}
+	if req.Dtstart != nil {
+		event.Dtstart = localTime(req.Dtstart)
+	}
+	if req.Duration != nil {
+		event.Duration = *req.Duration
+	}
+	if alerts != nil {
+		event.Alerts = alerts
+	}
+	if req.RecurrenceRule != nil {
+		event.RecurrenceRule = *req.RecurrenceRule
+	}
+	if req.Tags != nil {
+		event.Tags = *req.Tags
+	}
+	if req.Transparent != nil {
+		event.Transparent = *req.Transparent
+	}
+	if req.AllDay != nil && *req.AllDay != event.AllDay {
+		event.AllDay = *req.AllDay
+		if event.AllDay && event.Dtstart != nil {
+			day := time.Date(event.Dtstart.Year(), event.Dtstart.Month(), event.Dtstart.Day(), 0, 0, 0, 0, event.Dtstart.Location())
+			event.Dtstart = &day
+			event.Duration = max(event.Duration/models.MinutesPerDay, 1) * models.MinutesPerDay
+		}
+	}
+
+	// UpdateEvent recalculates the next occurrence and notifies the scheduler
+	if err := s.handlers.UpdateEvent(r.Context(), event); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+func (s *Server) deleteEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Event.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if err := s.repos.Event.Delete(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// localTime converts a client supplied time to local wall clock time, because
+// TIMESTAMP columns are stored without timezone in local time
+func localTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(time.Local)
+	return &local
+}
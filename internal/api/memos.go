@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type memoRequest struct {
+	Content *string `json:"content"`
+	Tags    *string `json:"tags"`
+}
+
+// listMemos supports ?q=keyword or ?limit=&offset= pagination
+func (s *Server) listMemos(w http.ResponseWriter, r *http.Request) {
+	var memos []*models.Memo
+	var err error
+	if keyword := r.URL.Query().Get("q"); keyword != "" {
+		memos, err = s.repos.Memo.Search(r.Context(), userID(r), keyword)
+	} else {
+		limit, offset := pagination(r)
+		memos, err = s.repos.Memo.GetByUserID(r.Context(), userID(r), limit, offset)
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, memos)
+}
+
+func (s *Server) createMemo(w http.ResponseWriter, r *http.Request) {
+	var req memoRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Content == nil || *req.Content == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	var tags string
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+
+	memo, err := s.handlers.CreateMemo(r.Context(), userID(r), userID(r), *req.Content, tags)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, memo)
+}
+
+func (s *Server) getMemo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	memo, err := s.repos.Memo.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, memo)
+}
+
+func (s *Server) updateMemo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req memoRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	memo, err := s.repos.Memo.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Content != nil {
+		memo.Content = *req.Content
+	}
+	if req.Tags != nil {
+		memo.Tags = *req.Tags
+	}
+
+	if err := s.repos.Memo.Update(r.Context(), memo); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, memo)
+}
+
+func (s *Server) deleteMemo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Memo.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if err := s.repos.Memo.Delete(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pagination reads ?limit= and ?offset=, defaulting to the first 20 rows
+func pagination(r *http.Request) (limit, offset int) {
+	limit = 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+		limit = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && v >= 0 {
+		offset = v
+	}
+	return limit, offset
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type reminderRequest struct {
+	Message        *string    `json:"message"`
+	Dtstart        *time.Time `json:"dtstart"`
+	RecurrenceRule *string    `json:"recurrence_rule"`
+	Description    *string    `json:"description"`
+	Tags           *string    `json:"tags"`
+	Enabled        *bool      `json:"enabled"`
+}
+
+func (s *Server) listReminders(w http.ResponseWriter, r *http.Request) {
+	var reminders []*models.Reminder
+	var err error
+	if keyword := r.URL.Query().Get("q"); keyword != "" {
+		reminders, err = s.repos.Reminder.Search(r.Context(), userID(r), keyword)
+	} else {
+		reminders, err = s.repos.Reminder.GetByUserID(r.Context(), userID(r))
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reminders)
+}
+
+func (s *Server) createReminder(w http.ResponseWriter, r *http.Request) {
+	var req reminderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Message == nil || *req.Message == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+	if req.Dtstart == nil {
+		writeError(w, http.StatusBadRequest, "dtstart is required")
+		return
+	}
+
+	var rruleStr string
+	if req.RecurrenceRule != nil {
+		rruleStr = *req.RecurrenceRule
+	}
+
+	// CreateReminder notifies the scheduler itself
+	reminder, err := s.handlers.CreateReminder(r.Context(), userID(r), userID(r), *req.Message, localTime(req.Dtstart), rruleStr)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, reminder)
+}
+
+func (s *Server) getReminder(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	reminder, err := s.repos.Reminder.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reminder)
+}
+
+func (s *Server) updateReminder(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req reminderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	reminder, err := s.repos.Reminder.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Message != nil {
+		reminder.Messages = *req.Message
+	}
+	if req.Dtstart != nil {
+		reminder.Dtstart = localTime(req.Dtstart)
+	}
+	if req.RecurrenceRule != nil {
+		reminder.RecurrenceRule = *req.RecurrenceRule
+	}
+	if req.Description != nil {
+		reminder.Description = *req.Description
+	}
+	if req.Tags != nil {
+		reminder.Tags = *req.Tags
+	}
+	if req.Enabled != nil {
+		reminder.Enabled = *req.Enabled
+	}
+
+	// Only reschedule when the timing changed, otherwise keep the current remind_at
+	if req.Dtstart != nil || req.RecurrenceRule != nil {
+		err = s.handlers.UpdateReminder(r.Context(), reminder)
+	} else {
+		err = s.repos.Reminder.Update(r.Context(), reminder)
+		if err == nil {
+			s.notifyScheduler()
+		}
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reminder)
+}
+
+func (s *Server) deleteReminder(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Reminder.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if err := s.repos.Reminder.Delete(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type balanceResponse struct {
+	Month   string  `json:"month"`
+	Income  float64 `json:"income"`
+	Expense float64 `json:"expense"`
+	Balance float64 `json:"balance"`
+}
+
+type scheduleResponse struct {
+	Start     time.Time          `json:"start"`
+	End       time.Time          `json:"end"`
+	Events    []*models.Event    `json:"events"`
+	Todos     []*models.Todo     `json:"todos"`
+	Reminders []*models.Reminder `json:"reminders"`
+}
+
+// getBalance returns income and expense totals for ?month=YYYY-MM, defaulting to the current month
+func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if v := r.URL.Query().Get("month"); v != "" {
+		t, err := time.ParseInLocation("2006-01", v, time.Local)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid month, expected YYYY-MM")
+			return
+		}
+		startOfMonth = t
+	}
+	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Second)
+
+	income, err := s.repos.Transaction.GetTotalByType(r.Context(), userID(r), startOfMonth, endOfMonth, models.TransactionTypeIncome)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	expense, err := s.repos.Transaction.GetTotalByType(r.Context(), userID(r), startOfMonth, endOfMonth, models.TransactionTypeExpense)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balanceResponse{
+		Month:   startOfMonth.Format("2006-01"),
+		Income:  income,
+		Expense: expense,
+		Balance: income - expense,
+	})
+}
+
+// getSchedule returns events, due todos and pending reminders between ?start= and ?end=
+// (YYYY-MM-DD, inclusive). Defaults to today.
+func (s *Server) getSchedule(w http.ResponseWriter, r *http.Request) {
+	start, err := parseDateParam(r, "start")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseDateParam(r, "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if start != nil {
+		from = *start
+	}
+	to := from
+	if end != nil {
+		to = *end
+	}
+	if to.Before(from) {
+		writeError(w, http.StatusBadRequest, "end must not be before start")
+		return
+	}
+	to = to.Add(24*time.Hour - time.Second)
+
+	resp := scheduleResponse{
+		Start:     from,
+		End:       to,
+		Events:    []*models.Event{},
+		Todos:     []*models.Todo{},
+		Reminders: []*models.Reminder{},
+	}
+
+	events, err := s.calendar.Occurrences(r.Context(), userID(r), from, to)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	resp.Events = append(resp.Events, events...)
+
+	todos, err := s.repos.Todo.GetByUserID(r.Context(), userID(r), false)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	for _, t := range todos {
+		if t.DueTime != nil && !t.DueTime.Before(from) && !t.DueTime.After(to) {
+			resp.Todos = append(resp.Todos, t)
+		}
+	}
+
+	reminders, err := s.repos.Reminder.GetByUserID(r.Context(), userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	for _, rem := range reminders {
+		if rem.Enabled && rem.RemindAt != nil && !rem.RemindAt.Before(from) && !rem.RemindAt.After(to) {
+			resp.Reminders = append(resp.Reminders, rem)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
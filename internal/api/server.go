@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/auth"
+	"github.com/hray3182/LifeLine/internal/bot/handlers"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/jackc/pgx/v5"
+)
+
+// Server exposes the repositories as a versioned JSON HTTP API.
+// Writes go through the same helpers as the Telegram handlers so that the
+// scheduler is notified in the same way.
+type Server struct {
+	handlers *handlers.Handlers
+	repos    *handlers.Repositories
+	calendar *calendar.Service
+	notify   func()
+	srv      *http.Server
+}
+
+type contextKey int
+
+const userIDKey contextKey = iota
+
+func New(addr string, h *handlers.Handlers, notify func()) *Server {
+	s := &Server{
+		handlers: h,
+		repos:    h.Repositories(),
+		calendar: h.Calendar(),
+		notify:   notify,
+	}
+
+	mux := http.NewServeMux()
+	s.routes(mux)
+
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+func (s *Server) routes(mux *http.ServeMux) {
+	v1 := func(pattern string, fn http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.Handle(method+" /v1"+path, s.authenticate(fn))
+	}
+
+	v1("GET /me", s.handleMe)
+
+	v1("GET /todos", s.listTodos)
+	v1("POST /todos", s.createTodo)
+	v1("GET /todos/{id}", s.getTodo)
+	v1("PATCH /todos/{id}", s.updateTodo)
+	v1("DELETE /todos/{id}", s.deleteTodo)
+	v1("POST /todos/{id}/complete", s.completeTodo)
+	v1("POST /todos/{id}/uncomplete", s.uncompleteTodo)
+	v1("POST /todos/{id}/status", s.setTodoStatus)
+
+	v1("GET /events", s.listEvents)
+	v1("POST /events", s.createEvent)
+	v1("GET /events/{id}", s.getEvent)
+	v1("PATCH /events/{id}", s.updateEvent)
+	v1("DELETE /events/{id}", s.deleteEvent)
+
+	v1("GET /reminders", s.listReminders)
+	v1("POST /reminders", s.createReminder)
+	v1("GET /reminders/{id}", s.getReminder)
+	v1("PATCH /reminders/{id}", s.updateReminder)
+	v1("DELETE /reminders/{id}", s.deleteReminder)
+
+	v1("GET /memos", s.listMemos)
+	v1("POST /memos", s.createMemo)
+	v1("GET /memos/{id}", s.getMemo)
+	v1("PATCH /memos/{id}", s.updateMemo)
+	v1("DELETE /memos/{id}", s.deleteMemo)
+
+	v1("GET /transactions", s.listTransactions)
+	v1("POST /transactions", s.createTransaction)
+	v1("GET /transactions/{id}", s.getTransaction)
+	v1("PATCH /transactions/{id}", s.updateTransaction)
+	v1("DELETE /transactions/{id}", s.deleteTransaction)
+
+	v1("GET /webhooks", s.listWebhooks)
+	v1("POST /webhooks", s.createWebhook)
+	v1("GET /webhooks/{id}", s.getWebhook)
+	v1("PATCH /webhooks/{id}", s.updateWebhook)
+	v1("DELETE /webhooks/{id}", s.deleteWebhook)
+	v1("GET /webhooks/{id}/deliveries", s.listWebhookDeliveries)
+
+	v1("GET /balance", s.getBalance)
+	v1("GET /schedule", s.getSchedule)
+}
+
+// Start serves the API until ctx is cancelled
+func (s *Server) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("API server listening on %s", s.srv.Addr)
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// authenticate resolves the bearer token to a user ID, checks that the user
+// still has access and stores the ID in the request context
+func (s *Server) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		plain, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || plain == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		token, err := s.repos.APIToken.GetActiveByHash(r.Context(), auth.HashToken(plain))
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		allowed, banned, err := s.handlers.CheckUserAccess(r.Context(), token.UserID)
+		if err != nil {
+			log.Printf("Failed to check API access for %d: %v", token.UserID, err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !allowed {
+			if banned {
+				writeError(w, http.StatusForbidden, "account disabled")
+			} else {
+				writeError(w, http.StatusForbidden, "access denied")
+			}
+			return
+		}
+
+		if err := s.repos.APIToken.SetLastUsedAt(r.Context(), token.TokenID, time.Now()); err != nil {
+			log.Printf("Failed to update API token last_used_at: %v", err)
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, token.UserID)
+		next(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) notifyScheduler() {
+	if s.notify != nil {
+		s.notify()
+	}
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	user, err := s.repos.User.GetByID(r.Context(), userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// --- Helpers ---
+
+func userID(r *http.Request) int64 {
+	id, _ := r.Context().Value(userIDKey).(int64)
+	return id
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// parseDateParam parses an optional YYYY-MM-DD query parameter in local time
+func parseDateParam(r *http.Request, name string) (*time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ", expected YYYY-MM-DD")
+	}
+	return &t, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeRepoError(w http.ResponseWriter, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	log.Printf("API repository error: %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type todoRequest struct {
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	Priority    *int       `json:"priority"`
+	DueTime     *time.Time `json:"due_time"`
+	Tags        *string    `json:"tags"`
+
+	EstimatedMinutes *int `json:"estimated_minutes"`
+}
+
+type todoStatusRequest struct {
+	Status models.TodoStatus `json:"status"`
+}
+
+func (s *Server) listTodos(w http.ResponseWriter, r *http.Request) {
+	includeCompleted := r.URL.Query().Get("include_completed") == "true"
+	keyword := r.URL.Query().Get("q")
+
+	var todos []*models.Todo
+	var err error
+	if keyword != "" {
+		todos, err = s.repos.Todo.Search(r.Context(), userID(r), keyword, includeCompleted)
+	} else {
+		todos, err = s.repos.Todo.GetByUserID(r.Context(), userID(r), includeCompleted)
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, todos)
+}
+
+func (s *Server) createTodo(w http.ResponseWriter, r *http.Request) {
+	var req todoRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Title == nil || *req.Title == "" {
+		writeError(w, http.StatusBadRequest, "title is required")
+		return
+	}
+
+	var description, tags string
+	var priority, estimatedMinutes int
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+	if req.EstimatedMinutes != nil {
+		estimatedMinutes = *req.EstimatedMinutes
+	}
+
+	todo, err := s.handlers.CreateTodo(r.Context(), userID(r), userID(r), *req.Title, description, priority, req.DueTime, tags, estimatedMinutes)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	writeJSON(w, http.StatusCreated, todo)
+}
+
+func (s *Server) getTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	todo, err := s.repos.Todo.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, todo)
+}
+
+func (s *Server) updateTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req todoRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	todo, err := s.repos.Todo.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Title != nil {
+		todo.Title = *req.Title
+	}
+	if req.Description != nil {
+		todo.Description = *req.Description
+	}
+	if req.Priority != nil {
+		todo.Priority = *req.Priority
+	}
+	if req.DueTime != nil {
+		todo.DueTime = req.DueTime
+	}
+	if req.Tags != nil {
+		todo.Tags = *req.Tags
+	}
+	if req.EstimatedMinutes != nil {
+		todo.EstimatedMinutes = max(*req.EstimatedMinutes, 0)
+	}
+
+	if err := s.repos.Todo.Update(r.Context(), todo); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	writeJSON(w, http.StatusOK, todo)
+}
+
+func (s *Server) deleteTodo(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Todo.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if err := s.repos.Todo.Delete(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) completeTodo(w http.ResponseWriter, r *http.Request) {
+	s.setTodoCompleted(w, r, true)
+}
+
+func (s *Server) uncompleteTodo(w http.ResponseWriter, r *http.Request) {
+	s.setTodoCompleted(w, r, false)
+}
+
+func (s *Server) setTodoCompleted(w http.ResponseWriter, r *http.Request, completed bool) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Todo.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	var err error
+	if completed {
+		// CompleteTodo also emits the todo.completed webhook event
+		_, err = s.handlers.CompleteTodo(r.Context(), id, userID(r))
+	} else {
+		err = s.repos.Todo.Uncomplete(r.Context(), id, userID(r))
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+
+	todo, err := s.repos.Todo.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, todo)
+}
+
+// setTodoStatus moves a todo to another board status
+func (s *Server) setTodoStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req todoStatusRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !req.Status.IsValid() {
+		writeError(w, http.StatusBadRequest, "status must be one of inbox, next, doing, waiting, done, cancelled")
+		return
+	}
+
+	todo, err := s.handlers.MoveTodo(r.Context(), id, userID(r), userID(r), req.Status)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	s.notifyScheduler()
+	writeJSON(w, http.StatusOK, todo)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type transactionRequest struct {
+	Type            *models.TransactionType `json:"type"`
+	Amount          *float64                `json:"amount"`
+	Description     *string                 `json:"description"`
+	Category        *string                 `json:"category"`
+	TransactionDate *time.Time              `json:"transaction_date"`
+	Tags            *string                 `json:"tags"`
+}
+
+// listTransactions supports ?q=keyword, ?start=&end= date range or ?limit=&offset= pagination
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request) {
+	start, err := parseDateParam(r, "start")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := parseDateParam(r, "end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var txs []*models.Transaction
+	switch {
+	case start != nil && end != nil:
+		txs, err = s.repos.Transaction.GetByDateRange(r.Context(), userID(r), *start, end.Add(24*time.Hour-time.Second))
+	case r.URL.Query().Get("q") != "":
+		txs, err = s.repos.Transaction.Search(r.Context(), userID(r), r.URL.Query().Get("q"))
+	default:
+		limit, offset := pagination(r)
+		txs, err = s.repos.Transaction.GetByUserID(r.Context(), userID(r), limit, offset)
+	}
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, txs)
+}
+
+func (s *Server) createTransaction(w http.ResponseWriter, r *http.Request) {
+	var req transactionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Type == nil || !validTransactionType(*req.Type) {
+		writeError(w, http.StatusBadRequest, "type must be income or expense")
+		return
+	}
+	if req.Amount == nil || *req.Amount <= 0 {
+		writeError(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	var description, category string
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.Category != nil {
+		category = *req.Category
+	}
+
+	tx, err := s.handlers.CreateTransaction(r.Context(), userID(r), userID(r), *req.Type, *req.Amount, description, category, localTime(req.TransactionDate))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tx)
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	tx, err := s.repos.Transaction.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tx)
+}
+
+func (s *Server) updateTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req transactionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tx, err := s.repos.Transaction.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Type != nil {
+		if !validTransactionType(*req.Type) {
+			writeError(w, http.StatusBadRequest, "type must be income or expense")
+			return
+		}
+		tx.Type = *req.Type
+	}
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			writeError(w, http.StatusBadRequest, "amount must be positive")
+			return
+		}
+		tx.Amount = *req.Amount
+	}
+	if req.Description != nil {
+		tx.Description = *req.Description
+	}
+	if req.Category != nil {
+		tx.CategoryID = nil
+		if *req.Category != "" {
+			cat, err := s.repos.Category.GetOrCreateByName(r.Context(), userID(r), *req.Category)
+			if err != nil {
+				writeRepoError(w, err)
+				return
+			}
+			tx.CategoryID = &cat.CategoryID
+		}
+	}
+	if req.TransactionDate != nil {
+		tx.TransactionDate = localTime(req.TransactionDate)
+	}
+	if req.Tags != nil {
+		tx.Tags = *req.Tags
+	}
+
+	if err := s.repos.Transaction.Update(r.Context(), tx); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tx)
+}
+
+func (s *Server) deleteTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Transaction.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if err := s.repos.Transaction.Delete(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validTransactionType(t models.TransactionType) bool {
+	return t == models.TransactionTypeIncome || t == models.TransactionTypeExpense
+}
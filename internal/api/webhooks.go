@@ -0,0 +1,160 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/hray3182/LifeLine/internal/auth"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/webhook"
+)
+
+type webhookRequest struct {
+	URL     *string `json:"url"`
+	Events  *string `json:"events"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// webhookCreatedResponse includes the signing secret, which is only returned once
+type webhookCreatedResponse struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.repos.Webhook.GetByUserID(r.Context(), userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if hooks == nil {
+		hooks = []*models.Webhook{}
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.URL == nil {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	if err := webhook.ValidateURL(r.Context(), *req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var rawEvents string
+	if req.Events != nil {
+		rawEvents = *req.Events
+	}
+	events, err := webhook.ParseEvents(rawEvents)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	hook := &models.Webhook{
+		UserID:  userID(r),
+		URL:     *req.URL,
+		Secret:  secret,
+		Events:  events,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.repos.Webhook.Create(r.Context(), hook); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, webhookCreatedResponse{Webhook: hook, Secret: secret})
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	hook, err := s.repos.Webhook.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// updateWebhook only supports toggling enabled; register a new webhook to change URL or events
+func (s *Server) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.URL != nil || req.Events != nil {
+		writeError(w, http.StatusBadRequest, "only enabled can be updated")
+		return
+	}
+
+	hook, err := s.repos.Webhook.GetByID(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	if req.Enabled != nil {
+		if err := s.repos.Webhook.SetEnabled(r.Context(), id, userID(r), *req.Enabled); err != nil {
+			writeRepoError(w, err)
+			return
+		}
+		hook.Enabled = *req.Enabled
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	deleted, err := s.repos.Webhook.Delete(r.Context(), id, userID(r))
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.repos.Webhook.GetByID(r.Context(), id, userID(r)); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	limit, _ := pagination(r)
+	deliveries, err := s.repos.Webhook.GetDeliveriesByWebhookID(r.Context(), id, limit)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// TokenPrefix marks LifeLine API tokens so they are easy to recognize in configs and logs
+const TokenPrefix = "ll_"
+
+// GenerateToken returns a new random API token together with its hash and display prefix.
+// Only the hash should be persisted; the plain token is shown to the user once.
+func GenerateToken() (token, hash, displayPrefix string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = TokenPrefix + hex.EncodeToString(buf)
+	return token, HashToken(token), token[:len(TokenPrefix)+6], nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a plain token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SecretPrefix marks webhook signing secrets
+const SecretPrefix = "whsec_"
+
+// GenerateSecret returns a new random secret for signing webhook payloads
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return SecretPrefix + hex.EncodeToString(buf), nil
+}
+
+// GenerateInviteCode returns a short random code that also works as a
+// Telegram deep-link start parameter
+func GenerateInviteCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
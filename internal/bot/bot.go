@@ -4,21 +4,38 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/access"
 	"github.com/hray3182/LifeLine/internal/ai"
 	"github.com/hray3182/LifeLine/internal/bot/handlers"
+	"github.com/hray3182/LifeLine/internal/bot/router"
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/export"
+	"github.com/hray3182/LifeLine/internal/quota"
 	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/hray3182/LifeLine/internal/webhook"
 )
 
 type Bot struct {
 	api      *tgbotapi.BotAPI
 	handlers *handlers.Handlers
+	router   *router.Router
 	ai       *ai.Client
+	opts     Options
 }
 
-func New(token string, db *database.DB, aiClient *ai.Client, devMode bool) (*Bot, error) {
+// Options tune how the bot processes updates
+type Options struct {
+	DevMode            bool
+	RateLimitPerMinute int           // Updates per user per minute, 0 disables the limit
+	Workers            int           // Updates handled concurrently; one user's updates are handled in order
+	ShutdownTimeout    time.Duration // How long Start waits for in-flight updates after ctx is cancelled
+}
+
+// New creates the bot
+func New(token string, db *database.DB, aiClient *ai.Client, opts Options) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
@@ -33,15 +50,33 @@ func New(token string, db *database.DB, aiClient *ai.Client, devMode bool) (*Bot
 		Transaction:  repository.NewTransactionRepository(db),
 		Event:        repository.NewEventRepository(db),
 		UserSettings: repository.NewUserSettingsRepository(db),
+		APIToken:     repository.NewAPITokenRepository(db),
+		Webhook:      repository.NewWebhookRepository(db),
+		Space:        repository.NewSpaceRepository(db),
+		Shopping:     repository.NewShoppingRepository(db),
+		Invite:       repository.NewInviteRepository(db),
+		Stats:        repository.NewStatsRepository(db),
+		Search:       repository.NewSearchRepository(db),
+		SavedFilter:  repository.NewSavedFilterRepository(db),
+		Tag:          repository.NewTagRepository(db),
+		Project:      repository.NewProjectRepository(db),
 	}
 
+	h := handlers.New(api, repos, aiClient, export.New(db), opts.DevMode)
+	r := router.New()
+	h.Register(r, opts.RateLimitPerMinute)
+
 	return &Bot{
 		api:      api,
-		handlers: handlers.New(api, repos, aiClient, devMode),
+		handlers: h,
+		router:   r,
 		ai:       aiClient,
+		opts:     opts,
 	}, nil
 }
 
+// Start polls for updates until ctx is cancelled. It then stops polling and
+// waits up to ShutdownTimeout for queued and in-flight updates before returning.
 func (b *Bot) Start(ctx context.Context) error {
 	log.Printf("Authorized on account %s", b.api.Self.UserName)
 
@@ -52,6 +87,8 @@ func (b *Bot) Start(ctx context.Context) error {
 		{Command: "events", Description: "📅 查看行事曆"},
 		{Command: "memos", Description: "📝 查看備忘錄"},
 		{Command: "balance", Description: "💰 查看收支餘額"},
+		{Command: "shopping", Description: "🛒 查看購物清單"},
+		{Command: "debts", Description: "🤝 查看分帳餘額"},
 		{Command: "settings", Description: "⚙️ 設定"},
 		{Command: "help", Description: "❓ 使用說明"},
 	}
@@ -65,38 +102,61 @@ func (b *Bot) Start(ctx context.Context) error {
 
 	updates := b.api.GetUpdatesChan(u)
 
+	// Background work such as broadcasts stops with the shutdown signal
+	b.handlers.SetBackground(ctx)
+
+	// Handlers must not be cut off by the shutdown signal, only by the drain timeout
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	pool := newUpdatePool(b.opts.Workers, func(update tgbotapi.Update) {
+		b.router.Dispatch(handlerCtx, update)
+	})
+
 	for {
 		select {
 		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			pool.close()
+			log.Println("Stopped polling, waiting for in-flight updates")
+
+			select {
+			case <-pool.done():
+				log.Println("All updates handled")
+			case <-time.After(b.opts.ShutdownTimeout):
+				log.Printf("Gave up waiting for updates after %s", b.opts.ShutdownTimeout)
+				cancelHandlers()
+			}
 			return ctx.Err()
 		case update := <-updates:
-			go b.handleUpdate(ctx, update)
+			if !pool.submit(ctx, update) {
+				log.Printf("Dropped update %d during shutdown", update.UpdateID)
+			}
 		}
 	}
 }
 
-func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
-	// Handle callback queries (inline keyboard button clicks)
-	if update.CallbackQuery != nil {
-		b.handlers.HandleCallbackQuery(ctx, update.CallbackQuery)
-		return
-	}
-
-	if update.Message == nil {
-		return
-	}
-
-	// Handle commands
-	if update.Message.IsCommand() {
-		b.handlers.HandleCommand(ctx, update.Message)
-		return
-	}
-
-	// Handle regular messages with AI
-	b.handlers.HandleMessage(ctx, update.Message)
+// Handlers returns the update handlers, e.g. to share their helpers with the HTTP API
+func (b *Bot) Handlers() *handlers.Handlers {
+	return b.handlers
 }
 
 // SetSchedulerNotify sets the scheduler notification function for the handlers
 func (b *Bot) SetSchedulerNotify(fn func()) {
 	b.handlers.SetSchedulerNotify(fn)
 }
+
+// SetAccess sets the access policy for the handlers
+func (b *Bot) SetAccess(p *access.Policy) {
+	b.handlers.SetAccess(p)
+}
+
+// SetQuota sets the AI usage tracker for the handlers
+func (b *Bot) SetQuota(t *quota.Tracker) {
+	b.handlers.SetQuota(t)
+}
+
+// SetWebhooks sets the webhook dispatcher for the handlers
+func (b *Bot) SetWebhooks(d *webhook.Dispatcher) {
+	b.handlers.SetWebhooks(d)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/access"
+	"github.com/hray3182/LifeLine/internal/metrics"
+	"github.com/jackc/pgx/v5"
+)
+
+// Authorize is the single access check run before commands, messages and
+// button presses are handled. Denied users get an explanation in private
+// chats; in groups they are ignored silently. In invite mode "/start <code>"
+// redeems an invite code.
+func (h *Handlers) Authorize(ctx context.Context, update tgbotapi.Update) bool {
+	var from *tgbotapi.User
+	var msg *tgbotapi.Message
+	switch {
+	case update.CallbackQuery != nil:
+		from = update.CallbackQuery.From
+	case update.Message != nil:
+		msg = update.Message
+		from = msg.From
+	}
+	if from == nil {
+		return false
+	}
+	allowed, banned, err := h.CheckUserAccess(ctx, from.ID)
+	if err != nil {
+		log.Printf("Failed to check access for %d: %v", from.ID, err)
+		return false
+	}
+	if allowed {
+		return true
+	}
+
+	reason := "not_allowed"
+	if banned {
+		reason = "banned"
+	}
+	metrics.UpdatesHandled.WithLabelValues("denied", reason).Inc()
+
+	if update.CallbackQuery != nil {
+		h.answerCallbackWithAlert(update.CallbackQuery.ID, "🚫 你沒有使用權限")
+		return false
+	}
+	if isGroupChat(msg.Chat) {
+		return false
+	}
+
+	switch {
+	case banned:
+		h.sendMessage(msg.Chat.ID, "🚫 你的帳號已被停用")
+	case h.access.Mode == access.ModeInvite:
+		if msg.IsCommand() && msg.Command() == "start" && strings.TrimSpace(msg.CommandArguments()) != "" {
+			return h.redeemInvite(ctx, msg)
+		}
+		h.sendMessage(msg.Chat.ID, "🔒 LifeLine 目前僅限受邀使用\n請使用 /start <邀請碼> 啟用")
+	default:
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🔒 LifeLine 目前僅限授權使用者\n請將你的 ID 提供給管理員: %d", from.ID))
+	}
+	return false
+}
+
+// CheckUserAccess applies the access policy to the stored state of a user.
+// It is shared by Authorize and the HTTP API so both enforce bans and
+// revoked access the same way. Unknown users count as neither approved nor banned.
+func (h *Handlers) CheckUserAccess(ctx context.Context, userID int64) (allowed, banned bool, err error) {
+	if h.access.IsAdmin(userID) {
+		return true, false, nil
+	}
+
+	approved := false
+	user, err := h.repos.User.GetByID(ctx, userID)
+	switch {
+	case err == nil:
+		approved, banned = user.ApprovedAt != nil, user.IsBanned()
+	case !errors.Is(err, pgx.ErrNoRows):
+		return false, false, err
+	}
+	return h.access.Allows(userID, approved, banned), banned, nil
+}
+
+// redeemInvite admits the sender with the code given to /start and reports
+// whether the command may continue
+func (h *Handlers) redeemInvite(ctx context.Context, msg *tgbotapi.Message) bool {
+	code := strings.TrimSpace(msg.CommandArguments())
+	if err := h.repos.Invite.Redeem(ctx, code, msg.From.ID, msg.From.UserName); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to redeem invite code: %v", err)
+		}
+		h.sendMessage(msg.Chat.ID, "❌ 邀請碼無效、已過期或已被使用完畢")
+		return false
+	}
+	h.sendMessage(msg.Chat.ID, "🎉 邀請碼已啟用，歡迎使用 LifeLine！")
+	return true
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+)
+
+// handleExportAll sends the user a zip archive containing all of their data
+func (h *Handlers) handleExportAll(ctx context.Context, msg *tgbotapi.Message) {
+	if h.exporter == nil {
+		h.sendMessage(msg.Chat.ID, "匯出功能尚未啟用")
+		return
+	}
+
+	archive, err := h.exporter.Collect(ctx, msg.From.ID)
+	if err != nil {
+		log.Printf("Failed to collect export for %d: %v", msg.From.ID, err)
+		h.sendMessage(msg.Chat.ID, "匯出資料失敗，請稍後再試")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := archive.WriteZip(&buf); err != nil {
+		log.Printf("Failed to write export archive for %d: %v", msg.From.ID, err)
+		h.sendMessage(msg.Chat.ID, "匯出資料失敗，請稍後再試")
+		return
+	}
+
+	fileName := fmt.Sprintf("lifeline-export-%s.zip", archive.ExportedAt.Format("20060102-150405"))
+	doc := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{Name: fileName, Bytes: buf.Bytes()})
+	doc.Caption = fmt.Sprintf("📦 資料匯出完成，共 %d 筆資料\n\n備忘錄 %d・待辦 %d・提醒 %d・事件 %d・交易 %d・分類 %d",
+		archive.Count(), len(archive.Memos), len(archive.Todos), len(archive.Reminders),
+		len(archive.Events), len(archive.Transactions), len(archive.Categories))
+
+	if _, err := h.api.Send(doc); err != nil {
+		log.Printf("Failed to send export archive: %v", err)
+		h.sendMessage(msg.Chat.ID, "傳送匯出檔案失敗，請稍後再試")
+	}
+}
+
+// handleDeleteAccount starts the two-step account deletion flow
+func (h *Handlers) handleDeleteAccount(ctx context.Context, msg *tgbotapi.Message) {
+	text := `⚠️ **刪除帳號**
+
+這將永久刪除你在 LifeLine 的所有資料：
+• 備忘錄、待辦事項、提醒
+• 行事曆事件
+• 收支記錄與分類
+• 所有設定
+
+建議先使用 /export_all 匯出資料。確定要繼續嗎？`
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⚠️ 繼續", fmt.Sprintf("account:delete:%d:1", msg.From.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("account:cancel:%d", msg.From.ID)),
+		),
+	)
+
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = keyboard
+
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send delete account confirmation: %v", err)
+	}
+}
+
+// handleAccountCallback handles account callbacks: "account:delete:<userID>:<step>" and "account:cancel:<userID>"
+func (h *Handlers) handleAccountCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, parts []string) {
+	if len(parts) < 2 {
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		h.debug("handleAccountCallback: failed to parse userID", "error", err)
+		return
+	}
+	if callback.From.ID != userID {
+		h.answerCallbackWithAlert(callback.ID, "這不是你的操作")
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	switch parts[0] {
+	case "cancel":
+		h.editMessageText(chatID, messageID, "❌ 已取消刪除帳號")
+
+	case "delete":
+		step := ""
+		if len(parts) > 2 {
+			step = parts[2]
+		}
+		switch step {
+		case "1":
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("🗑 永久刪除", fmt.Sprintf("account:delete:%d:2", userID)),
+					tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("account:cancel:%d", userID)),
+				),
+			)
+			h.editMessageWithKeyboard(chatID, messageID, "🚨 **最後確認**\n\n此操作無法復原，所有資料將立即刪除。", keyboard)
+		case "2":
+			if err := h.deleteAccount(ctx, userID); err != nil {
+				log.Printf("Failed to delete account %d: %v", userID, err)
+				h.editMessageText(chatID, messageID, "❌ 刪除帳號失敗，請稍後再試")
+				return
+			}
+			h.editMessageText(chatID, messageID, "✅ 帳號與所有資料已刪除\n\n感謝使用 LifeLine，如需重新開始請輸入 /start")
+		}
+	}
+}
+
+// deleteAccount removes Telegram-side artifacts, in-memory state and finally the user row
+func (h *Handlers) deleteAccount(ctx context.Context, userID int64) error {
+	// Delete reminder messages that are still shown in the chat
+	reminders, err := h.repos.Reminder.GetByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get reminders: %w", err)
+	}
+	for _, r := range reminders {
+		if r.LastMessageID != nil {
+			h.deleteMessage(userID, *r.LastMessageID)
+		}
+	}
+
+	// Delete the last combined todo reminder message
+	if settings, err := h.repos.UserSettings.GetByUserID(ctx, userID); err == nil && settings.LastTodoMessageID != nil {
+		h.deleteMessage(userID, *settings.LastTodoMessageID)
+	}
+
+	// Unpin everything the bot may have pinned in the private chat
+	if _, err := h.api.Request(tgbotapi.UnpinAllChatMessagesConfig{ChatID: userID}); err != nil {
+		h.debug("deleteAccount: failed to unpin messages", "error", err)
+	}
+
+	// Drop pending conversation state
+	pendingMutex.Lock()
+	delete(pendingConfirmations, userID)
+	pendingMutex.Unlock()
+	h.clearSession(userID)
+
+	// Everything else is removed through ON DELETE CASCADE
+	if err := h.repos.User.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	h.notifyScheduler()
+	log.Printf("Deleted account %d at %s", userID, time.Now().Format(time.RFC3339))
+	return nil
+}
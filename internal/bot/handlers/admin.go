@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/auth"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// broadcastInterval keeps broadcasts below Telegram's limit of 30 messages per second
+	broadcastInterval = 50 * time.Millisecond
+	// adminUsersPageSize keeps a page of the user list well below Telegram's message size limit
+	adminUsersPageSize = 30
+)
+
+// handleAdmin dispatches "/admin <subcommand>". Non-admins are told the
+// command is unknown, the same as for any other command.
+func (h *Handlers) handleAdmin(ctx context.Context, msg *tgbotapi.Message) {
+	if !h.access.IsAdmin(msg.From.ID) {
+		h.sendMessage(msg.Chat.ID, "未知指令，請使用 /help 查看可用指令")
+		return
+	}
+	if !h.requirePrivateChat(msg) {
+		return
+	}
+
+	usage := `🛠 **管理指令**
+/admin users [頁數] - 使用者列表
+/admin stats - 使用統計
+/admin broadcast <訊息> - 發送公告給所有使用者
+/admin ban <ID|@使用者> - 停用使用者
+/admin unban <ID|@使用者> - 解除停用
+/admin allow <ID|@使用者> - 授權使用者（allowlist / invite 模式）
+/admin invite [次數] [天數] - 建立邀請碼`
+
+	sub, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch sub {
+	case "users":
+		h.handleAdminUsers(ctx, msg, rest)
+	case "stats":
+		h.handleAdminStats(ctx, msg)
+	case "broadcast":
+		h.handleAdminBroadcast(ctx, msg, rest)
+	case "ban", "unban":
+		h.handleAdminBan(ctx, msg, rest, sub == "ban")
+	case "allow":
+		h.handleAdminAllow(ctx, msg, rest)
+	case "invite":
+		h.handleAdminInvite(ctx, msg, rest)
+	default:
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("%s\n\n目前存取模式: %s", usage, h.accessMode()))
+	}
+}
+
+// handleAdminUsers lists the users a page at a time: "/admin users [頁數]"
+func (h *Handlers) handleAdminUsers(ctx context.Context, msg *tgbotapi.Message, arg string) {
+	page := 1
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			h.sendMessage(msg.Chat.ID, "無效的頁數\n用法: /admin users [頁數]")
+			return
+		}
+		page = n
+	}
+
+	text, keyboard, err := h.adminUsersPage(ctx, page)
+	if err != nil {
+		log.Printf("Failed to get users: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得使用者列表失敗")
+		return
+	}
+
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+	reply.Entities = parsed.Entities
+	if keyboard != nil {
+		reply.ReplyMarkup = *keyboard
+	}
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send user list: %v", err)
+	}
+}
+
+// handleAdminCallback turns the pages of the user list: "admin:users:<page>"
+func (h *Handlers) handleAdminCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || !h.access.IsAdmin(callback.From.ID) {
+		return
+	}
+	if len(args) < 2 || args[0] != "users" {
+		return
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil || page < 1 {
+		return
+	}
+
+	text, keyboard, err := h.adminUsersPage(ctx, page)
+	if err != nil {
+		log.Printf("Failed to get users: %v", err)
+		h.answerCallbackWithAlert(callback.ID, "取得使用者列表失敗")
+		return
+	}
+	if keyboard == nil {
+		keyboard = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	}
+	h.editMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, *keyboard)
+}
+
+// adminUsersPage renders a page of the user list with buttons to the
+// neighbouring pages, or no keyboard when everything fits on one page
+func (h *Handlers) adminUsersPage(ctx context.Context, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	total, err := h.repos.User.CountPeople(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	pages := max((total+adminUsersPageSize-1)/adminUsersPageSize, 1)
+	page = min(page, pages)
+
+	users, err := h.repos.User.GetPeople(ctx, adminUsersPageSize, (page-1)*adminUsersPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👥 **使用者** (%d)\n🛠 管理員 ✅ 已授權 🚫 已停用\n\n", total))
+	for _, u := range users {
+		status := ""
+		switch {
+		case h.access.IsAdmin(u.UserID):
+			status = " 🛠"
+		case u.IsBanned():
+			status = " 🚫"
+		case u.ApprovedAt != nil:
+			status = " ✅"
+		}
+		name := u.UserName
+		if name != "" {
+			name = " @" + name
+		}
+		sb.WriteString(fmt.Sprintf("• `%d`%s%s\n", u.UserID, name, status))
+	}
+	if pages == 1 {
+		return sb.String(), nil, nil
+	}
+	sb.WriteString(fmt.Sprintf("\n第 %d / %d 頁", page, pages))
+
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️ 上一頁", fmt.Sprintf("admin:users:%d", page-1)))
+	}
+	if page < pages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("下一頁 ▶️", fmt.Sprintf("admin:users:%d", page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return sb.String(), &keyboard, nil
+}
+
+func (h *Handlers) handleAdminStats(ctx context.Context, msg *tgbotapi.Message) {
+	stats, err := h.repos.Stats.Get(ctx)
+	if err != nil {
+		log.Printf("Failed to get stats: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得統計失敗")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf(`📊 **使用統計**
+
+存取模式: %s
+使用者: %d（已授權 %d，已停用 %d）
+群組: %d
+
+備忘錄: %d
+待辦: %d（未完成 %d）
+提醒: %d
+事件: %d
+交易: %d`,
+		h.accessMode(), stats.Users, stats.Approved, stats.Banned, stats.Groups,
+		stats.Memos, stats.Todos, stats.OpenTodos, stats.Reminders, stats.Events, stats.Transactions))
+}
+
+// handleAdminBroadcast starts sending an announcement to every user who is
+// not banned. The sends are paced, so they run in the background, off the
+// admin's update worker, and the admin is told the result when they finish.
+func (h *Handlers) handleAdminBroadcast(ctx context.Context, msg *tgbotapi.Message, text string) {
+	if text == "" {
+		h.sendMessage(msg.Chat.ID, "請提供公告內容\n用法: /admin broadcast <訊息>")
+		return
+	}
+
+	users, err := h.repos.User.GetAll(ctx)
+	if err != nil {
+		log.Printf("Failed to get users: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得使用者列表失敗")
+		return
+	}
+	var recipients []int64
+	for _, u := range users {
+		if u.UserID > 0 && !u.IsBanned() {
+			recipients = append(recipients, u.UserID)
+		}
+	}
+
+	if !h.broadcasting.CompareAndSwap(false, true) {
+		h.sendMessage(msg.Chat.ID, "📢 已有公告正在發送中，請等待完成後再試")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("📢 開始發送公告給 %d 位使用者，完成後會通知你", len(recipients)))
+	go func() {
+		defer h.broadcasting.Store(false)
+		h.broadcast(h.background, msg.Chat.ID, recipients, text)
+	}()
+}
+
+// broadcast sends the announcement to each recipient, stopping when ctx is
+// cancelled, and reports the result in chatID
+func (h *Handlers) broadcast(ctx context.Context, chatID int64, recipients []int64, text string) {
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	sent, failed := 0, 0
+	for i, userID := range recipients {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				log.Printf("Broadcast interrupted after %d of %d users", sent+failed, len(recipients))
+				h.sendMessage(chatID, fmt.Sprintf("⚠️ 公告發送中斷: 成功 %d，失敗 %d，未發送 %d", sent, failed, len(recipients)-i))
+				return
+			case <-ticker.C:
+			}
+		}
+		if _, err := h.api.Send(tgbotapi.NewMessage(userID, "📢 "+text)); err != nil {
+			log.Printf("Failed to broadcast to %d: %v", userID, err)
+			failed++
+		} else {
+			sent++
+		}
+	}
+
+	h.sendMessage(chatID, fmt.Sprintf("📢 公告已發送: 成功 %d，失敗 %d", sent, failed))
+}
+
+func (h *Handlers) handleAdminBan(ctx context.Context, msg *tgbotapi.Message, arg string, ban bool) {
+	userID, label, ok := h.resolveAdminTarget(ctx, msg, arg)
+	if !ok {
+		return
+	}
+	if ban && h.access.IsAdmin(userID) {
+		h.sendMessage(msg.Chat.ID, "無法停用管理員")
+		return
+	}
+
+	if err := h.repos.User.SetBanned(ctx, userID, ban); err != nil {
+		log.Printf("Failed to update ban: %v", err)
+		h.sendMessage(msg.Chat.ID, "操作失敗，請稍後再試")
+		return
+	}
+
+	if ban {
+		if _, err := h.repos.APIToken.RevokeAllForUser(ctx, userID); err != nil {
+			log.Printf("Failed to revoke API tokens of banned user %d: %v", userID, err)
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🚫 已停用 %s", label))
+	} else {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 已解除停用 %s", label))
+	}
+}
+
+func (h *Handlers) handleAdminAllow(ctx context.Context, msg *tgbotapi.Message, arg string) {
+	userID, label, ok := h.resolveAdminTarget(ctx, msg, arg)
+	if !ok {
+		return
+	}
+
+	if err := h.repos.User.SetApproved(ctx, userID, true); err != nil {
+		log.Printf("Failed to approve user: %v", err)
+		h.sendMessage(msg.Chat.ID, "操作失敗，請稍後再試")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 已授權 %s", label))
+}
+
+// handleAdminInvite creates an invite code: "/admin invite [次數] [天數]"
+func (h *Handlers) handleAdminInvite(ctx context.Context, msg *tgbotapi.Message, arg string) {
+	usage := "用法: /admin invite [次數] [天數]\n例如: /admin invite 5 7（可使用 5 次，7 天內有效）"
+
+	invite := &models.InviteCode{CreatedBy: &msg.From.ID, MaxUses: 1}
+	args := strings.Fields(arg)
+	if len(args) > 0 {
+		uses, err := strconv.Atoi(args[0])
+		if err != nil || uses <= 0 {
+			h.sendMessage(msg.Chat.ID, "無效的次數\n"+usage)
+			return
+		}
+		invite.MaxUses = uses
+	}
+	if len(args) > 1 {
+		days, err := strconv.Atoi(args[1])
+		if err != nil || days <= 0 {
+			h.sendMessage(msg.Chat.ID, "無效的天數\n"+usage)
+			return
+		}
+		expiresAt := time.Now().AddDate(0, 0, days)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	code, err := auth.GenerateInviteCode()
+	if err != nil {
+		log.Printf("Failed to generate invite code: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立邀請碼失敗，請稍後再試")
+		return
+	}
+	invite.Code = code
+
+	if err := h.repos.Invite.Create(ctx, invite); err != nil {
+		log.Printf("Failed to create invite code: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立邀請碼失敗，請稍後再試")
+		return
+	}
+
+	text := fmt.Sprintf("🎟 **邀請碼**: `%s`\n可使用 %d 次", invite.Code, invite.MaxUses)
+	if invite.ExpiresAt != nil {
+		text += fmt.Sprintf("，%s 前有效", invite.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	text += fmt.Sprintf("\n\n邀請連結: https://t.me/%s?start=%s", h.api.Self.UserName, invite.Code)
+	h.sendMessage(msg.Chat.ID, text)
+}
+
+// resolveAdminTarget parses a user ID or @username and replies with an error
+// when it cannot be resolved
+func (h *Handlers) resolveAdminTarget(ctx context.Context, msg *tgbotapi.Message, arg string) (int64, string, bool) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		h.sendMessage(msg.Chat.ID, "請指定使用者 ID 或 @使用者名稱")
+		return 0, "", false
+	}
+
+	if userID, err := strconv.ParseInt(arg, 10, 64); err == nil && userID > 0 {
+		return userID, fmt.Sprintf("%d", userID), true
+	}
+
+	user, err := h.repos.User.GetByUserName(ctx, strings.TrimPrefix(arg, "@"))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to look up user: %v", err)
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("找不到使用者 %s，請改用數字 ID", arg))
+		return 0, "", false
+	}
+	return user.UserID, fmt.Sprintf("@%s (%d)", user.UserName, user.UserID), true
+}
+
+// accessMode names the configured access mode for admin replies
+func (h *Handlers) accessMode() string {
+	if h.access == nil {
+		return "open"
+	}
+	return string(h.access.Mode)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/hray3182/LifeLine/internal/ai"
+	"github.com/hray3182/LifeLine/internal/metrics"
 	"github.com/hray3182/LifeLine/internal/models"
 )
 
@@ -43,6 +45,8 @@ func (h *Handlers) handleAIMessage(ctx context.Context, msg *tgbotapi.Message) {
 		h.sendMessage(msg.Chat.ID, "AI 功能尚未啟用")
 		return
 	}
+	// AI usage is accounted to the sender, also in groups
+	ctx = ai.WithUserID(ctx, msg.From.ID)
 
 	h.debug("Incoming message", "from", msg.From.FirstName, "username", msg.From.UserName, "text", msg.Text)
 	if msg.ReplyToMessage != nil {
@@ -87,6 +91,10 @@ func (h *Handlers) handleAIMessage(ctx context.Context, msg *tgbotapi.Message) {
 
 	// Parse intent with conversation history
 	intent, err := h.ai.ParseIntentWithHistory(ctx, session.History)
+	if errors.Is(err, ai.ErrQuotaExceeded) {
+		h.sendMessage(msg.Chat.ID, quotaExceededText(err))
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to parse intent: %v", err)
 		h.sendMessage(msg.Chat.ID, "抱歉，我無法理解你的訊息。請試著用更清楚的方式描述，或使用 /help 查看可用指令。")
@@ -155,17 +163,22 @@ func (h *Handlers) handleAIMessage(ctx context.Context, msg *tgbotapi.Message) {
 		result := h.executeIntentWithResult(ctx, msg, intent)
 		h.debug("Tool result", "result", truncateString(result, 200))
 
-		// Add result to history for AI to process
+		h.debug("Sending tool result to AI for next action")
+
+		// Let AI decide next action based on result
+		nextIntent, err := h.ai.ContinueWithToolResult(ctx, session.History, result)
+
+		// Keep the result in the history for follow-up messages
 		session.History = append(session.History, ai.Message{
 			Role:    "assistant",
 			Content: "[工具執行結果]\n" + result,
 		})
 		h.saveSession(msg.From.ID, session)
 
-		h.debug("Sending tool result to AI for next action")
-
-		// Let AI decide next action based on result
-		nextIntent, err := h.ai.ParseIntentWithHistory(ctx, session.History)
+		if errors.Is(err, ai.ErrQuotaExceeded) {
+			h.sendMessage(msg.Chat.ID, result+"\n\n"+quotaExceededText(err))
+			return
+		}
 		if err != nil {
 			log.Printf("Failed to parse next intent: %v", err)
 			h.sendMessage(msg.Chat.ID, "處理失敗，請稍後再試")
@@ -212,7 +225,7 @@ func (h *Handlers) handleAIMessage(ctx context.Context, msg *tgbotapi.Message) {
 	}
 
 	// Clear session after successful action (unless it's a list/query action)
-	if !strings.HasPrefix(intent.Action, "list_") && intent.Action != "get_balance" && intent.Action != "query_schedule" {
+	if !strings.HasPrefix(intent.Action, "list_") && intent.Action != "get_balance" && intent.Action != "query_schedule" && intent.Action != "search" {
 		h.clearSession(msg.From.ID)
 	} else {
 		h.saveSession(msg.From.ID, session)
@@ -383,6 +396,7 @@ func (h *Handlers) executeIntentWithResult(ctx context.Context, msg *tgbotapi.Me
 // executeSingleAction executes a single action and returns the result
 func (h *Handlers) executeSingleAction(ctx context.Context, msg *tgbotapi.Message, action string, params map[string]string, sendMsg bool) string {
 	h.debug("executeSingleAction", "action", action, "params", params, "sendMsg", sendMsg)
+	metrics.UpdatesHandled.WithLabelValues("action", action).Inc()
 	var result string
 	switch action {
 	case "create_memo":
@@ -401,12 +415,16 @@ func (h *Handlers) executeSingleAction(ctx context.Context, msg *tgbotapi.Messag
 		result = h.handleAIDeleteTodoResult(ctx, msg, params, sendMsg)
 	case "update_todo":
 		result = h.handleAIUpdateTodoResult(ctx, msg, params, sendMsg)
+	case "assign_todo":
+		result = h.handleAIAssignTodoResult(ctx, msg, params, sendMsg)
 	case "create_reminder":
 		result = h.handleAICreateReminderResult(ctx, msg, params, sendMsg)
 	case "list_reminder":
 		result = h.handleAIListReminderResult(ctx, msg, params, sendMsg)
 	case "delete_reminder":
 		result = h.handleAIDeleteReminderResult(ctx, msg, params, sendMsg)
+	case "update_reminder":
+		result = h.handleAIUpdateReminderResult(ctx, msg, params, sendMsg)
 	case "create_expense":
 		result = h.handleAICreateTransactionResult(ctx, msg, params, models.TransactionTypeExpense, sendMsg)
 	case "create_income":
@@ -417,6 +435,12 @@ func (h *Handlers) executeSingleAction(ctx context.Context, msg *tgbotapi.Messag
 		result = h.handleAIDeleteTransactionResult(ctx, msg, params, sendMsg)
 	case "get_balance":
 		result = h.handleBalanceWithResult(ctx, msg)
+	case "split_expense":
+		result = h.handleAISplitExpenseResult(ctx, msg, params, sendMsg)
+	case "list_debts":
+		result = h.handleDebtsResult(ctx, msg, sendMsg)
+	case "settle_debt":
+		result = h.handleAISettleDebtResult(ctx, msg, params, sendMsg)
 	case "create_event":
 		result = h.handleAICreateEventResult(ctx, msg, params, sendMsg)
 	case "list_event":
@@ -427,11 +451,12 @@ func (h *Handlers) executeSingleAction(ctx context.Context, msg *tgbotapi.Messag
 		result = h.handleAIUpdateEventResult(ctx, msg, params, sendMsg)
 	case "query_schedule":
 		result = h.handleQueryScheduleResult(ctx, msg, params, sendMsg)
+	case "search":
+		result = h.handleAISearchResult(ctx, msg, params, sendMsg)
+	case "plan_schedule":
+		result = h.handleAIPlanScheduleResult(ctx, msg, params)
 	case "find_free_time":
-		result = h.handleFindFreeTime(ctx, msg, params)
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
-		}
+		result = h.handleFindFreeTime(ctx, msg, params, sendMsg)
 	case "unknown":
 		result = "無法識別的操作"
 		if sendMsg {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 	"github.com/hray3182/LifeLine/internal/rrule"
 )
@@ -25,16 +26,19 @@ func (h *Handlers) handleAIListEventResult(ctx context.Context, msg *tgbotapi.Me
 	var events []*models.Event
 	var err error
 
-	if dateStr != "" {
+	if tag := params["tag"]; tag != "" {
+		events, err = h.repos.Event.Filter(ctx, spaceID(msg), filter.ByTag(tag, keyword))
+		keyword = tagKeyword(tag, keyword)
+	} else if dateStr != "" {
 		// Search by specific date
 		date := parseDateTime(dateStr)
 		if date != nil {
 			// Get start and end of day
 			startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 			endOfDay := startOfDay.Add(24 * time.Hour)
-			events, err = h.repos.Event.GetByDateRange(ctx, msg.From.ID, startOfDay, endOfDay)
+			events, err = h.calendar.Occurrences(ctx, spaceID(msg), startOfDay, endOfDay)
 		} else {
-			events, err = h.repos.Event.GetByUserID(ctx, msg.From.ID)
+			events, err = h.repos.Event.GetByUserID(ctx, spaceID(msg))
 		}
 	} else if startDate != "" || endDate != "" {
 		// Search by date range
@@ -52,11 +56,11 @@ func (h *Handlers) handleAIListEventResult(ctx context.Context, msg *tgbotapi.Me
 				end = time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 23, 59, 59, 0, parsed.Location())
 			}
 		}
-		events, err = h.repos.Event.GetByDateRange(ctx, msg.From.ID, start, end)
+		events, err = h.calendar.Occurrences(ctx, spaceID(msg), start, end)
 	} else if keyword != "" {
-		events, err = h.repos.Event.Search(ctx, msg.From.ID, keyword)
+		events, err = h.repos.Event.Search(ctx, spaceID(msg), keyword)
 	} else {
-		events, err = h.repos.Event.GetByUserID(ctx, msg.From.ID)
+		events, err = h.repos.Event.GetByUserID(ctx, spaceID(msg))
 	}
 
 	if err != nil {
@@ -93,10 +97,13 @@ func (h *Handlers) handleAIListEventResult(ctx context.Context, msg *tgbotapi.Me
 		} else if event.Dtstart != nil {
 			timeStr = event.Dtstart.Format("01/02 15:04")
 		}
+		if event.AllDay {
+			timeStr = allDayText(event)
+		}
 
 		sb.WriteString(fmt.Sprintf("%d. %s\n", event.EventID, event.Title))
 		sb.WriteString(fmt.Sprintf("   時間: %s\n", timeStr))
-		if event.Duration > 0 {
+		if event.Duration > 0 && !event.AllDay {
 			sb.WriteString(fmt.Sprintf("   時長: %d 分鐘\n", event.Duration))
 		}
 		if event.IsRecurring() {
@@ -124,8 +131,7 @@ func (h *Handlers) handleAICreateEvent(ctx context.Context, msg *tgbotapi.Messag
 }
 
 func (h *Handlers) handleAICreateEventResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
-	title := params["title"]
-	if title == "" {
+	if params["title"] == "" {
 		result := "請提供事件標題"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
@@ -133,6 +139,20 @@ func (h *Handlers) handleAICreateEventResult(ctx context.Context, msg *tgbotapi.
 		return result
 	}
 
+	change := &changeRequest{ownerID: spaceID(msg), params: params}
+	result, asked := h.createEvent(ctx, msg.Chat.ID, msg.From.ID, change)
+	if sendMsg && !asked {
+		h.sendMessage(msg.Chat.ID, result)
+	}
+	return result
+}
+
+// createEvent creates the event described by the params of change on
+// behalf of userID and returns the outcome. An event overlapping other
+// events asks in chatID how to go on instead, reporting it asked.
+func (h *Handlers) createEvent(ctx context.Context, chatID, userID int64, change *changeRequest) (string, bool) {
+	params := change.params
+	title := params["title"]
 	description := params["description"]
 	tags := params["tags"]
 
@@ -159,29 +179,53 @@ func (h *Handlers) handleAICreateEventResult(ctx context.Context, msg *tgbotapi.
 	// Get RRULE
 	rruleStr := params["rrule"]
 
-	event, err := h.CreateEvent(ctx, msg.From.ID, title, description, dtstart, duration, 30, rruleStr, tags)
+	// Alerts like "1440,30"; without them the default alerts apply
+	alerts, err := alertsParam(params)
 	if err != nil {
-		result := "建立事件失敗，請稍後再試"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
+		return fmt.Sprintf("提醒時間格式錯誤，請用提前的分鐘數表示，例如 1440,30 (最多 %d 個)", models.MaxAlerts), false
+	}
+
+	candidate := &models.Event{UserID: change.ownerID, Title: title, Dtstart: dtstart, Duration: duration, RecurrenceRule: rruleStr}
+	allDay := dtstart != nil && isAllDay(params)
+	if allDay {
+		setAllDay(candidate, params)
+	}
+	if !change.force && dtstart != nil {
+		if text, asked := h.checkConflicts(ctx, chatID, userID, change, models.ScopeAll, candidate); asked {
+			return text, true
 		}
-		return result
+	}
+
+	var event *models.Event
+	if allDay {
+		event, err = h.CreateAllDayEvent(ctx, change.ownerID, userID, title, description, *dtstart, candidate.Days(), candidate.Transparent, alerts, rruleStr, tags)
+	} else {
+		event, err = h.CreateEvent(ctx, change.ownerID, userID, title, description, dtstart, duration, alerts, rruleStr, tags)
+	}
+	if err != nil {
+		return "建立事件失敗，請稍後再試", false
 	}
 
 	result := fmt.Sprintf("事件已建立 (ID: %d)\n標題: %s", event.EventID, title)
-	if dtstart != nil {
+	switch {
+	case allDay:
+		result += fmt.Sprintf("\n日期: %s", allDayText(event))
+		if !event.Transparent {
+			result += "\n整天忙碌"
+		}
+	case dtstart != nil:
 		result += fmt.Sprintf("\n首次時間: %s", dtstart.Format("2006-01-02 15:04"))
-	}
-	if duration > 0 {
-		result += fmt.Sprintf("\n時長: %d 分鐘", duration)
+		if duration > 0 {
+			result += fmt.Sprintf("\n時長: %d 分鐘", duration)
+		}
 	}
 	if rruleStr != "" {
 		result += fmt.Sprintf("\n重複: %s", rrule.HumanReadableChinese(rruleStr))
 	}
-	if sendMsg {
-		h.sendMessage(msg.Chat.ID, result)
+	if dtstart != nil {
+		result += fmt.Sprintf("\n提醒: %s", models.AlertsLabel(event.Alerts))
 	}
-	return result
+	return result, false
 }
 
 func (h *Handlers) handleAIDeleteEvent(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
@@ -189,28 +233,7 @@ func (h *Handlers) handleAIDeleteEvent(ctx context.Context, msg *tgbotapi.Messag
 }
 
 func (h *Handlers) handleAIDeleteEventResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
-	id, err := strconv.Atoi(params["id"])
-	if err != nil {
-		result := "請提供有效的事件編號"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
-		}
-		return result
-	}
-
-	if err := h.repos.Event.Delete(ctx, id, msg.From.ID); err != nil {
-		result := "刪除事件失敗，請確認編號是否正確"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
-		}
-		return result
-	}
-
-	result := fmt.Sprintf("事件 #%d 已刪除", id)
-	if sendMsg {
-		h.sendMessage(msg.Chat.ID, result)
-	}
-	return result
+	return h.handleAIChangeEventResult(ctx, msg, params, true, sendMsg)
 }
 
 func (h *Handlers) handleAIUpdateEvent(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
@@ -218,6 +241,12 @@ func (h *Handlers) handleAIUpdateEvent(ctx context.Context, msg *tgbotapi.Messag
 }
 
 func (h *Handlers) handleAIUpdateEventResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	return h.handleAIChangeEventResult(ctx, msg, params, false, sendMsg)
+}
+
+// handleAIChangeEventResult updates or deletes an event; for a recurring
+// event without a "scope" param it asks which occurrences are meant
+func (h *Handlers) handleAIChangeEventResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, remove, sendMsg bool) string {
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
 		result := "請提供有效的事件編號"
@@ -227,70 +256,25 @@ func (h *Handlers) handleAIUpdateEventResult(ctx context.Context, msg *tgbotapi.
 		return result
 	}
 
-	event, err := h.repos.Event.GetByID(ctx, id, msg.From.ID)
-	if err != nil {
-		result := "找不到該事件"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
-		}
-		return result
-	}
-
-	// Update fields if provided
-	if title, ok := params["title"]; ok && title != "" {
-		event.Title = title
-	}
-	if desc, ok := params["description"]; ok {
-		event.Description = desc
-	}
-	if dt, ok := params["dtstart"]; ok && dt != "" {
-		event.Dtstart = parseDateTime(dt)
-	}
-	// Fallback to start_time for backward compatibility
-	if dt, ok := params["start_time"]; ok && dt != "" && event.Dtstart == nil {
-		event.Dtstart = parseDateTime(dt)
-	}
-	if d, ok := params["duration"]; ok && d != "" {
-		if parsed, err := strconv.Atoi(d); err == nil {
-			event.Duration = parsed
-		}
-	}
-	if rruleStr, ok := params["rrule"]; ok {
-		event.RecurrenceRule = rruleStr
-	}
-	if tags, ok := params["tags"]; ok {
-		event.Tags = tags
-	}
-
-	// Recalculate NextOccurrence if dtstart or rrule changed
-	if event.Dtstart != nil {
-		now := time.Now()
-		if event.RecurrenceRule != "" {
-			if event.Dtstart.After(now) {
-				event.NextOccurrence = event.Dtstart
-			} else {
-				next, err := rrule.NextOccurrence(event.RecurrenceRule, *event.Dtstart, now)
-				if err != nil {
-					event.NextOccurrence = event.Dtstart
-				} else {
-					event.NextOccurrence = next
-				}
+	change := &changeRequest{ownerID: spaceID(msg), id: id, params: params, delete: remove}
+	scope := models.RecurrenceScope(params["scope"])
+	if !scope.IsValid() {
+		event, err := h.repos.Event.GetByID(ctx, id, spaceID(msg))
+		if err != nil {
+			result := "找不到該事件"
+			if sendMsg {
+				h.sendMessage(msg.Chat.ID, result)
 			}
-		} else {
-			event.NextOccurrence = event.Dtstart
+			return result
 		}
-	}
-
-	if err := h.repos.Event.Update(ctx, event); err != nil {
-		result := "更新事件失敗"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
+		if event.IsRecurring() {
+			return h.askScope(msg.Chat.ID, change, event.Title)
 		}
-		return result
+		scope = models.ScopeAll
 	}
 
-	result := fmt.Sprintf("事件 #%d 已更新", id)
-	if sendMsg {
+	result, asked := h.changeEvent(ctx, msg.Chat.ID, msg.From.ID, change, scope)
+	if sendMsg && !asked {
 		h.sendMessage(msg.Chat.ID, result)
 	}
 	return result
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 )
 
@@ -19,10 +20,13 @@ func (h *Handlers) handleAIListMemoResult(ctx context.Context, msg *tgbotapi.Mes
 	var memos []*models.Memo
 	var err error
 
-	if keyword != "" {
-		memos, err = h.repos.Memo.Search(ctx, msg.From.ID, keyword)
+	if tag := params["tag"]; tag != "" {
+		memos, err = h.repos.Memo.Filter(ctx, spaceID(msg), filter.ByTag(tag, keyword), filteredListLimit)
+		keyword = tagKeyword(tag, keyword)
+	} else if keyword != "" {
+		memos, err = h.repos.Memo.Search(ctx, spaceID(msg), keyword)
 	} else {
-		memos, err = h.repos.Memo.GetByUserID(ctx, msg.From.ID, 10, 0)
+		memos, err = h.repos.Memo.GetByUserID(ctx, spaceID(msg), 10, 0)
 	}
 
 	if err != nil {
@@ -79,7 +83,7 @@ func (h *Handlers) handleAICreateMemoResult(ctx context.Context, msg *tgbotapi.M
 	}
 
 	tags := params["tags"]
-	memo, err := h.CreateMemo(ctx, msg.From.ID, content, tags)
+	memo, err := h.CreateMemo(ctx, spaceID(msg), msg.From.ID, content, tags)
 	if err != nil {
 		result := "建立備忘錄失敗，請稍後再試"
 		if sendMsg {
@@ -109,7 +113,7 @@ func (h *Handlers) handleAIDeleteMemoResult(ctx context.Context, msg *tgbotapi.M
 		return result
 	}
 
-	if err := h.repos.Memo.Delete(ctx, id, msg.From.ID); err != nil {
+	if err := h.repos.Memo.Delete(ctx, id, spaceID(msg)); err != nil {
 		result := "刪除備忘錄失敗，請確認編號是否正確"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
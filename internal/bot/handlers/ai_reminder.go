@@ -8,6 +8,7 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 	"github.com/hray3182/LifeLine/internal/rrule"
 )
@@ -21,10 +22,13 @@ func (h *Handlers) handleAIListReminderResult(ctx context.Context, msg *tgbotapi
 	var reminders []*models.Reminder
 	var err error
 
-	if keyword != "" {
-		reminders, err = h.repos.Reminder.Search(ctx, msg.From.ID, keyword)
+	if tag := params["tag"]; tag != "" {
+		reminders, err = h.repos.Reminder.Filter(ctx, spaceID(msg), filter.ByTag(tag, keyword))
+		keyword = tagKeyword(tag, keyword)
+	} else if keyword != "" {
+		reminders, err = h.repos.Reminder.Search(ctx, spaceID(msg), keyword)
 	} else {
-		reminders, err = h.repos.Reminder.GetByUserID(ctx, msg.From.ID)
+		reminders, err = h.repos.Reminder.GetByUserID(ctx, spaceID(msg))
 	}
 
 	if err != nil {
@@ -121,7 +125,7 @@ func (h *Handlers) handleAICreateReminderResult(ctx context.Context, msg *tgbota
 	// Get RRULE
 	rruleStr := params["rrule"]
 
-	reminder, err := h.CreateReminder(ctx, msg.From.ID, message, dtstart, rruleStr)
+	reminder, err := h.CreateReminder(ctx, spaceID(msg), msg.From.ID, message, dtstart, rruleStr)
 	if err != nil {
 		result := "建立提醒失敗，請稍後再試"
 		if sendMsg {
@@ -148,6 +152,21 @@ func (h *Handlers) handleAIDeleteReminder(ctx context.Context, msg *tgbotapi.Mes
 }
 
 func (h *Handlers) handleAIDeleteReminderResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	return h.handleAIChangeReminderResult(ctx, msg, params, true, sendMsg)
+}
+
+func (h *Handlers) handleAIUpdateReminder(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
+	return h.handleAIUpdateReminderResult(ctx, msg, params, true)
+}
+
+func (h *Handlers) handleAIUpdateReminderResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	return h.handleAIChangeReminderResult(ctx, msg, params, false, sendMsg)
+}
+
+// handleAIChangeReminderResult updates or deletes a reminder; for a
+// recurring reminder without a "scope" param it asks which occurrences
+// are meant
+func (h *Handlers) handleAIChangeReminderResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, remove, sendMsg bool) string {
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
 		result := "請提供有效的提醒編號"
@@ -157,15 +176,24 @@ func (h *Handlers) handleAIDeleteReminderResult(ctx context.Context, msg *tgbota
 		return result
 	}
 
-	if err := h.repos.Reminder.Delete(ctx, id, msg.From.ID); err != nil {
-		result := "刪除提醒失敗，請確認編號是否正確"
-		if sendMsg {
-			h.sendMessage(msg.Chat.ID, result)
+	change := &changeRequest{ownerID: spaceID(msg), reminder: true, id: id, params: params, delete: remove}
+	scope := models.RecurrenceScope(params["scope"])
+	if !scope.IsValid() {
+		reminder, err := h.repos.Reminder.GetByID(ctx, id, spaceID(msg))
+		if err != nil {
+			result := "找不到該提醒"
+			if sendMsg {
+				h.sendMessage(msg.Chat.ID, result)
+			}
+			return result
 		}
-		return result
+		if reminder.IsRecurring() {
+			return h.askScope(msg.Chat.ID, change, reminder.Messages)
+		}
+		scope = models.ScopeAll
 	}
 
-	result := fmt.Sprintf("提醒 #%d 已刪除", id)
+	result := h.changeReminder(ctx, msg.From.ID, change, scope)
 	if sendMsg {
 		h.sendMessage(msg.Chat.ID, result)
 	}
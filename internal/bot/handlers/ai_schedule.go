@@ -3,10 +3,12 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
 	"github.com/hray3182/LifeLine/internal/models"
 )
 
@@ -79,7 +81,7 @@ func (h *Handlers) handleQueryScheduleResult(ctx context.Context, msg *tgbotapi.
 	var sb strings.Builder
 
 	// 1. Events in date range
-	events, err := h.repos.Event.GetByDateRange(ctx, msg.From.ID, startTime, endTime)
+	events, err := h.calendar.Occurrences(ctx, spaceID(msg), startTime, endTime)
 	if err == nil && len(events) > 0 {
 		sb.WriteString("【事件】\n")
 		for _, e := range events {
@@ -90,11 +92,17 @@ func (h *Handlers) handleQueryScheduleResult(ctx context.Context, msg *tgbotapi.
 				eventTime = e.Dtstart
 			}
 			timeStr := formatEventTime(eventTime)
+			if e.AllDay {
+				timeStr = calendar.AllDayLabel(e)
+				if isMultiDay && e.Days() == 1 {
+					timeStr = allDayText(e)
+				}
+			}
 			sb.WriteString(fmt.Sprintf("• [#%d] %s", e.EventID, e.Title))
 			if timeStr != "" {
 				sb.WriteString(fmt.Sprintf(" (%s)", timeStr))
 			}
-			if e.Duration > 0 && e.Duration != 60 {
+			if e.Duration > 0 && e.Duration != 60 && !e.AllDay {
 				sb.WriteString(fmt.Sprintf(" [%d分鐘]", e.Duration))
 			}
 			sb.WriteString("\n")
@@ -103,7 +111,7 @@ func (h *Handlers) handleQueryScheduleResult(ctx context.Context, msg *tgbotapi.
 	}
 
 	// 2. Todos with due date in range
-	todos, err := h.repos.Todo.GetByUserID(ctx, msg.From.ID, false)
+	todos, err := h.repos.Todo.GetByUserID(ctx, spaceID(msg), false)
 	if err == nil {
 		var relevantTodos []*models.Todo
 		var upcomingTodos []*models.Todo // 即將到期但不在查詢範圍內的 todo
@@ -148,7 +156,7 @@ func (h *Handlers) handleQueryScheduleResult(ctx context.Context, msg *tgbotapi.
 	}
 
 	// 3. Reminders in date range
-	reminders, err := h.repos.Reminder.GetByUserID(ctx, msg.From.ID)
+	reminders, err := h.repos.Reminder.GetByUserID(ctx, spaceID(msg))
 	if err == nil {
 		var relevantReminders []*models.Reminder
 		for _, r := range reminders {
@@ -283,166 +291,39 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// handleFindFreeTime finds free time slots on a given date
-func (h *Handlers) handleFindFreeTime(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
-	dateStr := params["date"]
-
+// handleFindFreeTime searches free time on the date, or from start_date to
+// end_date, for slots of at least min_minutes; when sendMsg is set the best
+// candidates are sent as buttons creating an event named title
+func (h *Handlers) handleFindFreeTime(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
 	now := time.Now()
 	loc := now.Location()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
 
-	// Parse target date
-	var targetDate time.Time
-	if dateStr != "" {
-		if parsed := parseDateTime(dateStr); parsed != nil {
-			targetDate = time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, loc)
-		} else {
-			targetDate = today
+	dayOf := func(s string) (time.Time, bool) {
+		parsed := parseDateTime(s)
+		if parsed == nil {
+			return time.Time{}, false
 		}
-	} else {
-		targetDate = today
+		return time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, loc), true
 	}
 
-	// Define working hours (08:00 - 22:00)
-	dayStart := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 8, 0, 0, 0, loc)
-	dayEnd := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 22, 0, 0, 0, loc)
-
-	// If target date is today and current time is past 8:00, start from now (rounded to next 30 min)
-	if targetDate.Equal(today) && now.After(dayStart) {
-		// Round up to next 30 minutes
-		minutes := now.Minute()
-		if minutes > 30 {
-			dayStart = time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, loc)
-		} else if minutes > 0 {
-			dayStart = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 30, 0, 0, loc)
-		} else {
-			dayStart = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, loc)
-		}
+	q := freeTimeQuery{from: today, days: 1, count: freeTimeDefaultCount, title: params["title"]}
+	startStr := params["start_date"]
+	if startStr == "" {
+		startStr = params["date"]
 	}
-
-	// Collect busy time slots
-	type timeSlot struct {
-		start time.Time
-		end   time.Time
-		title string
+	if from, ok := dayOf(startStr); ok && !from.Before(today) {
+		q.from = from
 	}
-	var busySlots []timeSlot
-
-	// Get events for the day
-	events, err := h.repos.Event.GetByDateRange(ctx, msg.From.ID, targetDate, targetDate.Add(24*time.Hour))
-	if err == nil {
-		for _, e := range events {
-			var eventStart *time.Time
-			if e.NextOccurrence != nil {
-				eventStart = e.NextOccurrence
-			} else if e.Dtstart != nil {
-				eventStart = e.Dtstart
-			}
-			if eventStart != nil {
-				duration := e.Duration
-				if duration == 0 {
-					duration = 60 // default 60 minutes
-				}
-				busySlots = append(busySlots, timeSlot{
-					start: *eventStart,
-					end:   eventStart.Add(time.Duration(duration) * time.Minute),
-					title: e.Title,
-				})
-			}
-		}
+	if until, ok := dayOf(params["end_date"]); ok && !until.Before(q.from) {
+		q.days = min(int(until.Sub(q.from).Hours()/24)+1, freeTimeMaxDays)
 	}
-
-	// Sort busy slots by start time
-	for i := 0; i < len(busySlots)-1; i++ {
-		for j := i + 1; j < len(busySlots); j++ {
-			if busySlots[j].start.Before(busySlots[i].start) {
-				busySlots[i], busySlots[j] = busySlots[j], busySlots[i]
-			}
-		}
+	if minutes, ok := parseMinutes(params["min_minutes"]); ok {
+		q.minutes = minutes
 	}
-
-	// Find free slots
-	var freeSlots []timeSlot
-	currentTime := dayStart
-
-	for _, busy := range busySlots {
-		// Skip if busy slot is outside our time range
-		if busy.end.Before(dayStart) || busy.start.After(dayEnd) {
-			continue
-		}
-
-		// Adjust busy slot to our time range
-		busyStart := busy.start
-		if busyStart.Before(dayStart) {
-			busyStart = dayStart
-		}
-
-		// If there's a gap before this busy slot, it's free time
-		if currentTime.Before(busyStart) {
-			freeSlots = append(freeSlots, timeSlot{
-				start: currentTime,
-				end:   busyStart,
-			})
-		}
-
-		// Move current time to end of busy slot
-		if busy.end.After(currentTime) {
-			currentTime = busy.end
-		}
-	}
-
-	// Add remaining time until end of day
-	if currentTime.Before(dayEnd) {
-		freeSlots = append(freeSlots, timeSlot{
-			start: currentTime,
-			end:   dayEnd,
-		})
-	}
-
-	// Build result
-	var sb strings.Builder
-	dateLabel := targetDate.Format("2006-01-02")
-	if targetDate.Equal(today) {
-		dateLabel = "今天 (" + targetDate.Format("01/02") + ")"
-	} else if targetDate.Equal(today.Add(24*time.Hour)) {
-		dateLabel = "明天 (" + targetDate.Format("01/02") + ")"
-	}
-
-	sb.WriteString(fmt.Sprintf("【%s 的空閒時段】\n\n", dateLabel))
-
-	if len(freeSlots) == 0 {
-		sb.WriteString("這天沒有空閒時間。\n")
-	} else {
-		for _, slot := range freeSlots {
-			duration := slot.end.Sub(slot.start)
-			hours := int(duration.Hours())
-			minutes := int(duration.Minutes()) % 60
-
-			durationStr := ""
-			if hours > 0 && minutes > 0 {
-				durationStr = fmt.Sprintf("%d小時%d分鐘", hours, minutes)
-			} else if hours > 0 {
-				durationStr = fmt.Sprintf("%d小時", hours)
-			} else {
-				durationStr = fmt.Sprintf("%d分鐘", minutes)
-			}
-
-			sb.WriteString(fmt.Sprintf("• %s - %s (%s)\n",
-				slot.start.Format("15:04"),
-				slot.end.Format("15:04"),
-				durationStr))
-		}
-	}
-
-	if len(busySlots) > 0 {
-		sb.WriteString("\n【已安排的事項】\n")
-		for _, busy := range busySlots {
-			sb.WriteString(fmt.Sprintf("• %s - %s: %s\n",
-				busy.start.Format("15:04"),
-				busy.end.Format("15:04"),
-				busy.title))
-		}
+	if count, err := strconv.Atoi(params["count"]); err == nil && count > 0 {
+		q.count = min(count, freeTimeMaxCount)
 	}
 
-	return sb.String()
+	return h.findFreeTime(ctx, msg.Chat.ID, spaceID(msg), q, sendMsg)
 }
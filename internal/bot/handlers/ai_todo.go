@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 func (h *Handlers) handleAIListTodo(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
@@ -20,10 +24,20 @@ func (h *Handlers) handleAIListTodoResult(ctx context.Context, msg *tgbotapi.Mes
 	var todos []*models.Todo
 	var err error
 
-	if keyword != "" {
-		todos, err = h.repos.Todo.Search(ctx, msg.From.ID, keyword, false)
+	if project := params["project"]; project != "" {
+		f := &filter.Filter{Query: "project:" + project, Conditions: []filter.Condition{{Field: filter.FieldProject, Op: filter.OpEq, Text: project}}}
+		if tag := params["tag"]; tag != "" {
+			f.Conditions = append(f.Conditions, filter.ByTag(tag, "").Conditions...)
+		}
+		todos, err = h.repos.Todo.Filter(ctx, spaceID(msg), f)
+		keyword = "專案 " + project
+	} else if tag := params["tag"]; tag != "" {
+		todos, err = h.repos.Todo.Filter(ctx, spaceID(msg), filter.ByTag(tag, keyword))
+		keyword = tagKeyword(tag, keyword)
+	} else if keyword != "" {
+		todos, err = h.repos.Todo.Search(ctx, spaceID(msg), keyword, false)
 	} else {
-		todos, err = h.repos.Todo.GetByUserID(ctx, msg.From.ID, false)
+		todos, err = h.repos.Todo.GetByUserID(ctx, spaceID(msg), false)
 	}
 
 	if err != nil {
@@ -64,7 +78,10 @@ func (h *Handlers) handleAIListTodoResult(ctx context.Context, msg *tgbotapi.Mes
 			title = title[:40] + "..."
 		}
 
-		sb.WriteString(fmt.Sprintf("%s %d. %s", status, todo.TodoID, title))
+		sb.WriteString(fmt.Sprintf("%s %d. %s%s", status, todo.TodoID, title, subtaskProgress(todo)))
+		if todo.ParentID != nil {
+			sb.WriteString(fmt.Sprintf(" (#%d 的子任務)", *todo.ParentID))
+		}
 		if todo.DueTime != nil {
 			sb.WriteString(fmt.Sprintf("\n   截止: %s", todo.DueTime.Format("2006-01-02 15:04")))
 		}
@@ -111,8 +128,56 @@ func (h *Handlers) handleAICreateTodoResult(ctx context.Context, msg *tgbotapi.M
 		}
 	}
 
-	todo, err := h.CreateTodo(ctx, msg.From.ID, title, description, priority, dueTime, tags)
-	if err != nil {
+	todo := &models.Todo{
+		UserID:      spaceID(msg),
+		Title:       title,
+		Description: description,
+		Priority:    priority,
+		DueTime:     dueTime,
+		Tags:        tags,
+		CreatedBy:   &msg.From.ID,
+	}
+	if m, ok := parseMinutes(params["estimated_minutes"]); ok {
+		todo.EstimatedMinutes = m
+	}
+
+	var parent *models.Todo
+	if idStr := params["parent_id"]; idStr != "" {
+		parentID, _ := strconv.Atoi(idStr)
+		p, err := h.repos.Todo.GetByID(ctx, parentID, spaceID(msg))
+		if err != nil {
+			result := fmt.Sprintf("找不到上層待辦事項 #%s", idStr)
+			if sendMsg {
+				h.sendMessage(msg.Chat.ID, result)
+			}
+			return result
+		}
+		parent = p
+	}
+
+	// A project named by the user is created when it does not exist yet
+	var project *models.Project
+	newProject := false
+	if name := strings.TrimSpace(params["project"]); name != "" {
+		p, err := h.repos.Project.GetByName(ctx, spaceID(msg), name)
+		if errors.Is(err, pgx.ErrNoRows) {
+			p = &models.Project{UserID: spaceID(msg), Name: name, CreatedBy: &msg.From.ID}
+			err = h.repos.Project.Create(ctx, p)
+			newProject = true
+		}
+		if err != nil {
+			log.Printf("Failed to get project %q: %v", name, err)
+			result := "建立待辦事項失敗，請稍後再試"
+			if sendMsg {
+				h.sendMessage(msg.Chat.ID, result)
+			}
+			return result
+		}
+		project = p
+	}
+	h.placeTodo(ctx, todo, project, parent)
+
+	if err := h.repos.Todo.Create(ctx, todo); err != nil {
 		result := "建立待辦事項失敗，請稍後再試"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
@@ -124,6 +189,16 @@ func (h *Handlers) handleAICreateTodoResult(ctx context.Context, msg *tgbotapi.M
 	if dueTime != nil {
 		result += fmt.Sprintf("\n截止時間: %s", dueTime.Format("2006-01-02 15:04"))
 	}
+	if project != nil {
+		result += fmt.Sprintf("\n專案: %s", project.Name)
+		if newProject {
+			result += " (新專案)"
+		}
+	}
+	if parent != nil {
+		result += fmt.Sprintf("\n上層: #%d %s", parent.TodoID, parent.Title)
+	}
+	result += h.blockByParam(ctx, todo.UserID, todo.TodoID, params["blocked_by"])
 	if sendMsg {
 		h.sendMessage(msg.Chat.ID, result)
 	}
@@ -153,7 +228,7 @@ func (h *Handlers) handleAICompleteTodoResult(ctx context.Context, msg *tgbotapi
 		return result
 	}
 
-	if err := h.repos.Todo.Complete(ctx, todoID, msg.From.ID); err != nil {
+	if _, err := h.CompleteTodo(ctx, todoID, spaceID(msg)); err != nil {
 		result := "完成待辦事項失敗，請確認編號是否正確"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
@@ -168,6 +243,25 @@ func (h *Handlers) handleAICompleteTodoResult(ctx context.Context, msg *tgbotapi
 	return result
 }
 
+func (h *Handlers) handleAIAssignTodoResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	reply := func(result string) string {
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	if params["id"] == "" || params["assignee"] == "" {
+		return reply("請提供待辦事項編號與指派對象")
+	}
+	todoID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		return reply("無效的編號")
+	}
+
+	return reply(h.assignTodo(ctx, msg, todoID, params["assignee"]))
+}
+
 func (h *Handlers) handleAIDeleteTodo(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
 	return h.handleAIDeleteTodoResult(ctx, msg, params, true)
 }
@@ -182,7 +276,7 @@ func (h *Handlers) handleAIDeleteTodoResult(ctx context.Context, msg *tgbotapi.M
 		return result
 	}
 
-	if err := h.repos.Todo.Delete(ctx, id, msg.From.ID); err != nil {
+	if err := h.repos.Todo.Delete(ctx, id, spaceID(msg)); err != nil {
 		result := "刪除待辦事項失敗，請確認編號是否正確"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
@@ -211,7 +305,7 @@ func (h *Handlers) handleAIUpdateTodoResult(ctx context.Context, msg *tgbotapi.M
 		return result
 	}
 
-	todo, err := h.repos.Todo.GetByID(ctx, id, msg.From.ID)
+	todo, err := h.repos.Todo.GetByID(ctx, id, spaceID(msg))
 	if err != nil {
 		result := "找不到該待辦事項"
 		if sendMsg {
@@ -236,6 +330,9 @@ func (h *Handlers) handleAIUpdateTodoResult(ctx context.Context, msg *tgbotapi.M
 	if tags, ok := params["tags"]; ok {
 		todo.Tags = tags
 	}
+	if m, ok := parseMinutes(params["estimated_minutes"]); ok {
+		todo.EstimatedMinutes = m
+	}
 
 	if err := h.repos.Todo.Update(ctx, todo); err != nil {
 		result := "更新待辦事項失敗"
@@ -246,6 +343,12 @@ func (h *Handlers) handleAIUpdateTodoResult(ctx context.Context, msg *tgbotapi.M
 	}
 
 	result := fmt.Sprintf("待辦事項 #%d 已更新", id)
+	result += h.blockByParam(ctx, todo.UserID, todo.TodoID, params["blocked_by"])
+	if value := params["status"]; value != "" {
+		if status, ok := filter.ParseStatus(value); ok {
+			result += "\n" + h.moveTodoText(ctx, todo.TodoID, todo.UserID, msg.From.ID, models.TodoStatus(status))
+		}
+	}
 	if sendMsg {
 		h.sendMessage(msg.Chat.ID, result)
 	}
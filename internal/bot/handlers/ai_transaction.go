@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 )
 
@@ -19,10 +20,13 @@ func (h *Handlers) handleAIListTransactionResult(ctx context.Context, msg *tgbot
 	var transactions []*models.Transaction
 	var err error
 
-	if keyword != "" {
-		transactions, err = h.repos.Transaction.Search(ctx, msg.From.ID, keyword)
+	if tag := params["tag"]; tag != "" {
+		transactions, err = h.repos.Transaction.Filter(ctx, spaceID(msg), filter.ByTag(tag, keyword), filteredListLimit)
+		keyword = tagKeyword(tag, keyword)
+	} else if keyword != "" {
+		transactions, err = h.repos.Transaction.Search(ctx, spaceID(msg), keyword)
 	} else {
-		transactions, err = h.repos.Transaction.GetByUserID(ctx, msg.From.ID, 20, 0)
+		transactions, err = h.repos.Transaction.GetByUserID(ctx, spaceID(msg), 20, 0)
 	}
 
 	if err != nil {
@@ -54,8 +58,11 @@ func (h *Handlers) handleAIListTransactionResult(ctx context.Context, msg *tgbot
 	}
 	for _, tx := range transactions {
 		typeStr := "支出"
-		if tx.Type == models.TransactionTypeIncome {
+		switch tx.Type {
+		case models.TransactionTypeIncome:
 			typeStr = "收入"
+		case models.TransactionTypeSettlement:
+			typeStr = "還款"
 		}
 
 		dateStr := ""
@@ -113,7 +120,7 @@ func (h *Handlers) handleAICreateTransactionResult(ctx context.Context, msg *tgb
 	}
 	category := params["category"]
 
-	tx, err := h.CreateTransaction(ctx, msg.From.ID, txType, amount, description, category, nil)
+	tx, err := h.CreateTransaction(ctx, spaceID(msg), msg.From.ID, txType, amount, description, category, nil)
 	if err != nil {
 		result := "記錄失敗，請稍後再試"
 		if sendMsg {
@@ -154,7 +161,7 @@ func (h *Handlers) handleAIDeleteTransactionResult(ctx context.Context, msg *tgb
 		return result
 	}
 
-	if err := h.repos.Transaction.Delete(ctx, id, msg.From.ID); err != nil {
+	if err := h.repos.Transaction.Delete(ctx, id, spaceID(msg)); err != nil {
 		result := "刪除交易記錄失敗，請確認編號是否正確"
 		if sendMsg {
 			h.sendMessage(msg.Chat.ID, result)
@@ -168,3 +175,81 @@ func (h *Handlers) handleAIDeleteTransactionResult(ctx context.Context, msg *tgb
 	}
 	return result
 }
+
+func (h *Handlers) handleAISplitExpenseResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	reply := func(result string) string {
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	amount, err := strconv.ParseFloat(params["amount"], 64)
+	if err != nil || amount <= 0 {
+		return reply("請提供有效的金額")
+	}
+
+	var tokens []string
+	for _, p := range strings.Split(params["participants"], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			tokens = append(tokens, p)
+		}
+	}
+	if len(tokens) == 0 {
+		return reply("請提供分攤的參與者")
+	}
+
+	specs, splitType, err := parseShareSpecs(msg, tokens)
+	if err != nil {
+		return reply(splitErrorText(err))
+	}
+
+	payer := participantLabel(msg.From)
+	if params["paid_by"] != "" {
+		payer = resolveParticipant(msg, params["paid_by"])
+	}
+
+	description := params["description"]
+	if description == "" {
+		description = params["item"]
+	}
+
+	tx, err := h.SplitExpense(ctx, spaceID(msg), msg.From.ID, payer, amount, description, splitType, specs)
+	if err != nil {
+		return reply(splitErrorText(err))
+	}
+	return reply(formatSplit(tx))
+}
+
+func (h *Handlers) handleAISettleDebtResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	reply := func(result string) string {
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	if params["to"] == "" {
+		return reply("請提供收款人")
+	}
+	to := resolveParticipant(msg, params["to"])
+	from := participantLabel(msg.From)
+	if params["from"] != "" {
+		from = resolveParticipant(msg, params["from"])
+	}
+
+	var amount float64
+	if params["amount"] != "" {
+		var err error
+		amount, err = strconv.ParseFloat(params["amount"], 64)
+		if err != nil || amount <= 0 {
+			return reply("無效的金額")
+		}
+	}
+
+	result, err := h.settle(ctx, msg, from, to, amount)
+	if err != nil {
+		return reply(splitErrorText(err))
+	}
+	return reply(result)
+}
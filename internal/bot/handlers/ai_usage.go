@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hray3182/LifeLine/internal/quota"
+)
+
+// quotaExceededText explains an exceeded AI quota
+func quotaExceededText(err error) string {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return "🚦 AI 額度已用完，請稍後再試"
+	}
+
+	when := "明天"
+	period := "今天"
+	if exceeded.Period == quota.PeriodMonthly {
+		when = "下個月"
+		period = "本月"
+	}
+	return fmt.Sprintf("🚦 %s的 AI 額度已用完（%s / %s tokens），%s會自動重置\n在此之前仍可使用 /todo、/remind、/expense 等指令",
+		period, formatTokens(exceeded.Used), formatTokens(exceeded.Limit), when)
+}
+
+// aiUsageText is the AI usage line of the settings menu; empty for groups or without tracking
+func (h *Handlers) aiUsageText(ctx context.Context, userID int64) string {
+	if h.quota == nil || userID < 0 {
+		return ""
+	}
+
+	summary, err := h.quota.Summary(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get AI usage: %v", err)
+		return ""
+	}
+
+	today := formatTokens(summary.Today.Tokens())
+	if limit := summary.Limits.DailyTokens; limit > 0 && !summary.Exempt {
+		today += " / " + formatTokens(limit)
+	}
+	month := formatTokens(summary.Month.Tokens())
+	if limit := summary.Limits.MonthlyTokens; limit > 0 && !summary.Exempt {
+		month += " / " + formatTokens(limit)
+	}
+
+	return fmt.Sprintf("\n🤖 AI 用量: 今日 %s · 本月 %s tokens（約 $%.3f）", today, month, summary.Month.Cost)
+}
+
+// formatTokens shortens token counts, e.g. 12345 -> "12.3k"
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	}
+	return fmt.Sprintf("%d", n)
+}
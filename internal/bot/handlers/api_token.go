@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/auth"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// handleAPIToken manages HTTP API tokens: "/api_token [name]", "/api_token list", "/api_token revoke <id>"
+func (h *Handlers) handleAPIToken(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			h.handleAPITokenList(ctx, msg)
+			return
+		case "revoke":
+			if len(args) < 2 {
+				h.sendMessage(msg.Chat.ID, "請提供 Token 編號\n用法: /api_token revoke <編號>")
+				return
+			}
+			h.handleAPITokenRevoke(ctx, msg, args[1])
+			return
+		}
+	}
+
+	name := strings.Join(args, " ")
+	if name == "" {
+		name = "default"
+	}
+
+	plain, hash, prefix, err := auth.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate API token: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立 Token 失敗，請稍後再試")
+		return
+	}
+
+	token := &models.APIToken{
+		UserID:      msg.From.ID,
+		Name:        name,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+	}
+	if err := h.repos.APIToken.Create(ctx, token); err != nil {
+		log.Printf("Failed to save API token: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立 Token 失敗，請稍後再試")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf(`🔑 **API Token 已建立** (ID: %d, 名稱: %s)
+
+`+"`%s`"+`
+
+此 Token 只會顯示一次，請妥善保存。
+使用方式: `+"`Authorization: Bearer <token>`"+`
+
+/api_token list - 查看 Token
+/api_token revoke <編號> - 撤銷 Token`, token.TokenID, name, plain))
+}
+
+func (h *Handlers) handleAPITokenList(ctx context.Context, msg *tgbotapi.Message) {
+	tokens, err := h.repos.APIToken.GetByUserID(ctx, msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "取得 Token 列表失敗，請稍後再試")
+		return
+	}
+
+	if len(tokens) == 0 {
+		h.sendMessage(msg.Chat.ID, "🔑 目前沒有 API Token\n使用 /api_token <名稱> 建立")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔑 **API Token 列表**\n\n")
+	for _, t := range tokens {
+		sb.WriteString(fmt.Sprintf("**%d.** %s (`%s…`)\n", t.TokenID, t.Name, t.TokenPrefix))
+		sb.WriteString(fmt.Sprintf("   建立於 %s", t.CreatedAt.Format("2006-01-02")))
+		if t.LastUsedAt != nil {
+			sb.WriteString(fmt.Sprintf(" | 最後使用 %s", t.LastUsedAt.Format("2006-01-02 15:04")))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+func (h *Handlers) handleAPITokenRevoke(ctx context.Context, msg *tgbotapi.Message, idStr string) {
+	tokenID, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "無效的編號")
+		return
+	}
+
+	revoked, err := h.repos.APIToken.Revoke(ctx, tokenID, msg.From.ID)
+	if err != nil || !revoked {
+		h.sendMessage(msg.Chat.ID, "撤銷 Token 失敗，請確認編號是否正確")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🔒 Token #%d 已撤銷", tokenID))
+}
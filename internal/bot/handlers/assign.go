@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// handleAssign hands a todo to another user: "/assign <編號> @username"
+func (h *Handlers) handleAssign(ctx context.Context, msg *tgbotapi.Message) {
+	usage := "用法: /assign <待辦編號> @使用者\n例如: /assign 12 @amy"
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		h.sendMessage(msg.Chat.ID, "請提供待辦編號與指派對象\n"+usage)
+		return
+	}
+
+	todoID, err := strconv.Atoi(args[0])
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "無效的編號\n"+usage)
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, h.assignTodo(ctx, msg, todoID, args[1]))
+}
+
+// assignTodo assigns a todo of the message's space to the user named by
+// assignee and asks them to accept. It returns the text for the assigner.
+func (h *Handlers) assignTodo(ctx context.Context, msg *tgbotapi.Message, todoID int, assignee string) string {
+	name := strings.TrimPrefix(strings.TrimSpace(assignee), "@")
+	if name == "" {
+		return "請指定指派對象"
+	}
+
+	user, err := h.repos.User.GetByUserName(ctx, name)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to look up user %s: %v", name, err)
+		}
+		return fmt.Sprintf("找不到使用者 @%s，對方需要先私訊我或在群組中使用過 LifeLine", name)
+	}
+	if user.UserID == msg.From.ID {
+		return "不能指派給自己"
+	}
+
+	if err := h.repos.Todo.Assign(ctx, todoID, spaceID(msg), user.UserID, msg.From.ID); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to assign todo: %v", err)
+		}
+		return "指派失敗，請確認編號是否正確且待辦尚未完成"
+	}
+
+	todo, err := h.repos.Todo.GetByID(ctx, todoID, spaceID(msg))
+	if err != nil {
+		log.Printf("Failed to get assigned todo: %v", err)
+		return "指派失敗，請稍後再試"
+	}
+
+	if !h.sendAssignmentOffer(msg, todo, user) {
+		return fmt.Sprintf("⚠️ 已指派 #%d 給 @%s，但無法私訊對方，請對方先私訊我 /start", todoID, user.UserName)
+	}
+	return fmt.Sprintf("📨 已將待辦 #%d 指派給 @%s，等待對方接受", todoID, user.UserName)
+}
+
+// sendAssignmentOffer asks the assignee to accept or decline, privately if
+// possible and otherwise in the group the assignment was made in
+func (h *Handlers) sendAssignmentOffer(msg *tgbotapi.Message, todo *models.Todo, assignee *models.User) bool {
+	text := fmt.Sprintf("📌 %s 指派了一項待辦給你\n\n**%s**", participantLabel(msg.From), todo.Title)
+	if todo.Description != "" {
+		text += "\n" + todo.Description
+	}
+	if todo.DueTime != nil {
+		text += fmt.Sprintf("\n📅 截止: %s", todo.DueTime.Format("2006-01-02 15:04"))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ 接受", fmt.Sprintf("todo_assign:accept:%d", todo.TodoID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ 拒絕", fmt.Sprintf("todo_assign:decline:%d", todo.TodoID)),
+	))
+
+	chatIDs := []int64{assignee.UserID}
+	if isGroupChat(msg.Chat) {
+		chatIDs = append(chatIDs, msg.Chat.ID)
+	}
+
+	parsed := format.ParseMarkdown(text)
+	for _, chatID := range chatIDs {
+		offer := tgbotapi.NewMessage(chatID, parsed.Text)
+		offer.Entities = parsed.Entities
+		offer.ReplyMarkup = keyboard
+		if _, err := h.api.Send(offer); err != nil {
+			log.Printf("Failed to send assignment offer to %d: %v", chatID, err)
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// handleAssignCallback handles "todo_assign:accept:<todoID>" and
+// "todo_assign:decline:<todoID>". Only the assignee may answer.
+func (h *Handlers) handleAssignCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, parts []string) {
+	if len(parts) < 2 || callback.Message == nil {
+		return
+	}
+
+	todoID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return
+	}
+
+	var status models.AssignmentStatus
+	switch parts[0] {
+	case "accept":
+		status = models.AssignmentAccepted
+	case "decline":
+		status = models.AssignmentDeclined
+	default:
+		return
+	}
+
+	todo, err := h.repos.Todo.GetAssignment(ctx, todoID, callback.From.ID)
+	if err != nil {
+		h.answerCallbackWithAlert(callback.ID, "這不是指派給你的待辦")
+		return
+	}
+
+	if err := h.repos.Todo.SetAssignmentStatus(ctx, todoID, callback.From.ID, status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.answerCallbackWithAlert(callback.ID, "此指派已經回覆過了")
+		} else {
+			log.Printf("Failed to update assignment: %v", err)
+		}
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	assignee := participantLabel(callback.From)
+
+	if status == models.AssignmentAccepted {
+		// Due-time reminders are sent by the todo reminder check, which needs settings
+		if _, err := h.repos.UserSettings.GetOrCreate(ctx, callback.From.ID); err != nil {
+			log.Printf("Failed to create settings for assignee: %v", err)
+		}
+		h.editMessageText(chatID, messageID, fmt.Sprintf("✅ %s 已接受待辦 #%d: %s\n到期前會提醒你，完成後使用 /done %d", assignee, todoID, todo.Title, todoID))
+	} else {
+		h.editMessageText(chatID, messageID, fmt.Sprintf("❌ %s 已拒絕待辦 #%d: %s", assignee, todoID, todo.Title))
+	}
+
+	// An answer in the group is already visible to the assigner
+	if todo.AssignedBy != nil && !isGroupChat(callback.Message.Chat) {
+		verb := "接受"
+		if status == models.AssignmentDeclined {
+			verb = "拒絕"
+		}
+		h.sendMessage(*todo.AssignedBy, fmt.Sprintf("📌 %s %s了你指派的待辦 #%d: %s", assignee, verb, todoID, todo.Title))
+	}
+}
+
+// notifyAssignmentCompleted tells the assigner that an assigned todo was completed
+func (h *Handlers) notifyAssignmentCompleted(ctx context.Context, todo *models.Todo, completedBy int64) {
+	if todo.AssignedBy == nil || todo.AssignmentStatus != models.AssignmentAccepted || *todo.AssignedBy == completedBy {
+		return
+	}
+
+	who := "對方"
+	if completedBy > 0 {
+		if user, err := h.repos.User.GetByID(ctx, completedBy); err == nil && user.UserName != "" {
+			who = "@" + user.UserName
+		}
+	}
+	h.sendMessage(*todo.AssignedBy, fmt.Sprintf("🎉 %s 完成了你指派的待辦 #%d: %s", who, todo.TodoID, todo.Title))
+}
+
+// assignmentSuffix shows who a todo was assigned to on the owner's list
+func (h *Handlers) assignmentSuffix(ctx context.Context, todo *models.Todo) string {
+	if todo.AssigneeID == nil || todo.AssignmentStatus == "" {
+		return ""
+	}
+
+	name := fmt.Sprintf("#%d", *todo.AssigneeID)
+	if user, err := h.repos.User.GetByID(ctx, *todo.AssigneeID); err == nil && user.UserName != "" {
+		name = "@" + user.UserName
+	}
+
+	switch todo.AssignmentStatus {
+	case models.AssignmentPending:
+		return fmt.Sprintf(" → %s (待回覆)", name)
+	case models.AssignmentDeclined:
+		return fmt.Sprintf(" → %s (已拒絕)", name)
+	}
+	return " → " + name
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// boardColumnLimit is how many todos a board column shows
+	boardColumnLimit = 8
+	// boardDoneWindow is how far back the board shows completed todos
+	boardDoneWindow = 7 * 24 * time.Hour
+)
+
+const statusUsage = `用法: /status <編號> [狀態]
+狀態: inbox 收件匣、next 下一步、doing 進行中、waiting 等待中、done 已完成、cancelled 已取消
+例如: /status 12 doing`
+
+// handleBoard shows the open todos in columns by status, or only those
+// matching "/board <篩選條件>"
+func (h *Handlers) handleBoard(ctx context.Context, msg *tgbotapi.Message) {
+	query := strings.TrimSpace(msg.CommandArguments())
+
+	var todos []*models.Todo
+	var err error
+	if query != "" {
+		f, ok := h.parseFilter(ctx, msg, query)
+		if !ok {
+			return
+		}
+		todos, err = h.repos.Todo.Filter(ctx, spaceID(msg), f)
+	} else {
+		todos, err = h.repos.Todo.GetByUserID(ctx, spaceID(msg), true)
+	}
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得待辦事項失敗，請稍後再試"))
+		return
+	}
+
+	columns := make(map[models.TodoStatus][]*models.Todo)
+	since := time.Now().Add(-boardDoneWindow)
+	for _, todo := range todos {
+		// Closed todos only show when they were closed recently
+		if todo.Status.IsClosed() && (todo.CompletedAt == nil || todo.CompletedAt.Before(since)) {
+			continue
+		}
+		columns[todo.Status] = append(columns[todo.Status], todo)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗂 **看板**")
+	if query != "" {
+		sb.WriteString(" · " + query)
+	}
+	sb.WriteString("\n")
+	for _, status := range models.TodoStatuses {
+		column := columns[status]
+		if status.IsClosed() && len(column) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n**%s** (%d)\n", status.Label(), len(column)))
+		if len(column) == 0 {
+			sb.WriteString("   —\n")
+			continue
+		}
+		for i, todo := range column {
+			if i == boardColumnLimit {
+				sb.WriteString(fmt.Sprintf("   ...還有 %d 項\n", len(column)-boardColumnLimit))
+				break
+			}
+			writeBoardCard(&sb, todo)
+		}
+	}
+	sb.WriteString("\n使用 /status <編號> <狀態> 移動待辦")
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// writeBoardCard writes a todo as one line of a board column
+func writeBoardCard(sb *strings.Builder, todo *models.Todo) {
+	bullet := "•"
+	if todo.IsBlocked() && !todo.IsCompleted() {
+		bullet = "🔒"
+	}
+	sb.WriteString(fmt.Sprintf("   %s **%d.** %s%s", bullet, todo.TodoID, truncateRunes(todo.Title, 30), blockedSuffix(todo)))
+	if todo.DueTime != nil && !todo.IsCompleted() {
+		sb.WriteString(fmt.Sprintf(" 📅 %s", todo.DueTime.Format("01-02 15:04")))
+	}
+	sb.WriteString("\n")
+}
+
+// handleStatus moves a todo: "/status <編號> <狀態>", or shows its status
+// history with buttons to move it when no status is given
+func (h *Handlers) handleStatus(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		h.sendMessage(msg.Chat.ID, "請提供待辦事項編號\n"+statusUsage)
+		return
+	}
+	todoID, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "無效的編號\n"+statusUsage)
+		return
+	}
+
+	if len(args) == 1 {
+		h.sendStatusCard(ctx, msg.Chat.ID, spaceID(msg), todoID)
+		return
+	}
+
+	value, ok := filter.ParseStatus(args[1])
+	if !ok {
+		h.sendMessage(msg.Chat.ID, "無效的狀態\n"+statusUsage)
+		return
+	}
+	h.sendMessage(msg.Chat.ID, h.moveTodoText(ctx, todoID, spaceID(msg), msg.From.ID, models.TodoStatus(value)))
+}
+
+// sendStatusCard shows a todo's status history with a button per other status
+func (h *Handlers) sendStatusCard(ctx context.Context, chatID, ownerID int64, todoID int) {
+	todo, err := h.repos.Todo.GetAccessible(ctx, todoID, ownerID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to get todo %d: %v", todoID, err)
+		}
+		h.sendMessage(chatID, fmt.Sprintf("找不到待辦事項 #%d", todoID))
+		return
+	}
+
+	text, keyboard := h.statusCard(ctx, todo)
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(chatID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = keyboard
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send todo status: %v", err)
+	}
+}
+
+// statusCard renders the status history of a todo and the buttons moving it
+func (h *Handlers) statusCard(ctx context.Context, todo *models.Todo) (string, tgbotapi.InlineKeyboardMarkup) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📌 **%d.** %s\n目前狀態: %s%s\n", todo.TodoID, todo.Title, todo.Status.Label(), blockedSuffix(todo)))
+
+	history, err := h.repos.Todo.GetStatusHistory(ctx, todo.TodoID)
+	if err != nil {
+		log.Printf("Failed to get status history of todo %d: %v", todo.TodoID, err)
+	}
+	if len(history) > 0 {
+		names := h.memberNames(ctx, todo.UserID)
+		sb.WriteString("\n**狀態紀錄**\n")
+		for _, change := range history {
+			sb.WriteString(fmt.Sprintf("• %s %s%s\n", change.ChangedAt.Format("01-02 15:04"), change.Status.Label(), creatorSuffix(names, change.ChangedBy)))
+		}
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, status := range models.TodoStatuses {
+		if status == todo.Status {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(status.Label(), fmt.Sprintf("todo_status:%d:%s", todo.TodoID, status)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleStatusCallback handles "todo_status:<id>:<status>" of the status card
+func (h *Handlers) handleStatusCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || len(args) < 2 {
+		return
+	}
+	todoID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return
+	}
+	status := models.TodoStatus(args[1])
+	if !status.IsValid() {
+		return
+	}
+
+	ownerID := callbackSpaceID(callback)
+	todo, err := h.MoveTodo(ctx, todoID, ownerID, callback.From.ID, status)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to move todo %d to %s: %v", todoID, status, err)
+		}
+		h.answerCallbackWithAlert(callback.ID, "更新狀態失敗，待辦事項可能已刪除")
+		return
+	}
+
+	text, keyboard := h.statusCard(ctx, todo)
+	h.editMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+}
+
+// MoveTodo changes the status of a todo of userID on behalf of changedBy.
+// Completing goes through CompleteTodo so its notifications are sent, and
+// cancelling unblocks the todos waiting for it.
+func (h *Handlers) MoveTodo(ctx context.Context, todoID int, userID, changedBy int64, status models.TodoStatus) (*models.Todo, error) {
+	if status == models.TodoDone {
+		return h.CompleteTodo(ctx, todoID, userID)
+	}
+	changed, err := h.repos.Todo.SetStatus(ctx, todoID, userID, changedBy, status)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := h.repos.Todo.GetAccessible(ctx, todoID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if changed && status == models.TodoCancelled {
+		h.notifyUnblocked(ctx, todo)
+	}
+	return todo, nil
+}
+
+// moveTodoText moves a todo and returns the text for the user
+func (h *Handlers) moveTodoText(ctx context.Context, todoID int, userID, changedBy int64, status models.TodoStatus) string {
+	todo, err := h.MoveTodo(ctx, todoID, userID, changedBy, status)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to move todo %d to %s: %v", todoID, status, err)
+			return "更新狀態失敗，請稍後再試"
+		}
+		return fmt.Sprintf("找不到待辦事項 #%d", todoID)
+	}
+
+	text := fmt.Sprintf("📌 #%d %s → %s", todo.TodoID, todo.Title, status.Label())
+	if status == models.TodoWaiting {
+		text += "\n等待中的待辦不會提醒"
+	}
+	return text
+}
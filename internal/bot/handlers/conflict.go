@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+const (
+	// conflictTimeout is how long a conflict can be resolved
+	conflictTimeout = 30 * time.Minute
+	// conflictSuggestions is how many other slots a conflict offers
+	conflictSuggestions = 3
+	// conflictListed caps the overlapping occurrences listed
+	conflictListed = 5
+)
+
+// eventConflict holds an event creation or change overlapping other
+// events until the user keeps both, picks another slot or cancels
+type eventConflict struct {
+	change     *changeRequest
+	userID     int64
+	scope      models.RecurrenceScope
+	candidates []time.Time
+	expiresAt  time.Time
+}
+
+var (
+	pendingConflicts = make(map[int]*eventConflict) // conflict ID -> conflict
+	conflictMutex    sync.Mutex
+	lastConflictID   int
+)
+
+// changesTime reports whether the params of a change move an event or
+// change how long or how often it takes place
+func changesTime(params map[string]string) bool {
+	for _, key := range []string{"dtstart", "start_time", "duration", "rrule", "all_day", "end_date", "busy"} {
+		if params[key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConflicts looks for events overlapping the occurrences of event,
+// the outcome of change, and asks in chatID how to go on when there are
+// any, returning the question and whether it asked
+func (h *Handlers) checkConflicts(ctx context.Context, chatID, userID int64, change *changeRequest, scope models.RecurrenceScope, event *models.Event) (string, bool) {
+	now := time.Now()
+	conflicts, err := h.calendar.Conflicts(ctx, change.ownerID, event, change.id, now)
+	if err != nil {
+		// A failed check doesn't hold up the change
+		log.Printf("Failed to check event conflicts: %v", err)
+		return "", false
+	}
+	if len(conflicts) == 0 {
+		return "", false
+	}
+
+	conflict := &eventConflict{
+		change:    change,
+		userID:    userID,
+		scope:     scope,
+		expiresAt: now.Add(conflictTimeout),
+	}
+	// Offer slots of the same length around the first conflict; whole
+	// days don't fit into free time
+	length := calendar.Length(event)
+	if !event.AllDay {
+		first := *conflicts[0].NextOccurrence
+		day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, first.Location())
+		free, _ := h.freeSlots(ctx, change.ownerID, day, day.AddDate(0, 0, 2), now)
+		conflict.candidates = freeTimeCandidates(free, length, conflictSuggestions)
+	}
+	conflictMutex.Lock()
+	lastConflictID++
+	id := lastConflictID
+	pendingConflicts[id] = conflict
+	for conflictID, c := range pendingConflicts {
+		if now.After(c.expiresAt) {
+			delete(pendingConflicts, conflictID)
+		}
+	}
+	conflictMutex.Unlock()
+
+	text := conflictText(event, conflicts)
+	reply := tgbotapi.NewMessage(chatID, text)
+	reply.ReplyMarkup = conflictKeyboard(id, conflict.candidates, length)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send event conflict: %v", err)
+	}
+	return text, true
+}
+
+// conflictText lists the occurrences overlapping the event
+func conflictText(event *models.Event, conflicts []*models.Event) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⚠️ 「%s」和以下行程時間重疊：\n\n", event.Title))
+	for i, c := range conflicts {
+		if i == conflictListed {
+			sb.WriteString(fmt.Sprintf("…還有 %d 個\n", len(conflicts)-conflictListed))
+			break
+		}
+		if c.AllDay {
+			sb.WriteString(fmt.Sprintf("• %s %s (#%d)\n", allDayText(c), c.Title, c.EventID))
+			continue
+		}
+		start := *c.NextOccurrence
+		sb.WriteString(fmt.Sprintf("• %s %s-%s %s (#%d)\n", planDayLabel(start), start.Format("15:04"),
+			calendar.End(c, start).Format("15:04"), c.Title, c.EventID))
+	}
+	sb.WriteString("\n要保留兩者、改到其他時段，還是取消？")
+	return sb.String()
+}
+
+// conflictKeyboard offers keeping both, each suggested slot and cancelling
+func conflictKeyboard(id int, candidates []time.Time, length time.Duration) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ 保留兩者", fmt.Sprintf("conflict:keep:%d", id)),
+	)}
+	for i, start := range candidates {
+		label := fmt.Sprintf("🕒 改到 %s %s-%s", planDayLabel(start), start.Format("15:04"), start.Add(length).Format("15:04"))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("conflict:slot:%d:%d", id, i)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("conflict:cancel:%d", id)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleConflictCallback handles "conflict:keep:<id>",
+// "conflict:slot:<id>:<index>" and "conflict:cancel:<id>" of a conflict
+func (h *Handlers) handleConflictCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || len(args) < 2 {
+		return
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	conflictMutex.Lock()
+	conflict, ok := pendingConflicts[id]
+	if ok && (time.Now().After(conflict.expiresAt) || conflict.change.ownerID != callbackSpaceID(callback)) {
+		ok = false
+	}
+	if ok {
+		delete(pendingConflicts, id)
+	}
+	conflictMutex.Unlock()
+	if !ok {
+		h.editMessageText(chatID, messageID, "⏰ 此操作已過期，請重新說一次")
+		return
+	}
+
+	change := *conflict.change
+	change.force = true
+	switch args[0] {
+	case "keep":
+	case "slot":
+		if len(args) < 3 {
+			return
+		}
+		i, err := strconv.Atoi(args[2])
+		if err != nil || i < 0 || i >= len(conflict.candidates) {
+			return
+		}
+		change.params = maps.Clone(change.params)
+		change.params["dtstart"] = conflict.candidates[i].Format("2006-01-02 15:04")
+		delete(change.params, "start_time")
+	default:
+		h.editMessageText(chatID, messageID, "已取消")
+		return
+	}
+
+	var result string
+	if change.id == 0 {
+		result, _ = h.createEvent(ctx, chatID, conflict.userID, &change)
+	} else {
+		result, _ = h.changeEvent(ctx, chatID, conflict.userID, &change, conflict.scope)
+	}
+	h.editMessageText(chatID, messageID, result)
+}
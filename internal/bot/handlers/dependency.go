@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// handleBlock makes a todo wait for others: "/block <編號> [by] <前置編號>..."
+func (h *Handlers) handleBlock(ctx context.Context, msg *tgbotapi.Message) {
+	usage := "用法: /block <待辦編號> <前置待辦編號>\n例如: /block 12 10 11 表示 #12 要等 #10 和 #11 完成"
+
+	todoID, blockerIDs, ok := parseDependencyArgs(msg.CommandArguments())
+	if !ok {
+		h.sendMessage(msg.Chat.ID, "請提供待辦編號與前置待辦編號\n"+usage)
+		return
+	}
+
+	var lines []string
+	for _, blockerID := range blockerIDs {
+		lines = append(lines, h.blockTodo(ctx, spaceID(msg), todoID, blockerID))
+	}
+	h.sendMessage(msg.Chat.ID, strings.Join(lines, "\n"))
+}
+
+// handleUnblock removes dependencies: "/unblock <編號> <前置編號>..."
+func (h *Handlers) handleUnblock(ctx context.Context, msg *tgbotapi.Message) {
+	usage := "用法: /unblock <待辦編號> <前置待辦編號>"
+
+	todoID, blockerIDs, ok := parseDependencyArgs(msg.CommandArguments())
+	if !ok {
+		h.sendMessage(msg.Chat.ID, "請提供待辦編號與前置待辦編號\n"+usage)
+		return
+	}
+
+	var lines []string
+	for _, blockerID := range blockerIDs {
+		lines = append(lines, h.unblockTodo(ctx, spaceID(msg), todoID, blockerID))
+	}
+	h.sendMessage(msg.Chat.ID, strings.Join(lines, "\n"))
+}
+
+// parseDependencyArgs parses "<編號> [by] <前置編號>...", also accepting
+// "#" before the numbers
+func parseDependencyArgs(args string) (todoID int, blockerIDs []int, ok bool) {
+	var ids []int
+	for _, word := range strings.Fields(strings.ReplaceAll(args, ",", " ")) {
+		if strings.EqualFold(word, "by") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(word, "#"))
+		if err != nil {
+			return 0, nil, false
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 {
+		return 0, nil, false
+	}
+	return ids[0], ids[1:], true
+}
+
+// blockTodo makes todoID of ownerID wait for blockerID and returns the text
+// for the user
+func (h *Handlers) blockTodo(ctx context.Context, ownerID int64, todoID, blockerID int) string {
+	err := h.repos.Todo.AddDependency(ctx, ownerID, todoID, blockerID)
+	switch {
+	case errors.Is(err, repository.ErrDependencyCycle):
+		return fmt.Sprintf("❌ #%d 不能等待 #%d，這會造成互相等待", todoID, blockerID)
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Sprintf("❌ 找不到待辦事項 #%d 或 #%d", todoID, blockerID)
+	case err != nil:
+		log.Printf("Failed to add dependency %d -> %d: %v", todoID, blockerID, err)
+		return "設定前置工作失敗，請稍後再試"
+	}
+	return fmt.Sprintf("🔒 #%d 會等 #%d 完成後才開始提醒", todoID, blockerID)
+}
+
+// blockByParam makes todoID wait for the todos listed in the AI's
+// blocked_by parameter and returns the results as extra lines
+func (h *Handlers) blockByParam(ctx context.Context, ownerID int64, todoID int, param string) string {
+	var result string
+	for _, word := range strings.Fields(strings.ReplaceAll(param, ",", " ")) {
+		blockerID, err := strconv.Atoi(strings.TrimPrefix(word, "#"))
+		if err != nil {
+			continue
+		}
+		result += "\n" + h.blockTodo(ctx, ownerID, todoID, blockerID)
+	}
+	return result
+}
+
+// unblockTodo stops todoID of ownerID waiting for blockerID and returns the
+// text for the user
+func (h *Handlers) unblockTodo(ctx context.Context, ownerID int64, todoID, blockerID int) string {
+	if err := h.repos.Todo.RemoveDependency(ctx, ownerID, todoID, blockerID); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to remove dependency %d -> %d: %v", todoID, blockerID, err)
+			return "移除前置工作失敗，請稍後再試"
+		}
+		return fmt.Sprintf("❌ #%d 沒有在等待 #%d", todoID, blockerID)
+	}
+	return fmt.Sprintf("🔓 #%d 不再等待 #%d", todoID, blockerID)
+}
+
+// notifyUnblocked tells the owner, or the assignee who accepted it, about
+// each todo whose last blocker was the completed todo
+func (h *Handlers) notifyUnblocked(ctx context.Context, completed *models.Todo) {
+	todos, err := h.repos.Todo.GetUnblockedBy(ctx, completed.TodoID)
+	if err != nil {
+		log.Printf("Failed to get todos unblocked by %d: %v", completed.TodoID, err)
+		return
+	}
+	for _, todo := range todos {
+		chatID := todo.UserID
+		if todo.AssigneeID != nil && todo.AssignmentStatus == models.AssignmentAccepted {
+			chatID = *todo.AssigneeID
+		}
+		h.sendMessage(chatID, fmt.Sprintf("🔓 待辦事項 #%d「%s」的前置工作都已完成，可以開始了！", todo.TodoID, todo.Title))
+	}
+}
+
+// todoStatusIcon is the checkbox of a todo line; blocked todos are greyed
+// out with a lock, todos in progress or waiting show their board status
+func todoStatusIcon(todo *models.Todo) string {
+	switch {
+	case todo.Status == models.TodoCancelled:
+		return "🚫"
+	case todo.IsCompleted():
+		return "✅"
+	case todo.IsBlocked():
+		return "🔒"
+	case todo.Status == models.TodoDoing:
+		return "🔄"
+	case todo.Status == models.TodoWaiting:
+		return "⏳"
+	}
+	return "⬜"
+}
+
+// blockedSuffix lists the open todos a todo waits for
+func blockedSuffix(todo *models.Todo) string {
+	if todo.IsCompleted() || !todo.IsBlocked() {
+		return ""
+	}
+	ids := make([]string, len(todo.BlockedBy))
+	for i, id := range todo.BlockedBy {
+		ids[i] = fmt.Sprintf("#%d", id)
+	}
+	return " (等待 " + strings.Join(ids, ", ") + ")"
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 	"github.com/hray3182/LifeLine/internal/rrule"
 )
@@ -14,7 +17,7 @@ import (
 func (h *Handlers) handleEvent(ctx context.Context, msg *tgbotapi.Message) {
 	args := strings.TrimSpace(msg.CommandArguments())
 	if args == "" {
-		h.sendMessage(msg.Chat.ID, "請提供事件標題\n用法: /event <標題> [時間]\n例如: /event 開會 15:30")
+		h.sendMessage(msg.Chat.ID, "請提供事件標題\n用法: /event <標題> [時間|日期|日期~日期]\n例如: /event 開會 15:30 或 /event 連假 2026-10-09~2026-10-11")
 		return
 	}
 
@@ -24,23 +27,40 @@ func (h *Handlers) handleEvent(ctx context.Context, msg *tgbotapi.Message) {
 	var dtstart *time.Time
 
 	if len(parts) > 1 {
-		// Try to parse the last part as time
+		// Try to parse the last part as time, or as the day or days of an all-day event
 		lastPart := parts[len(parts)-1]
 		if t, err := parseTimeToday(lastPart); err == nil {
 			dtstart = &t
 			title = strings.Join(parts[:len(parts)-1], " ")
+		} else if day, days, ok := parseDayRange(lastPart); ok {
+			title = strings.Join(parts[:len(parts)-1], " ")
+			h.createAllDayEvent(ctx, msg, title, day, days)
+			return
 		} else {
 			title = args
 		}
 	}
 
 	event := &models.Event{
-		UserID:              msg.From.ID,
-		Title:               title,
-		Dtstart:             dtstart,
-		NextOccurrence:      dtstart,
-		Duration:            60, // Default 60 minutes
-		NotificationMinutes: 30,
+		UserID:         spaceID(msg),
+		Title:          title,
+		Dtstart:        dtstart,
+		NextOccurrence: dtstart,
+		Duration:       60, // Default 60 minutes
+		Alerts:         h.eventAlerts(ctx, spaceID(msg)),
+		Tags:           hashtags(title),
+		CreatedBy:      &msg.From.ID,
+	}
+	if dtstart != nil {
+		// Overlaps ask the same way as creating the event by chat; the
+		// answer creates it from the change
+		change := &changeRequest{
+			ownerID: event.UserID,
+			params:  map[string]string{"title": title, "dtstart": dtstart.Format("2006-01-02 15:04"), "tags": event.Tags},
+		}
+		if _, asked := h.checkConflicts(ctx, msg.Chat.ID, msg.From.ID, change, models.ScopeAll, event); asked {
+			return
+		}
 	}
 
 	if err := h.repos.Event.Create(ctx, event); err != nil {
@@ -54,24 +74,96 @@ func (h *Handlers) handleEvent(ctx context.Context, msg *tgbotapi.Message) {
 		timeStr = dtstart.Format("2006-01-02 15:04")
 	}
 
-	h.sendMessage(msg.Chat.ID, fmt.Sprintf("📅 事件已建立\n標題: %s\n時間: %s", title, timeStr))
+	h.sendWithTagSuggestions(ctx, msg, fmt.Sprintf("📅 事件已建立\n標題: %s\n時間: %s", title, timeStr), models.EntityEvent, event.EventID, title)
 }
 
+// createAllDayEvent creates the all-day event of "/event <標題> <日期>"
+func (h *Handlers) createAllDayEvent(ctx context.Context, msg *tgbotapi.Message, title string, day time.Time, days int) {
+	event, err := h.CreateAllDayEvent(ctx, spaceID(msg), msg.From.ID, title, "", day, days, true, nil, "", hashtags(title))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "建立事件失敗，請稍後再試")
+		return
+	}
+	h.sendWithTagSuggestions(ctx, msg, fmt.Sprintf("📅 事件已建立\n標題: %s\n時間: %s", title, allDayText(event)), models.EntityEvent, event.EventID, title)
+}
+
+// allDayText describes the days of an all-day occurrence with their dates
+func allDayText(e *models.Event) string {
+	start := e.NextOccurrence
+	if start == nil {
+		start = e.Dtstart
+	}
+	if e.Days() > 1 || start == nil {
+		return calendar.AllDayLabel(e)
+	}
+	return planDayLabel(*start) + " " + calendar.AllDayLabel(e)
+}
+
+// parseDayRange parses "YYYY-MM-DD" or "YYYY-MM-DD~YYYY-MM-DD" into the
+// first day and the number of days, both ends included
+func parseDayRange(s string) (time.Time, int, bool) {
+	first, last, isRange := strings.Cut(s, "~")
+	day, err := time.ParseInLocation("2006-01-02", first, time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	if !isRange {
+		return day, 1, true
+	}
+	end, err := time.ParseInLocation("2006-01-02", last, time.Local)
+	if err != nil || end.Before(day) {
+		return time.Time{}, 0, false
+	}
+	return day, daysBetween(day, end) + 1, true
+}
+
+// daysBetween returns the number of calendar days from the day of a to the day of b
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 12, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 12, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// handleEventList lists all events, or the events matching "/events <篩選條件>"
 func (h *Handlers) handleEventList(ctx context.Context, msg *tgbotapi.Message) {
-	// Get all events for the user
-	events, err := h.repos.Event.GetByUserID(ctx, msg.From.ID)
+	h.listEvents(ctx, msg, msg.CommandArguments())
+}
+
+func (h *Handlers) listEvents(ctx context.Context, msg *tgbotapi.Message, query string) {
+	var events []*models.Event
+	var f *filter.Filter
+	var err error
+	if strings.TrimSpace(query) != "" {
+		var ok bool
+		if f, ok = h.parseFilter(ctx, msg, query); !ok {
+			return
+		}
+		events, err = h.repos.Event.Filter(ctx, spaceID(msg), f)
+	} else {
+		events, err = h.repos.Event.GetByUserID(ctx, spaceID(msg))
+	}
 	if err != nil {
-		h.sendMessage(msg.Chat.ID, "取得事件列表失敗，請稍後再試")
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得事件列表失敗，請稍後再試"))
 		return
 	}
 
 	if len(events) == 0 {
-		h.sendMessage(msg.Chat.ID, "📅 目前沒有事件")
+		if f != nil {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("📅 沒有符合「%s」的事件", f.Query))
+		} else {
+			h.sendMessage(msg.Chat.ID, "📅 目前沒有事件")
+		}
 		return
 	}
 
+	names := h.memberNames(ctx, spaceID(msg))
+
 	var sb strings.Builder
-	sb.WriteString("📅 **近期事件**\n")
+	if f != nil {
+		sb.WriteString(fmt.Sprintf("📅 **事件** · %s (%d)\n", f.Query, len(events)))
+	} else {
+		sb.WriteString("📅 **近期事件**\n")
+	}
 
 	// 按日期分組
 	currentDate := ""
@@ -117,10 +209,12 @@ func (h *Handlers) handleEventList(ctx context.Context, msg *tgbotapi.Message) {
 			timeStr = eventTime.Format("15:04")
 		}
 
-		if timeStr != "" {
-			sb.WriteString(fmt.Sprintf("🕐 %s  %s\n", timeStr, event.Title))
+		if event.AllDay {
+			sb.WriteString(fmt.Sprintf("📆 %s  %s%s\n", calendar.AllDayLabel(event), event.Title, creatorSuffix(names, event.CreatedBy)))
+		} else if timeStr != "" {
+			sb.WriteString(fmt.Sprintf("🕐 %s  %s%s\n", timeStr, event.Title, creatorSuffix(names, event.CreatedBy)))
 		} else {
-			sb.WriteString(fmt.Sprintf("• %s\n", event.Title))
+			sb.WriteString(fmt.Sprintf("• %s%s\n", event.Title, creatorSuffix(names, event.CreatedBy)))
 		}
 
 		if event.IsRecurring() {
@@ -138,47 +232,72 @@ func (h *Handlers) handleEventList(ctx context.Context, msg *tgbotapi.Message) {
 	h.sendMessage(msg.Chat.ID, sb.String())
 }
 
-func (h *Handlers) CreateEvent(ctx context.Context, userID int64, title, description string, dtstart *time.Time, duration int, notificationMinutes int, recurrenceRule string, tags string) (*models.Event, error) {
-	if notificationMinutes == 0 {
-		notificationMinutes = 30
+// allDayNotificationMinutes notifies all-day events at 09:00 the day before
+const allDayNotificationMinutes = 15 * 60
+
+// eventAlerts returns the alerts new events of userID get unless they are given
+func (h *Handlers) eventAlerts(ctx context.Context, userID int64) []int {
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		return models.DefaultEventAlerts
+	}
+	return settings.EventAlerts
+}
+
+// CreateEvent creates an event; nil alerts use the user's default alerts
+func (h *Handlers) CreateEvent(ctx context.Context, userID, createdBy int64, title, description string, dtstart *time.Time, duration int, alerts []int, recurrenceRule string, tags string) (*models.Event, error) {
+	if alerts == nil {
+		alerts = h.eventAlerts(ctx, userID)
 	}
 	if duration == 0 {
 		duration = 60 // Default 60 minutes
 	}
 
 	event := &models.Event{
-		UserID:              userID,
-		Title:               title,
-		Description:         description,
-		Dtstart:             dtstart,
-		Duration:            duration,
-		NotificationMinutes: notificationMinutes,
-		RecurrenceRule:      recurrenceRule,
-		Tags:                tags,
+		UserID:         userID,
+		Title:          title,
+		Description:    description,
+		Dtstart:        dtstart,
+		Duration:       duration,
+		Alerts:         alerts,
+		RecurrenceRule: recurrenceRule,
+		Tags:           tags,
+		CreatedBy:      &createdBy,
 	}
 
 	// Calculate NextOccurrence
-	if dtstart != nil {
-		now := time.Now()
-		if recurrenceRule != "" {
-			// For recurring events, calculate the next occurrence
-			if dtstart.After(now) {
-				event.NextOccurrence = dtstart
-			} else {
-				// dtstart is in the past, find next occurrence
-				next, err := rrule.NextOccurrence(recurrenceRule, *dtstart, now)
-				if err != nil {
-					// Fallback to dtstart if RRULE parsing fails
-					event.NextOccurrence = dtstart
-				} else {
-					event.NextOccurrence = next
-				}
-			}
-		} else {
-			// One-time event
-			event.NextOccurrence = dtstart
-		}
+	event.NextOccurrence = calculateNextOccurrence(dtstart, recurrenceRule, nil)
+
+	err := h.repos.Event.Create(ctx, event)
+	if err == nil {
+		h.notifyScheduler()
+	}
+	return event, err
+}
+
+// CreateAllDayEvent creates an event taking up days whole days from the
+// date of day; a transparent one leaves those days free. Nil alerts
+// notify the day before.
+func (h *Handlers) CreateAllDayEvent(ctx context.Context, userID, createdBy int64, title, description string, day time.Time, days int, transparent bool, alerts []int, recurrenceRule string, tags string) (*models.Event, error) {
+	if alerts == nil {
+		alerts = []int{allDayNotificationMinutes}
 	}
+	dtstart := dayStart(day)
+	event := &models.Event{
+		UserID:         userID,
+		Title:          title,
+		Description:    description,
+		Dtstart:        &dtstart,
+		Duration:       max(days, 1) * models.MinutesPerDay,
+		Alerts:         alerts,
+		RecurrenceRule: recurrenceRule,
+		Tags:           tags,
+		CreatedBy:      &createdBy,
+		AllDay:         true,
+		Transparent:    transparent,
+	}
+	event.NextOccurrence = calculateNextOccurrence(&dtstart, recurrenceRule, nil)
 
 	err := h.repos.Event.Create(ctx, event)
 	if err == nil {
@@ -186,3 +305,62 @@ func (h *Handlers) CreateEvent(ctx context.Context, userID int64, title, descrip
 	}
 	return event, err
 }
+
+// UpdateEvent saves an edited event, recalculating its next occurrence from dtstart and rrule
+func (h *Handlers) UpdateEvent(ctx context.Context, event *models.Event) error {
+	if event.Dtstart != nil {
+		if err := h.repos.Event.LoadExceptions(ctx, event); err != nil {
+			return err
+		}
+		next := calculateNextOccurrence(event.Dtstart, event.RecurrenceRule, event.Exceptions)
+		// A new occurrence time needs its alerts sent again
+		if !sameTime(next, event.NextOccurrence) {
+			event.AlertsSent = nil
+		}
+		event.NextOccurrence = next
+	}
+
+	err := h.repos.Event.Update(ctx, event)
+	if err == nil {
+		h.notifyScheduler()
+	}
+	return err
+}
+
+// dayStart returns midnight of the day of t
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// calculateNextOccurrence returns the first occurrence that is not in the past,
+// skipping cancelled and moved occurrences
+func calculateNextOccurrence(dtstart *time.Time, recurrenceRule string, exceptions []*models.RecurrenceException) *time.Time {
+	if dtstart == nil {
+		return nil
+	}
+	if recurrenceRule == "" {
+		// One-time event
+		return dtstart
+	}
+
+	// For recurring events, calculate the next occurrence
+	now := time.Now()
+	if len(exceptions) > 0 {
+		next, err := calendar.NextOccurrence(recurrenceRule, rrule.WallClock(*dtstart), exceptions, now)
+		if err != nil {
+			return dtstart
+		}
+		return next
+	}
+	if dtstart.After(now) {
+		return dtstart
+	}
+
+	// dtstart is in the past, find next occurrence
+	next, err := rrule.NextOccurrence(recurrenceRule, *dtstart, now)
+	if err != nil {
+		// Fallback to dtstart if RRULE parsing fails
+		return dtstart
+	}
+	return next
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// filteredListLimit caps memos and transactions shown for a filter
+const filteredListLimit = 50
+
+const filterHelp = `🔎 **篩選語法**
+條件以空白分隔，需全部符合：
+• tag:work - 標籤
+• due:<fri、after:2026-10-01、before:+7 - 日期（today、tomorrow、週一~週日、YYYY-MM-DD、+N 天）
+• priority:>=4、project:日本旅行 - 優先級、專案（待辦）
+• amount:>500、cat:餐飲、type:支出 - 金額、類別、類型（記帳）
+• done - 已完成的待辦、已停用的提醒
+• blocked - 等待其他待辦完成的待辦
+• status:doing - 看板狀態（inbox、next、doing、waiting、done、cancelled）
+• 關鍵字 - 標題或內容包含
+• 在條件前加 - 表示排除，例如 -tag:work
+
+例如: /todos tag:work due:<fri priority:>=4
+/transactions after:2026-10-01 amount:>500 cat:餐飲`
+
+var filterNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+// filterTargets are the list commands a filter can be saved for
+var filterTargets = map[string]string{
+	"todos":        "待辦事項",
+	"events":       "事件",
+	"reminders":    "提醒",
+	"memos":        "備忘錄",
+	"transactions": "交易記錄",
+}
+
+var filterFieldLabels = map[string]string{
+	filter.FieldText:     "關鍵字",
+	filter.FieldTag:      "標籤",
+	filter.FieldDone:     "完成狀態",
+	filter.FieldPriority: "優先級",
+	filter.FieldDate:     "日期",
+	filter.FieldAmount:   "金額",
+	filter.FieldCategory: "類別",
+	filter.FieldType:     "類型",
+	filter.FieldProject:  "專案",
+	filter.FieldBlocked:  "阻擋狀態",
+	filter.FieldStatus:   "看板狀態",
+}
+
+// parseFilter parses a list command's arguments with dates in the space's
+// time zone, and explains the syntax when they are invalid
+func (h *Handlers) parseFilter(ctx context.Context, msg *tgbotapi.Message, query string) (*filter.Filter, bool) {
+	now := time.Now()
+	if settings, err := h.repos.UserSettings.GetByUserID(ctx, spaceID(msg)); err == nil {
+		now = now.In(settings.Location())
+	}
+
+	f, err := filter.Parse(query, now)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "")+"\n\n"+filterHelp)
+		return nil, false
+	}
+	return f, true
+}
+
+// filterErrorText explains an invalid filter; other errors are logged and
+// reported with fallback
+func filterErrorText(err error, fallback string) string {
+	var unsupported *filter.UnsupportedError
+	if errors.As(err, &unsupported) {
+		return fmt.Sprintf("此列表不支援「%s」條件", filterFieldLabels[unsupported.Field])
+	}
+	var invalid *filter.Error
+	if errors.As(err, &invalid) {
+		return fmt.Sprintf("無法理解篩選條件「%s」", invalid.Term)
+	}
+	log.Printf("Failed to list filtered items: %v", err)
+	return fallback
+}
+
+// handleTransactionList lists the latest transactions, or those matching
+// "/transactions <篩選條件>"
+func (h *Handlers) handleTransactionList(ctx context.Context, msg *tgbotapi.Message) {
+	h.listTransactions(ctx, msg, msg.CommandArguments())
+}
+
+func (h *Handlers) listTransactions(ctx context.Context, msg *tgbotapi.Message, query string) {
+	var transactions []*models.Transaction
+	var f *filter.Filter
+	var err error
+	if strings.TrimSpace(query) != "" {
+		var ok bool
+		if f, ok = h.parseFilter(ctx, msg, query); !ok {
+			return
+		}
+		transactions, err = h.repos.Transaction.Filter(ctx, spaceID(msg), f, filteredListLimit)
+	} else {
+		transactions, err = h.repos.Transaction.GetByUserID(ctx, spaceID(msg), 20, 0)
+	}
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得交易記錄失敗，請稍後再試"))
+		return
+	}
+
+	if len(transactions) == 0 {
+		if f != nil {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("💰 沒有符合「%s」的交易記錄", f.Query))
+		} else {
+			h.sendMessage(msg.Chat.ID, "💰 目前沒有交易記錄")
+		}
+		return
+	}
+
+	var sb strings.Builder
+	if f != nil {
+		sb.WriteString(fmt.Sprintf("💰 **交易記錄** · %s (%d)\n\n", f.Query, len(transactions)))
+	} else {
+		sb.WriteString("💰 **最近的交易記錄**\n\n")
+	}
+
+	var income, expense float64
+	for _, tx := range transactions {
+		sign := "-"
+		switch tx.Type {
+		case models.TransactionTypeIncome:
+			sign = "+"
+			income += tx.Amount
+		case models.TransactionTypeSettlement:
+			sign = "↔"
+		default:
+			expense += tx.Amount
+		}
+
+		sb.WriteString(fmt.Sprintf("**%d.** %s$%.0f", tx.TransactionID, sign, tx.Amount))
+		if tx.Description != "" {
+			sb.WriteString(" " + truncateRunes(tx.Description, 30))
+		}
+		if tx.TransactionDate != nil {
+			sb.WriteString(fmt.Sprintf(" (%s)", tx.TransactionDate.Format("2006-01-02")))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("\n收入 $%.0f · 支出 $%.0f", income, expense))
+
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// handleFilter manages saved filters:
+// "/filter save <名稱> <todos|events|reminders|memos|transactions> <條件>", "/filter delete <名稱>"
+// and "/filter" to list them. Filters belong to the chat's space.
+func (h *Handlers) handleFilter(ctx context.Context, msg *tgbotapi.Message) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(sub) {
+	case "save":
+		h.saveFilter(ctx, msg, rest)
+	case "delete", "del", "rm":
+		if rest == "" {
+			h.sendMessage(msg.Chat.ID, "請提供名稱\n用法: /filter delete <名稱>")
+			return
+		}
+		if err := h.repos.SavedFilter.Delete(ctx, spaceID(msg), rest); err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("Failed to delete saved filter: %v", err)
+			}
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("找不到名為「%s」的篩選", rest))
+			return
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 已刪除篩選「%s」", rest))
+	case "help":
+		h.sendMessage(msg.Chat.ID, filterHelp)
+	default:
+		h.listSavedFilters(ctx, msg)
+	}
+}
+
+func (h *Handlers) saveFilter(ctx context.Context, msg *tgbotapi.Message, args string) {
+	usage := "用法: /filter save <名稱> <todos|events|reminders|memos|transactions> <條件>\n例如: /filter save work todos tag:work -done"
+
+	fields := strings.Fields(args)
+	if len(fields) < 3 {
+		h.sendMessage(msg.Chat.ID, usage)
+		return
+	}
+	name, target := fields[0], strings.ToLower(strings.TrimPrefix(fields[1], "/"))
+	query := strings.Join(fields[2:], " ")
+
+	if !filterNamePattern.MatchString(name) {
+		h.sendMessage(msg.Chat.ID, "名稱只能包含文字、數字、_ 或 -，最多 32 字\n"+usage)
+		return
+	}
+	if _, ok := filterTargets[target]; !ok {
+		h.sendMessage(msg.Chat.ID, "無效的列表，請使用 todos、events、reminders、memos 或 transactions\n"+usage)
+		return
+	}
+	if _, ok := h.parseFilter(ctx, msg, query); !ok {
+		return
+	}
+
+	saved := &models.SavedFilter{
+		UserID:    spaceID(msg),
+		Name:      name,
+		Target:    target,
+		Query:     query,
+		CreatedBy: &msg.From.ID,
+	}
+	if err := h.repos.SavedFilter.Save(ctx, saved); err != nil {
+		log.Printf("Failed to save filter: %v", err)
+		h.sendMessage(msg.Chat.ID, "儲存篩選失敗，請稍後再試")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 已儲存篩選「%s」（%s: %s）\n使用 /f %s 查看", name, filterTargets[target], query, name))
+}
+
+func (h *Handlers) listSavedFilters(ctx context.Context, msg *tgbotapi.Message) {
+	filters, err := h.repos.SavedFilter.GetByUserID(ctx, spaceID(msg))
+	if err != nil {
+		log.Printf("Failed to get saved filters: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得篩選列表失敗，請稍後再試")
+		return
+	}
+
+	usage := "/filter save <名稱> <todos|events|reminders|memos|transactions> <條件> - 儲存篩選\n/filter delete <名稱> - 刪除篩選\n/filter help - 篩選語法"
+	if len(filters) == 0 {
+		h.sendMessage(msg.Chat.ID, "🔖 還沒有儲存的篩選\n\n"+usage)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔖 **儲存的篩選**\n\n")
+	for _, f := range filters {
+		sb.WriteString(fmt.Sprintf("• /f %s - %s: %s\n", f.Name, filterTargets[f.Target], f.Query))
+	}
+	sb.WriteString("\n" + usage)
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// handleSmartList runs a saved filter: "/f <名稱> [額外條件]"
+func (h *Handlers) handleSmartList(ctx context.Context, msg *tgbotapi.Message) {
+	name, extra, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	if name == "" {
+		h.listSavedFilters(ctx, msg)
+		return
+	}
+
+	saved, err := h.repos.SavedFilter.GetByName(ctx, spaceID(msg), name)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to get saved filter: %v", err)
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("找不到名為「%s」的篩選，使用 /filter 查看已儲存的篩選", name))
+		return
+	}
+
+	query := strings.TrimSpace(saved.Query + " " + extra)
+	switch saved.Target {
+	case "todos":
+		h.listTodos(ctx, msg, query)
+	case "events":
+		h.listEvents(ctx, msg, query)
+	case "reminders":
+		h.listReminders(ctx, msg, query)
+	case "memos":
+		h.listMemos(ctx, msg, query)
+	case "transactions":
+		h.listTransactions(ctx, msg, query)
+	}
+}
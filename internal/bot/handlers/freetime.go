@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+const (
+	// freeTimeDefaultMinutes is the length of the event created from a
+	// candidate when the search has no minimum length
+	freeTimeDefaultMinutes = 60
+	// freeTimeDefaultCount is how many candidates a search offers
+	freeTimeDefaultCount = 5
+	// freeTimeMaxCount caps the candidates asked for
+	freeTimeMaxCount = 10
+	// freeTimeMaxDays caps the days a search covers
+	freeTimeMaxDays = 31
+	// freeTimeTimeout is how long the candidates can be tapped
+	freeTimeTimeout = 30 * time.Minute
+	// freeTimeDefaultTitle names the event when the search has no title
+	freeTimeDefaultTitle = "預留時間"
+)
+
+const freeUsage = `用法: /free [today|tomorrow|week|YYYY-MM-DD] [時長] [標題]
+例如: /free week 90 看牙醫 或 /free tomorrow 1h30m`
+
+// timeSlot is a span of time, with the title of what fills it when busy
+type timeSlot struct {
+	start time.Time
+	end   time.Time
+	title string
+}
+
+// freeTimeQuery is what a free time search looks for
+type freeTimeQuery struct {
+	from    time.Time // midnight of the first searched day
+	days    int
+	minutes int // minimum length of a slot, 0 for any
+	count   int
+	title   string
+}
+
+// freeTimeSearch holds the candidates of a search until one is tapped
+type freeTimeSearch struct {
+	ownerID    int64
+	title      string
+	minutes    int
+	candidates []time.Time
+	expiresAt  time.Time
+}
+
+var (
+	pendingFreeTimes = make(map[int]*freeTimeSearch) // search ID -> search
+	freeTimeMutex    sync.Mutex
+	lastFreeTimeID   int
+)
+
+// handleFree searches free time: "/free" for the next seven days,
+// optionally narrowed to a day, with a minimum length and the title of
+// the event to create from a candidate
+func (h *Handlers) handleFree(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+
+	now := time.Now()
+	q := freeTimeQuery{
+		from:  time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		days:  7,
+		count: freeTimeDefaultCount,
+	}
+	if len(args) > 0 {
+		if from, days, ok := parsePlanRange(args[0], ""); ok {
+			q.from, q.days = from, days
+			args = args[1:]
+		}
+	}
+	if len(args) > 0 {
+		if minutes, ok := parseMinutes(args[0]); ok {
+			q.minutes = minutes
+			args = args[1:]
+		}
+	}
+	if len(args) > 0 && args[0] == "help" {
+		h.sendMessage(msg.Chat.ID, freeUsage)
+		return
+	}
+	q.title = strings.Join(args, " ")
+
+	h.findFreeTime(ctx, msg.Chat.ID, spaceID(msg), q, true)
+}
+
+// findFreeTime searches the free time of ownerID and returns the text for
+// the user; when send is set the text is sent with the best candidates as
+// buttons creating an event
+func (h *Handlers) findFreeTime(ctx context.Context, chatID, ownerID int64, q freeTimeQuery, send bool) string {
+	now := time.Now()
+	to := q.from.AddDate(0, 0, q.days)
+	free, busy := h.freeSlots(ctx, ownerID, q.from, to, now)
+
+	minLength := time.Duration(q.minutes) * time.Minute
+	length := minLength
+	if length == 0 {
+		length = freeTimeDefaultMinutes * time.Minute
+	}
+	candidates := freeTimeCandidates(free, length, q.count)
+
+	text := freeTimeText(q, free, busy, candidates, length, now)
+	if !send {
+		return text
+	}
+	if len(candidates) == 0 {
+		h.sendMessage(chatID, text)
+		return text
+	}
+
+	title := q.title
+	if title == "" {
+		title = freeTimeDefaultTitle
+	}
+	search := &freeTimeSearch{
+		ownerID:    ownerID,
+		title:      title,
+		minutes:    int(length.Minutes()),
+		candidates: candidates,
+		expiresAt:  now.Add(freeTimeTimeout),
+	}
+	freeTimeMutex.Lock()
+	lastFreeTimeID++
+	id := lastFreeTimeID
+	pendingFreeTimes[id] = search
+	for searchID, s := range pendingFreeTimes {
+		if now.After(s.expiresAt) {
+			delete(pendingFreeTimes, searchID)
+		}
+	}
+	freeTimeMutex.Unlock()
+
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(chatID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = freeTimeKeyboard(id, search)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send free time: %v", err)
+	}
+	return text
+}
+
+// freeTimeText lists the free slots at least as long as the query's
+// minimum, the events of the range and the candidates
+func freeTimeText(q freeTimeQuery, free, busy []timeSlot, candidates []time.Time, length time.Duration, now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	minLength := time.Duration(q.minutes) * time.Minute
+
+	var sb strings.Builder
+	if q.days == 1 {
+		dateLabel := q.from.Format("2006-01-02")
+		if q.from.Equal(today) {
+			dateLabel = "今天 (" + q.from.Format("01/02") + ")"
+		} else if q.from.Equal(today.AddDate(0, 0, 1)) {
+			dateLabel = "明天 (" + q.from.Format("01/02") + ")"
+		}
+		sb.WriteString(fmt.Sprintf("【%s 的空閒時段】", dateLabel))
+	} else {
+		sb.WriteString(fmt.Sprintf("【%s - %s 的空閒時段】", planDayLabel(q.from), planDayLabel(q.from.AddDate(0, 0, q.days-1))))
+	}
+	if q.minutes > 0 {
+		sb.WriteString(fmt.Sprintf("\n至少 %s", formatSlotDuration(minLength)))
+	}
+	sb.WriteString("\n")
+
+	if q.days == 1 {
+		sb.WriteString("\n")
+	}
+	found := false
+	lastDay := ""
+	for _, slot := range free {
+		if slot.end.Sub(slot.start) < minLength {
+			continue
+		}
+		found = true
+		// Multi-day results are grouped under a heading per day
+		if day := planDayLabel(slot.start); q.days > 1 && day != lastDay {
+			sb.WriteString(fmt.Sprintf("\n**%s**\n", day))
+			lastDay = day
+		}
+		sb.WriteString(fmt.Sprintf("• %s - %s (%s)\n",
+			slot.start.Format("15:04"), slot.end.Format("15:04"), formatSlotDuration(slot.end.Sub(slot.start))))
+	}
+	if !found {
+		if q.days == 1 {
+			sb.WriteString("\n這天沒有空閒時間。\n")
+		} else {
+			sb.WriteString("\n這段期間沒有空閒時間。\n")
+		}
+	}
+
+	if len(busy) > 0 {
+		sb.WriteString("\n【已安排的事項】\n")
+		for _, slot := range busy {
+			when := slot.start.Format("15:04")
+			if q.days > 1 {
+				when = slot.start.Format("01/02 15:04")
+			}
+			sb.WriteString(fmt.Sprintf("• %s - %s: %s\n", when, slot.end.Format("15:04"), slot.title))
+		}
+	}
+
+	if len(candidates) > 0 {
+		sb.WriteString("\n【建議時段】\n")
+		for i, start := range candidates {
+			sb.WriteString(fmt.Sprintf("%d. %s %s - %s\n", i+1, planDayLabel(start),
+				start.Format("15:04"), start.Add(length).Format("15:04")))
+		}
+	}
+	return sb.String()
+}
+
+// formatSlotDuration formats the length of a slot in hours and minutes
+func formatSlotDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%d小時%d分鐘", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d小時", hours)
+	}
+	return fmt.Sprintf("%d分鐘", minutes)
+}
+
+// freeTimeCandidates picks up to count start times of free slots that fit
+// length. The earliest slot of each day comes first so the candidates
+// spread over the range, the remaining ones fill up by time.
+func freeTimeCandidates(free []timeSlot, length time.Duration, count int) []time.Time {
+	var fits []timeSlot
+	for _, slot := range free {
+		if slot.end.Sub(slot.start) >= length {
+			fits = append(fits, slot)
+		}
+	}
+
+	var picked []time.Time
+	days := make(map[string]bool)
+	for _, slot := range fits {
+		day := slot.start.Format("2006-01-02")
+		if len(picked) < count && !days[day] {
+			days[day] = true
+			picked = append(picked, slot.start)
+		}
+	}
+	for _, slot := range fits {
+		if len(picked) < count && !slices.ContainsFunc(picked, slot.start.Equal) {
+			picked = append(picked, slot.start)
+		}
+	}
+	slices.SortFunc(picked, time.Time.Compare)
+	return picked
+}
+
+// freeTimeKeyboard has a button per candidate creating the event there
+func freeTimeKeyboard(id int, search *freeTimeSearch) tgbotapi.InlineKeyboardMarkup {
+	length := time.Duration(search.minutes) * time.Minute
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, start := range search.candidates {
+		label := fmt.Sprintf("📅 %s %s-%s", planDayLabel(start), start.Format("15:04"), start.Add(length).Format("15:04"))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("free:pick:%d:%d", id, i)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("free:cancel:%d", id)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleFreeTimeCallback handles "free:pick:<id>:<index>" and
+// "free:cancel:<id>" of the candidates of a search
+func (h *Handlers) handleFreeTimeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || len(args) < 2 {
+		return
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	freeTimeMutex.Lock()
+	search, ok := pendingFreeTimes[id]
+	if ok && (time.Now().After(search.expiresAt) || search.ownerID != callbackSpaceID(callback)) {
+		ok = false
+	}
+	if ok {
+		delete(pendingFreeTimes, id)
+	}
+	freeTimeMutex.Unlock()
+	if !ok {
+		h.editMessageText(chatID, messageID, "⏰ 建議時段已過期，請重新搜尋空閒時間")
+		return
+	}
+
+	if args[0] != "pick" || len(args) < 3 {
+		h.editMessageText(chatID, messageID, "已取消")
+		return
+	}
+	i, err := strconv.Atoi(args[2])
+	if err != nil || i < 0 || i >= len(search.candidates) {
+		return
+	}
+
+	start := search.candidates[i]
+	event, err := h.CreateEvent(ctx, search.ownerID, callback.From.ID, search.title, "", &start, search.minutes, nil, "", "")
+	if err != nil {
+		log.Printf("Failed to create event from free time: %v", err)
+		h.editMessageText(chatID, messageID, "建立事件失敗，請稍後再試")
+		return
+	}
+	h.editMessageText(chatID, messageID, fmt.Sprintf("✅ 已建立事件 #%d「%s」\n📅 %s %s - %s", event.EventID, event.Title,
+		planDayLabel(start), start.Format("15:04"), start.Add(time.Duration(search.minutes)*time.Minute).Format("15:04")))
+}
+
+// freeSlots returns the free time of ownerID in [from, to) within the
+// working hours of each day, keeping the meeting buffer around events and
+// leaving out quiet hours and the time already past, with the busy
+// event occurrences of the range
+func (h *Handlers) freeSlots(ctx context.Context, ownerID int64, from, to, now time.Time) (freeSlots, busySlots []timeSlot) {
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, ownerID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		settings = models.NewDefaultUserSettings(ownerID)
+	}
+
+	busySlots = h.busySlots(ctx, ownerID, from, to)
+
+	buffer := time.Duration(settings.MeetingBuffer) * time.Minute
+	var blocked []timeSlot
+	for _, busy := range busySlots {
+		blocked = append(blocked, timeSlot{start: busy.start.Add(-buffer), end: busy.end.Add(buffer)})
+	}
+	// Quiet hours of the evening before can reach into the first morning
+	for day := from.AddDate(0, 0, -1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if start, end, ok := settings.QuietHoursFrom(day); ok {
+			blocked = append(blocked, timeSlot{start: start, end: end})
+		}
+	}
+	slices.SortFunc(blocked, func(a, b timeSlot) int {
+		return a.start.Compare(b.start)
+	})
+
+	earliest := nextHalfHour(now)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		start, end := settings.WorkingHours(day)
+		if start.Before(earliest) {
+			start = earliest
+		}
+		if !start.Before(end) {
+			continue
+		}
+
+		current := start
+		for _, b := range blocked {
+			if !b.end.After(current) || !b.start.Before(end) {
+				continue
+			}
+			if b.start.After(current) {
+				freeSlots = append(freeSlots, timeSlot{start: current, end: b.start})
+			}
+			current = b.end
+		}
+		if current.Before(end) {
+			freeSlots = append(freeSlots, timeSlot{start: current, end: end})
+		}
+	}
+	return freeSlots, busySlots
+}
+
+// busySlots returns every occurrence of the events of ownerID overlapping
+// [from, to), sorted by start
+func (h *Handlers) busySlots(ctx context.Context, ownerID int64, from, to time.Time) []timeSlot {
+	occurrences, err := h.calendar.Occurrences(ctx, ownerID, from, to)
+	if err != nil {
+		log.Printf("Failed to get events of %d: %v", ownerID, err)
+		return nil
+	}
+
+	busy := make([]timeSlot, 0, len(occurrences))
+	for _, e := range occurrences {
+		// Holidays, birthdays and other transparent events leave the time free
+		if e.Transparent {
+			continue
+		}
+		start := *e.NextOccurrence
+		busy = append(busy, timeSlot{start: start, end: calendar.End(e, start), title: e.Title})
+	}
+	return busy
+}
+
+// nextHalfHour rounds t up to the next full or half hour
+func nextHalfHour(t time.Time) time.Time {
+	rounded := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	for rounded.Before(t) {
+		rounded = rounded.Add(30 * time.Minute)
+	}
+	return rounded
+}
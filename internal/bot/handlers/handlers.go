@@ -8,13 +8,19 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/access"
 	"github.com/hray3182/LifeLine/internal/ai"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/export"
 	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/quota"
 	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/hray3182/LifeLine/internal/webhook"
 )
 
 type Repositories struct {
@@ -26,18 +32,37 @@ type Repositories struct {
 	Transaction  *repository.TransactionRepository
 	Event        *repository.EventRepository
 	UserSettings *repository.UserSettingsRepository
+	APIToken     *repository.APITokenRepository
+	Webhook      *repository.WebhookRepository
+	Space        *repository.SpaceRepository
+	Shopping     *repository.ShoppingRepository
+	Invite       *repository.InviteRepository
+	Stats        *repository.StatsRepository
+	Search       *repository.SearchRepository
+	SavedFilter  *repository.SavedFilterRepository
+	Tag          *repository.TagRepository
+	Project      *repository.ProjectRepository
 }
 
 type Handlers struct {
 	api             *tgbotapi.BotAPI
 	repos           *Repositories
+	calendar        *calendar.Service
 	ai              *ai.Client
+	exporter        *export.Exporter
 	devMode         bool
 	logger          *slog.Logger
 	schedulerNotify func()
+	webhooks        *webhook.Dispatcher
+	access          *access.Policy
+	quota           *quota.Tracker
+
+	// background is cancelled on shutdown; work outliving an update runs under it
+	background   context.Context
+	broadcasting atomic.Bool
 }
 
-func New(api *tgbotapi.BotAPI, repos *Repositories, aiClient *ai.Client, devMode bool) *Handlers {
+func New(api *tgbotapi.BotAPI, repos *Repositories, aiClient *ai.Client, exporter *export.Exporter, devMode bool) *Handlers {
 	// Setup logger based on devMode
 	var logger *slog.Logger
 	if devMode {
@@ -47,19 +72,53 @@ func New(api *tgbotapi.BotAPI, repos *Repositories, aiClient *ai.Client, devMode
 	}
 
 	return &Handlers{
-		api:     api,
-		repos:   repos,
-		ai:      aiClient,
-		devMode: devMode,
-		logger:  logger,
+		api:        api,
+		repos:      repos,
+		calendar:   calendar.New(repos.Event),
+		ai:         aiClient,
+		exporter:   exporter,
+		devMode:    devMode,
+		logger:     logger,
+		background: context.Background(),
 	}
 }
 
+// Repositories returns the repositories used by the handlers
+func (h *Handlers) Repositories() *Repositories {
+	return h.repos
+}
+
+// Calendar returns the service expanding events into occurrences
+func (h *Handlers) Calendar() *calendar.Service {
+	return h.calendar
+}
+
 // SetSchedulerNotify sets the scheduler notification function
 func (h *Handlers) SetSchedulerNotify(fn func()) {
 	h.schedulerNotify = fn
 }
 
+// SetWebhooks sets the dispatcher used to emit webhook events
+func (h *Handlers) SetWebhooks(d *webhook.Dispatcher) {
+	h.webhooks = d
+}
+
+// SetAccess sets the access policy checked by Authorize; without one the bot is open
+func (h *Handlers) SetAccess(p *access.Policy) {
+	h.access = p
+}
+
+// SetBackground sets the context of work that handlers start in the
+// background, such as broadcasts; it should be cancelled on shutdown
+func (h *Handlers) SetBackground(ctx context.Context) {
+	h.background = ctx
+}
+
+// SetQuota sets the AI usage tracker shown in the settings menu
+func (h *Handlers) SetQuota(t *quota.Tracker) {
+	h.quota = t
+}
+
 // notifyScheduler triggers the scheduler to check for pending items
 func (h *Handlers) notifyScheduler() {
 	if h.schedulerNotify != nil {
@@ -72,103 +131,21 @@ func (h *Handlers) debug(msg string, args ...any) {
 	h.logger.Debug(msg, args...)
 }
 
-func (h *Handlers) HandleCommand(ctx context.Context, msg *tgbotapi.Message) {
-	// Ensure user exists
-	_, err := h.repos.User.GetOrCreate(ctx, msg.From.ID, msg.From.UserName)
+// handleConfirmationCallback handles the AI confirmation buttons
+// "confirm:userID", "cancel:userID" and "option:userID:index". Unlike other
+// buttons they may only be pressed by the user who started the action.
+func (h *Handlers) handleConfirmationCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, action string, args []string) {
+	userID, err := strconv.ParseInt(args[0], 10, 64)
 	if err != nil {
-		log.Printf("Failed to get/create user: %v", err)
+		h.debug("handleConfirmationCallback: failed to parse userID", "error", err)
 		return
 	}
 
-	switch msg.Command() {
-	case "start":
-		h.handleStart(ctx, msg)
-	case "help":
-		h.handleHelp(ctx, msg)
-	case "memo":
-		h.handleMemo(ctx, msg)
-	case "memos":
-		h.handleMemoList(ctx, msg)
-	case "todo":
-		h.handleTodo(ctx, msg)
-	case "todos":
-		h.handleTodoList(ctx, msg)
-	case "done":
-		h.handleTodoDone(ctx, msg)
-	case "remind":
-		h.handleReminder(ctx, msg)
-	case "reminders":
-		h.handleReminderList(ctx, msg)
-	case "expense":
-		h.handleExpense(ctx, msg)
-	case "income":
-		h.handleIncome(ctx, msg)
-	case "balance":
-		h.handleBalance(ctx, msg)
-	case "event":
-		h.handleEvent(ctx, msg)
-	case "events":
-		h.handleEventList(ctx, msg)
-	case "settings":
-		h.handleSettings(ctx, msg)
-	default:
-		h.sendMessage(msg.Chat.ID, "未知指令，請使用 /help 查看可用指令")
-	}
-}
-
-func (h *Handlers) HandleMessage(ctx context.Context, msg *tgbotapi.Message) {
-	// Ensure user exists
-	_, err := h.repos.User.GetOrCreate(ctx, msg.From.ID, msg.From.UserName)
-	if err != nil {
-		log.Printf("Failed to get/create user: %v", err)
-		return
-	}
-
-	// Process with AI
-	h.handleAIMessage(ctx, msg)
-}
-
-func (h *Handlers) HandleCallbackQuery(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	h.debug("HandleCallbackQuery received", "data", callback.Data, "user_id", callback.From.ID)
-
-	// Answer callback to remove loading state
-	answer := tgbotapi.NewCallback(callback.ID, "")
-	if _, err := h.api.Request(answer); err != nil {
-		log.Printf("Failed to answer callback: %v", err)
-	}
-
-	// Parse callback data: "confirm:userID", "cancel:userID", "option:userID:index", or "remind_ack:reminderID"
-	parts := strings.Split(callback.Data, ":")
-	if len(parts) < 2 {
-		h.debug("HandleCallbackQuery: invalid callback data format", "parts", len(parts))
-		return
-	}
-
-	action := parts[0]
-
-	// Handle reminder acknowledgement separately (different format)
-	if action == "remind_ack" {
-		h.handleReminderAcknowledge(ctx, callback, parts[1])
-		return
-	}
-
-	// Handle settings callbacks (different format: settings:action:...)
-	if action == "settings" {
-		h.handleSettingsCallback(ctx, callback, parts[1:])
-		return
-	}
-
-	userID, err := strconv.ParseInt(parts[1], 10, 64)
-	if err != nil {
-		h.debug("HandleCallbackQuery: failed to parse userID", "error", err)
-		return
-	}
-
-	h.debug("HandleCallbackQuery parsed", "action", action, "target_user_id", userID)
+	h.debug("handleConfirmationCallback parsed", "action", action, "target_user_id", userID)
 
 	// Verify the callback is from the correct user
 	if callback.From.ID != userID {
-		h.debug("HandleCallbackQuery: user mismatch", "from_id", callback.From.ID, "target_id", userID)
+		h.debug("handleConfirmationCallback: user mismatch", "from_id", callback.From.ID, "target_id", userID)
 		h.answerCallbackWithAlert(callback.ID, "這不是你的操作")
 		return
 	}
@@ -178,10 +155,10 @@ func (h *Handlers) HandleCallbackQuery(ctx context.Context, callback *tgbotapi.C
 	pending, exists := pendingConfirmations[userID]
 	pendingMutex.RUnlock()
 
-	h.debug("HandleCallbackQuery: pending check", "exists", exists)
+	h.debug("handleConfirmationCallback: pending check", "exists", exists)
 
 	if !exists || time.Now().After(pending.ExpiresAt) {
-		h.debug("HandleCallbackQuery: confirmation expired or not found", "exists", exists)
+		h.debug("handleConfirmationCallback: confirmation expired or not found", "exists", exists)
 		if exists {
 			pendingMutex.Lock()
 			delete(pendingConfirmations, userID)
@@ -191,7 +168,7 @@ func (h *Handlers) HandleCallbackQuery(ctx context.Context, callback *tgbotapi.C
 		return
 	}
 
-	h.debug("HandleCallbackQuery: found valid pending confirmation", "intent_action", pending.Intent.Action)
+	h.debug("handleConfirmationCallback: found valid pending confirmation", "intent_action", pending.Intent.Action)
 
 	// Clear pending
 	pendingMutex.Lock()
@@ -206,28 +183,28 @@ func (h *Handlers) HandleCallbackQuery(ctx context.Context, callback *tgbotapi.C
 
 	switch action {
 	case "confirm":
-		h.debug("HandleCallbackQuery: executing confirm action")
+		h.debug("handleConfirmationCallback: executing confirm action")
 		h.executeAfterConfirmation(ctx, fakeMsg, callback.Message.Chat.ID, callback.Message.MessageID, pending.Intent, "已確認")
 	case "cancel":
-		h.debug("HandleCallbackQuery: executing cancel action")
+		h.debug("handleConfirmationCallback: executing cancel action")
 		h.editMessageText(callback.Message.Chat.ID, callback.Message.MessageID, "❌ 已取消操作")
 	case "option":
-		h.debug("HandleCallbackQuery: processing option selection")
+		h.debug("handleConfirmationCallback: processing option selection")
 		// Parse option index
-		if len(parts) != 3 {
-			h.debug("HandleCallbackQuery: invalid option format", "parts", len(parts))
+		if len(args) != 2 {
+			h.debug("handleConfirmationCallback: invalid option format", "args", len(args))
 			return
 		}
-		optionIndex, err := strconv.Atoi(parts[2])
+		optionIndex, err := strconv.Atoi(args[1])
 		if err != nil || optionIndex < 0 || optionIndex >= len(pending.Intent.ConfirmationOptions) {
-			h.debug("HandleCallbackQuery: invalid option index", "index", parts[2], "error", err)
+			h.debug("handleConfirmationCallback: invalid option index", "index", args[1], "error", err)
 			h.editMessageText(callback.Message.Chat.ID, callback.Message.MessageID, "❌ 無效的選項")
 			return
 		}
 
 		// Get selected option and merge parameters
 		selectedOption := pending.Intent.ConfirmationOptions[optionIndex]
-		h.debug("HandleCallbackQuery: selected option", "label", selectedOption.Label, "params", selectedOption.Parameters)
+		h.debug("handleConfirmationCallback: selected option", "label", selectedOption.Label, "params", selectedOption.Parameters)
 		if pending.Intent.Parameters == nil {
 			pending.Intent.Parameters = make(map[string]string)
 		}
@@ -235,13 +212,14 @@ func (h *Handlers) HandleCallbackQuery(ctx context.Context, callback *tgbotapi.C
 			pending.Intent.Parameters[key] = value
 		}
 
-		h.debug("HandleCallbackQuery: executing option action", "merged_params", pending.Intent.Parameters)
+		h.debug("handleConfirmationCallback: executing option action", "merged_params", pending.Intent.Parameters)
 		h.executeAfterConfirmation(ctx, fakeMsg, callback.Message.Chat.ID, callback.Message.MessageID, pending.Intent, fmt.Sprintf("已選擇「%s」", selectedOption.Label))
 	}
 }
 
 // executeAfterConfirmation handles execution after user confirmation, supporting ReturnResultToAI flow
 func (h *Handlers) executeAfterConfirmation(ctx context.Context, fakeMsg *tgbotapi.Message, chatID int64, messageID int, intent *ai.Intent, confirmText string) {
+	ctx = ai.WithUserID(ctx, fakeMsg.From.ID)
 	h.debug("executeAfterConfirmation", "action", intent.Action, "return_result_to_ai", intent.ReturnResultToAI)
 
 	var result string
@@ -264,13 +242,8 @@ func (h *Handlers) executeAfterConfirmation(ctx context.Context, fakeMsg *tgbota
 	if intent.ReturnResultToAI && h.ai != nil {
 		h.debug("ReturnResultToAI flow after confirmation")
 
-		// Build conversation history with the tool result
-		history := []ai.Message{
-			{Role: "assistant", Content: "[工具執行結果]\n" + result},
-		}
-
 		// Let AI decide next action
-		nextIntent, err := h.ai.ParseIntentWithHistory(ctx, history)
+		nextIntent, err := h.ai.ContinueWithToolResult(ctx, nil, result)
 		if err != nil {
 			log.Printf("Failed to parse next intent after confirmation: %v", err)
 			h.editMessageText(chatID, messageID, fmt.Sprintf("✅ %s\n\n%s", confirmText, result))
@@ -366,32 +339,91 @@ func (h *Handlers) handleHelp(ctx context.Context, msg *tgbotapi.Message) {
 
 **備忘錄**
 /memo <內容> - 新增備忘錄
-/memos - 查看備忘錄列表
+/memos [條件] - 查看備忘錄列表
 
 **待辦事項**
 /todo <標題> - 新增待辦
-/todos - 查看待辦列表
+/todos [條件] - 查看待辦列表
+/subtask <編號> <標題> - 新增子任務
+/block <編號> <前置編號> - 設定要等其他待辦完成
+/unblock <編號> <前置編號> - 移除前置待辦
 /done <編號> - 完成待辦
+/board [條件] - 看板檢視
+/status <編號> [狀態] - 移動待辦 (inbox、next、doing、waiting、done、cancelled) 或查看狀態紀錄
+/estimate <編號> <分鐘> - 設定預估時間
+/plan [today|tomorrow|week] - 把待辦排進空閒時間，接受後建立時間區塊事件
+/assign <編號> @使用者 - 指派待辦給他人
 • 設定截止時間的待辦會自動提醒
+• 對方接受指派後改由對方收到提醒，完成時會通知你
+• 🔒 被阻擋的待辦不會提醒，前置工作都完成時會通知你
+• ⏳ 等待中與已取消的待辦不會提醒
+
+**專案**
+/projects - 查看專案與進度
+/project new <名稱> [color:<顏色>] [tags:<標籤>] - 建立專案
+/project <名稱> - 查看專案的待辦事項
+/todo <標題> project:<名稱> - 在專案中新增待辦
 
 **提醒**
 /remind <時間> <訊息> - 設定提醒
-/reminders - 查看提醒列表
+/reminders [條件] - 查看提醒列表
 
 **記帳**
 /expense <金額> <說明> - 記錄支出
 /income <金額> <說明> - 記錄收入
+/transactions [條件] - 查看交易記錄
 /balance - 查看收支統計
 
+**分帳**
+/split <金額> <說明> @成員... - 記錄分攤支出
+• 平分、百分比 (@amy:60%) 或指定金額 (@amy:300)
+• 他人付款: 加上 by:@付款人
+/debts - 查看誰欠誰與建議還款方式
+/settle @成員 [金額] - 記錄還款
+
 **行事曆**
 /event <標題> <時間> - 新增事件
-/events - 查看近期事件
+/event <標題> <日期>[~<日期>] - 新增全天或跨日事件
+/events [條件] - 查看近期事件
+/free [today|tomorrow|week|日期] [時長] [標題] - 尋找空閒時段，點選建議時段建立事件
+• 重複事件與提醒可以只取消或改動其中一次，或從某次起改為新的系列（例如「這週五的晨會取消」）
+• 用自然語言新增或改動事件時，若與其他行程重疊會提醒你，可保留兩者、改到建議時段或取消
+
+**購物清單**
+/buy <品項> [數量] - 加入購物清單
+/shopping - 查看購物清單（點選標記已買）
+
+**群組**
+• 在群組中使用時，待辦、購物清單、行事曆與記帳由全體成員共用
+• 提醒與每日摘要會發送到群組
+• 在群組中請 @提及我 或回覆我的訊息來使用自然語言
+
+**搜尋**
+/search <關鍵字> - 同時搜尋備忘錄、待辦、提醒、事件與記帳
+
+**標籤**
+• 在內容中加上 #標籤，例如 /memo 買牛奶 #家裡
+• 建立後可點選建議的標籤按鈕加上或移除
+/tags [前綴] - 查看標籤與各類項目數量
+
+**篩選**
+• 列表指令可加上條件，例如 /todos tag:work due:<fri priority:>=4 或 /reminders tag:家裡
+/filter save <名稱> <列表> <條件> - 儲存為智慧清單
+/f <名稱> - 開啟智慧清單
+/filter help - 篩選語法
 
 **設定**
 /settings - 調整提醒設定
 • Todo 提醒開關與頻率
 • 每日摘要時間
 • 勿擾時段
+• 工作時段與會議間緩衝（尋找空閒時間時使用）
+
+**帳號**
+/export_all - 匯出所有資料
+/delete_account - 刪除帳號與所有資料
+/api_token [名稱] - 建立 API Token
+/webhook add <網址> [事件] - 註冊 Webhook
 
 💡 你也可以直接用自然語言告訴我！`
 	h.sendMessage(msg.Chat.ID, text)
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
 )
 
@@ -17,8 +18,10 @@ func (h *Handlers) handleMemo(ctx context.Context, msg *tgbotapi.Message) {
 	}
 
 	memo := &models.Memo{
-		UserID:  msg.From.ID,
-		Content: content,
+		UserID:    spaceID(msg),
+		Content:   content,
+		Tags:      hashtags(content),
+		CreatedBy: &msg.From.ID,
 	}
 
 	if err := h.repos.Memo.Create(ctx, memo); err != nil {
@@ -26,23 +29,47 @@ func (h *Handlers) handleMemo(ctx context.Context, msg *tgbotapi.Message) {
 		return
 	}
 
-	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 備忘錄已建立 (ID: %d)", memo.MemoID))
+	h.sendWithTagSuggestions(ctx, msg, fmt.Sprintf("✅ 備忘錄已建立 (ID: %d)", memo.MemoID), models.EntityMemo, memo.MemoID, content)
 }
 
+// handleMemoList lists the latest memos, or the memos matching "/memos <篩選條件>"
 func (h *Handlers) handleMemoList(ctx context.Context, msg *tgbotapi.Message) {
-	memos, err := h.repos.Memo.GetByUserID(ctx, msg.From.ID, 10, 0)
+	h.listMemos(ctx, msg, msg.CommandArguments())
+}
+
+func (h *Handlers) listMemos(ctx context.Context, msg *tgbotapi.Message, query string) {
+	var memos []*models.Memo
+	var f *filter.Filter
+	var err error
+	if strings.TrimSpace(query) != "" {
+		var ok bool
+		if f, ok = h.parseFilter(ctx, msg, query); !ok {
+			return
+		}
+		memos, err = h.repos.Memo.Filter(ctx, spaceID(msg), f, filteredListLimit)
+	} else {
+		memos, err = h.repos.Memo.GetByUserID(ctx, spaceID(msg), 10, 0)
+	}
 	if err != nil {
-		h.sendMessage(msg.Chat.ID, "取得備忘錄失敗，請稍後再試")
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得備忘錄失敗，請稍後再試"))
 		return
 	}
 
 	if len(memos) == 0 {
-		h.sendMessage(msg.Chat.ID, "📝 目前沒有備忘錄")
+		if f != nil {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("📝 沒有符合「%s」的備忘錄", f.Query))
+		} else {
+			h.sendMessage(msg.Chat.ID, "📝 目前沒有備忘錄")
+		}
 		return
 	}
 
 	var sb strings.Builder
-	sb.WriteString("📝 **備忘錄列表**\n\n")
+	if f != nil {
+		sb.WriteString(fmt.Sprintf("📝 **備忘錄** · %s (%d)\n\n", f.Query, len(memos)))
+	} else {
+		sb.WriteString("📝 **備忘錄列表**\n\n")
+	}
 	for _, memo := range memos {
 		content := memo.Content
 		if len(content) > 50 {
@@ -55,11 +82,12 @@ func (h *Handlers) handleMemoList(ctx context.Context, msg *tgbotapi.Message) {
 	h.sendMessage(msg.Chat.ID, sb.String())
 }
 
-func (h *Handlers) CreateMemo(ctx context.Context, userID int64, content string, tags string) (*models.Memo, error) {
+func (h *Handlers) CreateMemo(ctx context.Context, userID, createdBy int64, content string, tags string) (*models.Memo, error) {
 	memo := &models.Memo{
-		UserID:  userID,
-		Content: content,
-		Tags:    tags,
+		UserID:    userID,
+		Content:   content,
+		Tags:      tags,
+		CreatedBy: &createdBy,
 	}
 	err := h.repos.Memo.Create(ctx, memo)
 	return memo, err
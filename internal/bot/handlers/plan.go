@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+const (
+	// planDefaultMinutes is the time planned for a todo without an estimate
+	planDefaultMinutes = 30
+	// planTimeout is how long a proposed plan can be accepted
+	planTimeout = 30 * time.Minute
+	// planBlockNotification is how many minutes before a time block it is announced
+	planBlockNotification = 5
+	// planUnplacedLimit is how many todos that did not fit the plan lists
+	planUnplacedLimit = 5
+)
+
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// planBlock is a todo placed into free time
+type planBlock struct {
+	todo  *models.Todo
+	start time.Time
+	end   time.Time
+}
+
+// todoPlan is a proposed packing of open todos into the free time of a
+// space, waiting for the user to accept or adjust it
+type todoPlan struct {
+	// mu serializes the members of a space adjusting and accepting the plan
+	mu        sync.Mutex
+	closed    bool // accepted or cancelled
+	ownerID   int64
+	from      time.Time // midnight of the first planned day
+	days      int
+	dropped   map[int]bool // todos the user took out of the plan
+	blocks    []planBlock
+	unplaced  []*models.Todo
+	expiresAt time.Time
+}
+
+var (
+	pendingPlans = make(map[int]*todoPlan) // plan ID -> plan
+	planMutex    sync.Mutex
+	lastPlanID   int
+)
+
+// handlePlan proposes a plan: "/plan" for the rest of today, "/plan tomorrow",
+// "/plan week" for the next seven days or "/plan <日期>"
+func (h *Handlers) handlePlan(ctx context.Context, msg *tgbotapi.Message) {
+	from, days, ok := parsePlanRange(msg.CommandArguments(), "")
+	if !ok {
+		h.sendMessage(msg.Chat.ID, "無法理解的日期\n用法: /plan [today|tomorrow|week|YYYY-MM-DD]")
+		return
+	}
+	h.proposePlan(ctx, msg.Chat.ID, spaceID(msg), from, days)
+}
+
+// parsePlanRange returns the first day and number of days named by when,
+// or by date for a single day
+func parsePlanRange(when, date string) (time.Time, int, bool) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(strings.TrimSpace(when)) {
+	case "", "day", "today", "今天", "今日":
+		if date == "" {
+			return today, 1, true
+		}
+	case "tomorrow", "明天":
+		return today.AddDate(0, 0, 1), 1, true
+	case "week", "本週", "這週", "一週":
+		return today, 7, true
+	default:
+		date = when
+	}
+
+	t := parseDateTime(strings.TrimSpace(date))
+	if t == nil {
+		return time.Time{}, 0, false
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+	if day.Before(today) {
+		return time.Time{}, 0, false
+	}
+	return day, 1, true
+}
+
+// proposePlan packs the open todos of ownerID into the free time of the
+// days and sends the plan with buttons to accept or adjust it. It returns
+// the plan's text.
+func (h *Handlers) proposePlan(ctx context.Context, chatID, ownerID int64, from time.Time, days int) string {
+	plan := &todoPlan{ownerID: ownerID, from: from, days: days, dropped: make(map[int]bool)}
+	if err := h.fillPlan(ctx, plan); err != nil {
+		log.Printf("Failed to plan todos for %d: %v", ownerID, err)
+		h.sendMessage(chatID, "排程失敗，請稍後再試")
+		return "排程失敗，請稍後再試"
+	}
+
+	text := planText(plan)
+	if len(plan.blocks) == 0 {
+		h.sendMessage(chatID, text)
+		return text
+	}
+
+	planMutex.Lock()
+	lastPlanID++
+	id := lastPlanID
+	plan.expiresAt = time.Now().Add(planTimeout)
+	pendingPlans[id] = plan
+	for planID, p := range pendingPlans {
+		if time.Now().After(p.expiresAt) {
+			delete(pendingPlans, planID)
+		}
+	}
+	planMutex.Unlock()
+
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(chatID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = planKeyboard(id, plan, false)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send plan: %v", err)
+	}
+	return text
+}
+
+// fillPlan places the open todos of the plan's space into its free time:
+// todos due within the planned days first, then by priority and due time.
+// Each todo goes into the earliest free slot that fits it before its due time.
+func (h *Handlers) fillPlan(ctx context.Context, plan *todoPlan) error {
+	now := time.Now()
+	todos, err := h.repos.Todo.GetByUserID(ctx, plan.ownerID, false)
+	if err != nil {
+		return err
+	}
+	scheduled, err := h.repos.Event.GetScheduledTodoIDs(ctx, plan.ownerID, now)
+	if err != nil {
+		return err
+	}
+
+	end := plan.from.AddDate(0, 0, plan.days)
+	var candidates []*models.Todo
+	for _, todo := range todos {
+		// Blocked and waiting todos cannot be worked on, parents are done through their subtasks
+		if todo.IsBlocked() || todo.Status == models.TodoWaiting || todo.SubtaskCount > todo.SubtaskDone ||
+			plan.dropped[todo.TodoID] || slices.Contains(scheduled, todo.TodoID) {
+			continue
+		}
+		candidates = append(candidates, todo)
+	}
+	dueInRange := func(t *models.Todo) bool {
+		return t.DueTime != nil && t.DueTime.Before(end)
+	}
+	slices.SortStableFunc(candidates, func(a, b *models.Todo) int {
+		switch {
+		case dueInRange(a) != dueInRange(b):
+			if dueInRange(a) {
+				return -1
+			}
+			return 1
+		case a.Priority != b.Priority:
+			return b.Priority - a.Priority
+		case a.DueTime == nil || b.DueTime == nil:
+			if a.DueTime != nil {
+				return -1
+			}
+			if b.DueTime != nil {
+				return 1
+			}
+			return 0
+		}
+		return a.DueTime.Compare(*b.DueTime)
+	})
+
+	free, _ := h.freeSlots(ctx, plan.ownerID, plan.from, end, now)
+
+	plan.blocks, plan.unplaced = nil, nil
+	for _, todo := range candidates {
+		length := time.Duration(plannedMinutes(todo)) * time.Minute
+		placed := false
+		for i := range free {
+			start := free[i].start
+			if free[i].end.Sub(start) < length {
+				continue
+			}
+			// Overdue todos are planned anyway, others must fit before their due time
+			if todo.DueTime != nil && todo.DueTime.After(now) && start.Add(length).After(*todo.DueTime) {
+				continue
+			}
+			plan.blocks = append(plan.blocks, planBlock{todo: todo, start: start, end: start.Add(length)})
+			free[i].start = start.Add(length)
+			placed = true
+			break
+		}
+		if !placed {
+			plan.unplaced = append(plan.unplaced, todo)
+		}
+	}
+	slices.SortFunc(plan.blocks, func(a, b planBlock) int {
+		return a.start.Compare(b.start)
+	})
+	return nil
+}
+
+// plannedMinutes is the time planned for a todo
+func plannedMinutes(todo *models.Todo) int {
+	if todo.EstimatedMinutes > 0 {
+		return todo.EstimatedMinutes
+	}
+	return planDefaultMinutes
+}
+
+// planText renders the proposed time blocks by day
+func planText(plan *todoPlan) string {
+	var sb strings.Builder
+	last := plan.from.AddDate(0, 0, plan.days-1)
+	if plan.days == 1 {
+		sb.WriteString(fmt.Sprintf("🗓 **%s 排程建議**\n", planDayLabel(plan.from)))
+	} else {
+		sb.WriteString(fmt.Sprintf("🗓 **排程建議** %s ~ %s\n", plan.from.Format("01/02"), last.Format("01/02")))
+	}
+
+	if len(plan.blocks) == 0 {
+		sb.WriteString("\n沒有可以排進空閒時間的待辦事項")
+	}
+	var day time.Time
+	estimated := true
+	for _, block := range plan.blocks {
+		blockDay := time.Date(block.start.Year(), block.start.Month(), block.start.Day(), 0, 0, 0, 0, block.start.Location())
+		if plan.days > 1 && !blockDay.Equal(day) {
+			day = blockDay
+			sb.WriteString(fmt.Sprintf("\n**%s**\n", planDayLabel(day)))
+		} else if day.IsZero() {
+			day = blockDay
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("• %s-%s **#%d** %s", block.start.Format("15:04"), block.end.Format("15:04"),
+			block.todo.TodoID, truncateRunes(block.todo.Title, 30)))
+		if block.todo.EstimatedMinutes == 0 {
+			sb.WriteString(" ⏱?")
+			estimated = false
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(plan.unplaced) > 0 {
+		sb.WriteString("\n⚠️ **排不進去的待辦**\n")
+		for i, todo := range plan.unplaced {
+			if i == planUnplacedLimit {
+				sb.WriteString(fmt.Sprintf("• ...還有 %d 項\n", len(plan.unplaced)-planUnplacedLimit))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("• #%d %s (%d 分鐘)\n", todo.TodoID, truncateRunes(todo.Title, 30), plannedMinutes(todo)))
+		}
+	}
+	if !estimated {
+		sb.WriteString(fmt.Sprintf("\n⏱? 未估計時間的待辦以 %d 分鐘計算，可用 /estimate <編號> <分鐘> 設定", planDefaultMinutes))
+	}
+	return sb.String()
+}
+
+func planDayLabel(day time.Time) string {
+	return fmt.Sprintf("%s (週%s)", day.Format("01/02"), weekdayNames[day.Weekday()])
+}
+
+// planKeyboard offers accepting or adjusting the plan; while adjusting
+// there is a button per planned todo to take it out
+func planKeyboard(id int, plan *todoPlan, adjusting bool) tgbotapi.InlineKeyboardMarkup {
+	if !adjusting {
+		return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 接受", fmt.Sprintf("plan:accept:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ 調整", fmt.Sprintf("plan:adjust:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("plan:cancel:%d", id)),
+		))
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, block := range plan.blocks {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("➖ #%d", block.todo.TodoID),
+			fmt.Sprintf("plan:drop:%d:%d", id, block.todo.TodoID)))
+		if len(row) == 4 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ 完成調整", fmt.Sprintf("plan:back:%d", id)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handlePlanCallback handles "plan:<accept|adjust|back|cancel>:<id>" and
+// "plan:drop:<id>:<todoID>" of a proposed plan
+func (h *Handlers) handlePlanCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || len(args) < 2 {
+		return
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	planMutex.Lock()
+	plan, ok := pendingPlans[id]
+	planMutex.Unlock()
+	if ok && (time.Now().After(plan.expiresAt) || plan.ownerID != callbackSpaceID(callback)) {
+		ok = false
+	}
+	if !ok {
+		h.editMessageText(chatID, messageID, "⏰ 排程建議已過期，請重新使用 /plan")
+		return
+	}
+
+	// Members of a space share the plan and may press its buttons at once
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	if plan.closed {
+		return
+	}
+	if args[0] == "accept" || args[0] == "cancel" {
+		plan.closed = true
+		planMutex.Lock()
+		delete(pendingPlans, id)
+		planMutex.Unlock()
+	}
+
+	switch args[0] {
+	case "accept":
+		h.editMessageText(chatID, messageID, h.acceptPlan(ctx, plan, callback.From.ID))
+	case "cancel":
+		h.editMessageText(chatID, messageID, "已取消排程建議")
+	case "adjust", "back":
+		h.editMessageWithKeyboard(chatID, messageID, planText(plan), planKeyboard(id, plan, args[0] == "adjust"))
+	case "drop":
+		if len(args) < 3 {
+			return
+		}
+		todoID, err := strconv.Atoi(args[2])
+		if err != nil {
+			return
+		}
+		// Replanning gives the freed time to the next todos
+		plan.dropped[todoID] = true
+		if err := h.fillPlan(ctx, plan); err != nil {
+			log.Printf("Failed to replan todos for %d: %v", plan.ownerID, err)
+			h.answerCallbackWithAlert(callback.ID, "重新排程失敗，請稍後再試")
+			return
+		}
+		h.editMessageWithKeyboard(chatID, messageID, planText(plan), planKeyboard(id, plan, true))
+	}
+}
+
+// acceptPlan creates a time block event linked to each planned todo and
+// returns the text for the user
+func (h *Handlers) acceptPlan(ctx context.Context, plan *todoPlan, createdBy int64) string {
+	var sb strings.Builder
+	created := 0
+	for _, block := range plan.blocks {
+		start := block.start
+		event, err := h.CreateEvent(ctx, plan.ownerID, createdBy, block.todo.Title,
+			fmt.Sprintf("待辦 #%d 的時間區塊", block.todo.TodoID), &start, int(block.end.Sub(block.start).Minutes()),
+			[]int{planBlockNotification}, "", block.todo.Tags)
+		if err != nil {
+			log.Printf("Failed to create time block for todo %d: %v", block.todo.TodoID, err)
+			sb.WriteString(fmt.Sprintf("\n❌ #%d %s 建立失敗", block.todo.TodoID, block.todo.Title))
+			continue
+		}
+		if err := h.repos.Event.LinkTodo(ctx, event.EventID, plan.ownerID, block.todo.TodoID); err != nil {
+			log.Printf("Failed to link event %d to todo %d: %v", event.EventID, block.todo.TodoID, err)
+		}
+		created++
+		sb.WriteString(fmt.Sprintf("\n• %s %s-%s #%d %s", start.Format("01/02"), start.Format("15:04"),
+			block.end.Format("15:04"), block.todo.TodoID, block.todo.Title))
+	}
+	return fmt.Sprintf("✅ 已建立 %d 個時間區塊，開始前 %d 分鐘會提醒你\n%s", created, planBlockNotification, sb.String())
+}
+
+// handleAIPlanScheduleResult proposes a plan for the day or week named by
+// the range and date parameters; the plan is always sent since it needs its buttons
+func (h *Handlers) handleAIPlanScheduleResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string) string {
+	from, days, ok := parsePlanRange(params["range"], params["date"])
+	if !ok {
+		result := "無法理解要排程的日期"
+		h.sendMessage(msg.Chat.ID, result)
+		return result
+	}
+	return h.proposePlan(ctx, msg.Chat.ID, spaceID(msg), from, days)
+}
+
+// handleEstimate sets how long a todo takes: "/estimate <編號> <分鐘>",
+// also accepting durations such as 1h30m
+func (h *Handlers) handleEstimate(ctx context.Context, msg *tgbotapi.Message) {
+	usage := "用法: /estimate <待辦編號> <時間>\n例如: /estimate 12 90 或 /estimate 12 1h30m"
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		h.sendMessage(msg.Chat.ID, "請提供待辦編號與預估時間\n"+usage)
+		return
+	}
+	todoID, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "無效的編號\n"+usage)
+		return
+	}
+	minutes, ok := parseMinutes(args[1])
+	if !ok {
+		h.sendMessage(msg.Chat.ID, "無效的時間\n"+usage)
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, h.estimateTodo(ctx, spaceID(msg), todoID, minutes))
+}
+
+// estimateTodo saves the estimated minutes of a todo and returns the text for the user
+func (h *Handlers) estimateTodo(ctx context.Context, ownerID int64, todoID, minutes int) string {
+	todo, err := h.repos.Todo.GetByID(ctx, todoID, ownerID)
+	if err != nil {
+		return fmt.Sprintf("找不到待辦事項 #%d", todoID)
+	}
+	todo.EstimatedMinutes = minutes
+	if err := h.repos.Todo.Update(ctx, todo); err != nil {
+		log.Printf("Failed to set estimate of todo %d: %v", todoID, err)
+		return "設定預估時間失敗，請稍後再試"
+	}
+	return fmt.Sprintf("⏱ #%d %s 預估 %d 分鐘", todoID, todo.Title, minutes)
+}
+
+// parseMinutes accepts a number of minutes or a duration such as 1h30m
+func parseMinutes(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n > 0 && n <= 24*60
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Minute || d > 24*time.Hour {
+		return 0, false
+	}
+	return int(d.Minutes()), true
+}
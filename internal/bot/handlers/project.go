@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const projectUsage = `/projects - 查看專案與進度
+/project <名稱> - 查看專案的待辦事項
+/project new <名稱> [color:<顏色>] [tags:<標籤>] - 建立專案
+/project edit <名稱> [color:<顏色>] [tags:<標籤>] - 修改顏色或預設標籤
+/project delete <名稱> - 刪除專案（保留待辦事項）
+顏色: red、orange、yellow、green、blue、purple、brown、black、white`
+
+// handleProjectList lists the projects with their progress
+func (h *Handlers) handleProjectList(ctx context.Context, msg *tgbotapi.Message) {
+	projects, err := h.repos.Project.GetByUserID(ctx, spaceID(msg))
+	if err != nil {
+		log.Printf("Failed to get projects: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得專案失敗，請稍後再試")
+		return
+	}
+	if len(projects) == 0 {
+		h.sendMessage(msg.Chat.ID, "📁 還沒有專案\n\n"+projectUsage)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📁 **專案**\n\n")
+	for _, p := range projects {
+		total := p.OpenTodos + p.DoneTodos
+		sb.WriteString(fmt.Sprintf("%s **%s** %d/%d 完成", p.Emoji(), p.Name, p.DoneTodos, total))
+		if p.DefaultTags != "" {
+			sb.WriteString(" · 🏷 " + p.DefaultTags)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n使用 /project <名稱> 查看，/todo <標題> project:<名稱> 新增待辦")
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// handleProject manages projects, see projectUsage
+func (h *Handlers) handleProject(ctx context.Context, msg *tgbotapi.Message) {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(sub) {
+	case "":
+		h.handleProjectList(ctx, msg)
+	case "new", "add", "create":
+		h.createProject(ctx, msg, rest)
+	case "edit", "set":
+		h.editProject(ctx, msg, rest)
+	case "delete", "del", "rm":
+		project, ok := h.findProject(ctx, msg, rest)
+		if !ok {
+			return
+		}
+		if err := h.repos.Project.Delete(ctx, project.ProjectID, spaceID(msg)); err != nil {
+			log.Printf("Failed to delete project: %v", err)
+			h.sendMessage(msg.Chat.ID, "刪除專案失敗，請稍後再試")
+			return
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 已刪除專案「%s」，其待辦事項已移出專案", project.Name))
+	case "help":
+		h.sendMessage(msg.Chat.ID, projectUsage)
+	default:
+		project, ok := h.findProject(ctx, msg, strings.TrimSpace(msg.CommandArguments()))
+		if !ok {
+			return
+		}
+		h.showProject(ctx, msg, project)
+	}
+}
+
+// parseProjectArgs splits "<名稱> color:<顏色> tags:<標籤>" into its parts
+func parseProjectArgs(args string) (name, color, tags string, hasColor, hasTags bool) {
+	var words []string
+	for _, word := range strings.Fields(args) {
+		key, value, _ := strings.Cut(word, ":")
+		switch strings.ToLower(key) {
+		case "color", "顏色":
+			color, hasColor = strings.ToLower(value), true
+		case "tags", "tag", "標籤":
+			tags, hasTags = value, true
+		default:
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), color, tags, hasColor, hasTags
+}
+
+func (h *Handlers) createProject(ctx context.Context, msg *tgbotapi.Message, args string) {
+	name, color, tags, _, _ := parseProjectArgs(args)
+	if name == "" {
+		h.sendMessage(msg.Chat.ID, "請提供專案名稱\n用法: /project new <名稱> [color:<顏色>] [tags:<標籤>]\n例如: /project new 日本旅行 color:red tags:旅遊")
+		return
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		h.sendMessage(msg.Chat.ID, "專案名稱最多 100 字")
+		return
+	}
+	if _, ok := models.ProjectColors[color]; color != "" && !ok {
+		h.sendMessage(msg.Chat.ID, "無效的顏色\n"+projectUsage)
+		return
+	}
+	if _, err := h.repos.Project.GetByName(ctx, spaceID(msg), name); err == nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("專案「%s」已存在", name))
+		return
+	}
+
+	project := &models.Project{
+		UserID:      spaceID(msg),
+		Name:        name,
+		Color:       color,
+		DefaultTags: tags,
+		CreatedBy:   &msg.From.ID,
+	}
+	if err := h.repos.Project.Create(ctx, project); err != nil {
+		log.Printf("Failed to create project: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立專案失敗，請稍後再試")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("%s 已建立專案「%s」\n使用 /todo <標題> project:%s 新增待辦", project.Emoji(), project.Name, project.Name))
+}
+
+func (h *Handlers) editProject(ctx context.Context, msg *tgbotapi.Message, args string) {
+	name, color, tags, hasColor, hasTags := parseProjectArgs(args)
+	if name == "" || (!hasColor && !hasTags) {
+		h.sendMessage(msg.Chat.ID, "用法: /project edit <名稱> [color:<顏色>] [tags:<標籤>]\n例如: /project edit 日本旅行 color:blue")
+		return
+	}
+	if _, ok := models.ProjectColors[color]; hasColor && color != "" && !ok {
+		h.sendMessage(msg.Chat.ID, "無效的顏色\n"+projectUsage)
+		return
+	}
+	project, ok := h.findProject(ctx, msg, name)
+	if !ok {
+		return
+	}
+
+	if hasColor {
+		project.Color = color
+	}
+	if hasTags {
+		project.DefaultTags = tags
+	}
+	if err := h.repos.Project.Update(ctx, project); err != nil {
+		log.Printf("Failed to update project: %v", err)
+		h.sendMessage(msg.Chat.ID, "更新專案失敗，請稍後再試")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("%s 已更新專案「%s」", project.Emoji(), project.Name))
+}
+
+// findProject looks the project up by name and explains when there is none
+func (h *Handlers) findProject(ctx context.Context, msg *tgbotapi.Message, name string) (*models.Project, bool) {
+	if name == "" {
+		h.sendMessage(msg.Chat.ID, "請提供專案名稱\n\n"+projectUsage)
+		return nil, false
+	}
+	project, err := h.repos.Project.GetByName(ctx, spaceID(msg), name)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to get project: %v", err)
+		}
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("找不到專案「%s」，使用 /projects 查看專案", name))
+		return nil, false
+	}
+	return project, true
+}
+
+func (h *Handlers) showProject(ctx context.Context, msg *tgbotapi.Message, project *models.Project) {
+	todos, err := h.repos.Todo.GetByProject(ctx, spaceID(msg), project.ProjectID, true)
+	if err != nil {
+		log.Printf("Failed to get project todos: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得專案待辦事項失敗，請稍後再試")
+		return
+	}
+
+	done := 0
+	var open []*models.Todo
+	for _, todo := range todos {
+		if todo.IsCompleted() {
+			done++
+		} else {
+			open = append(open, todo)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s **%s** · %d/%d 完成\n", project.Emoji(), project.Name, done, len(todos)))
+	if project.DefaultTags != "" {
+		sb.WriteString("🏷 " + project.DefaultTags + "\n")
+	}
+	sb.WriteString("\n")
+	if len(open) == 0 {
+		sb.WriteString("沒有未完成的待辦事項\n")
+	}
+
+	names := h.memberNames(ctx, spaceID(msg))
+	writeTodoTree(&sb, open, func(todo *models.Todo) string {
+		return creatorSuffix(names, todo.CreatedBy) + h.assignmentSuffix(ctx, todo)
+	})
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// writeTodoGroups writes todos without a project first, then a tree per
+// project in name order
+func (h *Handlers) writeTodoGroups(ctx context.Context, sb *strings.Builder, ownerID int64, todos []*models.Todo, suffix func(*models.Todo) string) {
+	byProject := make(map[int][]*models.Todo)
+	var loose []*models.Todo
+	for _, todo := range todos {
+		if todo.ProjectID == nil {
+			loose = append(loose, todo)
+		} else {
+			byProject[*todo.ProjectID] = append(byProject[*todo.ProjectID], todo)
+		}
+	}
+	writeTodoTree(sb, loose, suffix)
+	if len(byProject) == 0 {
+		return
+	}
+
+	projects, err := h.repos.Project.GetByUserID(ctx, ownerID)
+	if err != nil {
+		log.Printf("Failed to get projects: %v", err)
+	}
+	listed := make(map[int]bool)
+	for _, p := range projects {
+		group, ok := byProject[p.ProjectID]
+		if !ok {
+			continue
+		}
+		listed[p.ProjectID] = true
+		sb.WriteString(fmt.Sprintf("%s **%s** · %d/%d 完成\n\n", p.Emoji(), p.Name, p.DoneTodos, p.OpenTodos+p.DoneTodos))
+		writeTodoTree(sb, group, suffix)
+	}
+
+	// Projects that could not be loaded keep their todos in the list
+	var rest []int
+	for id := range byProject {
+		if !listed[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Ints(rest)
+	for _, id := range rest {
+		sb.WriteString(fmt.Sprintf("📁 **專案 #%d**\n\n", id))
+		writeTodoTree(sb, byProject[id], suffix)
+	}
+}
+
+// writeTodoTree writes todos with their subtasks indented below them. A
+// subtask whose parent is not in the list is written at the top level.
+func writeTodoTree(sb *strings.Builder, todos []*models.Todo, suffix func(*models.Todo) string) {
+	inList := make(map[int]bool, len(todos))
+	for _, todo := range todos {
+		inList[todo.TodoID] = true
+	}
+	children := make(map[int][]*models.Todo)
+	var roots []*models.Todo
+	for _, todo := range todos {
+		if todo.ParentID != nil && inList[*todo.ParentID] {
+			children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+		} else {
+			roots = append(roots, todo)
+		}
+	}
+
+	var writeChildren func(parentID int, depth int)
+	writeChildren = func(parentID int, depth int) {
+		for _, child := range children[parentID] {
+			writeSubtaskLine(sb, child, depth, suffix(child))
+			writeChildren(child.TodoID, depth+1)
+		}
+	}
+	for _, todo := range roots {
+		writeTodoLine(sb, todo, suffix(todo))
+		if len(children[todo.TodoID]) > 0 {
+			writeChildren(todo.TodoID, 1)
+			sb.WriteString("\n")
+		}
+	}
+}
+
+func writeSubtaskLine(sb *strings.Builder, todo *models.Todo, depth int, suffix string) {
+	sb.WriteString(strings.Repeat("   ", depth) + fmt.Sprintf("↳ %s **%d.** %s%s%s%s",
+		todoStatusIcon(todo), todo.TodoID, truncateRunes(todo.Title, 40), subtaskProgress(todo), blockedSuffix(todo), suffix))
+	if todo.DueTime != nil {
+		sb.WriteString(fmt.Sprintf(" 📅 %s", todo.DueTime.Format("01-02 15:04")))
+	}
+	sb.WriteString("\n")
+}
+
+// subtaskProgress is the roll-up shown after a todo with subtasks
+func subtaskProgress(todo *models.Todo) string {
+	if todo.SubtaskCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d/%d 完成)", todo.SubtaskDone, todo.SubtaskCount)
+}
+
+// placeTodo puts a new todo under parent and into project; a subtask
+// belongs to its parent's project. The project's default tags are added.
+func (h *Handlers) placeTodo(ctx context.Context, todo *models.Todo, project *models.Project, parent *models.Todo) {
+	if parent != nil {
+		todo.ParentID = &parent.TodoID
+		if project == nil && parent.ProjectID != nil {
+			if p, err := h.repos.Project.GetByID(ctx, *parent.ProjectID, todo.UserID); err == nil {
+				project = p
+			}
+		}
+	}
+	if project != nil {
+		todo.ProjectID = &project.ProjectID
+		if project.DefaultTags != "" {
+			todo.Tags = models.JoinTags(models.ParseTags(todo.Tags + "," + project.DefaultTags))
+		}
+	}
+}
+
+// cutProjectOption removes a "project:<名稱>" word from text
+func cutProjectOption(text string) (rest, project string) {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		key, value, found := strings.Cut(word, ":")
+		if found && value != "" && (strings.EqualFold(key, "project") || key == "專案") {
+			project = value
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), project
+}
+
+// handleSubtask adds a subtask: "/subtask <父編號> <標題>"
+func (h *Handlers) handleSubtask(ctx context.Context, msg *tgbotapi.Message) {
+	idStr, title, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	title = strings.TrimSpace(title)
+	parentID, err := strconv.Atoi(idStr)
+	if err != nil || title == "" {
+		h.sendMessage(msg.Chat.ID, "用法: /subtask <父待辦編號> <標題>\n例如: /subtask 12 訂機票")
+		return
+	}
+
+	parent, err := h.repos.Todo.GetByID(ctx, parentID, spaceID(msg))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("找不到待辦事項 #%d", parentID))
+		return
+	}
+
+	todo := &models.Todo{
+		UserID:    spaceID(msg),
+		Title:     title,
+		Tags:      hashtags(title),
+		CreatedBy: &msg.From.ID,
+	}
+	h.placeTodo(ctx, todo, nil, parent)
+	if err := h.repos.Todo.Create(ctx, todo); err != nil {
+		log.Printf("Failed to create subtask: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立子任務失敗，請稍後再試")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 已在「%s」下建立子任務 (ID: %d)\n進度: %d/%d 完成",
+		parent.Title, todo.TodoID, parent.SubtaskDone, parent.SubtaskCount+1))
+}
@@ -0,0 +1,638 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/rrule"
+)
+
+// scopeTimeout is how long the scope of a change can be picked
+const scopeTimeout = 30 * time.Minute
+
+// changeRequest is an AI creation, update or deletion of an event or
+// reminder, kept while the user picks which occurrences it applies to or
+// how to handle its conflicts
+type changeRequest struct {
+	ownerID   int64
+	reminder  bool // a reminder instead of an event
+	id        int  // 0 when creating
+	params    map[string]string
+	delete    bool
+	force     bool // keep the change even when it overlaps other events
+	expiresAt time.Time
+}
+
+var (
+	pendingScopes = make(map[int]*changeRequest) // change ID -> change
+	scopeMutex    sync.Mutex
+	lastScopeID   int
+)
+
+// askScope asks which occurrences of the series named title the change
+// applies to and returns the question
+func (h *Handlers) askScope(chatID int64, change *changeRequest, title string) string {
+	now := time.Now()
+	change.expiresAt = now.Add(scopeTimeout)
+	scopeMutex.Lock()
+	lastScopeID++
+	id := lastScopeID
+	pendingScopes[id] = change
+	for changeID, c := range pendingScopes {
+		if now.After(c.expiresAt) {
+			delete(pendingScopes, changeID)
+		}
+	}
+	scopeMutex.Unlock()
+
+	kind, verb := "事件", "修改"
+	if change.reminder {
+		kind = "提醒"
+	}
+	if change.delete {
+		verb = "刪除"
+	}
+	text := fmt.Sprintf("🔄 %s #%d「%s」會重複，要%s哪幾次？", kind, change.id, title, verb)
+	if occurrence := change.params["occurrence"]; occurrence != "" {
+		text += fmt.Sprintf("\n📅 指定的那次: %s", occurrence)
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, scope := range models.RecurrenceScopes {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(scope.Label(), fmt.Sprintf("scope:%d:%s", id, scope)))
+	}
+	reply := tgbotapi.NewMessage(chatID, text)
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("❌ 取消", fmt.Sprintf("scope:%d:cancel", id))),
+	)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send scope question: %v", err)
+	}
+	return text
+}
+
+// handleScopeCallback handles "scope:<id>:<scope>" and "scope:<id>:cancel"
+// of a change waiting for its scope
+func (h *Handlers) handleScopeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil || len(args) < 2 {
+		return
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return
+	}
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	scopeMutex.Lock()
+	change, ok := pendingScopes[id]
+	if ok && (time.Now().After(change.expiresAt) || change.ownerID != callbackSpaceID(callback)) {
+		ok = false
+	}
+	if ok {
+		delete(pendingScopes, id)
+	}
+	scopeMutex.Unlock()
+	if !ok {
+		h.editMessageText(chatID, messageID, "⏰ 此操作已過期，請重新說一次")
+		return
+	}
+
+	scope := models.RecurrenceScope(args[1])
+	if !scope.IsValid() {
+		h.editMessageText(chatID, messageID, "已取消")
+		return
+	}
+	if change.reminder {
+		h.editMessageText(chatID, messageID, h.changeReminder(ctx, callback.From.ID, change, scope))
+		return
+	}
+	result, asked := h.changeEvent(ctx, chatID, callback.From.ID, change, scope)
+	if asked {
+		result = "🔄 " + scope.Label()
+	}
+	h.editMessageText(chatID, messageID, result)
+}
+
+// occurrenceStart returns the original start of the occurrence a change
+// names with its "occurrence" param, a date or date and time, or else of
+// the next occurrence at next
+func occurrenceStart(ruleStr string, dtstart time.Time, exceptions []*models.RecurrenceException, next *time.Time, param string) (time.Time, bool) {
+	if param != "" {
+		t := parseDateTime(param)
+		if t == nil {
+			return time.Time{}, false
+		}
+		return calendar.OccurrenceOn(ruleStr, dtstart, exceptions, *t)
+	}
+	if next == nil {
+		return time.Time{}, false
+	}
+	if x := calendar.MovedTo(exceptions, *next); x != nil {
+		return rrule.WallClock(x.OriginalStart), true
+	}
+	return rrule.WallClock(*next), true
+}
+
+// exceptionsBefore returns the exceptions of occurrences originally before t
+func exceptionsBefore(exceptions []*models.RecurrenceException, t time.Time) []*models.RecurrenceException {
+	return slices.DeleteFunc(slices.Clone(exceptions), func(x *models.RecurrenceException) bool {
+		return !rrule.WallClock(x.OriginalStart).Before(t)
+	})
+}
+
+// exceptionsFrom returns the exceptions of occurrences originally at or after
+// t as the series continuing at start carries them over on a split
+func exceptionsFrom(exceptions []*models.RecurrenceException, t, start time.Time) []*models.RecurrenceException {
+	var moved []*models.RecurrenceException
+	for _, x := range exceptions {
+		if rrule.WallClock(x.OriginalStart).Before(t) {
+			continue
+		}
+		copied := *x
+		copied.OriginalStart = x.OriginalStart.Add(start.Sub(t))
+		moved = append(moved, &copied)
+	}
+	return moved
+}
+
+// exceptionFor returns the saved exception of the occurrence originally
+// at original, or a new one for it
+func exceptionFor(exceptions []*models.RecurrenceException, parentID int, original time.Time) *models.RecurrenceException {
+	for _, x := range exceptions {
+		if rrule.WallClock(x.OriginalStart).Equal(original) {
+			return x
+		}
+	}
+	return &models.RecurrenceException{ParentID: parentID, OriginalStart: original}
+}
+
+// occurrenceLabel formats the start of an occurrence
+func occurrenceLabel(t time.Time) string {
+	return planDayLabel(t) + " " + t.Format("15:04")
+}
+
+// applyEventParams sets the fields of the event given in params
+func applyEventParams(event *models.Event, params map[string]string) {
+	if title, ok := params["title"]; ok && title != "" {
+		event.Title = title
+	}
+	if desc, ok := params["description"]; ok {
+		event.Description = desc
+	}
+	if dt, ok := params["dtstart"]; ok && dt != "" {
+		event.Dtstart = parseDateTime(dt)
+	}
+	// Fallback to start_time for backward compatibility
+	if dt, ok := params["start_time"]; ok && dt != "" && event.Dtstart == nil {
+		event.Dtstart = parseDateTime(dt)
+	}
+	if d, ok := params["duration"]; ok && d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil {
+			event.Duration = parsed
+		}
+	}
+	if rruleStr, ok := params["rrule"]; ok {
+		event.RecurrenceRule = rruleStr
+	}
+	if tags, ok := params["tags"]; ok {
+		event.Tags = tags
+	}
+	if alerts, err := alertsParam(params); err == nil && alerts != nil {
+		event.Alerts = alerts
+	}
+
+	switch {
+	case params["all_day"] == "false" && event.AllDay:
+		event.AllDay = false
+		if params["duration"] == "" {
+			event.Duration = 60
+		}
+	case isAllDay(params) || event.AllDay:
+		setAllDay(event, params)
+	}
+}
+
+// followingEvent returns the new series taking over event from its
+// occurrence at original with the rule continued, created by userID with
+// the changes in params applied
+func followingEvent(event *models.Event, original time.Time, continued string, userID int64, params map[string]string) *models.Event {
+	next := &models.Event{
+		UserID:         event.UserID,
+		Title:          event.Title,
+		Description:    event.Description,
+		Dtstart:        &original,
+		Duration:       event.Duration,
+		Alerts:         event.Alerts,
+		RecurrenceRule: continued,
+		Tags:           event.Tags,
+		CreatedBy:      &userID,
+		AllDay:         event.AllDay,
+		Transparent:    event.Transparent,
+	}
+	applyEventParams(next, params)
+	if next.Dtstart == nil {
+		next.Dtstart = &original
+	}
+	return next
+}
+
+// alertsParam parses the "alerts" param, minutes before the event like
+// "1440,30" or "none", returning nil when it isn't given
+func alertsParam(params map[string]string) ([]int, error) {
+	switch alerts := params["alerts"]; alerts {
+	case "":
+		return nil, nil
+	case "none":
+		return []int{}, nil
+	default:
+		return models.ParseAlerts(alerts)
+	}
+}
+
+// isAllDay reports whether the params of a change ask for an all-day event
+func isAllDay(params map[string]string) bool {
+	return params["all_day"] == "true" || params["end_date"] != ""
+}
+
+// setAllDay turns the event into an all-day event from the date of its
+// dtstart through the "end_date" param, and sets whether it keeps the
+// days busy from the "busy" param; all-day events leave their days free
+// unless they are busy, like a trip
+func setAllDay(event *models.Event, params map[string]string) {
+	if event.Dtstart == nil {
+		return
+	}
+	day := dayStart(*event.Dtstart)
+	event.Dtstart = &day
+	if !event.AllDay {
+		event.AllDay = true
+		event.Duration = models.MinutesPerDay
+		if params["alerts"] == "" {
+			event.Alerts = []int{allDayNotificationMinutes}
+		}
+		event.Transparent = true
+	}
+	if days := eventDays(params, day); days > 0 {
+		event.Duration = days * models.MinutesPerDay
+	}
+	if busy := params["busy"]; busy != "" {
+		event.Transparent = busy != "true"
+	}
+}
+
+// eventDays returns how many days an all-day event from day takes up
+// according to the "end_date" param, its last day, or 0 without one
+func eventDays(params map[string]string, day time.Time) int {
+	end := parseDateTime(params["end_date"])
+	if end == nil {
+		return 0
+	}
+	return max(daysBetween(day, *end)+1, 1)
+}
+
+// changeEvent applies the change of an event to its occurrences in scope
+// on behalf of userID and returns the outcome. A change overlapping other
+// events asks in chatID how to go on instead, reporting it asked.
+func (h *Handlers) changeEvent(ctx context.Context, chatID, userID int64, change *changeRequest, scope models.RecurrenceScope) (string, bool) {
+	ownerID, id, params, remove := change.ownerID, change.id, change.params, change.delete
+	event, err := h.repos.Event.GetByID(ctx, id, ownerID)
+	if err != nil {
+		return "找不到該事件", false
+	}
+	if !event.IsRecurring() || event.Dtstart == nil {
+		scope = models.ScopeAll
+	}
+	// Only changes of the time need to be checked for conflicts
+	check := !remove && !change.force && changesTime(params)
+
+	var original, dtstart time.Time
+	if scope != models.ScopeAll {
+		if err := h.repos.Event.LoadExceptions(ctx, event); err != nil {
+			log.Printf("Failed to load event exceptions: %v", err)
+			return "更新事件失敗", false
+		}
+		dtstart = rrule.WallClock(*event.Dtstart)
+		var ok bool
+		original, ok = occurrenceStart(event.RecurrenceRule, dtstart, event.Exceptions, event.NextOccurrence, params["occurrence"])
+		if !ok {
+			return fmt.Sprintf("找不到事件 #%d 指定的那一次", id), false
+		}
+		// Changing from the first occurrence on changes the whole series
+		if scope == models.ScopeFollowing && !original.After(dtstart) {
+			scope = models.ScopeAll
+		}
+	}
+
+	switch scope {
+	case models.ScopeThis:
+		x := exceptionFor(event.Exceptions, id, original)
+		if remove {
+			x.Cancelled = true
+		} else {
+			x.Cancelled = false
+			if title := params["title"]; title != "" {
+				x.Title = title
+			}
+			if d, err := strconv.Atoi(params["duration"]); err == nil {
+				x.Duration = &d
+			}
+			if dt := params["dtstart"]; dt != "" {
+				x.Start = parseDateTime(dt)
+			}
+			if event.AllDay {
+				if days := eventDays(params, x.StartTime()); days > 0 {
+					minutes := days * models.MinutesPerDay
+					x.Duration = &minutes
+				}
+			}
+		}
+		if check {
+			// The changed occurrence on its own
+			occurrence := calendar.Override(event, x)
+			occurrence.Dtstart = occurrence.NextOccurrence
+			occurrence.RecurrenceRule = ""
+			if text, asked := h.checkConflicts(ctx, chatID, userID, change, scope, occurrence); asked {
+				return text, true
+			}
+		}
+		if err := h.repos.Event.SaveException(ctx, ownerID, x); err != nil {
+			log.Printf("Failed to save event exception: %v", err)
+			return "更新事件失敗", false
+		}
+		h.refreshEventOccurrence(ctx, event)
+		if remove {
+			return fmt.Sprintf("已取消事件 #%d「%s」%s 的那一次", id, event.Title, occurrenceLabel(original)), false
+		}
+		return fmt.Sprintf("已修改事件 #%d「%s」%s 的那一次", id, event.Title, occurrenceLabel(original)), false
+
+	case models.ScopeFollowing:
+		ended, err := rrule.EndBefore(event.RecurrenceRule, original)
+		if err != nil {
+			return "更新事件失敗", false
+		}
+		continued, err := rrule.ContinueFrom(event.RecurrenceRule, dtstart, original)
+		if err != nil {
+			return "更新事件失敗", false
+		}
+		var next *models.Event
+		if !remove {
+			next = followingEvent(event, original, continued, userID, params)
+			next.Exceptions = exceptionsFrom(event.Exceptions, original, *next.Dtstart)
+			next.NextOccurrence = calculateNextOccurrence(next.Dtstart, next.RecurrenceRule, next.Exceptions)
+			if check {
+				if text, asked := h.checkConflicts(ctx, chatID, userID, change, scope, next); asked {
+					return text, true
+				}
+			}
+		}
+		old := *event
+		old.RecurrenceRule = ended
+		old.NextOccurrence, err = calendar.NextOccurrence(ended, dtstart, exceptionsBefore(event.Exceptions, original), time.Now())
+		if err != nil {
+			return "更新事件失敗", false
+		}
+		if err := h.repos.Event.Split(ctx, &old, next, original); err != nil {
+			log.Printf("Failed to split event series: %v", err)
+			return "更新事件失敗", false
+		}
+		h.notifyScheduler()
+		if remove {
+			return fmt.Sprintf("已結束事件 #%d「%s」，%s 起不再重複", id, event.Title, occurrenceLabel(original)), false
+		}
+		return fmt.Sprintf("事件 #%d「%s」%s 起改為新事件 #%d", id, event.Title, occurrenceLabel(original), next.EventID), false
+	}
+
+	if remove {
+		if err := h.repos.Event.Delete(ctx, id, ownerID); err != nil {
+			return "刪除事件失敗，請確認編號是否正確", false
+		}
+		return fmt.Sprintf("事件 #%d 已刪除", id), false
+	}
+	applyEventParams(event, params)
+	if check {
+		if err := h.repos.Event.LoadExceptions(ctx, event); err != nil {
+			log.Printf("Failed to load event exceptions: %v", err)
+		}
+		if text, asked := h.checkConflicts(ctx, chatID, userID, change, scope, event); asked {
+			return text, true
+		}
+	}
+	// UpdateEvent recalculates NextOccurrence if dtstart or rrule changed
+	if err := h.UpdateEvent(ctx, event); err != nil {
+		return "更新事件失敗", false
+	}
+	return fmt.Sprintf("事件 #%d 已更新", id), false
+}
+
+// refreshEventOccurrence recalculates the next occurrence of the event
+// after its exceptions changed, keeping the notification state when it
+// stays the same
+func (h *Handlers) refreshEventOccurrence(ctx context.Context, event *models.Event) {
+	if err := h.repos.Event.LoadExceptions(ctx, event); err != nil {
+		log.Printf("Failed to load event exceptions: %v", err)
+		return
+	}
+	next, err := calendar.NextOccurrence(event.RecurrenceRule, rrule.WallClock(*event.Dtstart), event.Exceptions, time.Now())
+	if err != nil {
+		return
+	}
+	if sameTime(next, event.NextOccurrence) {
+		return
+	}
+	if err := h.repos.Event.UpdateNextOccurrence(ctx, event.EventID, next); err != nil {
+		log.Printf("Failed to update next occurrence: %v", err)
+		return
+	}
+	h.notifyScheduler()
+}
+
+// applyReminderParams sets the fields of the reminder given in params
+func applyReminderParams(reminder *models.Reminder, params map[string]string) {
+	if message := params["message"]; message != "" {
+		reminder.Messages = message
+	} else if content := params["content"]; content != "" {
+		reminder.Messages = content
+	}
+	if desc, ok := params["description"]; ok {
+		reminder.Description = desc
+	}
+	if dt := params["dtstart"]; dt != "" {
+		if parsed := parseDateTime(dt); parsed != nil {
+			reminder.Dtstart = parsed
+		}
+	}
+	if rruleStr, ok := params["rrule"]; ok {
+		reminder.RecurrenceRule = rruleStr
+	}
+	if tags, ok := params["tags"]; ok {
+		reminder.Tags = tags
+	}
+}
+
+// changeReminder applies the change of a reminder to its occurrences in
+// scope on behalf of userID and returns the outcome
+func (h *Handlers) changeReminder(ctx context.Context, userID int64, change *changeRequest, scope models.RecurrenceScope) string {
+	ownerID, id, params, remove := change.ownerID, change.id, change.params, change.delete
+	reminder, err := h.repos.Reminder.GetByID(ctx, id, ownerID)
+	if err != nil {
+		return "找不到該提醒"
+	}
+	if !reminder.IsRecurring() || reminder.Dtstart == nil {
+		scope = models.ScopeAll
+	}
+
+	var original, dtstart time.Time
+	if scope != models.ScopeAll {
+		if err := h.repos.Reminder.LoadExceptions(ctx, reminder); err != nil {
+			log.Printf("Failed to load reminder exceptions: %v", err)
+			return "更新提醒失敗"
+		}
+		dtstart = rrule.WallClock(*reminder.Dtstart)
+		var ok bool
+		original, ok = occurrenceStart(reminder.RecurrenceRule, dtstart, reminder.Exceptions, reminder.RemindAt, params["occurrence"])
+		if !ok {
+			return fmt.Sprintf("找不到提醒 #%d 指定的那一次", id)
+		}
+		// Changing from the first occurrence on changes the whole series
+		if scope == models.ScopeFollowing && !original.After(dtstart) {
+			scope = models.ScopeAll
+		}
+	}
+
+	switch scope {
+	case models.ScopeThis:
+		x := exceptionFor(reminder.Exceptions, id, original)
+		if remove {
+			x.Cancelled = true
+		} else {
+			x.Cancelled = false
+			if message := params["message"]; message != "" {
+				x.Title = message
+			}
+			if dt := params["dtstart"]; dt != "" {
+				x.Start = parseDateTime(dt)
+			}
+		}
+		if err := h.repos.Reminder.SaveException(ctx, ownerID, x); err != nil {
+			log.Printf("Failed to save reminder exception: %v", err)
+			return "更新提醒失敗"
+		}
+		h.refreshRemindAt(ctx, reminder)
+		if remove {
+			return fmt.Sprintf("已取消提醒 #%d「%s」%s 的那一次", id, reminder.Messages, occurrenceLabel(original))
+		}
+		return fmt.Sprintf("已修改提醒 #%d「%s」%s 的那一次", id, reminder.Messages, occurrenceLabel(original))
+
+	case models.ScopeFollowing:
+		ended, err := rrule.EndBefore(reminder.RecurrenceRule, original)
+		if err != nil {
+			return "更新提醒失敗"
+		}
+		continued, err := rrule.ContinueFrom(reminder.RecurrenceRule, dtstart, original)
+		if err != nil {
+			return "更新提醒失敗"
+		}
+		var next *models.Reminder
+		if !remove {
+			next = &models.Reminder{
+				UserID:         reminder.UserID,
+				Enabled:        true,
+				Messages:       reminder.Messages,
+				Dtstart:        &original,
+				RecurrenceRule: continued,
+				Description:    reminder.Description,
+				Tags:           reminder.Tags,
+				CreatedBy:      &userID,
+			}
+			applyReminderParams(next, params)
+			next.Exceptions = exceptionsFrom(reminder.Exceptions, original, *next.Dtstart)
+			setFirstRemindAt(next)
+		}
+		old := *reminder
+		old.RecurrenceRule = ended
+		old.RemindAt, err = calendar.NextOccurrence(ended, dtstart, exceptionsBefore(reminder.Exceptions, original), remindAfter(reminder))
+		if err != nil {
+			return "更新提醒失敗"
+		}
+		old.Enabled = old.Enabled && old.RemindAt != nil
+		if err := h.repos.Reminder.Split(ctx, &old, next, original); err != nil {
+			log.Printf("Failed to split reminder series: %v", err)
+			return "更新提醒失敗"
+		}
+		h.notifyScheduler()
+		if remove {
+			return fmt.Sprintf("已結束提醒 #%d「%s」，%s 起不再重複", id, reminder.Messages, occurrenceLabel(original))
+		}
+		return fmt.Sprintf("提醒 #%d「%s」%s 起改為新提醒 #%d", id, reminder.Messages, occurrenceLabel(original), next.ReminderID)
+	}
+
+	if remove {
+		if err := h.repos.Reminder.Delete(ctx, id, ownerID); err != nil {
+			return "刪除提醒失敗，請確認編號是否正確"
+		}
+		return fmt.Sprintf("提醒 #%d 已刪除", id)
+	}
+	applyReminderParams(reminder, params)
+	// UpdateReminder recalculates remind_at if dtstart or rrule changed
+	if err := h.UpdateReminder(ctx, reminder); err != nil {
+		return "更新提醒失敗"
+	}
+	return fmt.Sprintf("提醒 #%d 已更新", id)
+}
+
+// remindAfter returns the time after which the next occurrence of the
+// reminder is due; an occurrence that is due but not acknowledged yet
+// stays due
+func remindAfter(reminder *models.Reminder) time.Time {
+	now := time.Now()
+	if reminder.RemindAt != nil {
+		if remindAt := rrule.WallClock(*reminder.RemindAt); remindAt.Before(now) {
+			return remindAt.Add(-time.Second)
+		}
+	}
+	return now
+}
+
+// refreshRemindAt recalculates when the reminder is next due after its
+// exceptions changed, keeping the notification state when it stays the same
+func (h *Handlers) refreshRemindAt(ctx context.Context, reminder *models.Reminder) {
+	if err := h.repos.Reminder.LoadExceptions(ctx, reminder); err != nil {
+		log.Printf("Failed to load reminder exceptions: %v", err)
+		return
+	}
+	next, err := calendar.NextOccurrence(reminder.RecurrenceRule, rrule.WallClock(*reminder.Dtstart), reminder.Exceptions, remindAfter(reminder))
+	if err != nil {
+		return
+	}
+	if sameTime(next, reminder.RemindAt) {
+		return
+	}
+	if next == nil {
+		err = h.repos.Reminder.SetEnabled(ctx, reminder.ReminderID, reminder.UserID, false)
+	} else {
+		err = h.repos.Reminder.UpdateRemindAt(ctx, reminder.ReminderID, next)
+	}
+	if err != nil {
+		log.Printf("Failed to update reminder time: %v", err)
+		return
+	}
+	h.notifyScheduler()
+}
+
+// sameTime reports whether a and b are both nil or the same wall clock time
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return rrule.WallClock(*a).Equal(rrule.WallClock(*b))
+}
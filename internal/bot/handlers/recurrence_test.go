@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+func TestFollowingEventKeepsAllDay(t *testing.T) {
+	dtstart := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	original := dtstart.AddDate(0, 0, 7)
+	event := &models.Event{
+		EventID:        1,
+		UserID:         42,
+		Title:          "假期",
+		Dtstart:        &dtstart,
+		Duration:       2 * models.MinutesPerDay,
+		Alerts:         []int{allDayNotificationMinutes},
+		RecurrenceRule: "FREQ=WEEKLY",
+		AllDay:         true,
+		Transparent:    true,
+	}
+
+	tests := []struct {
+		name            string
+		params          map[string]string
+		wantDays        int
+		wantTransparent bool
+	}{
+		{"title only", map[string]string{"title": "長假"}, 2, true},
+		{"busy", map[string]string{"busy": "true"}, 2, false},
+		{"longer", map[string]string{"end_date": "2026-03-11"}, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := followingEvent(event, original, "FREQ=WEEKLY", 7, tt.params)
+			if !next.AllDay {
+				t.Fatal("the new series is not all-day")
+			}
+			if next.Days() != tt.wantDays {
+				t.Errorf("new series takes %d days, want %d", next.Days(), tt.wantDays)
+			}
+			if next.Transparent != tt.wantTransparent {
+				t.Errorf("transparent = %v, want %v", next.Transparent, tt.wantTransparent)
+			}
+			if !next.Dtstart.Equal(original) {
+				t.Errorf("new series starts %v, want %v", next.Dtstart, original)
+			}
+			if len(next.Alerts) != 1 || next.Alerts[0] != allDayNotificationMinutes {
+				t.Errorf("alerts = %v, want the series' alerts", next.Alerts)
+			}
+		})
+	}
+
+	timed := *event
+	timed.AllDay, timed.Transparent, timed.Duration = false, false, 90
+	start := original.Add(9 * time.Hour)
+	next := followingEvent(&timed, start, "FREQ=WEEKLY", 7, map[string]string{})
+	if next.AllDay || next.Transparent || next.Duration != 90 {
+		t.Errorf("timed series split into all-day %v, transparent %v, duration %d", next.AllDay, next.Transparent, next.Duration)
+	}
+}
+
+func TestExceptionsFrom(t *testing.T) {
+	split := time.Date(2026, 3, 9, 9, 0, 0, 0, time.Local)
+	exceptions := []*models.RecurrenceException{
+		{ParentID: 1, OriginalStart: split.AddDate(0, 0, -7), Cancelled: true},
+		{ParentID: 1, OriginalStart: split, Title: "改名"},
+		{ParentID: 1, OriginalStart: split.AddDate(0, 0, 7), Cancelled: true},
+	}
+
+	moved := exceptionsFrom(exceptions, split, split)
+	if len(moved) != 2 {
+		t.Fatalf("got %d exceptions, want the 2 from the split on", len(moved))
+	}
+	if !moved[1].OriginalStart.Equal(split.AddDate(0, 0, 7)) || !moved[1].Cancelled {
+		t.Errorf("moved %+v, want the cancellation a week after the split", moved[1])
+	}
+
+	// A new series an hour later carries them to its own occurrences
+	later := split.Add(time.Hour)
+	moved = exceptionsFrom(exceptions, split, later)
+	if !moved[0].OriginalStart.Equal(later) {
+		t.Errorf("moved to %v, want %v", moved[0].OriginalStart, later)
+	}
+	if !exceptions[1].OriginalStart.Equal(split) {
+		t.Error("exceptionsFrom changed the old series' exceptions")
+	}
+}
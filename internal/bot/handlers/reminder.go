@@ -8,6 +8,7 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
 	"github.com/hray3182/LifeLine/internal/models"
 	"github.com/hray3182/LifeLine/internal/rrule"
 )
@@ -37,10 +38,11 @@ func (h *Handlers) handleReminder(ctx context.Context, msg *tgbotapi.Message) {
 	}
 
 	reminder := &models.Reminder{
-		UserID:   msg.From.ID,
-		Enabled:  true,
-		Messages: message,
-		RemindAt: &remindTime,
+		UserID:    spaceID(msg),
+		Enabled:   true,
+		Messages:  message,
+		RemindAt:  &remindTime,
+		CreatedBy: &msg.From.ID,
 	}
 
 	if err := h.repos.Reminder.Create(ctx, reminder); err != nil {
@@ -53,8 +55,19 @@ func (h *Handlers) handleReminder(ctx context.Context, msg *tgbotapi.Message) {
 		remindTime.Format("2006-01-02 15:04"), message))
 }
 
+// handleReminderList lists upcoming reminders, or the reminders matching
+// "/reminders <篩選條件>"
 func (h *Handlers) handleReminderList(ctx context.Context, msg *tgbotapi.Message) {
-	reminders, err := h.repos.Reminder.GetByUserID(ctx, msg.From.ID)
+	h.listReminders(ctx, msg, msg.CommandArguments())
+}
+
+func (h *Handlers) listReminders(ctx context.Context, msg *tgbotapi.Message, query string) {
+	if strings.TrimSpace(query) != "" {
+		h.listFilteredReminders(ctx, msg, query)
+		return
+	}
+
+	reminders, err := h.repos.Reminder.GetByUserID(ctx, spaceID(msg))
 	if err != nil {
 		h.sendMessage(msg.Chat.ID, "取得提醒列表失敗，請稍後再試")
 		return
@@ -93,6 +106,41 @@ func (h *Handlers) handleReminderList(ctx context.Context, msg *tgbotapi.Message
 	h.sendMessage(msg.Chat.ID, sb.String())
 }
 
+// listFilteredReminders lists every reminder matching the filter, including
+// past and disabled ones
+func (h *Handlers) listFilteredReminders(ctx context.Context, msg *tgbotapi.Message, query string) {
+	f, ok := h.parseFilter(ctx, msg, query)
+	if !ok {
+		return
+	}
+	reminders, err := h.repos.Reminder.Filter(ctx, spaceID(msg), f)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得提醒列表失敗，請稍後再試"))
+		return
+	}
+	if len(reminders) == 0 {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("⏰ 沒有符合「%s」的提醒", f.Query))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⏰ **提醒** · %s (%d)\n\n", f.Query, len(reminders)))
+	for _, r := range reminders {
+		timeStr := "未設定"
+		if r.RemindAt != nil {
+			timeStr = r.RemindAt.Format("2006-01-02 15:04")
+		}
+		status := ""
+		if !r.Enabled {
+			status = " (已停用)"
+		}
+		sb.WriteString(fmt.Sprintf("**%d.** %s%s\n", r.ReminderID, r.Messages, status))
+		sb.WriteString(fmt.Sprintf("   📅 %s\n\n", timeStr))
+	}
+
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
 func parseTimeToday(timeStr string) (time.Time, error) {
 	now := time.Now()
 	t, err := time.Parse("15:04", timeStr)
@@ -126,8 +174,8 @@ func (h *Handlers) handleReminderAcknowledge(ctx context.Context, callback *tgbo
 		return
 	}
 
-	// Verify the callback is from the correct user
-	if callback.From.ID != reminder.UserID {
+	// Verify the reminder belongs to this chat; in groups any member may acknowledge it
+	if callbackSpaceID(callback) != reminder.UserID {
 		h.answerCallbackWithAlert(callback.ID, "這不是你的提醒")
 		return
 	}
@@ -143,8 +191,11 @@ func (h *Handlers) handleReminderAcknowledge(ctx context.Context, callback *tgbo
 
 	// Handle recurrence: calculate next occurrence
 	if reminder.IsRecurring() && reminder.Dtstart != nil {
-		// Use strict version to get the next occurrence after now
-		next, err := rrule.NextOccurrenceStrict(reminder.RecurrenceRule, *reminder.Dtstart, now)
+		// Get the next occurrence after now, skipping cancelled ones
+		if err := h.repos.Reminder.LoadExceptions(ctx, reminder); err != nil {
+			h.debug("handleReminderAcknowledge: failed to load exceptions", "error", err)
+		}
+		next, err := calendar.NextOccurrence(reminder.RecurrenceRule, rrule.WallClock(*reminder.Dtstart), reminder.Exceptions, now)
 		h.debug("handleReminderAcknowledge: recurring", "next", next, "err", err)
 		if err != nil || next == nil {
 			// No more occurrences, disable it
@@ -166,41 +217,18 @@ func (h *Handlers) handleReminderAcknowledge(ctx context.Context, callback *tgbo
 		fmt.Sprintf("✅ 已確認提醒\n\n%s", reminder.Messages))
 }
 
-func (h *Handlers) CreateReminder(ctx context.Context, userID int64, message string, dtstart *time.Time, recurrenceRule string) (*models.Reminder, error) {
+func (h *Handlers) CreateReminder(ctx context.Context, userID, createdBy int64, message string, dtstart *time.Time, recurrenceRule string) (*models.Reminder, error) {
 	reminder := &models.Reminder{
 		UserID:         userID,
 		Enabled:        true,
 		Messages:       message,
 		Dtstart:        dtstart,
 		RecurrenceRule: recurrenceRule,
+		CreatedBy:      &createdBy,
 	}
 
 	// Calculate first remind_at time
-	if dtstart != nil {
-		if recurrenceRule != "" {
-			// For recurring reminders, calculate the first occurrence that is in the future
-			now := time.Now()
-			if dtstart.After(now) {
-				reminder.RemindAt = dtstart
-			} else {
-				// dtstart is in the past, find next occurrence
-				next, err := rrule.NextOccurrence(recurrenceRule, *dtstart, now)
-				if err != nil {
-					// Fallback to dtstart if RRULE parsing fails
-					reminder.RemindAt = dtstart
-				} else if next != nil {
-					reminder.RemindAt = next
-				} else {
-					// No more occurrences
-					reminder.RemindAt = nil
-					reminder.Enabled = false
-				}
-			}
-		} else {
-			// One-time reminder
-			reminder.RemindAt = dtstart
-		}
-	}
+	setFirstRemindAt(reminder)
 
 	err := h.repos.Reminder.Create(ctx, reminder)
 	if err == nil {
@@ -208,3 +236,59 @@ func (h *Handlers) CreateReminder(ctx context.Context, userID int64, message str
 	}
 	return reminder, err
 }
+
+// UpdateReminder saves an edited reminder, recalculating remind_at from dtstart and rrule
+func (h *Handlers) UpdateReminder(ctx context.Context, reminder *models.Reminder) error {
+	if reminder.Dtstart != nil {
+		if err := h.repos.Reminder.LoadExceptions(ctx, reminder); err != nil {
+			return err
+		}
+		setFirstRemindAt(reminder)
+		// A new time needs a new notification
+		reminder.NotifiedAt = nil
+		reminder.AcknowledgedAt = nil
+	}
+
+	err := h.repos.Reminder.Update(ctx, reminder)
+	if err == nil {
+		h.notifyScheduler()
+	}
+	return err
+}
+
+// setFirstRemindAt sets RemindAt to the first occurrence that is not in the
+// past, skipping cancelled and moved occurrences
+func setFirstRemindAt(reminder *models.Reminder) {
+	dtstart := reminder.Dtstart
+	if dtstart == nil {
+		return
+	}
+	if reminder.RecurrenceRule == "" {
+		// One-time reminder
+		reminder.RemindAt = dtstart
+		return
+	}
+
+	// For recurring reminders, calculate the first occurrence that is in the future
+	now := time.Now()
+	if dtstart.After(now) && len(reminder.Exceptions) == 0 {
+		reminder.RemindAt = dtstart
+		return
+	}
+
+	// dtstart is in the past, find next occurrence
+	next, err := rrule.NextOccurrence(reminder.RecurrenceRule, *dtstart, now)
+	if len(reminder.Exceptions) > 0 {
+		next, err = calendar.NextOccurrence(reminder.RecurrenceRule, rrule.WallClock(*dtstart), reminder.Exceptions, now)
+	}
+	if err != nil {
+		// Fallback to dtstart if RRULE parsing fails
+		reminder.RemindAt = dtstart
+	} else if next != nil {
+		reminder.RemindAt = next
+	} else {
+		// No more occurrences
+		reminder.RemindAt = nil
+		reminder.Enabled = false
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/bot/router"
+)
+
+// Register adds every command, callback prefix and the AI message handler to
+// the router, together with the middleware chain. A new feature only needs a
+// line here.
+func (h *Handlers) Register(r *router.Router, rateLimitPerMinute int) {
+	r.Use(
+		router.Recover(),
+		router.Logging(h.logger),
+		h.ignoreGroupChatter,
+		h.checkAccess,
+		router.RateLimit(rateLimitPerMinute, h.rateLimited),
+		h.ensureUser,
+		h.answerCallbacks,
+	)
+
+	r.Command("start", h.handleStart)
+	r.Command("help", h.handleHelp)
+	r.Command("memo", h.handleMemo)
+	r.Command("memos", h.handleMemoList)
+	r.Command("todo", h.handleTodo)
+	r.Command("todos", h.handleTodoList)
+	r.Command("subtask", h.handleSubtask)
+	r.Command("block", h.handleBlock)
+	r.Command("unblock", h.handleUnblock)
+	r.Command("projects", h.handleProjectList)
+	r.Command("project", h.handleProject)
+	r.Command("done", h.handleTodoDone)
+	r.Command("board", h.handleBoard)
+	r.Command("status", h.handleStatus)
+	r.Command("estimate", h.handleEstimate)
+	r.Command("plan", h.handlePlan)
+	r.Command("free", h.handleFree)
+	r.Command("assign", h.handleAssign)
+	r.Command("remind", h.handleReminder)
+	r.Command("reminders", h.handleReminderList)
+	r.Command("expense", h.handleExpense)
+	r.Command("income", h.handleIncome)
+	r.Command("transactions", h.handleTransactionList)
+	r.Command("balance", h.handleBalance)
+	r.Command("split", h.handleSplit)
+	r.Command("debts", h.handleDebts)
+	r.Command("settle", h.handleSettle)
+	r.Command("event", h.handleEvent)
+	r.Command("events", h.handleEventList)
+	r.Command("settings", h.handleSettings)
+	r.Command("buy", h.handleShoppingAdd)
+	r.Command("shopping", h.handleShoppingList)
+	r.Command("search", h.handleSearch)
+	r.Command("tags", h.handleTags)
+	r.Command("filter", h.handleFilter)
+	r.Command("f", h.handleSmartList)
+	r.Command("export_all", h.privateOnly(h.handleExportAll))
+	r.Command("delete_account", h.privateOnly(h.handleDeleteAccount))
+	r.Command("api_token", h.privateOnly(h.handleAPIToken))
+	r.Command("webhook", h.privateOnly(h.handleWebhook))
+	r.Command("admin", h.handleAdmin)
+	r.NotFound(func(ctx context.Context, msg *tgbotapi.Message) {
+		h.sendMessage(msg.Chat.ID, "未知指令，請使用 /help 查看可用指令")
+	})
+
+	// AI confirmations stay with the initiating user; other buttons act on the chat's space
+	for _, action := range []string{"confirm", "cancel", "option"} {
+		r.Callback(action, func(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+			h.handleConfirmationCallback(ctx, callback, action, args)
+		})
+	}
+	r.Callback("remind_ack", func(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+		h.handleReminderAcknowledge(ctx, callback, args[0])
+	})
+	r.Callback("settings", h.handleSettingsCallback)
+	r.Callback("shop", h.handleShoppingCallback)
+	r.Callback("todo_assign", h.handleAssignCallback)
+	r.Callback("todo_status", h.handleStatusCallback)
+	r.Callback("plan", h.handlePlanCallback)
+	r.Callback("free", h.handleFreeTimeCallback)
+	r.Callback("scope", h.handleScopeCallback)
+	r.Callback("conflict", h.handleConflictCallback)
+	r.Callback("account", h.handleAccountCallback)
+	r.Callback("search", h.handleSearchCallback)
+	r.Callback("tag", h.handleTagCallback)
+	r.Callback("admin", h.handleAdminCallback)
+
+	r.Message(h.handleAIMessage)
+}
+
+// privateOnly wraps commands that concern the personal account
+func (h *Handlers) privateOnly(next router.CommandHandler) router.CommandHandler {
+	return func(ctx context.Context, msg *tgbotapi.Message) {
+		if h.requirePrivateChat(msg) {
+			next(ctx, msg)
+		}
+	}
+}
+
+// ignoreGroupChatter drops group messages that are not meant for the bot
+// before anything else looks at them
+func (h *Handlers) ignoreGroupChatter(next router.Handler) router.Handler {
+	return func(ctx context.Context, req *router.Request) {
+		if req.Kind == router.KindMessage && isGroupChat(req.Chat) && !h.addressedToBot(req.Message) {
+			return
+		}
+		next(ctx, req)
+	}
+}
+
+// checkAccess runs Authorize for every update
+func (h *Handlers) checkAccess(next router.Handler) router.Handler {
+	return func(ctx context.Context, req *router.Request) {
+		if h.Authorize(ctx, req.Update) {
+			next(ctx, req)
+		}
+	}
+}
+
+// rateLimited tells a user who sends too many updates to slow down
+func (h *Handlers) rateLimited(ctx context.Context, req *router.Request) {
+	if req.Callback != nil {
+		h.answerCallbackWithAlert(req.Callback.ID, "⏳ 操作太頻繁，請稍後再試")
+		return
+	}
+	h.sendMessage(req.Chat.ID, "⏳ 訊息太頻繁，請稍後再試")
+}
+
+// ensureUser creates the sender's user row and, in groups, registers the
+// group as a space with the sender as a member
+func (h *Handlers) ensureUser(next router.Handler) router.Handler {
+	return func(ctx context.Context, req *router.Request) {
+		if _, err := h.repos.User.GetOrCreate(ctx, req.From.ID, req.From.UserName); err != nil {
+			log.Printf("Failed to get/create user: %v", err)
+			return
+		}
+		if err := h.ensureSpace(ctx, req.Chat, req.From); err != nil {
+			log.Printf("Failed to ensure space: %v", err)
+			return
+		}
+		next(ctx, req)
+	}
+}
+
+// answerCallbacks removes the loading state of pressed buttons
+func (h *Handlers) answerCallbacks(next router.Handler) router.Handler {
+	return func(ctx context.Context, req *router.Request) {
+		if req.Callback != nil {
+			if _, err := h.api.Request(tgbotapi.NewCallback(req.Callback.ID, "")); err != nil {
+				log.Printf("Failed to answer callback: %v", err)
+			}
+		}
+		next(ctx, req)
+	}
+}
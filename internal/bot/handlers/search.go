@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+const (
+	searchLimit = 20
+	// searchActionLimit is how many of the best results get quick-action buttons
+	searchActionLimit = 5
+)
+
+var entityLabels = map[models.EntityKind]string{
+	models.EntityTodo:        "📋 待辦事項",
+	models.EntityEvent:       "📅 事件",
+	models.EntityReminder:    "⏰ 提醒",
+	models.EntityMemo:        "📝 備忘錄",
+	models.EntityTransaction: "💰 交易記錄",
+}
+
+// handleSearch searches everything in the chat's space: "/search <關鍵字>"
+func (h *Handlers) handleSearch(ctx context.Context, msg *tgbotapi.Message) {
+	query := strings.TrimSpace(msg.CommandArguments())
+	if query == "" {
+		h.sendMessage(msg.Chat.ID, "請提供關鍵字\n用法: /search <關鍵字>\n例如: /search 牙醫")
+		return
+	}
+	h.handleAISearchResult(ctx, msg, map[string]string{"keyword": query}, true)
+}
+
+func (h *Handlers) handleAISearchResult(ctx context.Context, msg *tgbotapi.Message, params map[string]string, sendMsg bool) string {
+	query := strings.TrimSpace(params["keyword"])
+	if query == "" {
+		result := "請提供搜尋關鍵字"
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	results, err := h.repos.Search.Search(ctx, spaceID(msg), query, searchLimit)
+	if err != nil {
+		log.Printf("Failed to search: %v", err)
+		result := "搜尋失敗，請稍後再試"
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	if len(results) == 0 {
+		result := fmt.Sprintf("🔍 找不到與「%s」相關的資料", query)
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	result := searchResultsText(query, results)
+	if sendMsg {
+		parsed := format.ParseMarkdown(result)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+		reply.Entities = parsed.Entities
+		reply.ReplyMarkup = searchKeyboard(results)
+		if _, err := h.api.Send(reply); err != nil {
+			log.Printf("Failed to send search results: %v", err)
+		}
+	}
+	return result
+}
+
+// searchResultsText groups the results by kind; within a group they keep
+// their rank order
+func searchResultsText(query string, results []*models.SearchResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔍 **「%s」的搜尋結果** (%d)\n", query, len(results)))
+	writeGroupedResults(&sb, results)
+	return sb.String()
+}
+
+// writeGroupedResults writes the results under a heading per kind
+func writeGroupedResults(sb *strings.Builder, results []*models.SearchResult) {
+	for _, kind := range models.EntityKinds {
+		first := true
+		for _, r := range results {
+			if r.Kind != kind {
+				continue
+			}
+			if first {
+				sb.WriteString(fmt.Sprintf("\n**%s**\n", entityLabels[kind]))
+				first = false
+			}
+			writeSearchLine(sb, r)
+		}
+	}
+}
+
+func writeSearchLine(sb *strings.Builder, r *models.SearchResult) {
+	title := truncateRunes(strings.ReplaceAll(r.Title, "\n", " "), 40)
+	if title == "" {
+		title = "(無標題)"
+	}
+
+	status := "•"
+	if r.Done {
+		status = "✔️"
+	}
+	sb.WriteString(fmt.Sprintf("%s #%d %s", status, r.ID, title))
+
+	if r.Kind == models.EntityTransaction {
+		sb.WriteString(fmt.Sprintf(" $%.0f", r.Amount))
+	}
+	if r.Date != nil {
+		switch r.Kind {
+		case models.EntityMemo, models.EntityTransaction:
+			sb.WriteString(fmt.Sprintf(" (%s)", r.Date.Format("2006-01-02")))
+		default:
+			sb.WriteString(fmt.Sprintf(" (%s)", r.Date.Format("2006-01-02 15:04")))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// searchKeyboard offers "complete" for open todos and "delete" for the best
+// results. Deleting asks for confirmation in a separate message.
+func searchKeyboard(results []*models.SearchResult) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, r := range results {
+		if i == searchActionLimit {
+			break
+		}
+		var row []tgbotapi.InlineKeyboardButton
+		if r.Kind == models.EntityTodo && !r.Done {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("✅ 完成 #%d", r.ID), fmt.Sprintf("search:done:%d", r.ID)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("🗑 %s #%d", strings.Fields(entityLabels[r.Kind])[1], r.ID),
+			fmt.Sprintf("search:delete:%s:%d", r.Kind, r.ID)))
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleSearchCallback handles the quick actions of search results:
+// "search:done:<todoID>", "search:delete:<kind>:<id>" (asks for confirmation),
+// "search:confirm:<kind>:<id>" and "search:cancel". They act on the chat's
+// space, like the list buttons.
+func (h *Handlers) handleSearchCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil {
+		return
+	}
+	ownerID := callbackSpaceID(callback)
+	chatID := callback.Message.Chat.ID
+
+	switch args[0] {
+	case "done":
+		if len(args) < 2 {
+			return
+		}
+		todoID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+		if _, err := h.CompleteTodo(ctx, todoID, ownerID); err != nil {
+			h.answerCallbackWithAlert(callback.ID, "完成失敗，待辦事項可能已完成或已刪除")
+			return
+		}
+		h.sendMessage(chatID, fmt.Sprintf("✅ 待辦事項 #%d 已完成！", todoID))
+
+	case "delete", "confirm":
+		if len(args) < 3 {
+			return
+		}
+		kind := models.EntityKind(args[1])
+		label, ok := entityLabels[kind]
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			return
+		}
+
+		if args[0] == "delete" {
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗑 刪除", fmt.Sprintf("search:confirm:%s:%d", kind, id)),
+				tgbotapi.NewInlineKeyboardButtonData("❌ 取消", "search:cancel"),
+			))
+			reply := tgbotapi.NewMessage(chatID, fmt.Sprintf("確定要刪除%s #%d 嗎？", label, id))
+			reply.ReplyMarkup = keyboard
+			if _, err := h.api.Send(reply); err != nil {
+				log.Printf("Failed to send delete confirmation: %v", err)
+			}
+			return
+		}
+
+		if err := h.deleteSearchResult(ctx, kind, id, ownerID); err != nil {
+			log.Printf("Failed to delete %s %d: %v", kind, id, err)
+			h.editMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("刪除%s #%d 失敗，請稍後再試", label, id))
+			return
+		}
+		h.editMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("🗑 %s #%d 已刪除", label, id))
+
+	case "cancel":
+		h.deleteMessage(chatID, callback.Message.MessageID)
+	}
+}
+
+func (h *Handlers) deleteSearchResult(ctx context.Context, kind models.EntityKind, id int, ownerID int64) error {
+	switch kind {
+	case models.EntityMemo:
+		return h.repos.Memo.Delete(ctx, id, ownerID)
+	case models.EntityTodo:
+		return h.repos.Todo.Delete(ctx, id, ownerID)
+	case models.EntityReminder:
+		return h.repos.Reminder.Delete(ctx, id, ownerID)
+	case models.EntityEvent:
+		return h.repos.Event.Delete(ctx, id, ownerID)
+	case models.EntityTransaction:
+		return h.repos.Transaction.Delete(ctx, id, ownerID)
+	}
+	return fmt.Errorf("unknown search kind %q", kind)
+}
+
+// truncateRunes shortens s to at most n characters without splitting one
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -4,22 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
 )
 
 // handleSettings shows the settings menu
 func (h *Handlers) handleSettings(ctx context.Context, msg *tgbotapi.Message) {
-	settings, err := h.repos.UserSettings.GetOrCreate(ctx, msg.From.ID)
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, spaceID(msg))
 	if err != nil {
 		log.Printf("Failed to get user settings: %v", err)
 		h.sendMessage(msg.Chat.ID, "無法取得設定，請稍後再試")
 		return
 	}
 
-	text := h.buildSettingsMainText(settings.TodoRemindersEnabled, settings.DailySummaryEnabled, settings.DailySummaryTime)
+	text := h.buildSettingsMainText(settings.TodoRemindersEnabled, settings.DailySummaryEnabled, settings.DailySummaryTime) +
+		h.aiUsageText(ctx, spaceID(msg))
 	keyboard := h.buildSettingsMainKeyboard()
 
 	parsed := format.ParseMarkdown(text)
@@ -38,7 +41,8 @@ func (h *Handlers) handleSettingsCallback(ctx context.Context, callback *tgbotap
 		return
 	}
 
-	userID := callback.From.ID
+	// Settings of a group apply to the group's reminders and summaries
+	userID := callbackSpaceID(callback)
 	chatID := callback.Message.Chat.ID
 	messageID := callback.Message.MessageID
 
@@ -93,6 +97,35 @@ func (h *Handlers) handleSettingsCallback(ctx context.Context, callback *tgbotap
 			}
 		}
 
+	case "work":
+		if len(parts) > 1 {
+			switch parts[1] {
+			case "menu":
+				h.showWorkSettings(ctx, chatID, messageID, userID)
+			case "start", "end":
+				// Format: work:start:HH, the hour avoids ":" in the callback data
+				if len(parts) > 2 {
+					h.setWorkingHours(ctx, chatID, messageID, userID, parts[1], parts[2])
+				} else {
+					h.showWorkHourPicker(ctx, chatID, messageID, parts[1])
+				}
+			case "buffer":
+				if len(parts) > 2 {
+					h.setMeetingBuffer(ctx, chatID, messageID, userID, parts[2])
+				} else {
+					h.showBufferPicker(ctx, chatID, messageID)
+				}
+			}
+		}
+
+	case "alerts":
+		// Format: alerts:toggle:minutes
+		if len(parts) > 2 && parts[1] == "toggle" {
+			h.toggleEventAlert(ctx, chatID, messageID, userID, parts[2])
+		} else {
+			h.showAlertSettings(ctx, chatID, messageID, userID)
+		}
+
 	case "limit":
 		if len(parts) > 1 {
 			h.setDailyLimit(ctx, chatID, messageID, userID, parts[1])
@@ -148,6 +181,10 @@ func (h *Handlers) buildSettingsMainKeyboard() tgbotapi.InlineKeyboardMarkup {
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("⏱ 提醒頻率", "settings:interval:menu"),
+			tgbotapi.NewInlineKeyboardButtonData("🕘 工作時段", "settings:work:menu"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔔 事件提醒", "settings:alerts"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("❌ 關閉", "settings:close"),
@@ -162,7 +199,8 @@ func (h *Handlers) showSettingsMain(ctx context.Context, chatID int64, messageID
 		return
 	}
 
-	text := h.buildSettingsMainText(settings.TodoRemindersEnabled, settings.DailySummaryEnabled, settings.DailySummaryTime)
+	text := h.buildSettingsMainText(settings.TodoRemindersEnabled, settings.DailySummaryEnabled, settings.DailySummaryTime) +
+		h.aiUsageText(ctx, userID)
 	keyboard := h.buildSettingsMainKeyboard()
 
 	h.editMessageWithKeyboard(chatID, messageID, text, keyboard)
@@ -409,6 +447,186 @@ func (h *Handlers) disableQuietHours(ctx context.Context, chatID int64, messageI
 	h.showQuietSettings(ctx, chatID, messageID, userID)
 }
 
+// --- Working Hours Settings ---
+
+func (h *Handlers) showWorkSettings(ctx context.Context, chatID int64, messageID int, userID int64) {
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		return
+	}
+
+	bufferText := "無"
+	if settings.MeetingBuffer > 0 {
+		bufferText = fmt.Sprintf("%d 分鐘", settings.MeetingBuffer)
+	}
+	text := fmt.Sprintf("🕘 **工作時段**\n\n工作時段: %s - %s\n會議間緩衝: %s\n\n尋找空閒時間與安排待辦時只會使用工作時段，並在每個事件前後保留緩衝時間",
+		settings.WorkStart, settings.WorkEnd, bufferText)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("設定開始時間", "settings:work:start"),
+			tgbotapi.NewInlineKeyboardButtonData("設定結束時間", "settings:work:end"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("設定緩衝時間", "settings:work:buffer"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "settings:main"),
+		),
+	)
+
+	h.editMessageWithKeyboard(chatID, messageID, text, keyboard)
+}
+
+// showWorkHourPicker shows the hours for the start or end of the working hours
+func (h *Handlers) showWorkHourPicker(ctx context.Context, chatID int64, messageID int, which string) {
+	text := "🕘 **選擇工作開始時間**"
+	hours := []int{6, 7, 8, 9, 10, 11}
+	if which == "end" {
+		text = "🕘 **選擇工作結束時間**"
+		hours = []int{17, 18, 19, 20, 21, 22}
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(hours); i += 3 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, hour := range hours[i : i+3] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%02d:00", hour), fmt.Sprintf("settings:work:%s:%02d", which, hour)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "settings:work:menu"),
+	))
+
+	h.editMessageWithKeyboard(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (h *Handlers) setWorkingHours(ctx context.Context, chatID int64, messageID int, userID int64, which, hourStr string) {
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return
+	}
+
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		return
+	}
+
+	start, end := settings.WorkStart, settings.WorkEnd
+	if which == "start" {
+		start = fmt.Sprintf("%02d:00", hour)
+	} else {
+		end = fmt.Sprintf("%02d:00", hour)
+	}
+	if err := h.repos.UserSettings.SetWorkingHours(ctx, userID, start, end); err != nil {
+		log.Printf("Failed to set working hours: %v", err)
+		return
+	}
+
+	h.showWorkSettings(ctx, chatID, messageID, userID)
+}
+
+func (h *Handlers) showBufferPicker(ctx context.Context, chatID int64, messageID int) {
+	text := "🕘 **選擇會議間緩衝時間**"
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("無", "settings:work:buffer:0"),
+			tgbotapi.NewInlineKeyboardButtonData("5 分鐘", "settings:work:buffer:5"),
+			tgbotapi.NewInlineKeyboardButtonData("10 分鐘", "settings:work:buffer:10"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("15 分鐘", "settings:work:buffer:15"),
+			tgbotapi.NewInlineKeyboardButtonData("30 分鐘", "settings:work:buffer:30"),
+			tgbotapi.NewInlineKeyboardButtonData("60 分鐘", "settings:work:buffer:60"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "settings:work:menu"),
+		),
+	)
+
+	h.editMessageWithKeyboard(chatID, messageID, text, keyboard)
+}
+
+func (h *Handlers) setMeetingBuffer(ctx context.Context, chatID int64, messageID int, userID int64, minutesStr string) {
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil || minutes < 0 {
+		return
+	}
+
+	if err := h.repos.UserSettings.SetMeetingBuffer(ctx, userID, minutes); err != nil {
+		log.Printf("Failed to set meeting buffer: %v", err)
+		return
+	}
+
+	h.showWorkSettings(ctx, chatID, messageID, userID)
+}
+
+// --- Event Alert Settings ---
+
+// eventAlertChoices are the alerts offered as defaults for new events
+var eventAlertChoices = []int{0, 5, 10, 15, 30, 60, 120, 1440}
+
+func (h *Handlers) showAlertSettings(ctx context.Context, chatID int64, messageID int, userID int64) {
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		return
+	}
+
+	text := fmt.Sprintf("🔔 **事件提醒**\n\n新事件預設提醒: %s\n\n點選切換，最多 %d 個。全天事件預設在前一天 09:00 提醒",
+		models.AlertsLabel(settings.EventAlerts), models.MaxAlerts)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(eventAlertChoices); i += 4 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, minutes := range eventAlertChoices[i:min(i+4, len(eventAlertChoices))] {
+			label := models.AlertLabel(minutes)
+			if slices.Contains(settings.EventAlerts, minutes) {
+				label = "✅ " + label
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("settings:alerts:toggle:%d", minutes)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ 返回", "settings:main"),
+	))
+
+	h.editMessageWithKeyboard(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// toggleEventAlert adds or removes a default alert of new events
+func (h *Handlers) toggleEventAlert(ctx context.Context, chatID int64, messageID int, userID int64, minutesStr string) {
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil || minutes < 0 || minutes > models.MaxAlertMinutes {
+		return
+	}
+
+	settings, err := h.repos.UserSettings.GetOrCreate(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		return
+	}
+
+	alerts := slices.DeleteFunc(slices.Clone(settings.EventAlerts), func(m int) bool { return m == minutes })
+	if len(alerts) == len(settings.EventAlerts) {
+		if len(alerts) >= models.MaxAlerts {
+			return
+		}
+		alerts = append(alerts, minutes)
+	}
+	if err := h.repos.UserSettings.SetEventAlerts(ctx, userID, models.NormalizeAlerts(alerts)); err != nil {
+		log.Printf("Failed to set event alerts: %v", err)
+		return
+	}
+
+	h.showAlertSettings(ctx, chatID, messageID, userID)
+}
+
 // --- Daily Limit Settings ---
 
 func (h *Handlers) showLimitSettings(ctx context.Context, chatID int64, messageID int, userID int64) {
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// handleShoppingAdd adds an item: "/buy <item> [quantity]", e.g. "/buy 牛奶 2瓶"
+func (h *Handlers) handleShoppingAdd(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		h.sendMessage(msg.Chat.ID, "請提供品項\n用法: /buy <品項> [數量]\n例如: /buy 牛奶 2瓶")
+		return
+	}
+
+	name := strings.Join(args, " ")
+	quantity := ""
+	if len(args) > 1 && startsWithDigit(args[len(args)-1]) {
+		name = strings.Join(args[:len(args)-1], " ")
+		quantity = args[len(args)-1]
+	}
+
+	item := &models.ShoppingItem{
+		UserID:    spaceID(msg),
+		Name:      name,
+		Quantity:  quantity,
+		CreatedBy: &msg.From.ID,
+	}
+	if err := h.repos.Shopping.Create(ctx, item); err != nil {
+		log.Printf("Failed to create shopping item: %v", err)
+		h.sendMessage(msg.Chat.ID, "加入購物清單失敗，請稍後再試")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🛒 已加入購物清單: %s (ID: %d)\n使用 /shopping 查看清單", shoppingItemLabel(item), item.ItemID))
+}
+
+// handleShoppingList shows the list with a toggle button per item
+func (h *Handlers) handleShoppingList(ctx context.Context, msg *tgbotapi.Message) {
+	text, keyboard, err := h.buildShoppingList(ctx, spaceID(msg))
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "取得購物清單失敗，請稍後再試")
+		return
+	}
+
+	parsed := format.ParseMarkdown(text)
+	reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+	reply.Entities = parsed.Entities
+	if keyboard != nil {
+		reply.ReplyMarkup = *keyboard
+	}
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send shopping list: %v", err)
+	}
+}
+
+// handleShoppingCallback handles "shop:buy:<itemID>" and "shop:clear". The
+// list belongs to the chat the button was pressed in, so any member may use it.
+func (h *Handlers) handleShoppingCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, parts []string) {
+	if len(parts) == 0 || callback.Message == nil {
+		return
+	}
+
+	ownerID := callbackSpaceID(callback)
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	switch parts[0] {
+	case "buy":
+		if len(parts) < 2 {
+			return
+		}
+		itemID, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return
+		}
+		item, err := h.repos.Shopping.GetByID(ctx, itemID, ownerID)
+		if err != nil {
+			h.answerCallbackWithAlert(callback.ID, "找不到此品項")
+			return
+		}
+		var boughtBy *int64
+		if !item.IsBought() {
+			boughtBy = &callback.From.ID
+		}
+		if err := h.repos.Shopping.SetBought(ctx, itemID, ownerID, boughtBy); err != nil {
+			log.Printf("Failed to update shopping item: %v", err)
+			return
+		}
+	case "clear":
+		if _, err := h.repos.Shopping.ClearBought(ctx, ownerID); err != nil {
+			log.Printf("Failed to clear shopping list: %v", err)
+			return
+		}
+	default:
+		return
+	}
+
+	text, keyboard, err := h.buildShoppingList(ctx, ownerID)
+	if err != nil {
+		return
+	}
+	if keyboard == nil {
+		h.editMessageText(chatID, messageID, text)
+		return
+	}
+	h.editMessageWithKeyboard(chatID, messageID, text, *keyboard)
+}
+
+// buildShoppingList renders the list; the keyboard is nil when the list is empty
+func (h *Handlers) buildShoppingList(ctx context.Context, ownerID int64) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	items, err := h.repos.Shopping.GetByUserID(ctx, ownerID, true)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(items) == 0 {
+		return "🛒 購物清單是空的\n使用 /buy <品項> 新增", nil, nil
+	}
+
+	names := h.memberNames(ctx, ownerID)
+
+	var sb strings.Builder
+	sb.WriteString("🛒 **購物清單**\n\n")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	hasBought := false
+	for _, item := range items {
+		status := "⬜"
+		if item.IsBought() {
+			status = "✅"
+			hasBought = true
+		}
+		sb.WriteString(fmt.Sprintf("%s %s%s", status, shoppingItemLabel(item), creatorSuffix(names, item.CreatedBy)))
+		if item.IsBought() && item.BoughtBy != nil && names != nil {
+			if name, ok := names[*item.BoughtBy]; ok {
+				sb.WriteString(fmt.Sprintf(" (%s 已買)", name))
+			}
+		}
+		sb.WriteString("\n")
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", status, item.Name), fmt.Sprintf("shop:buy:%d", item.ItemID)),
+		))
+	}
+	if hasBought {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧹 清除已買", "shop:clear"),
+		))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return sb.String(), &keyboard, nil
+}
+
+func shoppingItemLabel(item *models.ShoppingItem) string {
+	if item.Quantity == "" {
+		return item.Name
+	}
+	return fmt.Sprintf("%s × %s", item.Name, item.Quantity)
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// isGroupChat reports whether the chat is shared by several members
+func isGroupChat(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// spaceID returns the owner of the data a message acts on: the group chat for
+// group messages, the sender for private chats. Group data is stored under the
+// chat ID, so reminders and summaries for it are sent to the group.
+func spaceID(msg *tgbotapi.Message) int64 {
+	if isGroupChat(msg.Chat) {
+		return msg.Chat.ID
+	}
+	return msg.From.ID
+}
+
+// callbackSpaceID returns the space of the chat an inline button was pressed in
+func callbackSpaceID(callback *tgbotapi.CallbackQuery) int64 {
+	if callback.Message != nil && isGroupChat(callback.Message.Chat) {
+		return callback.Message.Chat.ID
+	}
+	return callback.From.ID
+}
+
+// ensureSpace registers the group chat as a space and the sender as a member.
+// It is a no-op for private chats.
+func (h *Handlers) ensureSpace(ctx context.Context, chat *tgbotapi.Chat, from *tgbotapi.User) error {
+	if !isGroupChat(chat) {
+		return nil
+	}
+	if _, err := h.repos.User.GetOrCreate(ctx, from.ID, from.UserName); err != nil {
+		return err
+	}
+	space := &models.Space{
+		SpaceID:  chat.ID,
+		ChatType: chat.Type,
+		Title:    chat.Title,
+	}
+	return h.repos.Space.Ensure(ctx, space, from.ID)
+}
+
+// addressedToBot reports whether a group message is meant for the bot, i.e. it
+// mentions the bot or replies to one of its messages. The mention is stripped.
+func (h *Handlers) addressedToBot(msg *tgbotapi.Message) bool {
+	if msg.ReplyToMessage != nil && msg.ReplyToMessage.From != nil && msg.ReplyToMessage.From.ID == h.api.Self.ID {
+		return true
+	}
+	mention := "@" + h.api.Self.UserName
+	if h.api.Self.UserName != "" && strings.Contains(msg.Text, mention) {
+		msg.Text = strings.TrimSpace(strings.ReplaceAll(msg.Text, mention, ""))
+		return true
+	}
+	return false
+}
+
+// requirePrivateChat replies with a hint and returns false when a personal
+// command is used in a group
+func (h *Handlers) requirePrivateChat(msg *tgbotapi.Message) bool {
+	if isGroupChat(msg.Chat) {
+		h.sendMessage(msg.Chat.ID, "🔒 此指令涉及個人帳號，請在私訊中使用")
+		return false
+	}
+	return true
+}
+
+// memberNames returns display names of the members of a group space, or nil
+// for personal spaces where showing the creator is pointless
+func (h *Handlers) memberNames(ctx context.Context, spaceID int64) map[int64]string {
+	if spaceID > 0 {
+		return nil
+	}
+	members, err := h.repos.Space.GetMembers(ctx, spaceID)
+	if err != nil {
+		log.Printf("Failed to get space members: %v", err)
+		return nil
+	}
+	names := make(map[int64]string, len(members))
+	for _, m := range members {
+		if m.UserName != "" {
+			names[m.UserID] = "@" + m.UserName
+		} else {
+			names[m.UserID] = fmt.Sprintf("#%d", m.UserID)
+		}
+	}
+	return names
+}
+
+// creatorSuffix formats the creator of a row for group lists
+func creatorSuffix(names map[int64]string, createdBy *int64) string {
+	if names == nil || createdBy == nil {
+		return ""
+	}
+	if name, ok := names[*createdBy]; ok {
+		return " · " + name
+	}
+	return ""
+}
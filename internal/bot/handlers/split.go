@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/ledger"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// participantLabel is how a Telegram user appears in split expenses
+func participantLabel(u *tgbotapi.User) string {
+	if u.UserName != "" {
+		return "@" + u.UserName
+	}
+	return u.FirstName
+}
+
+// resolveParticipant maps "我"/"me" to the sender and normalizes "@Name" case
+func resolveParticipant(msg *tgbotapi.Message, name string) string {
+	name = strings.TrimSpace(name)
+	switch strings.ToLower(name) {
+	case "我", "me", "self", "@me":
+		return participantLabel(msg.From)
+	}
+	return name
+}
+
+// parseShareSpecs parses participants like "@amy", "@amy:60%" or "@amy:300" and
+// derives the split type from them. Percentages and amounts cannot be mixed.
+func parseShareSpecs(msg *tgbotapi.Message, tokens []string) ([]ledger.ShareSpec, models.SplitType, error) {
+	var specs []ledger.ShareSpec
+	percent, exact := false, false
+	for _, token := range tokens {
+		spec, isPercent, err := ledger.ParseShareSpec(token)
+		if err != nil {
+			return nil, "", err
+		}
+		spec.Participant = resolveParticipant(msg, spec.Participant)
+		if spec.HasValue {
+			if isPercent {
+				percent = true
+			} else {
+				exact = true
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	switch {
+	case percent && exact:
+		return nil, "", fmt.Errorf("%w: percentages and amounts cannot be mixed", ledger.ErrInvalidSplit)
+	case percent:
+		return specs, models.SplitPercentage, nil
+	case exact:
+		return specs, models.SplitExact, nil
+	}
+	return specs, models.SplitEqual, nil
+}
+
+// handleSplit records a shared expense:
+// "/split <金額> <說明> @a @b [by:@c]", "/split 1000 房租 @a:60% @b:40%", "/split 900 門票 @a:300 @b:200"
+func (h *Handlers) handleSplit(ctx context.Context, msg *tgbotapi.Message) {
+	usage := "用法: /split <金額> <說明> @成員... [by:@付款人]\n" +
+		"• 平分: /split 1200 晚餐 @amy @bob @cat\n" +
+		"• 百分比: /split 1000 房租 @amy:60% @bob:40%\n" +
+		"• 指定金額: /split 900 門票 @amy:300 @bob:200\n" +
+		"未分配的部分由付款人負擔"
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) < 2 {
+		h.sendMessage(msg.Chat.ID, "請提供金額與參與者\n"+usage)
+		return
+	}
+
+	amount, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || amount <= 0 {
+		h.sendMessage(msg.Chat.ID, "無效的金額\n"+usage)
+		return
+	}
+
+	payer := participantLabel(msg.From)
+	var description []string
+	var participants []string
+	for _, token := range args[1:] {
+		switch {
+		case strings.HasPrefix(token, "by:"):
+			payer = resolveParticipant(msg, strings.TrimPrefix(token, "by:"))
+		case strings.HasPrefix(token, "@") || strings.Contains(token, ":"):
+			participants = append(participants, token)
+		default:
+			description = append(description, token)
+		}
+	}
+	if len(participants) == 0 {
+		h.sendMessage(msg.Chat.ID, "請至少指定一位參與者\n"+usage)
+		return
+	}
+
+	specs, splitType, err := parseShareSpecs(msg, participants)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, splitErrorText(err)+"\n"+usage)
+		return
+	}
+
+	tx, err := h.SplitExpense(ctx, spaceID(msg), msg.From.ID, payer, amount, strings.Join(description, " "), splitType, specs)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, splitErrorText(err)+"\n"+usage)
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, formatSplit(tx))
+}
+
+// handleDebts shows who owes whom and the simplified settlement plan
+func (h *Handlers) handleDebts(ctx context.Context, msg *tgbotapi.Message) {
+	h.handleDebtsResult(ctx, msg, true)
+}
+
+func (h *Handlers) handleDebtsResult(ctx context.Context, msg *tgbotapi.Message, sendMsg bool) string {
+	balances, err := h.repos.Transaction.GetBalances(ctx, spaceID(msg))
+	if err != nil {
+		log.Printf("Failed to get balances: %v", err)
+		result := "取得分帳餘額失敗，請稍後再試"
+		if sendMsg {
+			h.sendMessage(msg.Chat.ID, result)
+		}
+		return result
+	}
+
+	result := formatDebts(balances)
+	if sendMsg {
+		h.sendMessage(msg.Chat.ID, result)
+	}
+	return result
+}
+
+// handleSettle records that the sender paid someone back: "/settle @amy [金額]".
+// Without an amount the sender's debt to that person in the settlement plan is used.
+func (h *Handlers) handleSettle(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		h.sendMessage(msg.Chat.ID, "請指定還款對象\n用法: /settle @成員 [金額]")
+		return
+	}
+
+	to := resolveParticipant(msg, args[0])
+	var amount float64
+	if len(args) > 1 {
+		var err error
+		amount, err = strconv.ParseFloat(args[1], 64)
+		if err != nil || amount <= 0 {
+			h.sendMessage(msg.Chat.ID, "無效的金額")
+			return
+		}
+	}
+
+	result, err := h.settle(ctx, msg, participantLabel(msg.From), to, amount)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, splitErrorText(err))
+		return
+	}
+	h.sendMessage(msg.Chat.ID, result)
+}
+
+// settle records a settlement and returns the text describing it. A zero
+// amount settles the debt from the settlement plan.
+func (h *Handlers) settle(ctx context.Context, msg *tgbotapi.Message, from, to string, amount float64) (string, error) {
+	if from == to {
+		return "", fmt.Errorf("%w: cannot settle with oneself", ledger.ErrInvalidSplit)
+	}
+	if amount == 0 {
+		balances, err := h.repos.Transaction.GetBalances(ctx, spaceID(msg))
+		if err != nil {
+			return "", err
+		}
+		for _, t := range ledger.Simplify(balances) {
+			if t.From == from && t.To == to {
+				amount = t.Amount
+			}
+		}
+		if amount == 0 {
+			return fmt.Sprintf("%s 目前不需要還款給 %s", from, to), nil
+		}
+	}
+
+	tx, err := h.RecordSettlement(ctx, spaceID(msg), msg.From.ID, from, to, amount)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🤝 已記錄還款 (ID: %d)\n%s → %s %s", tx.TransactionID, from, to, formatAmount(amount)), nil
+}
+
+// SplitExpense records an expense paid by payer and shared between participants
+func (h *Handlers) SplitExpense(ctx context.Context, userID, createdBy int64, payer string, amount float64, description string, splitType models.SplitType, specs []ledger.ShareSpec) (*models.Transaction, error) {
+	shares, err := ledger.Shares(amount, splitType, payer, specs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tx := &models.Transaction{
+		UserID:          userID,
+		Type:            models.TransactionTypeExpense,
+		Amount:          amount,
+		Description:     description,
+		TransactionDate: &now,
+		CreatedBy:       &createdBy,
+		PaidBy:          payer,
+		SplitType:       splitType,
+		Shares:          shares,
+	}
+	if err := h.repos.Transaction.CreateSplit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	h.webhooks.Emit(ctx, userID, models.WebhookEventTransactionCreated, tx)
+	return tx, nil
+}
+
+// RecordSettlement records that from paid amount back to to
+func (h *Handlers) RecordSettlement(ctx context.Context, userID, createdBy int64, from, to string, amount float64) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ledger.ErrInvalidSplit)
+	}
+
+	now := time.Now()
+	tx := &models.Transaction{
+		UserID:          userID,
+		Type:            models.TransactionTypeSettlement,
+		Amount:          amount,
+		Description:     fmt.Sprintf("%s 還款給 %s", from, to),
+		TransactionDate: &now,
+		CreatedBy:       &createdBy,
+		PaidBy:          from,
+		SplitType:       models.SplitExact,
+		Shares:          []*models.TransactionShare{{Participant: to, Amount: amount}},
+	}
+	if err := h.repos.Transaction.CreateSplit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	h.webhooks.Emit(ctx, userID, models.WebhookEventTransactionCreated, tx)
+	return tx, nil
+}
+
+func formatSplit(tx *models.Transaction) string {
+	splitLabel := map[models.SplitType]string{
+		models.SplitEqual:      "平分",
+		models.SplitPercentage: "按比例",
+		models.SplitExact:      "指定金額",
+	}[tx.SplitType]
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧾 **分帳已記錄** (ID: %d)\n", tx.TransactionID))
+	if tx.Description != "" {
+		sb.WriteString(fmt.Sprintf("說明: %s\n", tx.Description))
+	}
+	sb.WriteString(fmt.Sprintf("金額: %s（%s，%s 付款）\n\n", formatAmount(tx.Amount), splitLabel, tx.PaidBy))
+	for _, share := range tx.Shares {
+		sb.WriteString(fmt.Sprintf("• %s: %s\n", share.Participant, formatAmount(share.Amount)))
+	}
+	sb.WriteString("\n使用 /debts 查看誰欠誰")
+	return sb.String()
+}
+
+func formatDebts(balances map[string]float64) string {
+	if len(balances) == 0 {
+		return "🤝 目前沒有未結清的分帳"
+	}
+
+	names := make([]string, 0, len(balances))
+	for name := range balances {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return balances[names[i]] > balances[names[j]] })
+
+	var sb strings.Builder
+	sb.WriteString("💰 **分帳餘額**\n\n")
+	for _, name := range names {
+		b := balances[name]
+		if b > 0 {
+			sb.WriteString(fmt.Sprintf("🟢 %s 應收 %s\n", name, formatAmount(b)))
+		} else {
+			sb.WriteString(fmt.Sprintf("🔴 %s 應付 %s\n", name, formatAmount(-b)))
+		}
+	}
+
+	sb.WriteString("\n**建議還款方式**\n")
+	for _, t := range ledger.Simplify(balances) {
+		sb.WriteString(fmt.Sprintf("• %s → %s %s\n", t.From, t.To, formatAmount(t.Amount)))
+	}
+	sb.WriteString("\n還款後使用 /settle @成員 [金額] 記錄")
+	return sb.String()
+}
+
+// formatAmount shows whole amounts without decimals
+func formatAmount(v float64) string {
+	if v == float64(int64(v)) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// splitErrorText explains invalid input and hides other failures
+func splitErrorText(err error) string {
+	if errors.Is(err, ledger.ErrInvalidSplit) {
+		return "分攤設定有誤，請確認參與者、金額或比例（比例合計不可超過 100%，金額合計不可超過總額）"
+	}
+	log.Printf("Failed to record split: %v", err)
+	return "記錄分帳失敗，請稍後再試"
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// tagSuggestionLimit is how many tags are offered after creating an item
+	tagSuggestionLimit = 6
+	// tagButtonLimit is how many tags of the overview get a button
+	tagButtonLimit = 12
+)
+
+// handleTags shows the tags in use with their counts per kind:
+// "/tags [前綴]" lists only the tags starting with the prefix
+func (h *Handlers) handleTags(ctx context.Context, msg *tgbotapi.Message) {
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(msg.CommandArguments()), "#"))
+
+	counts, err := h.repos.Tag.GetCounts(ctx, spaceID(msg))
+	if err != nil {
+		log.Printf("Failed to get tags: %v", err)
+		h.sendMessage(msg.Chat.ID, "取得標籤失敗，請稍後再試")
+		return
+	}
+
+	var tags []*models.TagCount
+	for _, tag := range counts {
+		if strings.HasPrefix(strings.ToLower(tag.Name), prefix) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		if prefix != "" {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("🏷 沒有以「%s」開頭的標籤", prefix))
+		} else {
+			h.sendMessage(msg.Chat.ID, "🏷 還沒有標籤\n在內容中加上 #標籤，例如: /memo 買牛奶 #家裡")
+		}
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🏷 **標籤** (%d)\n\n", len(tags)))
+	for _, tag := range tags {
+		sb.WriteString(fmt.Sprintf("• #%s %d", tag.Name, tag.Total()))
+		var parts []string
+		for _, kind := range models.EntityKinds {
+			if n := tag.Counts[kind]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", strings.Fields(entityLabels[kind])[0], n))
+			}
+		}
+		sb.WriteString(" (" + strings.Join(parts, " · ") + ")\n")
+	}
+	sb.WriteString("\n點選標籤查看項目，或在列表使用 tag:名稱 篩選")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, tag := range tags {
+		if i == tagButtonLimit {
+			break
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("#"+tag.Name, fmt.Sprintf("tag:show:%d", tag.TagID)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	parsed := format.ParseMarkdown(sb.String())
+	reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send tags: %v", err)
+	}
+}
+
+// sendWithTagSuggestions sends the confirmation of a newly created item with
+// buttons for the tags that suit its text, or just the text when there are none
+func (h *Handlers) sendWithTagSuggestions(ctx context.Context, msg *tgbotapi.Message, text string, kind models.EntityKind, id int, itemText string) {
+	tags, err := h.repos.Tag.Suggest(ctx, spaceID(msg), itemText, "", tagSuggestionLimit)
+	if err != nil {
+		log.Printf("Failed to suggest tags: %v", err)
+	}
+	if len(tags) == 0 {
+		h.sendMessage(msg.Chat.ID, text)
+		return
+	}
+
+	linked := make(map[int]bool)
+	if ids, err := h.repos.Tag.GetTagIDs(ctx, kind, id); err == nil {
+		for _, tagID := range ids {
+			linked[tagID] = true
+		}
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, tag := range tags {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			tagButtonText(tag.Name, linked[tag.TagID]),
+			fmt.Sprintf("tag:toggle:%s:%d:%d", kind, id, tag.TagID)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	parsed := format.ParseMarkdown(text + "\n\n🏷 加上標籤？")
+	reply := tgbotapi.NewMessage(msg.Chat.ID, parsed.Text)
+	reply.Entities = parsed.Entities
+	reply.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := h.api.Send(reply); err != nil {
+		log.Printf("Failed to send tag suggestions: %v", err)
+	}
+}
+
+func tagButtonText(name string, linked bool) string {
+	if linked {
+		return "✅ #" + name
+	}
+	return "#" + name
+}
+
+// handleTagCallback handles "tag:show:<tagID>", which lists the items with
+// the tag, and "tag:toggle:<kind>:<id>:<tagID>" of the suggestion buttons.
+// They act on the chat's space.
+func (h *Handlers) handleTagCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string) {
+	if callback.Message == nil {
+		return
+	}
+	ownerID := callbackSpaceID(callback)
+	chatID := callback.Message.Chat.ID
+
+	switch args[0] {
+	case "show":
+		if len(args) < 2 {
+			return
+		}
+		tagID, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+		tag, err := h.repos.Tag.GetByID(ctx, tagID, ownerID)
+		if err != nil {
+			h.answerCallbackWithAlert(callback.ID, "標籤不存在")
+			return
+		}
+		results, err := h.repos.Search.ByTag(ctx, ownerID, tagID, searchLimit)
+		if err != nil {
+			log.Printf("Failed to list items by tag: %v", err)
+			h.sendMessage(chatID, "取得標籤項目失敗，請稍後再試")
+			return
+		}
+		if len(results) == 0 {
+			h.sendMessage(chatID, fmt.Sprintf("🏷 沒有標籤為 #%s 的項目", tag.Name))
+			return
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("🏷 **#%s** (%d)\n", tag.Name, len(results)))
+		writeGroupedResults(&sb, results)
+		h.sendMessage(chatID, sb.String())
+
+	case "toggle":
+		if len(args) < 4 {
+			return
+		}
+		kind := models.EntityKind(args[1])
+		if _, ok := entityLabels[kind]; !ok {
+			return
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			return
+		}
+		tagID, err := strconv.Atoi(args[3])
+		if err != nil {
+			return
+		}
+
+		added, err := h.repos.Tag.Toggle(ctx, ownerID, kind, id, tagID)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				log.Printf("Failed to toggle tag %d of %s %d: %v", tagID, kind, id, err)
+			}
+			h.answerCallbackWithAlert(callback.ID, "更新標籤失敗，項目可能已刪除")
+			return
+		}
+
+		// Flip the pressed button, the others keep their state
+		markup := callback.Message.ReplyMarkup
+		if markup == nil {
+			return
+		}
+		for _, row := range markup.InlineKeyboard {
+			for i, button := range row {
+				if button.CallbackData != nil && *button.CallbackData == callback.Data {
+					name := strings.TrimPrefix(strings.TrimPrefix(button.Text, "✅ "), "#")
+					row[i].Text = tagButtonText(name, added)
+				}
+			}
+		}
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID, *markup)
+		if _, err := h.api.Send(edit); err != nil {
+			log.Printf("Failed to update tag buttons: %v", err)
+		}
+	}
+}
+
+// hashtags returns the #tags written in text, as stored in a tags column
+func hashtags(text string) string {
+	var tags []string
+	for _, word := range strings.Fields(text) {
+		if tag, ok := strings.CutPrefix(word, "#"); ok && tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return models.JoinTags(models.ParseTags(strings.Join(tags, ",")))
+}
+
+// tagKeyword describes a tag filter with an optional keyword in list results
+func tagKeyword(tag, keyword string) string {
+	tag = "#" + strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	if keyword != "" {
+		return tag + " " + keyword
+	}
+	return tag
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -12,15 +13,24 @@ import (
 )
 
 func (h *Handlers) handleTodo(ctx context.Context, msg *tgbotapi.Message) {
-	title := strings.TrimSpace(msg.CommandArguments())
+	title, projectName := cutProjectOption(strings.TrimSpace(msg.CommandArguments()))
 	if title == "" {
-		h.sendMessage(msg.Chat.ID, "請提供待辦事項標題\n用法: /todo <標題>")
+		h.sendMessage(msg.Chat.ID, "請提供待辦事項標題\n用法: /todo <標題> [project:<專案>]")
 		return
 	}
 
 	todo := &models.Todo{
-		UserID: msg.From.ID,
-		Title:  title,
+		UserID:    spaceID(msg),
+		Title:     title,
+		Tags:      hashtags(title),
+		CreatedBy: &msg.From.ID,
+	}
+	if projectName != "" {
+		project, ok := h.findProject(ctx, msg, projectName)
+		if !ok {
+			return
+		}
+		h.placeTodo(ctx, todo, project, nil)
 	}
 
 	if err := h.repos.Todo.Create(ctx, todo); err != nil {
@@ -28,48 +38,108 @@ func (h *Handlers) handleTodo(ctx context.Context, msg *tgbotapi.Message) {
 		return
 	}
 
-	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 待辦事項已建立 (ID: %d)", todo.TodoID))
+	h.sendWithTagSuggestions(ctx, msg, fmt.Sprintf("✅ 待辦事項已建立 (ID: %d)", todo.TodoID), models.EntityTodo, todo.TodoID, title)
 }
 
+// handleTodoList lists open todos, or the todos matching "/todos <篩選條件>"
 func (h *Handlers) handleTodoList(ctx context.Context, msg *tgbotapi.Message) {
-	todos, err := h.repos.Todo.GetByUserID(ctx, msg.From.ID, false)
+	h.listTodos(ctx, msg, msg.CommandArguments())
+}
+
+func (h *Handlers) listTodos(ctx context.Context, msg *tgbotapi.Message, query string) {
+	if strings.TrimSpace(query) != "" {
+		h.listFilteredTodos(ctx, msg, query)
+		return
+	}
+
+	todos, err := h.repos.Todo.GetByUserID(ctx, spaceID(msg), false)
 	if err != nil {
 		h.sendMessage(msg.Chat.ID, "取得待辦事項失敗，請稍後再試")
 		return
 	}
 
-	if len(todos) == 0 {
+	// Todos assigned by others are listed in the assignee's private chat
+	var assigned []*models.Todo
+	if !isGroupChat(msg.Chat) {
+		assigned, err = h.repos.Todo.GetAssignedTo(ctx, msg.From.ID, false)
+		if err != nil {
+			log.Printf("Failed to get assigned todos: %v", err)
+		}
+	}
+
+	if len(todos) == 0 && len(assigned) == 0 {
 		h.sendMessage(msg.Chat.ID, "✅ 目前沒有待辦事項")
 		return
 	}
 
+	names := h.memberNames(ctx, spaceID(msg))
+
 	var sb strings.Builder
 	sb.WriteString("📋 **待辦事項列表**\n\n")
-	for _, todo := range todos {
-		status := "⬜"
-		if todo.IsCompleted() {
-			status = "✅"
-		}
-
-		title := todo.Title
-		if len(title) > 40 {
-			title = title[:40] + "..."
+	h.writeTodoGroups(ctx, &sb, spaceID(msg), todos, func(todo *models.Todo) string {
+		return creatorSuffix(names, todo.CreatedBy) + h.assignmentSuffix(ctx, todo)
+	})
+
+	if len(assigned) > 0 {
+		sb.WriteString("📌 **指派給我的**\n\n")
+		for _, todo := range assigned {
+			suffix := ""
+			if todo.AssignedBy != nil {
+				if user, err := h.repos.User.GetByID(ctx, *todo.AssignedBy); err == nil && user.UserName != "" {
+					suffix = " · 來自 @" + user.UserName
+				}
+			}
+			writeTodoLine(&sb, todo, suffix)
 		}
+	}
 
-		sb.WriteString(fmt.Sprintf("%s **%d.** %s", status, todo.TodoID, title))
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
 
-		if todo.DueTime != nil {
-			sb.WriteString(fmt.Sprintf("\n   📅 %s", todo.DueTime.Format("2006-01-02 15:04")))
-		}
-		if todo.Priority > 0 {
-			sb.WriteString(fmt.Sprintf(" | 優先級: %d", todo.Priority))
-		}
-		sb.WriteString("\n\n")
+func (h *Handlers) listFilteredTodos(ctx context.Context, msg *tgbotapi.Message, query string) {
+	f, ok := h.parseFilter(ctx, msg, query)
+	if !ok {
+		return
 	}
+	todos, err := h.repos.Todo.Filter(ctx, spaceID(msg), f)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, filterErrorText(err, "取得待辦事項失敗，請稍後再試"))
+		return
+	}
+	if len(todos) == 0 {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("📋 沒有符合「%s」的待辦事項", f.Query))
+		return
+	}
+
+	names := h.memberNames(ctx, spaceID(msg))
 
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📋 **待辦事項** · %s (%d)\n\n", f.Query, len(todos)))
+	writeTodoTree(&sb, todos, func(todo *models.Todo) string {
+		return creatorSuffix(names, todo.CreatedBy) + h.assignmentSuffix(ctx, todo)
+	})
 	h.sendMessage(msg.Chat.ID, sb.String())
 }
 
+func writeTodoLine(sb *strings.Builder, todo *models.Todo, suffix string) {
+	status := todoStatusIcon(todo)
+
+	title := todo.Title
+	if len(title) > 40 {
+		title = title[:40] + "..."
+	}
+
+	sb.WriteString(fmt.Sprintf("%s **%d.** %s%s%s%s", status, todo.TodoID, title, subtaskProgress(todo), blockedSuffix(todo), suffix))
+
+	if todo.DueTime != nil {
+		sb.WriteString(fmt.Sprintf("\n   📅 %s", todo.DueTime.Format("2006-01-02 15:04")))
+	}
+	if todo.Priority > 0 {
+		sb.WriteString(fmt.Sprintf(" | 優先級: %d", todo.Priority))
+	}
+	sb.WriteString("\n\n")
+}
+
 func (h *Handlers) handleTodoDone(ctx context.Context, msg *tgbotapi.Message) {
 	args := strings.TrimSpace(msg.CommandArguments())
 	if args == "" {
@@ -83,7 +153,7 @@ func (h *Handlers) handleTodoDone(ctx context.Context, msg *tgbotapi.Message) {
 		return
 	}
 
-	if err := h.repos.Todo.Complete(ctx, todoID, msg.From.ID); err != nil {
+	if _, err := h.CompleteTodo(ctx, todoID, spaceID(msg)); err != nil {
 		h.sendMessage(msg.Chat.ID, "完成待辦事項失敗，請確認編號是否正確")
 		return
 	}
@@ -91,14 +161,36 @@ func (h *Handlers) handleTodoDone(ctx context.Context, msg *tgbotapi.Message) {
 	h.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ 待辦事項 #%d 已完成！", todoID))
 }
 
-func (h *Handlers) CreateTodo(ctx context.Context, userID int64, title, description string, priority int, dueTime *time.Time, tags string) (*models.Todo, error) {
+// CompleteTodo marks a todo as completed and emits the todo.completed webhook event.
+// The todo may be owned by userID or assigned to them; the assigner is notified.
+// Completing a todo that is already done sends nothing.
+func (h *Handlers) CompleteTodo(ctx context.Context, todoID int, userID int64) (*models.Todo, error) {
+	changed, err := h.repos.Todo.Complete(ctx, todoID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	todo, err := h.repos.Todo.GetAccessible(ctx, todoID, userID)
+	if err != nil || !changed {
+		return todo, err
+	}
+
+	h.webhooks.Emit(ctx, todo.UserID, models.WebhookEventTodoCompleted, todo)
+	h.notifyAssignmentCompleted(ctx, todo, userID)
+	h.notifyUnblocked(ctx, todo)
+	return todo, nil
+}
+
+func (h *Handlers) CreateTodo(ctx context.Context, userID, createdBy int64, title, description string, priority int, dueTime *time.Time, tags string, estimatedMinutes int) (*models.Todo, error) {
 	todo := &models.Todo{
-		UserID:      userID,
-		Title:       title,
-		Description: description,
-		Priority:    priority,
-		DueTime:     dueTime,
-		Tags:        tags,
+		UserID:           userID,
+		Title:            title,
+		Description:      description,
+		Priority:         priority,
+		DueTime:          dueTime,
+		Tags:             tags,
+		CreatedBy:        &createdBy,
+		EstimatedMinutes: max(estimatedMinutes, 0),
 	}
 	err := h.repos.Todo.Create(ctx, todo)
 	return todo, err
@@ -45,16 +45,7 @@ func (h *Handlers) handleTransaction(ctx context.Context, msg *tgbotapi.Message,
 		description = parts[1]
 	}
 
-	now := time.Now()
-	tx := &models.Transaction{
-		UserID:          msg.From.ID,
-		Type:            txType,
-		Amount:          amount,
-		Description:     description,
-		TransactionDate: &now,
-	}
-
-	if err := h.repos.Transaction.Create(ctx, tx); err != nil {
+	if _, err := h.CreateTransaction(ctx, spaceID(msg), msg.From.ID, txType, amount, description, "", nil); err != nil {
 		h.sendMessage(msg.Chat.ID, "記錄失敗，請稍後再試")
 		return
 	}
@@ -80,14 +71,14 @@ func (h *Handlers) handleBalanceWithResult(ctx context.Context, msg *tgbotapi.Me
 	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Second)
 
-	income, err := h.repos.Transaction.GetTotalByType(ctx, msg.From.ID, startOfMonth, endOfMonth, models.TransactionTypeIncome)
+	income, err := h.repos.Transaction.GetTotalByType(ctx, spaceID(msg), startOfMonth, endOfMonth, models.TransactionTypeIncome)
 	if err != nil {
 		result := "取得統計失敗，請稍後再試"
 		h.sendMessage(msg.Chat.ID, result)
 		return result
 	}
 
-	expense, err := h.repos.Transaction.GetTotalByType(ctx, msg.From.ID, startOfMonth, endOfMonth, models.TransactionTypeExpense)
+	expense, err := h.repos.Transaction.GetTotalByType(ctx, spaceID(msg), startOfMonth, endOfMonth, models.TransactionTypeExpense)
 	if err != nil {
 		result := "取得統計失敗，請稍後再試"
 		h.sendMessage(msg.Chat.ID, result)
@@ -113,7 +104,7 @@ func (h *Handlers) handleBalanceWithResult(ctx context.Context, msg *tgbotapi.Me
 	return result
 }
 
-func (h *Handlers) CreateTransaction(ctx context.Context, userID int64, txType models.TransactionType, amount float64, description string, categoryName string, date *time.Time) (*models.Transaction, error) {
+func (h *Handlers) CreateTransaction(ctx context.Context, userID, createdBy int64, txType models.TransactionType, amount float64, description string, categoryName string, date *time.Time) (*models.Transaction, error) {
 	var categoryID *int
 	if categoryName != "" {
 		cat, err := h.repos.Category.GetOrCreateByName(ctx, userID, categoryName)
@@ -135,7 +126,12 @@ func (h *Handlers) CreateTransaction(ctx context.Context, userID int64, txType m
 		Amount:          amount,
 		Description:     description,
 		TransactionDate: date,
+		CreatedBy:       &createdBy,
 	}
-	err := h.repos.Transaction.Create(ctx, tx)
-	return tx, err
+	if err := h.repos.Transaction.Create(ctx, tx); err != nil {
+		return tx, err
+	}
+
+	h.webhooks.Emit(ctx, userID, models.WebhookEventTransactionCreated, tx)
+	return tx, nil
 }
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/auth"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/webhook"
+)
+
+const webhookUsage = `🪝 **Webhook 用法**
+
+/webhook add <網址> [事件] - 註冊 Webhook
+/webhook list - 查看 Webhook
+/webhook log <編號> - 查看最近傳送紀錄
+/webhook on <編號> / off <編號> - 啟用或停用
+/webhook delete <編號> - 刪除 Webhook
+
+可用事件（以逗號分隔，預設全部）:
+` + "`todo.completed`" + `, ` + "`transaction.created`" + `, ` + "`reminder.fired`"
+
+// handleWebhook manages outgoing webhooks: "/webhook add|list|log|on|off|delete ..."
+func (h *Handlers) handleWebhook(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		h.sendMessage(msg.Chat.ID, webhookUsage)
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			h.sendMessage(msg.Chat.ID, "請提供網址\n用法: /webhook add <網址> [事件]")
+			return
+		}
+		events := ""
+		if len(args) > 2 {
+			events = strings.Join(args[2:], ",")
+		}
+		h.handleWebhookAdd(ctx, msg, args[1], events)
+	case "list":
+		h.handleWebhookList(ctx, msg)
+	case "log", "on", "off", "delete":
+		if len(args) < 2 {
+			h.sendMessage(msg.Chat.ID, fmt.Sprintf("請提供 Webhook 編號\n用法: /webhook %s <編號>", args[0]))
+			return
+		}
+		webhookID, err := strconv.Atoi(args[1])
+		if err != nil {
+			h.sendMessage(msg.Chat.ID, "無效的編號")
+			return
+		}
+		switch args[0] {
+		case "log":
+			h.handleWebhookLog(ctx, msg, webhookID)
+		case "on", "off":
+			h.handleWebhookSetEnabled(ctx, msg, webhookID, args[0] == "on")
+		case "delete":
+			h.handleWebhookDelete(ctx, msg, webhookID)
+		}
+	default:
+		h.sendMessage(msg.Chat.ID, webhookUsage)
+	}
+}
+
+func (h *Handlers) handleWebhookAdd(ctx context.Context, msg *tgbotapi.Message, rawURL, rawEvents string) {
+	if err := webhook.ValidateURL(ctx, rawURL); err != nil {
+		if errors.Is(err, webhook.ErrForbiddenHost) {
+			h.sendMessage(msg.Chat.ID, "無效的網址，不允許指向本機或內部網路位址")
+			return
+		}
+		h.sendMessage(msg.Chat.ID, "無效的網址，請使用 http:// 或 https:// 開頭的完整網址")
+		return
+	}
+	events, err := webhook.ParseEvents(rawEvents)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("未知的事件: %s\n\n%s", rawEvents, webhookUsage))
+		return
+	}
+
+	secret, err := auth.GenerateSecret()
+	if err != nil {
+		log.Printf("Failed to generate webhook secret: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立 Webhook 失敗，請稍後再試")
+		return
+	}
+
+	hook := &models.Webhook{
+		UserID:  msg.From.ID,
+		URL:     rawURL,
+		Secret:  secret,
+		Events:  events,
+		Enabled: true,
+	}
+	if err := h.repos.Webhook.Create(ctx, hook); err != nil {
+		log.Printf("Failed to save webhook: %v", err)
+		h.sendMessage(msg.Chat.ID, "建立 Webhook 失敗，請稍後再試")
+		return
+	}
+
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf(`🪝 **Webhook 已建立** (ID: %d)
+
+網址: %s
+事件: `+"`%s`"+`
+
+簽章密鑰（只會顯示一次）:
+`+"`%s`"+`
+
+每次傳送會附上 `+"`%s`"+` 標頭，內容為 `+"`sha256=HMAC(密鑰, 時間戳.內容)`"+`，時間戳見 `+"`%s`"+`。`,
+		hook.WebhookID, hook.URL, hook.Events, secret, webhook.HeaderSignature, webhook.HeaderTimestamp))
+}
+
+func (h *Handlers) handleWebhookList(ctx context.Context, msg *tgbotapi.Message) {
+	hooks, err := h.repos.Webhook.GetByUserID(ctx, msg.From.ID)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "取得 Webhook 列表失敗，請稍後再試")
+		return
+	}
+
+	if len(hooks) == 0 {
+		h.sendMessage(msg.Chat.ID, "🪝 目前沒有 Webhook\n使用 /webhook add <網址> 建立")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🪝 **Webhook 列表**\n\n")
+	for _, hook := range hooks {
+		status := "🟢"
+		if !hook.Enabled {
+			status = "⚪"
+		}
+		sb.WriteString(fmt.Sprintf("%s **%d.** %s\n", status, hook.WebhookID, hook.URL))
+		sb.WriteString(fmt.Sprintf("   事件: `%s`\n\n", hook.Events))
+	}
+
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+func (h *Handlers) handleWebhookLog(ctx context.Context, msg *tgbotapi.Message, webhookID int) {
+	if _, err := h.repos.Webhook.GetByID(ctx, webhookID, msg.From.ID); err != nil {
+		h.sendMessage(msg.Chat.ID, "找不到此 Webhook，請確認編號是否正確")
+		return
+	}
+
+	deliveries, err := h.repos.Webhook.GetDeliveriesByWebhookID(ctx, webhookID, 10)
+	if err != nil {
+		h.sendMessage(msg.Chat.ID, "取得傳送紀錄失敗，請稍後再試")
+		return
+	}
+
+	if len(deliveries) == 0 {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🪝 Webhook #%d 尚無傳送紀錄", webhookID))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🪝 **Webhook #%d 最近傳送紀錄**\n\n", webhookID))
+	for _, d := range deliveries {
+		icon := "⏳"
+		switch d.Status {
+		case models.WebhookDeliverySuccess:
+			icon = "✅"
+		case models.WebhookDeliveryFailed:
+			icon = "❌"
+		}
+		sb.WriteString(fmt.Sprintf("%s `%s` %s | 嘗試 %d 次", icon, d.Event, d.CreatedAt.Format("01-02 15:04"), d.Attempts))
+		if d.ResponseStatus != nil {
+			sb.WriteString(fmt.Sprintf(" | HTTP %d", *d.ResponseStatus))
+		}
+		if d.Status == models.WebhookDeliveryPending && d.NextAttemptAt != nil && d.Attempts > 0 {
+			sb.WriteString(fmt.Sprintf("\n   下次重試 %s", d.NextAttemptAt.Format("15:04")))
+		}
+		if d.LastError != "" && d.Status != models.WebhookDeliverySuccess {
+			sb.WriteString(fmt.Sprintf("\n   錯誤: %s", d.LastError))
+		}
+		sb.WriteString("\n")
+	}
+
+	h.sendMessage(msg.Chat.ID, sb.String())
+}
+
+func (h *Handlers) handleWebhookSetEnabled(ctx context.Context, msg *tgbotapi.Message, webhookID int, enabled bool) {
+	if _, err := h.repos.Webhook.GetByID(ctx, webhookID, msg.From.ID); err != nil {
+		h.sendMessage(msg.Chat.ID, "找不到此 Webhook，請確認編號是否正確")
+		return
+	}
+	if err := h.repos.Webhook.SetEnabled(ctx, webhookID, msg.From.ID, enabled); err != nil {
+		h.sendMessage(msg.Chat.ID, "更新 Webhook 失敗，請稍後再試")
+		return
+	}
+
+	if enabled {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("🟢 Webhook #%d 已啟用", webhookID))
+	} else {
+		h.sendMessage(msg.Chat.ID, fmt.Sprintf("⚪ Webhook #%d 已停用", webhookID))
+	}
+}
+
+func (h *Handlers) handleWebhookDelete(ctx context.Context, msg *tgbotapi.Message, webhookID int) {
+	deleted, err := h.repos.Webhook.Delete(ctx, webhookID, msg.From.ID)
+	if err != nil || !deleted {
+		h.sendMessage(msg.Chat.ID, "刪除 Webhook 失敗，請確認編號是否正確")
+		return
+	}
+	h.sendMessage(msg.Chat.ID, fmt.Sprintf("🗑 Webhook #%d 已刪除", webhookID))
+}
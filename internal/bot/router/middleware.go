@@ -0,0 +1,138 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/metrics"
+)
+
+// slowUpdate is the handling time above which an update is logged as a warning
+const slowUpdate = 10 * time.Second
+
+type loggerKey struct{}
+
+// Logger returns the request-scoped logger set by the Logging middleware,
+// or the default logger outside a request
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// Recover turns a panic in a handler into an error log so one bad update
+// cannot take down the bot
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) {
+			defer func() {
+				if r := recover(); r != nil {
+					metrics.UpdatesHandled.WithLabelValues("panic", req.Name).Inc()
+					Logger(ctx).Error("panic while handling update", "panic", r, "stack", string(debug.Stack()))
+				}
+			}()
+			next(ctx, req)
+		}
+	}
+}
+
+// Logging attaches a logger with the update's ID, user, kind and name to the
+// context, counts the update and measures how long it took
+func Logging(base *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) {
+			logger := base.With(
+				"update_id", req.Update.UpdateID,
+				"user_id", req.From.ID,
+				"kind", req.Kind,
+				"name", req.Name,
+			)
+			ctx = context.WithValue(ctx, loggerKey{}, logger)
+
+			start := time.Now()
+			metrics.UpdatesHandled.WithLabelValues(req.Kind, req.Name).Inc()
+			next(ctx, req)
+			elapsed := time.Since(start)
+			metrics.UpdateDuration.WithLabelValues(req.Kind).Observe(elapsed.Seconds())
+
+			if elapsed > slowUpdate {
+				logger.Warn("slow update", "duration", elapsed)
+			} else {
+				logger.Debug("update handled", "duration", elapsed)
+			}
+		}
+	}
+}
+
+// RateLimit allows each user perMinute updates per minute with bursts of the
+// same size (a token bucket). Rejected requests go to onLimited, which should
+// tell the user to slow down. A perMinute of zero disables the limit.
+func RateLimit(perMinute int, onLimited Handler) Middleware {
+	if perMinute <= 0 {
+		return func(next Handler) Handler { return next }
+	}
+	limiter := &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[int64]*bucket),
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) {
+			if !limiter.allow(req.From.ID, time.Now()) {
+				metrics.UpdatesHandled.WithLabelValues("rate_limited", req.Name).Inc()
+				if onLimited != nil {
+					onLimited(ctx, req)
+				}
+				return
+			}
+			next(ctx, req)
+		}
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[int64]*bucket
+}
+
+func (l *rateLimiter) allow(userID int64, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[userID]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[userID] = b
+		l.prune(now)
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune drops buckets that have refilled completely, so the map only holds
+// recently active users
+func (l *rateLimiter) prune(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for id, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package router dispatches Telegram updates to the handlers registered for
+// commands, callback data prefixes and plain messages, wrapped by middleware.
+package router
+
+import (
+	"context"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Update kinds, also used as the "type" label of the update metrics
+const (
+	KindCommand  = "command"
+	KindCallback = "callback"
+	KindMessage  = "message"
+)
+
+// Request is an update resolved to its route
+type Request struct {
+	Update tgbotapi.Update
+	Kind   string // KindCommand, KindCallback or KindMessage
+	Name   string // command name, callback prefix or "text"; "unknown" when no route matched
+	From   *tgbotapi.User
+	Chat   *tgbotapi.Chat // nil for callbacks on inline messages
+
+	// Message is set for commands and messages, Callback for callbacks
+	Message  *tgbotapi.Message
+	Callback *tgbotapi.CallbackQuery
+}
+
+// Handler handles a resolved request
+type Handler func(ctx context.Context, req *Request)
+
+// Middleware wraps a handler, e.g. to recover panics or check access
+type Middleware func(next Handler) Handler
+
+// CommandHandler handles "/name args"
+type CommandHandler func(ctx context.Context, msg *tgbotapi.Message)
+
+// CallbackHandler handles callback data "prefix:arg1:arg2..." and receives the args
+type CallbackHandler func(ctx context.Context, callback *tgbotapi.CallbackQuery, args []string)
+
+// MessageHandler handles a message that is not a command
+type MessageHandler func(ctx context.Context, msg *tgbotapi.Message)
+
+type Router struct {
+	commands   map[string]CommandHandler
+	callbacks  map[string]CallbackHandler
+	message    MessageHandler
+	notFound   CommandHandler
+	middleware []Middleware
+}
+
+func New() *Router {
+	return &Router{
+		commands:  make(map[string]CommandHandler),
+		callbacks: make(map[string]CallbackHandler),
+	}
+}
+
+// Use appends middleware; the first one added runs outermost
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Command registers the handler of "/name"
+func (r *Router) Command(name string, h CommandHandler) {
+	r.commands[name] = h
+}
+
+// Callback registers the handler of callback data starting with "prefix:"
+func (r *Router) Callback(prefix string, h CallbackHandler) {
+	r.callbacks[prefix] = h
+}
+
+// Message registers the handler of plain messages
+func (r *Router) Message(h MessageHandler) {
+	r.message = h
+}
+
+// NotFound registers the handler of unknown commands
+func (r *Router) NotFound(h CommandHandler) {
+	r.notFound = h
+}
+
+// Dispatch routes an update and runs it through the middleware chain.
+// Updates without a route, such as edited messages, are ignored.
+func (r *Router) Dispatch(ctx context.Context, update tgbotapi.Update) {
+	req, h := r.resolve(update)
+	if h == nil {
+		return
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	h(ctx, req)
+}
+
+func (r *Router) resolve(update tgbotapi.Update) (*Request, Handler) {
+	req := &Request{Update: update}
+
+	if cb := update.CallbackQuery; cb != nil {
+		req.Kind = KindCallback
+		req.Callback = cb
+		req.From = cb.From
+		if cb.Message != nil {
+			req.Chat = cb.Message.Chat
+		}
+
+		parts := strings.Split(cb.Data, ":")
+		h, ok := r.callbacks[parts[0]]
+		if !ok || len(parts) < 2 {
+			return nil, nil
+		}
+		req.Name = parts[0]
+		return req, func(ctx context.Context, req *Request) {
+			h(ctx, req.Callback, parts[1:])
+		}
+	}
+
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return nil, nil
+	}
+	req.Message = msg
+	req.From = msg.From
+	req.Chat = msg.Chat
+
+	if msg.IsCommand() {
+		req.Kind = KindCommand
+		req.Name = msg.Command()
+		h, ok := r.commands[req.Name]
+		if !ok {
+			req.Name = "unknown"
+			h = r.notFound
+		}
+		if h == nil {
+			return nil, nil
+		}
+		return req, func(ctx context.Context, req *Request) {
+			h(ctx, req.Message)
+		}
+	}
+
+	if r.message == nil {
+		return nil, nil
+	}
+	req.Kind = KindMessage
+	req.Name = "text"
+	return req, func(ctx context.Context, req *Request) {
+		r.message(ctx, req.Message)
+	}
+}
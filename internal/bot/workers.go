@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// shardQueueSize is how many updates may wait per worker before polling blocks
+const shardQueueSize = 64
+
+// updatePool handles updates on a fixed number of workers. All updates of a
+// user go to the same worker, so they are handled one at a time and in order,
+// while different users are handled concurrently.
+type updatePool struct {
+	shards []chan tgbotapi.Update
+	wg     sync.WaitGroup
+}
+
+func newUpdatePool(workers int, handle func(tgbotapi.Update)) *updatePool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &updatePool{shards: make([]chan tgbotapi.Update, workers)}
+	for i := range p.shards {
+		ch := make(chan tgbotapi.Update, shardQueueSize)
+		p.shards[i] = ch
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for update := range ch {
+				handle(update)
+			}
+		}()
+	}
+	return p
+}
+
+// submit queues an update on its user's worker. It blocks while that worker's
+// queue is full, which slows down polling instead of piling up goroutines.
+// It reports false if ctx was cancelled before the update could be queued.
+func (p *updatePool) submit(ctx context.Context, update tgbotapi.Update) bool {
+	key := uint64(updateUserID(update))
+	select {
+	case p.shards[key%uint64(len(p.shards))] <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// close stops accepting updates; queued ones are still handled
+func (p *updatePool) close() {
+	for _, ch := range p.shards {
+		close(ch)
+	}
+}
+
+// done is closed once every queued update has been handled after close
+func (p *updatePool) done() <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// updateUserID returns the user an update belongs to, or the chat for updates
+// without a sender
+func updateUserID(update tgbotapi.Update) int64 {
+	switch {
+	case update.CallbackQuery != nil && update.CallbackQuery.From != nil:
+		return update.CallbackQuery.From.ID
+	case update.Message != nil && update.Message.From != nil:
+		return update.Message.From.ID
+	case update.Message != nil && update.Message.Chat != nil:
+		return update.Message.Chat.ID
+	}
+	return 0
+}
@@ -0,0 +1,315 @@
+// Package calendar answers range queries over events with every occurrence
+// of recurring events, instead of only their next one
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/hray3182/LifeLine/internal/rrule"
+)
+
+const (
+	// defaultDuration is how long an event without a duration takes up
+	defaultDuration = 60 * time.Minute
+	// conflictDays is how far ahead the occurrences of a recurring event
+	// are checked for conflicts
+	conflictDays = 28
+)
+
+type Service struct {
+	events *repository.EventRepository
+}
+
+func New(events *repository.EventRepository) *Service {
+	return &Service{events: events}
+}
+
+// Occurrences returns the occurrences of the events of userID overlapping
+// [start, end), sorted by start
+func (s *Service) Occurrences(ctx context.Context, userID int64, start, end time.Time) ([]*models.Event, error) {
+	events, err := s.events.GetInRange(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.events.LoadExceptions(ctx, events...); err != nil {
+		return nil, err
+	}
+	return Expand(events, start, end), nil
+}
+
+// Day returns the occurrences of the events of userID on the day of t
+func (s *Service) Day(ctx context.Context, userID int64, t time.Time) ([]*models.Event, error) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return s.Occurrences(ctx, userID, start, start.AddDate(0, 0, 1))
+}
+
+// Conflicts returns the occurrences of the other events of userID that
+// overlap an occurrence of event, which need not be saved yet. Occurrences
+// of exclude, the event being changed, don't count, and neither do
+// transparent events. A recurring event is checked over its next four
+// weeks from now.
+func (s *Service) Conflicts(ctx context.Context, userID int64, event *models.Event, exclude int, now time.Time) ([]*models.Event, error) {
+	if event.Dtstart == nil || event.Transparent {
+		return nil, nil
+	}
+	start := rrule.WallClock(*event.Dtstart)
+	end := End(event, start)
+	if event.IsRecurring() {
+		if start.Before(now) {
+			start = now
+		}
+		end = start.AddDate(0, 0, conflictDays)
+	}
+	own := Expand([]*models.Event{event}, start, end)
+	if len(own) == 0 {
+		return nil, nil
+	}
+
+	last := own[len(own)-1]
+	others, err := s.Occurrences(ctx, userID, *own[0].NextOccurrence, End(last, *last.NextOccurrence))
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []*models.Event
+	for _, other := range others {
+		if other.Transparent || (exclude != 0 && other.EventID == exclude) {
+			continue
+		}
+		if slices.ContainsFunc(own, func(e *models.Event) bool { return Overlaps(e, other) }) {
+			conflicts = append(conflicts, other)
+		}
+	}
+	return conflicts, nil
+}
+
+// Overlaps reports whether the occurrences a and b take up the same time
+func Overlaps(a, b *models.Event) bool {
+	aStart, bStart := *a.NextOccurrence, *b.NextOccurrence
+	return aStart.Before(End(b, bStart)) && bStart.Before(End(a, aStart))
+}
+
+// Overlapping returns the pairs of occurrences taking up the same time,
+// given occurrences sorted by start; transparent events don't count
+func Overlapping(occurrences []*models.Event) [][2]*models.Event {
+	var pairs [][2]*models.Event
+	for i, a := range occurrences {
+		if a.Transparent {
+			continue
+		}
+		for _, b := range occurrences[i+1:] {
+			if !b.NextOccurrence.Before(End(a, *a.NextOccurrence)) {
+				break
+			}
+			if !b.Transparent {
+				pairs = append(pairs, [2]*models.Event{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+// Expand returns a copy of the events per occurrence overlapping
+// [start, end), with NextOccurrence set to the start of that occurrence,
+// sorted by start. Cancelled occurrences are left out and overridden ones
+// carry their changes. Times read from the database hold the local wall
+// clock, so the copies carry their times in time.Local. An event whose
+// rule can't be parsed counts as a one-time event.
+func Expand(events []*models.Event, start, end time.Time) []*models.Event {
+	var occurrences []*models.Event
+	add := func(occurrence *models.Event) {
+		t := *occurrence.NextOccurrence
+		if t.Before(end) && End(occurrence, t).After(start) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	for _, e := range events {
+		if e.Dtstart == nil {
+			continue
+		}
+		dtstart := rrule.WallClock(*e.Dtstart)
+		if !e.IsRecurring() {
+			occurrence := *e
+			occurrence.Dtstart = &dtstart
+			occurrence.NextOccurrence = &dtstart
+			add(&occurrence)
+			continue
+		}
+
+		// Occurrences starting before start may still run into the range
+		starts, err := rrule.Between(e.RecurrenceRule, dtstart, start.Add(-Length(e)), end)
+		if err != nil {
+			starts = []time.Time{dtstart}
+		}
+		for _, t := range starts {
+			if exceptionOf(e.Exceptions, t) == nil {
+				add(occurrenceAt(e, dtstart, t))
+			}
+		}
+		// Moved occurrences may come from anywhere in the series
+		for _, x := range e.Exceptions {
+			if !x.Cancelled {
+				add(Override(e, x))
+			}
+		}
+	}
+	slices.SortStableFunc(occurrences, func(a, b *models.Event) int {
+		return a.NextOccurrence.Compare(*b.NextOccurrence)
+	})
+	return occurrences
+}
+
+// occurrenceAt returns a copy of the recurring event for its occurrence at t
+func occurrenceAt(e *models.Event, dtstart, t time.Time) *models.Event {
+	occurrence := *e
+	occurrence.Dtstart = &dtstart
+	occurrence.NextOccurrence = &t
+	occurrence.RecurrenceID = &t
+	return &occurrence
+}
+
+// Override returns a copy of the recurring event for the occurrence the
+// exception replaces, with the exception's changes applied
+func Override(e *models.Event, x *models.RecurrenceException) *models.Event {
+	var dtstart time.Time
+	if e.Dtstart != nil {
+		dtstart = rrule.WallClock(*e.Dtstart)
+	}
+	occurrence := occurrenceAt(e, dtstart, rrule.WallClock(x.StartTime()))
+	original := rrule.WallClock(x.OriginalStart)
+	occurrence.RecurrenceID = &original
+	if x.Title != "" {
+		occurrence.Title = x.Title
+	}
+	if x.Duration != nil {
+		occurrence.Duration = *x.Duration
+	}
+	return occurrence
+}
+
+// exceptionOf returns the exception of the occurrence originally at t
+func exceptionOf(exceptions []*models.RecurrenceException, t time.Time) *models.RecurrenceException {
+	for _, x := range exceptions {
+		if rrule.WallClock(x.OriginalStart).Equal(t) {
+			return x
+		}
+	}
+	return nil
+}
+
+// MovedTo returns the exception whose occurrence now takes place at t, if any
+func MovedTo(exceptions []*models.RecurrenceException, t time.Time) *models.RecurrenceException {
+	t = rrule.WallClock(t)
+	for _, x := range exceptions {
+		if !x.Cancelled && rrule.WallClock(x.StartTime()).Equal(t) {
+			return x
+		}
+	}
+	return nil
+}
+
+// NextOccurrence returns the first occurrence of the series strictly after
+// after, skipping cancelled occurrences and using the new start of moved
+// ones; nil when the series has ended
+func NextOccurrence(ruleStr string, dtstart time.Time, exceptions []*models.RecurrenceException, after time.Time) (*time.Time, error) {
+	var next *time.Time
+	current := after
+	for range 1000 { // Safety limit
+		t, err := rrule.NextOccurrenceStrict(ruleStr, dtstart, current)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			break
+		}
+		if exceptionOf(exceptions, *t) == nil {
+			next = t
+			break
+		}
+		current = *t
+	}
+
+	for _, x := range exceptions {
+		if x.Cancelled {
+			continue
+		}
+		start := rrule.WallClock(x.StartTime())
+		if start.After(after) && (next == nil || start.Before(*next)) {
+			next = &start
+		}
+	}
+	return next, nil
+}
+
+// OccurrenceOn returns the original start of the series' occurrence taking
+// place on the day of t; a moved occurrence is found on its new day
+func OccurrenceOn(ruleStr string, dtstart time.Time, exceptions []*models.RecurrenceException, t time.Time) (time.Time, bool) {
+	dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	for _, x := range exceptions {
+		start := rrule.WallClock(x.StartTime())
+		if !x.Cancelled && !start.Before(dayStart) && start.Before(dayEnd) {
+			return rrule.WallClock(x.OriginalStart), true
+		}
+	}
+	starts, err := rrule.Between(ruleStr, dtstart, dayStart, dayEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, start := range starts {
+		if exceptionOf(exceptions, start) == nil {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Length is how long each occurrence of the event takes up
+func Length(e *models.Event) time.Duration {
+	if e.AllDay {
+		return time.Duration(e.Days()) * 24 * time.Hour
+	}
+	if e.Duration <= 0 {
+		return defaultDuration
+	}
+	return time.Duration(e.Duration) * time.Minute
+}
+
+// End returns when the occurrence of the event starting at start ends; an
+// all-day occurrence ends at midnight after its last day
+func End(e *models.Event, start time.Time) time.Time {
+	if e.AllDay {
+		return start.AddDate(0, 0, e.Days())
+	}
+	return start.Add(Length(e))
+}
+
+// AllDayLabel describes the days an all-day occurrence takes up, like
+// "全天" or "全天 12/24 (週三) - 12/26 (週五)"
+func AllDayLabel(e *models.Event) string {
+	if e.Days() == 1 {
+		return "全天"
+	}
+	start := e.NextOccurrence
+	if start == nil {
+		start = e.Dtstart
+	}
+	if start == nil {
+		return fmt.Sprintf("全天 (%d 天)", e.Days())
+	}
+	last := start.AddDate(0, 0, e.Days()-1)
+	return fmt.Sprintf("全天 %s - %s", dayLabel(*start), dayLabel(last))
+}
+
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// dayLabel formats a date with its weekday
+func dayLabel(t time.Time) string {
+	return fmt.Sprintf("%s (週%s)", t.Format("01/02"), weekdayNames[t.Weekday()])
+}
@@ -0,0 +1,264 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+func at(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2026, month, day, hour, min, 0, 0, time.Local)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func event(id int, start time.Time, duration int, rule string) *models.Event {
+	return &models.Event{
+		EventID:        id,
+		Title:          "event",
+		Dtstart:        &start,
+		Duration:       duration,
+		RecurrenceRule: rule,
+	}
+}
+
+// starts returns the start of each occurrence
+func starts(occurrences []*models.Event) []time.Time {
+	var times []time.Time
+	for _, o := range occurrences {
+		times = append(times, *o.NextOccurrence)
+	}
+	return times
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExpand(t *testing.T) {
+	weekStart, weekEnd := at(3, 2, 0, 0), at(3, 9, 0, 0)
+
+	tests := []struct {
+		name   string
+		events []*models.Event
+		want   []time.Time
+	}{
+		{
+			name:   "one-time event in range",
+			events: []*models.Event{event(1, at(3, 4, 10, 0), 30, "")},
+			want:   []time.Time{at(3, 4, 10, 0)},
+		},
+		{
+			name:   "one-time event outside range",
+			events: []*models.Event{event(1, at(3, 10, 10, 0), 30, "")},
+		},
+		{
+			name:   "daily event",
+			events: []*models.Event{event(1, at(3, 1, 9, 0), 60, "FREQ=DAILY")},
+			want: []time.Time{
+				at(3, 2, 9, 0), at(3, 3, 9, 0), at(3, 4, 9, 0), at(3, 5, 9, 0),
+				at(3, 6, 9, 0), at(3, 7, 9, 0), at(3, 8, 9, 0),
+			},
+		},
+		{
+			name:   "occurrence running into the range",
+			events: []*models.Event{event(1, at(2, 28, 23, 0), 120, "FREQ=WEEKLY")},
+			want:   []time.Time{at(3, 7, 23, 0)},
+		},
+		{
+			name:   "count ends the series",
+			events: []*models.Event{event(1, at(3, 3, 9, 0), 60, "FREQ=DAILY;COUNT=2")},
+			want:   []time.Time{at(3, 3, 9, 0), at(3, 4, 9, 0)},
+		},
+		{
+			name: "sorted across events",
+			events: []*models.Event{
+				event(1, at(3, 5, 12, 0), 60, ""),
+				event(2, at(3, 4, 8, 0), 60, "FREQ=DAILY;COUNT=2"),
+			},
+			want: []time.Time{at(3, 4, 8, 0), at(3, 5, 8, 0), at(3, 5, 12, 0)},
+		},
+		{
+			name:   "event without start",
+			events: []*models.Event{{EventID: 1, Title: "no start"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := starts(Expand(tt.events, weekStart, weekEnd))
+			if !equalTimes(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandExceptions(t *testing.T) {
+	e := event(1, at(3, 2, 9, 0), 60, "FREQ=DAILY;COUNT=5")
+	e.Exceptions = []*models.RecurrenceException{
+		{OriginalStart: at(3, 3, 9, 0), Cancelled: true},
+		{OriginalStart: at(3, 4, 9, 0), Start: ptr(at(3, 4, 15, 0)), Title: "moved"},
+		// Moved out of the range
+		{OriginalStart: at(3, 5, 9, 0), Start: ptr(at(3, 20, 9, 0))},
+	}
+
+	occurrences := Expand([]*models.Event{e}, at(3, 2, 0, 0), at(3, 9, 0, 0))
+	want := []time.Time{at(3, 2, 9, 0), at(3, 4, 15, 0), at(3, 6, 9, 0)}
+	if got := starts(occurrences); !equalTimes(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	moved := occurrences[1]
+	if moved.Title != "moved" {
+		t.Errorf("moved occurrence has title %q, want %q", moved.Title, "moved")
+	}
+	if moved.RecurrenceID == nil || !moved.RecurrenceID.Equal(at(3, 4, 9, 0)) {
+		t.Errorf("moved occurrence has recurrence ID %v, want its original start", moved.RecurrenceID)
+	}
+	if e.Title != "event" {
+		t.Error("Expand changed the event it expanded")
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	dtstart := at(3, 2, 9, 0)
+	exceptions := []*models.RecurrenceException{
+		{OriginalStart: at(3, 3, 9, 0), Cancelled: true},
+		{OriginalStart: at(3, 4, 9, 0), Start: ptr(at(3, 4, 7, 0))},
+	}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  *time.Time
+	}{
+		{"skips cancelled", at(3, 2, 9, 0), ptr(at(3, 4, 7, 0))},
+		{"moved occurrence", at(3, 4, 6, 0), ptr(at(3, 4, 7, 0))},
+		{"after moved", at(3, 4, 7, 0), ptr(at(3, 5, 9, 0))},
+		{"series ended", at(3, 6, 9, 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextOccurrence("FREQ=DAILY;COUNT=5", dtstart, exceptions, tt.after)
+			if err != nil {
+				t.Fatalf("NextOccurrence: %v", err)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("got %v, want nil", got)
+			case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+				t.Errorf("got %v, want %v", got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	occurrence := func(start time.Time, duration int, allDay bool) *models.Event {
+		e := event(0, start, duration, "")
+		e.NextOccurrence = &start
+		e.AllDay = allDay
+		return e
+	}
+
+	tests := []struct {
+		name string
+		a, b *models.Event
+		want bool
+	}{
+		{"same time", occurrence(at(3, 2, 9, 0), 60, false), occurrence(at(3, 2, 9, 0), 30, false), true},
+		{"partial", occurrence(at(3, 2, 9, 0), 60, false), occurrence(at(3, 2, 9, 30), 60, false), true},
+		{"back to back", occurrence(at(3, 2, 9, 0), 60, false), occurrence(at(3, 2, 10, 0), 60, false), false},
+		{"default duration", occurrence(at(3, 2, 9, 0), 0, false), occurrence(at(3, 2, 9, 59), 10, false), true},
+		{"apart", occurrence(at(3, 2, 9, 0), 60, false), occurrence(at(3, 2, 12, 0), 60, false), false},
+		{"all-day covers the day", occurrence(at(3, 2, 0, 0), models.MinutesPerDay, true), occurrence(at(3, 2, 23, 0), 30, false), true},
+		{"all-day ends at midnight", occurrence(at(3, 2, 0, 0), models.MinutesPerDay, true), occurrence(at(3, 3, 0, 0), 30, false), false},
+		{"multi-day", occurrence(at(3, 2, 0, 0), 3*models.MinutesPerDay, true), occurrence(at(3, 4, 12, 0), 30, false), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlaps(tt.a, tt.b); got != tt.want {
+				t.Errorf("Overlaps(a, b) = %v, want %v", got, tt.want)
+			}
+			if got := Overlaps(tt.b, tt.a); got != tt.want {
+				t.Errorf("Overlaps(b, a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlapping(t *testing.T) {
+	events := []*models.Event{
+		event(1, at(3, 2, 9, 0), 120, ""),
+		event(2, at(3, 2, 10, 0), 30, ""),
+		event(3, at(3, 2, 10, 30), 60, ""),
+		event(4, at(3, 2, 13, 0), 60, ""),
+		event(5, at(3, 2, 13, 30), 60, ""),
+	}
+	events[4].Transparent = true
+
+	pairs := Overlapping(Expand(events, at(3, 2, 0, 0), at(3, 3, 0, 0)))
+	var got [][2]int
+	for _, p := range pairs {
+		got = append(got, [2]int{p[0].EventID, p[1].EventID})
+	}
+	want := [][2]int{{1, 2}, {1, 3}}
+	if len(got) != len(want) {
+		t.Fatalf("got pairs %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got pairs %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	start := at(3, 2, 0, 0)
+	tests := []struct {
+		name  string
+		event *models.Event
+		want  time.Time
+	}{
+		{"timed", &models.Event{Duration: 90}, at(3, 2, 1, 30)},
+		{"no duration", &models.Event{}, at(3, 2, 1, 0)},
+		{"all-day", &models.Event{AllDay: true, Duration: models.MinutesPerDay}, at(3, 3, 0, 0)},
+		{"all-day without duration", &models.Event{AllDay: true}, at(3, 3, 0, 0)},
+		{"three days", &models.Event{AllDay: true, Duration: 3 * models.MinutesPerDay}, at(3, 5, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := End(tt.event, start); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllDayLabel(t *testing.T) {
+	start := at(12, 24, 0, 0)
+	tests := []struct {
+		event *models.Event
+		want  string
+	}{
+		{&models.Event{AllDay: true, Duration: models.MinutesPerDay, Dtstart: &start}, "全天"},
+		{&models.Event{AllDay: true, Duration: 3 * models.MinutesPerDay, Dtstart: &start}, "全天 12/24 (週四) - 12/26 (週六)"},
+		{&models.Event{AllDay: true, Duration: 2 * models.MinutesPerDay}, "全天 (2 天)"},
+	}
+	for _, tt := range tests {
+		if got := AllDayLabel(tt.event); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
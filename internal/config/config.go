@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,7 +16,22 @@ type Config struct {
 	AIAPIKey      string
 	AIBaseURL     string
 	AIModel       string
+	APIAddr       string // Listen address for the HTTP API, empty disables it
+	MetricsAddr   string // Listen address for /healthz, /readyz and /metrics, empty disables it
 	DevMode       bool
+
+	AIDailyTokenLimit   int64   // Per-user AI tokens per day, 0 for unlimited
+	AIMonthlyTokenLimit int64   // Per-user AI tokens per month, 0 for unlimited
+	AIPromptPrice       float64 // USD per million prompt tokens, for cost estimates
+	AICompletionPrice   float64 // USD per million completion tokens
+
+	RateLimitPerMinute int           // Updates per user per minute, 0 disables the limit
+	UpdateWorkers      int           // Updates handled concurrently, one user at a time per worker
+	ShutdownTimeout    time.Duration // How long to wait for in-flight work on shutdown
+
+	AccessMode     string  // open, allowlist or invite
+	AdminIDs       []int64 // Telegram user IDs with the admin role
+	AllowedUserIDs []int64 // Users admitted in allowlist mode without approval
 }
 
 func Load() (*Config, error) {
@@ -20,13 +39,67 @@ func Load() (*Config, error) {
 		// .env file is optional in production
 	}
 
+	adminIDs, err := getEnvIDs("ADMIN_IDS")
+	if err != nil {
+		return nil, err
+	}
+	allowedUserIDs, err := getEnvIDs("ALLOWED_USERS")
+	if err != nil {
+		return nil, err
+	}
+
+	dailyLimit, err := getEnvInt("AI_DAILY_TOKEN_LIMIT", 0)
+	if err != nil {
+		return nil, err
+	}
+	monthlyLimit, err := getEnvInt("AI_MONTHLY_TOKEN_LIMIT", 0)
+	if err != nil {
+		return nil, err
+	}
+	promptPrice, err := getEnvFloat("AI_PROMPT_PRICE", 0.15)
+	if err != nil {
+		return nil, err
+	}
+	completionPrice, err := getEnvFloat("AI_COMPLETION_PRICE", 0.60)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := getEnvInt("RATE_LIMIT_PER_MINUTE", 30)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := getEnvInt("UPDATE_WORKERS", 16)
+	if err != nil {
+		return nil, err
+	}
+	shutdownSeconds, err := getEnvInt("SHUTDOWN_TIMEOUT", 30)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		DatabaseURI:   os.Getenv("DATABASE_URI"),
 		TelegramToken: os.Getenv("TELEGRAM_TOKEN"),
 		AIAPIKey:      os.Getenv("AI_API_KEY"),
 		AIBaseURL:     getEnvOrDefault("AI_BASE_URL", "https://openrouter.ai/api/v1"),
 		AIModel:       getEnvOrDefault("AI_MODEL", "openai/gpt-4o-mini"),
+		APIAddr:       os.Getenv("API_ADDR"),
+		MetricsAddr:   os.Getenv("METRICS_ADDR"),
 		DevMode:       os.Getenv("DEV") == "true",
+
+		AIDailyTokenLimit:   dailyLimit,
+		AIMonthlyTokenLimit: monthlyLimit,
+		AIPromptPrice:       promptPrice,
+		AICompletionPrice:   completionPrice,
+
+		RateLimitPerMinute: int(rateLimit),
+		UpdateWorkers:      int(workers),
+		ShutdownTimeout:    time.Duration(shutdownSeconds) * time.Second,
+
+		AccessMode:     getEnvOrDefault("ACCESS_MODE", "open"),
+		AdminIDs:       adminIDs,
+		AllowedUserIDs: allowedUserIDs,
 	}, nil
 }
 
@@ -36,3 +109,44 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvIDs parses a comma-separated list of Telegram user IDs
+func getEnvIDs(key string) ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.Split(os.Getenv(key), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q in %s", field, key)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func getEnvInt(key string, defaultValue int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return n, nil
+}
+
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return f, nil
+}
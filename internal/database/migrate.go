@@ -5,37 +5,29 @@ import (
 	"embed"
 	"fmt"
 	"sort"
+	"time"
 )
 
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
+// MigrationStatus describes one migration and whether it has been applied
+type MigrationStatus struct {
+	Version   string
+	Applied   bool
+	AppliedAt *time.Time
+	Embedded  bool // false if recorded in schema_migrations but unknown to this binary
+}
+
 func (db *DB) Migrate(ctx context.Context) error {
-	// Create migrations tracking table
-	_, err := db.Pool.Exec(ctx, `
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version VARCHAR(255) PRIMARY KEY,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return err
 	}
 
-	// Read migration files
-	entries, err := migrationsFS.ReadDir("migrations")
+	migrationFiles, err := migrationFiles()
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
-	}
-
-	// Sort migrations by filename
-	var migrationFiles []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			migrationFiles = append(migrationFiles, entry.Name())
-		}
+		return err
 	}
-	sort.Strings(migrationFiles)
 
 	// Apply each migration
 	for _, filename := range migrationFiles {
@@ -78,3 +70,81 @@ func (db *DB) Migrate(ctx context.Context) error {
 
 	return nil
 }
+
+// MigrationStatus lists all embedded and applied migrations in version order
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	files, err := migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]*MigrationStatus)
+	for _, f := range files {
+		statuses[f] = &MigrationStatus{Version: f, Embedded: true}
+	}
+
+	rows, err := db.Pool.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		var appliedAt *time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		st, ok := statuses[version]
+		if !ok {
+			st = &MigrationStatus{Version: version}
+			statuses[version] = st
+		}
+		st.Applied = true
+		st.AppliedAt = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(statuses))
+	for _, st := range statuses {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	_, err := db.Pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// migrationFiles returns the embedded migration filenames sorted by name
+func migrationFiles() ([]string, error) {
+	entries, err := migrationsFS.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
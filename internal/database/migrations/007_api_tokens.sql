@@ -0,0 +1,16 @@
+-- Migration: 007_api_tokens
+-- Description: Per-user tokens for the JSON HTTP API
+
+CREATE TABLE IF NOT EXISTS api_token (
+    token_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+    name VARCHAR(255),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16),
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_token_user_id ON api_token(user_id);
@@ -0,0 +1,31 @@
+-- Migration: 008_webhooks
+-- Description: Outgoing webhooks and their delivery log
+
+CREATE TABLE IF NOT EXISTS webhook (
+    webhook_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '*', -- Comma separated event names, '*' for all
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_user_id ON webhook(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    delivery_id SERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhook(webhook_id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, success, failed
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_pending ON webhook_delivery(next_attempt_at) WHERE status = 'pending';
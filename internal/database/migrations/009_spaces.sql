@@ -0,0 +1,48 @@
+-- Migration: 009_spaces
+-- Description: Shared spaces for group chats, row creators and shopping lists
+
+-- A space is owned by a Telegram group chat. The chat is registered as a "user"
+-- row keyed by its (negative) chat ID so every user_id keyed table and every
+-- scheduled notification works for groups unchanged.
+CREATE TABLE IF NOT EXISTS space (
+    space_id BIGINT PRIMARY KEY REFERENCES "user"(user_id) ON DELETE CASCADE,
+    chat_type VARCHAR(20) NOT NULL, -- 'group' or 'supergroup'
+    title VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS space_member (
+    space_id BIGINT REFERENCES space(space_id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES "user"(user_id) ON DELETE CASCADE,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (space_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_space_member_user_id ON space_member(user_id);
+
+-- Record who created each row; for personal rows this equals user_id
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE event ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE transaction ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE reminders ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE memo ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+
+UPDATE todo SET created_by = user_id WHERE created_by IS NULL;
+UPDATE event SET created_by = user_id WHERE created_by IS NULL;
+UPDATE transaction SET created_by = user_id WHERE created_by IS NULL;
+UPDATE reminders SET created_by = user_id WHERE created_by IS NULL;
+UPDATE memo SET created_by = user_id WHERE created_by IS NULL;
+
+-- Shopping list items
+CREATE TABLE IF NOT EXISTS shopping_item (
+    item_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    quantity VARCHAR(50),
+    created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL,
+    bought_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL,
+    bought_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shopping_item_user_id ON shopping_item(user_id);
@@ -0,0 +1,17 @@
+-- Migration: 010_expense_splits
+-- Description: Split expenses between participants and settlement payments
+
+-- paid_by is set for split expenses and settlements (type 'settlement')
+ALTER TABLE transaction ADD COLUMN IF NOT EXISTS paid_by VARCHAR(64);
+ALTER TABLE transaction ADD COLUMN IF NOT EXISTS split_type VARCHAR(20); -- 'equal', 'percentage' or 'exact'
+
+CREATE TABLE IF NOT EXISTS transaction_share (
+    share_id SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transaction(transaction_id) ON DELETE CASCADE,
+    participant VARCHAR(64) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL,
+    UNIQUE (transaction_id, participant)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_share_transaction_id ON transaction_share(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_paid_by ON transaction(user_id, paid_by) WHERE paid_by IS NOT NULL;
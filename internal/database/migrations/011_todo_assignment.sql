@@ -0,0 +1,10 @@
+-- Migration: 011_todo_assignment
+-- Description: Assign todos to other users with accept/decline
+
+-- The todo stays owned by user_id; an accepted assignee gets its due-time reminders
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS assignee_id BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS assigned_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS assignment_status VARCHAR(20); -- 'pending', 'accepted' or 'declined'
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_todo_assignee ON todo(assignee_id) WHERE assignee_id IS NOT NULL;
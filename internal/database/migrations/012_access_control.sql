@@ -0,0 +1,14 @@
+-- Migration: 012_access_control
+-- Description: Approved/banned users and invite codes for restricted access modes
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP; -- admitted by an admin or an invite code
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS banned_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS invite_code (
+    code VARCHAR(32) PRIMARY KEY,
+    created_by BIGINT REFERENCES "user"(user_id) ON DELETE SET NULL,
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,15 @@
+-- Migration: 013_ai_usage
+-- Description: Token usage and estimated cost of AI calls per user
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+    usage_id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES "user"(user_id) ON DELETE CASCADE, -- NULL for calls not made for a user
+    call VARCHAR(50) NOT NULL, -- 'parse_intent_history', 'tool_continuation', 'format_query_result', ...
+    model VARCHAR(100),
+    prompt_tokens INT NOT NULL DEFAULT 0,
+    completion_tokens INT NOT NULL DEFAULT 0,
+    cost DECIMAL(12, 6) NOT NULL DEFAULT 0, -- USD
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_user_created ON ai_usage(user_id, created_at);
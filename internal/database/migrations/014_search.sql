@@ -0,0 +1,21 @@
+-- Migration: 014_search
+-- Description: Trigram indexes for searching across memos, todos, reminders, events and transactions
+
+-- Trigrams match substrings regardless of word boundaries, which also works
+-- for Chinese text. Each index is on the same expression the search query uses.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_memo_search ON memo
+    USING GIN ((COALESCE(content, '') || ' ' || COALESCE(tags, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_todo_search ON todo
+    USING GIN ((COALESCE(title, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_search ON reminders
+    USING GIN ((COALESCE(messages, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_event_search ON event
+    USING GIN ((COALESCE(title, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, '')) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_search ON transaction
+    USING GIN ((COALESCE(description, '') || ' ' || COALESCE(tags, '')) gin_trgm_ops);
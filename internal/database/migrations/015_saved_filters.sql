@@ -0,0 +1,13 @@
+-- Migration: 015_saved_filters
+-- Description: Named filters of list commands, recalled with /f <name>
+
+CREATE TABLE IF NOT EXISTS saved_filter (
+    filter_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE, -- owner or space
+    name VARCHAR(32) NOT NULL,
+    target VARCHAR(20) NOT NULL, -- 'todos', 'events', 'memos' or 'transactions'
+    query TEXT NOT NULL,
+    created_by BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
@@ -0,0 +1,106 @@
+-- Migration: 016_tags
+-- Description: Tags shared across memos, todos, reminders, events and transactions
+
+-- A tag belongs to a user or space; names are unique regardless of case
+CREATE TABLE IF NOT EXISTS tag (
+    tag_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_user_name ON tag(user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS memo_tag (
+    memo_id INTEGER NOT NULL REFERENCES memo(memo_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (memo_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS todo_tag (
+    todo_id INTEGER NOT NULL REFERENCES todo(todo_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS reminder_tag (
+    reminders_id INTEGER NOT NULL REFERENCES reminders(reminders_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (reminders_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS event_tag (
+    event_id INTEGER NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tag (
+    transaction_id INTEGER NOT NULL REFERENCES transaction(transaction_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memo_tag_tag ON memo_tag(tag_id);
+CREATE INDEX IF NOT EXISTS idx_todo_tag_tag ON todo_tag(tag_id);
+CREATE INDEX IF NOT EXISTS idx_reminder_tag_tag ON reminder_tag(tag_id);
+CREATE INDEX IF NOT EXISTS idx_event_tag_tag ON event_tag(tag_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_tag_tag ON transaction_tag(tag_id);
+
+-- Move the existing free-form tag strings into the tables. Tags were separated
+-- by commas, spaces or '#'. The tags columns stay as a display copy that the
+-- repositories keep in sync with the links.
+CREATE TEMP TABLE tag_import AS
+    SELECT DISTINCT 'memo' AS kind, memo_id AS id, user_id, TRIM(t) AS name
+    FROM memo, regexp_split_to_table(COALESCE(tags, ''), '[,，、#[:space:]]+') t
+    UNION
+    SELECT DISTINCT 'todo', todo_id, user_id, TRIM(t)
+    FROM todo, regexp_split_to_table(COALESCE(tags, ''), '[,，、#[:space:]]+') t
+    UNION
+    SELECT DISTINCT 'reminder', reminders_id, user_id, TRIM(t)
+    FROM reminders, regexp_split_to_table(COALESCE(tags, ''), '[,，、#[:space:]]+') t
+    UNION
+    SELECT DISTINCT 'event', event_id, user_id, TRIM(t)
+    FROM event, regexp_split_to_table(COALESCE(tags, ''), '[,，、#[:space:]]+') t
+    UNION
+    SELECT DISTINCT 'transaction', transaction_id, user_id, TRIM(t)
+    FROM transaction, regexp_split_to_table(COALESCE(tags, ''), '[,，、#[:space:]]+') t;
+
+-- Overlong tags are cut to the 50 character limit rather than lost
+UPDATE tag_import SET name = TRIM(LEFT(name, 50)) WHERE LENGTH(name) > 50;
+DELETE FROM tag_import WHERE name = '';
+
+INSERT INTO tag (user_id, name)
+SELECT DISTINCT ON (user_id, LOWER(name)) user_id, name FROM tag_import
+ORDER BY user_id, LOWER(name), name
+ON CONFLICT DO NOTHING;
+
+INSERT INTO memo_tag (memo_id, tag_id)
+SELECT i.id, t.tag_id FROM tag_import i JOIN tag t ON t.user_id = i.user_id AND LOWER(t.name) = LOWER(i.name)
+WHERE i.kind = 'memo' ON CONFLICT DO NOTHING;
+INSERT INTO todo_tag (todo_id, tag_id)
+SELECT i.id, t.tag_id FROM tag_import i JOIN tag t ON t.user_id = i.user_id AND LOWER(t.name) = LOWER(i.name)
+WHERE i.kind = 'todo' ON CONFLICT DO NOTHING;
+INSERT INTO reminder_tag (reminders_id, tag_id)
+SELECT i.id, t.tag_id FROM tag_import i JOIN tag t ON t.user_id = i.user_id AND LOWER(t.name) = LOWER(i.name)
+WHERE i.kind = 'reminder' ON CONFLICT DO NOTHING;
+INSERT INTO event_tag (event_id, tag_id)
+SELECT i.id, t.tag_id FROM tag_import i JOIN tag t ON t.user_id = i.user_id AND LOWER(t.name) = LOWER(i.name)
+WHERE i.kind = 'event' ON CONFLICT DO NOTHING;
+INSERT INTO transaction_tag (transaction_id, tag_id)
+SELECT i.id, t.tag_id FROM tag_import i JOIN tag t ON t.user_id = i.user_id AND LOWER(t.name) = LOWER(i.name)
+WHERE i.kind = 'transaction' ON CONFLICT DO NOTHING;
+
+DROP TABLE tag_import;
+
+-- Rewrite the display copies in the canonical "a, b" form
+UPDATE memo e SET tags = (SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name)) FROM memo_tag l JOIN tag t ON t.tag_id = l.tag_id WHERE l.memo_id = e.memo_id)
+WHERE tags IS NOT NULL AND tags <> '';
+UPDATE todo e SET tags = (SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name)) FROM todo_tag l JOIN tag t ON t.tag_id = l.tag_id WHERE l.todo_id = e.todo_id)
+WHERE tags IS NOT NULL AND tags <> '';
+UPDATE reminders e SET tags = (SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name)) FROM reminder_tag l JOIN tag t ON t.tag_id = l.tag_id WHERE l.reminders_id = e.reminders_id)
+WHERE tags IS NOT NULL AND tags <> '';
+UPDATE event e SET tags = (SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name)) FROM event_tag l JOIN tag t ON t.tag_id = l.tag_id WHERE l.event_id = e.event_id)
+WHERE tags IS NOT NULL AND tags <> '';
+UPDATE transaction e SET tags = (SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name)) FROM transaction_tag l JOIN tag t ON t.tag_id = l.tag_id WHERE l.transaction_id = e.transaction_id)
+WHERE tags IS NOT NULL AND tags <> '';
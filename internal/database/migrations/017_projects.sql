@@ -0,0 +1,22 @@
+-- Migration: 017_projects
+-- Description: Projects grouping todos, and subtasks under a parent todo
+
+-- A project belongs to a user or space; names are unique regardless of case
+CREATE TABLE IF NOT EXISTS project (
+    project_id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES "user"(user_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(20) NOT NULL DEFAULT '',   -- see models.ProjectColors
+    default_tags TEXT NOT NULL DEFAULT '',   -- added to every todo created in the project
+    created_by BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_user_name ON project(user_id, LOWER(name));
+
+-- Deleting a project keeps its todos; deleting a todo deletes its subtasks
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS project_id INTEGER REFERENCES project(project_id) ON DELETE SET NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES todo(todo_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todo_project ON todo(project_id) WHERE project_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_todo_parent ON todo(parent_id) WHERE parent_id IS NOT NULL;
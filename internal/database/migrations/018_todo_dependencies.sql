@@ -0,0 +1,12 @@
+-- Migration: 018_todo_dependencies
+-- Description: Todos blocked by other todos until those are completed
+
+CREATE TABLE IF NOT EXISTS todo_dependency (
+    todo_id INTEGER NOT NULL REFERENCES todo(todo_id) ON DELETE CASCADE,
+    blocked_by_id INTEGER NOT NULL REFERENCES todo(todo_id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (todo_id, blocked_by_id),
+    CHECK (todo_id <> blocked_by_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependency_blocked_by ON todo_dependency(blocked_by_id);
@@ -0,0 +1,22 @@
+-- Migration: 019_todo_status
+-- Description: Workflow status of todos with the time of every transition
+
+-- completed_at stays the closing time: it is set for done and cancelled todos
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'inbox'
+    CHECK (status IN ('inbox', 'next', 'doing', 'waiting', 'done', 'cancelled'));
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+UPDATE todo SET status = 'done', status_changed_at = completed_at
+WHERE completed_at IS NOT NULL AND status = 'inbox';
+
+CREATE INDEX IF NOT EXISTS idx_todo_status ON todo(user_id, status);
+
+CREATE TABLE IF NOT EXISTS todo_status_history (
+    history_id SERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todo(todo_id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL,
+    changed_by BIGINT,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_status_history_todo ON todo_status_history(todo_id, changed_at);
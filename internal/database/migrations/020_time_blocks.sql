@@ -0,0 +1,9 @@
+-- Migration: 020_time_blocks
+-- Description: Estimated duration of todos and events blocking time for a todo
+
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS estimated_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- A time block is an event reserving time to work on a todo
+ALTER TABLE event ADD COLUMN IF NOT EXISTS todo_id INTEGER REFERENCES todo(todo_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_event_todo_id ON event(todo_id);
@@ -0,0 +1,8 @@
+-- Migration: 021_free_time_preferences
+-- Description: Working hours and buffer between meetings used when searching free time
+
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS work_start TIME NOT NULL DEFAULT '08:00';
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS work_end TIME NOT NULL DEFAULT '22:00';
+
+-- Minutes kept free before and after each event
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS meeting_buffer INTEGER NOT NULL DEFAULT 0;
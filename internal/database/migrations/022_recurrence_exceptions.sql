@@ -0,0 +1,29 @@
+-- Migration: 022_recurrence_exceptions
+-- Description: Cancelled and overridden single occurrences of recurring events and reminders
+
+-- An exception replaces the occurrence of a series originally starting at
+-- original_start: cancelled drops it, otherwise the set fields override it
+CREATE TABLE IF NOT EXISTS event_exception (
+    exception_id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES event(event_id) ON DELETE CASCADE,
+    original_start TIMESTAMP NOT NULL,
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    start_time TIMESTAMP,
+    duration INTEGER,
+    title TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, original_start)
+);
+
+-- Same shape as event_exception so both are read alike; reminders leave duration NULL
+CREATE TABLE IF NOT EXISTS reminder_exception (
+    exception_id SERIAL PRIMARY KEY,
+    reminder_id INTEGER NOT NULL REFERENCES reminders(reminders_id) ON DELETE CASCADE,
+    original_start TIMESTAMP NOT NULL,
+    cancelled BOOLEAN NOT NULL DEFAULT FALSE,
+    start_time TIMESTAMP,
+    duration INTEGER,
+    title TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reminder_id, original_start)
+);
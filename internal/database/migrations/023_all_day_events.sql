@@ -0,0 +1,9 @@
+-- Migration: 023_all_day_events
+-- Description: All-day and multi-day events, and events that don't block time
+
+-- An all-day event starts at midnight of dtstart and takes up whole days;
+-- its duration is a multiple of 1440 minutes
+ALTER TABLE event ADD COLUMN IF NOT EXISTS all_day BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A transparent event, like TRANSP:TRANSPARENT in iCalendar, leaves its time free
+ALTER TABLE event ADD COLUMN IF NOT EXISTS transparent BOOLEAN NOT NULL DEFAULT FALSE;
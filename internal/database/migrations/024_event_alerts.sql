@@ -0,0 +1,19 @@
+-- Migration: 024_event_alerts
+-- Description: Several notifications per event, each sent once per occurrence
+
+-- alerts holds the minutes before each occurrence to notify, alerts_sent
+-- those already sent for the current next_occurrence
+ALTER TABLE event ADD COLUMN IF NOT EXISTS alerts INTEGER[] NOT NULL DEFAULT '{}';
+ALTER TABLE event ADD COLUMN IF NOT EXISTS alerts_sent INTEGER[] NOT NULL DEFAULT '{}';
+
+-- Migrate existing data: the single notification becomes the only alert
+UPDATE event SET alerts = ARRAY[notification_minutes] WHERE notification_minutes IS NOT NULL;
+UPDATE event SET alerts_sent = alerts WHERE notified_at IS NOT NULL;
+
+-- Drop old columns from event table
+DROP INDEX IF EXISTS idx_event_notified_at;
+ALTER TABLE event DROP COLUMN IF EXISTS notification_minutes;
+ALTER TABLE event DROP COLUMN IF EXISTS notified_at;
+
+-- Alerts of new events unless they are given
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS event_alerts INTEGER[] NOT NULL DEFAULT '{30}';
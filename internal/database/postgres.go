@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hray3182/LifeLine/internal/metrics"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -12,7 +13,13 @@ type DB struct {
 }
 
 func New(ctx context.Context, databaseURI string) (*DB, error) {
-	pool, err := pgxpool.New(ctx, databaseURI)
+	config, err := pgxpool.ParseConfig(databaseURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URI: %w", err)
+	}
+	config.ConnConfig.Tracer = metrics.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
@@ -24,6 +31,11 @@ func New(ctx context.Context, databaseURI string) (*DB, error) {
 	return &DB{Pool: pool}, nil
 }
 
+// Ping checks that the database is reachable
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Pool.Ping(ctx)
+}
+
 func (db *DB) Close() {
 	db.Pool.Close()
 }
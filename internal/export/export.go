@@ -0,0 +1,182 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// FormatVersion is bumped whenever the archive layout changes
+const FormatVersion = 1
+
+// Archive holds every entity that belongs to a single user
+type Archive struct {
+	Version       int                    `json:"version"`
+	ExportedAt    time.Time              `json:"exported_at"`
+	User          *models.User           `json:"user"`
+	Settings      *models.UserSettings   `json:"settings,omitempty"`
+	Memos         []*models.Memo         `json:"memos"`
+	Projects      []*models.Project      `json:"projects"`
+	Todos         []*models.Todo         `json:"todos"`
+	Reminders     []*models.Reminder     `json:"reminders"`
+	Events        []*models.Event        `json:"events"`
+	Transactions  []*models.Transaction  `json:"transactions"`
+	Categories    []*models.Category     `json:"categories"`
+	Subcategories []*models.Subcategory  `json:"subcategories"`
+	ShoppingItems []*models.ShoppingItem `json:"shopping_items"`
+}
+
+type Exporter struct {
+	db           *database.DB
+	user         *repository.UserRepository
+	memo         *repository.MemoRepository
+	todo         *repository.TodoRepository
+	project      *repository.ProjectRepository
+	reminder     *repository.ReminderRepository
+	event        *repository.EventRepository
+	transaction  *repository.TransactionRepository
+	category     *repository.CategoryRepository
+	userSettings *repository.UserSettingsRepository
+	shopping     *repository.ShoppingRepository
+}
+
+func New(db *database.DB) *Exporter {
+	return &Exporter{
+		db:           db,
+		user:         repository.NewUserRepository(db),
+		memo:         repository.NewMemoRepository(db),
+		todo:         repository.NewTodoRepository(db),
+		project:      repository.NewProjectRepository(db),
+		reminder:     repository.NewReminderRepository(db),
+		event:        repository.NewEventRepository(db),
+		transaction:  repository.NewTransactionRepository(db),
+		category:     repository.NewCategoryRepository(db),
+		userSettings: repository.NewUserSettingsRepository(db),
+		shopping:     repository.NewShoppingRepository(db),
+	}
+}
+
+// Collect loads all data of a user into an Archive
+func (e *Exporter) Collect(ctx context.Context, userID int64) (*Archive, error) {
+	user, err := e.user.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	archive := &Archive{
+		Version:    FormatVersion,
+		ExportedAt: time.Now(),
+		User:       user,
+	}
+
+	// Settings are created lazily, so a missing row is not an error
+	settings, err := e.userSettings.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	archive.Settings = settings
+
+	if archive.Memos, err = e.memo.GetAllByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get memos: %w", err)
+	}
+	if archive.Projects, err = e.project.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+	if archive.Todos, err = e.todo.GetByUserID(ctx, userID, true); err != nil {
+		return nil, fmt.Errorf("failed to get todos: %w", err)
+	}
+	if archive.Reminders, err = e.reminder.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	if err := e.reminder.LoadExceptions(ctx, archive.Reminders...); err != nil {
+		return nil, fmt.Errorf("failed to get reminder exceptions: %w", err)
+	}
+	if archive.Events, err = e.event.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	if err := e.event.LoadExceptions(ctx, archive.Events...); err != nil {
+		return nil, fmt.Errorf("failed to get event exceptions: %w", err)
+	}
+	if archive.Transactions, err = e.transaction.GetAllByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	shares, err := e.transaction.GetSharesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction shares: %w", err)
+	}
+	for _, t := range archive.Transactions {
+		t.Shares = shares[t.TransactionID]
+	}
+	if archive.Categories, err = e.category.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	if archive.Subcategories, err = e.category.GetSubcategoriesByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get subcategories: %w", err)
+	}
+	if archive.ShoppingItems, err = e.shopping.GetByUserID(ctx, userID, true); err != nil {
+		return nil, fmt.Errorf("failed to get shopping items: %w", err)
+	}
+
+	return archive, nil
+}
+
+// Count returns the total number of exported rows, excluding the user itself
+func (a *Archive) Count() int {
+	n := len(a.Memos) + len(a.Projects) + len(a.Todos) + len(a.Reminders) + len(a.Events) +
+		len(a.Transactions) + len(a.Categories) + len(a.Subcategories) + len(a.ShoppingItems)
+	if a.Settings != nil {
+		n++
+	}
+	return n
+}
+
+// WriteZip writes the archive as a zip file with one JSON document per entity
+// plus an archive.json containing everything
+func (a *Archive) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"archive.json", a},
+		{"user.json", a.User},
+		{"settings.json", a.Settings},
+		{"memos.json", a.Memos},
+		{"projects.json", a.Projects},
+		{"todos.json", a.Todos},
+		{"reminders.json", a.Reminders},
+		{"events.json", a.Events},
+		{"transactions.json", a.Transactions},
+		{"categories.json", a.Categories},
+		{"subcategories.json", a.Subcategories},
+		{"shopping_items.json", a.ShoppingItems},
+	}
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: a.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", f.name, err)
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,327 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReadArchive decodes an archive previously written as archive.json
+func ReadArchive(r io.Reader) (*Archive, error) {
+	archive := &Archive{}
+	if err := json.NewDecoder(r).Decode(archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	if archive.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive version %d (expected %d)", archive.Version, FormatVersion)
+	}
+	if archive.User == nil {
+		return nil, errors.New("archive has no user")
+	}
+	return archive, nil
+}
+
+// Restore writes an archive back into the database in a single transaction.
+// Rows get new IDs; category references are remapped. If replace is false the
+// restore fails when the user already exists, otherwise the existing user and
+// all of their data are deleted first.
+func (e *Exporter) Restore(ctx context.Context, a *Archive, replace bool) error {
+	tx, err := e.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID := a.User.UserID
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM "user" WHERE user_id = $1)`, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if exists {
+		if !replace {
+			return fmt.Errorf("user %d already exists", userID)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM "user" WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete existing user: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO "user" (user_id, user_name) VALUES ($1, $2)`, userID, a.User.UserName); err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if err := restoreSettings(ctx, tx, a); err != nil {
+		return err
+	}
+
+	categoryIDs, err := restoreCategories(ctx, tx, a)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range a.Memos {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO memo (user_id, content, tags, created_at, created_by) VALUES ($1, $2, $3, $4, $5)`,
+			userID, m.Content, m.Tags, m.CreatedAt, ownCreator(m.CreatedBy, userID),
+		); err != nil {
+			return fmt.Errorf("failed to restore memo %d: %w", m.MemoID, err)
+		}
+	}
+
+	projectIDs := make(map[int]int, len(a.Projects))
+	for _, p := range a.Projects {
+		var projectID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO project (user_id, name, color, default_tags, created_by, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6) RETURNING project_id`,
+			userID, p.Name, p.Color, p.DefaultTags, ownCreator(p.CreatedBy, userID), p.CreatedAt,
+		).Scan(&projectID); err != nil {
+			return fmt.Errorf("failed to restore project %d: %w", p.ProjectID, err)
+		}
+		projectIDs[p.ProjectID] = projectID
+	}
+
+	// Subtasks are linked to their parents once every todo has its new ID
+	todoIDs := make(map[int]int, len(a.Todos))
+	for _, t := range a.Todos {
+		var projectID *int
+		if t.ProjectID != nil {
+			if id, ok := projectIDs[*t.ProjectID]; ok {
+				projectID = &id
+			}
+		}
+		// Archives from before the status workflow only know completion
+		status := t.Status
+		if !status.IsValid() {
+			status = models.TodoInbox
+			if t.CompletedAt != nil {
+				status = models.TodoDone
+			}
+		}
+		var todoID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO todo (user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at, created_by,
+			 project_id, status, status_changed_at, estimated_minutes)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING todo_id`,
+			userID, t.Title, t.Priority, t.Description, t.DueTime, t.CompletedAt, t.Tags, t.CreatedAt, t.LastNotifiedAt,
+			ownCreator(t.CreatedBy, userID), projectID, status, t.StatusChangedAt, t.EstimatedMinutes,
+		).Scan(&todoID); err != nil {
+			return fmt.Errorf("failed to restore todo %d: %w", t.TodoID, err)
+		}
+		todoIDs[t.TodoID] = todoID
+	}
+	for _, t := range a.Todos {
+		for _, blockerID := range t.BlockedBy {
+			id, ok := todoIDs[blockerID]
+			if !ok {
+				continue
+			}
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO todo_dependency (todo_id, blocked_by_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				todoIDs[t.TodoID], id,
+			); err != nil {
+				return fmt.Errorf("failed to restore blockers of todo %d: %w", t.TodoID, err)
+			}
+		}
+		if t.ParentID == nil {
+			continue
+		}
+		parentID, ok := todoIDs[*t.ParentID]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `UPDATE todo SET parent_id = $1 WHERE todo_id = $2`, parentID, todoIDs[t.TodoID]); err != nil {
+			return fmt.Errorf("failed to restore parent of todo %d: %w", t.TodoID, err)
+		}
+	}
+
+	// Telegram message IDs are not restored, the old messages may no longer exist
+	for _, r := range a.Reminders {
+		var reminderID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO reminders (user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags,
+			 notified_at, acknowledged_at, created_at, created_by)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING reminders_id`,
+			userID, r.Enabled, r.RecurrenceRule, r.Dtstart, r.Messages, r.RemindAt, r.Description, r.Tags,
+			r.NotifiedAt, r.AcknowledgedAt, r.CreatedAt, ownCreator(r.CreatedBy, userID),
+		).Scan(&reminderID); err != nil {
+			return fmt.Errorf("failed to restore reminder %d: %w", r.ReminderID, err)
+		}
+		for _, x := range r.Exceptions {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO reminder_exception (reminder_id, original_start, cancelled, start_time, title, created_at)
+				 VALUES ($1, $2, $3, $4, $5, $6)`,
+				reminderID, x.OriginalStart, x.Cancelled, x.Start, x.Title, x.CreatedAt,
+			); err != nil {
+				return fmt.Errorf("failed to restore exceptions of reminder %d: %w", r.ReminderID, err)
+			}
+		}
+	}
+
+	for _, ev := range a.Events {
+		var eventID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO event (user_id, title, description, dtstart, duration, next_occurrence, alerts,
+			 recurrence_rule, tags, alerts_sent, created_at, created_by, todo_id, all_day, transparent)
+			 VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::integer[], '{}'), $8, $9, COALESCE($10::integer[], '{}'),
+			 $11, $12, $13, $14, $15) RETURNING event_id`,
+			userID, ev.Title, ev.Description, ev.Dtstart, ev.Duration, ev.NextOccurrence, ev.Alerts,
+			ev.RecurrenceRule, ev.Tags, ev.AlertsSent, ev.CreatedAt, ownCreator(ev.CreatedBy, userID), restoredID(todoIDs, ev.TodoID),
+			ev.AllDay, ev.Transparent,
+		).Scan(&eventID); err != nil {
+			return fmt.Errorf("failed to restore event %d: %w", ev.EventID, err)
+		}
+		for _, x := range ev.Exceptions {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO event_exception (event_id, original_start, cancelled, start_time, duration, title, created_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				eventID, x.OriginalStart, x.Cancelled, x.Start, x.Duration, x.Title, x.CreatedAt,
+			); err != nil {
+				return fmt.Errorf("failed to restore exceptions of event %d: %w", ev.EventID, err)
+			}
+		}
+	}
+
+	for _, t := range a.Transactions {
+		var categoryID *int
+		if t.CategoryID != nil {
+			if id, ok := categoryIDs[*t.CategoryID]; ok {
+				categoryID = &id
+			}
+		}
+		var transactionID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO transaction (user_id, category_id, type, amount, description, transaction_date, tags,
+			 recurrence_rule, frequency, interval, by_day, until, created_at, created_by, paid_by, split_type)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), NULLIF($16, ''))
+			 RETURNING transaction_id`,
+			userID, categoryID, t.Type, t.Amount, t.Description, t.TransactionDate, t.Tags,
+			t.RecurrenceRule, t.Frequency, t.Interval, t.ByDay, t.Until, t.CreatedAt, ownCreator(t.CreatedBy, userID),
+			t.PaidBy, string(t.SplitType),
+		).Scan(&transactionID); err != nil {
+			return fmt.Errorf("failed to restore transaction %d: %w", t.TransactionID, err)
+		}
+		for _, share := range t.Shares {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO transaction_share (transaction_id, participant, amount) VALUES ($1, $2, $3)`,
+				transactionID, share.Participant, share.Amount,
+			); err != nil {
+				return fmt.Errorf("failed to restore share of transaction %d: %w", t.TransactionID, err)
+			}
+		}
+	}
+
+	for _, item := range a.ShoppingItems {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO shopping_item (user_id, name, quantity, created_by, bought_by, bought_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			userID, item.Name, item.Quantity, ownCreator(item.CreatedBy, userID), ownCreator(item.BoughtBy, userID),
+			item.BoughtAt, item.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to restore shopping item %d: %w", item.ItemID, err)
+		}
+	}
+
+	if err := repository.RebuildTags(ctx, tx, userID); err != nil {
+		return fmt.Errorf("failed to restore tags: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ownCreator keeps a creator reference only when it is the restored user.
+// Other members of a group space may not exist in the target database.
+func ownCreator(createdBy *int64, userID int64) *int64 {
+	if createdBy == nil || *createdBy != userID {
+		return nil
+	}
+	return createdBy
+}
+
+// restoredID maps an ID of the archive to the ID of the restored row, nil
+// when it was not restored
+func restoredID(ids map[int]int, id *int) *int {
+	if id == nil {
+		return nil
+	}
+	restored, ok := ids[*id]
+	if !ok {
+		return nil
+	}
+	return &restored
+}
+
+func restoreSettings(ctx context.Context, tx pgx.Tx, a *Archive) error {
+	s := a.Settings
+	if s == nil {
+		return nil
+	}
+
+	intervalsJSON, err := s.ReminderIntervals.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	// Archives made before working hours existed keep the defaults
+	workStart, workEnd := s.WorkStart, s.WorkEnd
+	if workStart == "" || workEnd == "" {
+		defaults := models.NewDefaultUserSettings(a.User.UserID)
+		workStart, workEnd = defaults.WorkStart, defaults.WorkEnd
+	}
+	// Archives made before event alerts existed get the default ones
+	eventAlerts := s.EventAlerts
+	if eventAlerts == nil {
+		eventAlerts = models.DefaultEventAlerts
+	}
+
+	// The last todo message ID is not restored, the message may no longer exist
+	_, err = tx.Exec(ctx,
+		`INSERT INTO user_settings (user_id, max_daily_reminders, quiet_start, quiet_end, timezone, reminder_intervals,
+		 todo_reminders_enabled, daily_summary_enabled, daily_summary_time, last_daily_summary_date,
+		 work_start, work_end, meeting_buffer, event_alerts, updated_at)
+		 VALUES ($1, $2, $3::time, $4::time, $5, $6, $7, $8, $9::time, $10, $11::time, $12::time, $13, $14, $15)`,
+		a.User.UserID, s.MaxDailyReminders, s.QuietStart, s.QuietEnd, s.Timezone, intervalsJSON,
+		s.TodoRemindersEnabled, s.DailySummaryEnabled, s.DailySummaryTime, s.LastDailySummaryDate,
+		workStart, workEnd, s.MeetingBuffer, eventAlerts, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore settings: %w", err)
+	}
+	return nil
+}
+
+// restoreCategories inserts categories and subcategories and returns a map from archived to new category IDs
+func restoreCategories(ctx context.Context, tx pgx.Tx, a *Archive) (map[int]int, error) {
+	ids := make(map[int]int, len(a.Categories))
+	for _, c := range a.Categories {
+		var newID int
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO category (user_id, category_name, usage_count) VALUES ($1, $2, $3) RETURNING category_id`,
+			a.User.UserID, c.CategoryName, c.UsageCount,
+		).Scan(&newID); err != nil {
+			return nil, fmt.Errorf("failed to restore category %d: %w", c.CategoryID, err)
+		}
+		ids[c.CategoryID] = newID
+	}
+
+	for _, sc := range a.Subcategories {
+		categoryID, ok := ids[sc.CategoryID]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO subcategory (category_id, subcategory_name, usage_count) VALUES ($1, $2, $3)`,
+			categoryID, sc.SubcategoryName, sc.UsageCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to restore subcategory %d: %w", sc.SubcategoryID, err)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,393 @@
+// Package filter parses the compact filter syntax of list commands, e.g.
+// "tag:work due:<fri priority:>=4 -done" or "after:2026-10-01 amount:>500 cat:餐飲".
+//
+// A filter is a list of space-separated terms, all of which must match:
+//
+//	key:value    compare a field; value may start with <, <=, >, >= or =
+//	-key:value   negate a term
+//	done, -done  completed or open todos
+//	blocked      todos waiting for open todos
+//	word         free text contained in the title, description or tags
+//
+// Values with spaces can be quoted: tag:"side project".
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is wrapped by every parse error
+var ErrInvalid = errors.New("invalid filter")
+
+// Op is a comparison operator
+type Op string
+
+const (
+	OpEq  Op = "="
+	OpLt  Op = "<"
+	OpLte Op = "<="
+	OpGt  Op = ">"
+	OpGte Op = ">="
+)
+
+// Fields a condition can refer to. Not every list supports every field.
+const (
+	FieldText     = "text"
+	FieldTag      = "tag"
+	FieldDone     = "done"
+	FieldPriority = "priority"
+	FieldDate     = "date" // due time of todos, start of events, date of transactions, creation of memos
+	FieldAmount   = "amount"
+	FieldCategory = "cat"
+	FieldType     = "type"
+	FieldProject  = "project"
+	FieldBlocked  = "blocked"
+	FieldStatus   = "status"
+)
+
+// valueKind is how the value of a field is parsed
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindNumber
+	kindDate
+	kindFlag
+)
+
+var fieldKinds = map[string]valueKind{
+	FieldText:     kindString,
+	FieldTag:      kindString,
+	FieldDone:     kindFlag,
+	FieldPriority: kindNumber,
+	FieldDate:     kindDate,
+	FieldAmount:   kindNumber,
+	FieldCategory: kindString,
+	FieldType:     kindString,
+	FieldProject:  kindString,
+	FieldBlocked:  kindFlag,
+	FieldStatus:   kindString,
+}
+
+// aliases maps the keys users type to fields
+var aliases = map[string]string{
+	"text":     FieldText,
+	"tag":      FieldTag,
+	"priority": FieldPriority,
+	"p":        FieldPriority,
+	"due":      FieldDate,
+	"date":     FieldDate,
+	"on":       FieldDate,
+	"amount":   FieldAmount,
+	"cat":      FieldCategory,
+	"category": FieldCategory,
+	"type":     FieldType,
+	"project":  FieldProject,
+	"proj":     FieldProject,
+	"status":   FieldStatus,
+	"is":       FieldStatus,
+}
+
+// transactionTypes maps type values to the stored transaction types
+var transactionTypes = map[string]string{
+	"expense":    "expense",
+	"支出":         "expense",
+	"income":     "income",
+	"收入":         "income",
+	"settlement": "settlement",
+	"還款":         "settlement",
+}
+
+// todoStatuses maps status values to the stored todo statuses
+var todoStatuses = map[string]string{
+	"inbox":     "inbox",
+	"收件匣":       "inbox",
+	"next":      "next",
+	"下一步":       "next",
+	"doing":     "doing",
+	"進行中":       "doing",
+	"waiting":   "waiting",
+	"等待中":       "waiting",
+	"done":      "done",
+	"已完成":       "done",
+	"cancelled": "cancelled",
+	"canceled":  "cancelled",
+	"已取消":       "cancelled",
+}
+
+// ParseStatus returns the stored todo status named by value
+func ParseStatus(value string) (string, bool) {
+	s, ok := todoStatuses[strings.ToLower(strings.TrimSpace(value))]
+	return s, ok
+}
+
+// Condition is one term of a filter
+type Condition struct {
+	Field  string
+	Op     Op
+	Negate bool
+
+	Text   string    // string fields
+	Number float64   // number fields
+	Date   time.Time // date fields: midnight of the day in the user's time zone
+}
+
+// DayEnd is the start of the day after a date condition's day
+func (c Condition) DayEnd() time.Time {
+	return c.Date.AddDate(0, 0, 1)
+}
+
+// Filter is a parsed filter; conditions are combined with AND
+type Filter struct {
+	Query      string
+	Conditions []Condition
+}
+
+// IsEmpty reports whether the filter has no conditions
+func (f *Filter) IsEmpty() bool {
+	return f == nil || len(f.Conditions) == 0
+}
+
+// Has reports whether the filter has a condition on field
+func (f *Filter) Has(field string) bool {
+	if f == nil {
+		return false
+	}
+	for _, c := range f.Conditions {
+		if c.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ByTag returns a filter for items with tag that also contain text, when
+// it is not empty
+func ByTag(tag, text string) *Filter {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	query := "tag:" + tag
+	if strings.ContainsAny(tag, " \t　") {
+		query = `tag:"` + tag + `"`
+	}
+	f := &Filter{
+		Query:      query,
+		Conditions: []Condition{{Field: FieldTag, Op: OpEq, Text: tag}},
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		f.Query += " " + text
+		f.Conditions = append(f.Conditions, Condition{Field: FieldText, Op: OpEq, Text: text})
+	}
+	return f
+}
+
+// Error is a term that could not be parsed
+type Error struct {
+	Term   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %q: %s", ErrInvalid, e.Term, e.Reason)
+}
+
+func (e *Error) Unwrap() error {
+	return ErrInvalid
+}
+
+// UnsupportedError is a condition on a field the filtered list does not have
+type UnsupportedError struct {
+	Field string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s: field %q is not supported here", ErrInvalid, e.Field)
+}
+
+func (e *UnsupportedError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Parse parses query. Relative dates such as "today" or "fri" are resolved
+// against now, whose location is used for every date.
+func Parse(query string, now time.Time) (*Filter, error) {
+	terms, err := split(query)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{Query: strings.TrimSpace(query)}
+	for _, term := range terms {
+		c, err := parseTerm(term, now)
+		if err != nil {
+			return nil, err
+		}
+		f.Conditions = append(f.Conditions, c)
+	}
+	return f, nil
+}
+
+func parseTerm(term string, now time.Time) (Condition, error) {
+	raw := term
+	c := Condition{Op: OpEq}
+	if len(term) > 1 && term[0] == '-' {
+		c.Negate = true
+		term = term[1:]
+	}
+
+	key, value, found := strings.Cut(term, ":")
+	if !found {
+		switch strings.ToLower(term) {
+		case "done", "completed", "已完成":
+			c.Field = FieldDone
+			return c, nil
+		case "open", "未完成":
+			c.Field = FieldDone
+			c.Negate = !c.Negate
+			return c, nil
+		case "blocked", "被阻擋":
+			c.Field = FieldBlocked
+			return c, nil
+		}
+		c.Field = FieldText
+		c.Text = term
+		return c, nil
+	}
+
+	key = strings.ToLower(key)
+	switch key {
+	case "after", "since":
+		c.Field, c.Op = FieldDate, OpGte
+	case "before", "until":
+		c.Field, c.Op = FieldDate, OpLt
+	default:
+		field, ok := aliases[key]
+		if !ok {
+			return c, &Error{Term: raw, Reason: "unknown key"}
+		}
+		c.Field = field
+		if op, rest := cutOp(value); op != "" {
+			if fieldKinds[field] == kindString {
+				return c, &Error{Term: raw, Reason: "comparison on a text field"}
+			}
+			c.Op, value = op, rest
+		}
+	}
+
+	if value == "" {
+		return c, &Error{Term: raw, Reason: "missing value"}
+	}
+
+	switch fieldKinds[c.Field] {
+	case kindString:
+		c.Text = value
+		if t, ok := transactionTypes[strings.ToLower(value)]; ok && c.Field == FieldType {
+			c.Text = t
+		}
+		if c.Field == FieldStatus {
+			s, ok := ParseStatus(value)
+			if !ok {
+				return c, &Error{Term: raw, Reason: "unknown status"}
+			}
+			c.Text = s
+		}
+	case kindNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c, &Error{Term: raw, Reason: "not a number"}
+		}
+		c.Number = n
+	case kindDate:
+		d, ok := parseDate(value, now)
+		if !ok {
+			return c, &Error{Term: raw, Reason: "not a date"}
+		}
+		c.Date = d
+	}
+	return c, nil
+}
+
+// cutOp splits a leading comparison operator off value
+func cutOp(value string) (Op, string) {
+	for _, op := range []Op{OpLte, OpGte, OpLt, OpGt, OpEq} {
+		if rest, ok := strings.CutPrefix(value, string(op)); ok {
+			return op, rest
+		}
+	}
+	return "", value
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday, "週日": time.Sunday, "周日": time.Sunday, "週天": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday, "週一": time.Monday, "周一": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday, "週二": time.Tuesday, "周二": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "週三": time.Wednesday, "周三": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday, "週四": time.Thursday, "周四": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "週五": time.Friday, "周五": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "週六": time.Saturday, "周六": time.Saturday,
+}
+
+// parseDate accepts YYYY-MM-DD, MM-DD, today/tomorrow/yesterday (also in
+// Chinese), a weekday meaning its next occurrence from today on, and an
+// offset in days such as +3 or -7
+func parseDate(value string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	v := strings.ToLower(value)
+
+	switch v {
+	case "today", "今天":
+		return today, true
+	case "tomorrow", "明天":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday", "昨天":
+		return today.AddDate(0, 0, -1), true
+	}
+	if wd, ok := weekdays[v]; ok {
+		return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7), true
+	}
+	if v[0] == '+' || v[0] == '-' {
+		if days, err := strconv.Atoi(v); err == nil {
+			return today.AddDate(0, 0, days), true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, now.Location()); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("01-02", v, now.Location()); err == nil {
+		return time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// split breaks query into terms at spaces outside double quotes and removes
+// the quotes
+func split(query string) ([]string, error) {
+	var terms []string
+	var sb strings.Builder
+	inQuote := false
+	flush := func() {
+		if sb.Len() > 0 {
+			terms = append(terms, sb.String())
+			sb.Reset()
+		}
+	}
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n' || r == '　'):
+			flush()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, &Error{Term: query, Reason: "unterminated quote"}
+	}
+	flush()
+	return terms, nil
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// now is a Wednesday
+var now = time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []Condition
+	}{
+		{"", nil},
+		{"tag:work", []Condition{{Field: FieldTag, Op: OpEq, Text: "work"}}},
+		{`tag:"side project"`, []Condition{{Field: FieldTag, Op: OpEq, Text: "side project"}}},
+		{"-tag:work", []Condition{{Field: FieldTag, Op: OpEq, Negate: true, Text: "work"}}},
+		{"priority:>=4", []Condition{{Field: FieldPriority, Op: OpGte, Number: 4}}},
+		{"p:3", []Condition{{Field: FieldPriority, Op: OpEq, Number: 3}}},
+		{"amount:>500", []Condition{{Field: FieldAmount, Op: OpGt, Number: 500}}},
+		{"due:<fri", []Condition{{Field: FieldDate, Op: OpLt, Date: day(10, 16)}}},
+		{"due:wed", []Condition{{Field: FieldDate, Op: OpEq, Date: day(10, 14)}}},
+		{"after:2026-10-01", []Condition{{Field: FieldDate, Op: OpGte, Date: day(10, 1)}}},
+		{"before:tomorrow", []Condition{{Field: FieldDate, Op: OpLt, Date: day(10, 15)}}},
+		{"on:+3", []Condition{{Field: FieldDate, Op: OpEq, Date: day(10, 17)}}},
+		{"date:-7", []Condition{{Field: FieldDate, Op: OpEq, Date: day(10, 7)}}},
+		{"date:12-25", []Condition{{Field: FieldDate, Op: OpEq, Date: day(12, 25)}}},
+		{"date:今天", []Condition{{Field: FieldDate, Op: OpEq, Date: day(10, 14)}}},
+		{"done", []Condition{{Field: FieldDone, Op: OpEq}}},
+		{"-done", []Condition{{Field: FieldDone, Op: OpEq, Negate: true}}},
+		{"open", []Condition{{Field: FieldDone, Op: OpEq, Negate: true}}},
+		{"blocked", []Condition{{Field: FieldBlocked, Op: OpEq}}},
+		{"type:支出", []Condition{{Field: FieldType, Op: OpEq, Text: "expense"}}},
+		{"is:進行中", []Condition{{Field: FieldStatus, Op: OpEq, Text: "doing"}}},
+		{"cat:餐飲", []Condition{{Field: FieldCategory, Op: OpEq, Text: "餐飲"}}},
+		{"milk", []Condition{{Field: FieldText, Op: OpEq, Text: "milk"}}},
+		{
+			"tag:work  due:<fri　-done",
+			[]Condition{
+				{Field: FieldTag, Op: OpEq, Text: "work"},
+				{Field: FieldDate, Op: OpLt, Date: day(10, 16)},
+				{Field: FieldDone, Op: OpEq, Negate: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := Parse(tt.query, now)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(f.Conditions, tt.want) {
+				t.Errorf("got %+v, want %+v", f.Conditions, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"color:red",
+		"tag:",
+		"tag:>work",
+		"priority:high",
+		"due:someday",
+		"is:later",
+		`tag:"side project`,
+	}
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			_, err := Parse(query, now)
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("got %v, want an error wrapping ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestByTag(t *testing.T) {
+	tests := []struct {
+		tag, text string
+		query     string
+	}{
+		{"work", "", "tag:work"},
+		{"#work", "report", "tag:work report"},
+		{"side project", "", `tag:"side project"`},
+	}
+	for _, tt := range tests {
+		f := ByTag(tt.tag, tt.text)
+		if f.Query != tt.query {
+			t.Errorf("ByTag(%q, %q).Query = %q, want %q", tt.tag, tt.text, f.Query, tt.query)
+		}
+
+		// The query reads back as the same filter
+		parsed, err := Parse(f.Query, now)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", f.Query, err)
+		}
+		if !reflect.DeepEqual(parsed.Conditions, f.Conditions) {
+			t.Errorf("Parse(%q) = %+v, want %+v", f.Query, parsed.Conditions, f.Conditions)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	f, err := Parse("tag:work -done", now)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !f.Has(FieldTag) || !f.Has(FieldDone) || f.Has(FieldDate) {
+		t.Errorf("Has reports the wrong fields for %+v", f.Conditions)
+	}
+	var empty *Filter
+	if !empty.IsEmpty() || empty.Has(FieldTag) {
+		t.Error("a nil filter should be empty")
+	}
+}
@@ -0,0 +1,211 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// ErrInvalidSplit is wrapped by every validation error of Shares
+var ErrInvalidSplit = errors.New("invalid split")
+
+// ShareSpec is a participant with an optional share value as given by the user:
+// a percentage for percentage splits, an amount for exact splits, unused for equal splits
+type ShareSpec struct {
+	Participant string
+	Value       float64
+	HasValue    bool
+}
+
+// Transfer is a single payment in a settlement plan
+type Transfer struct {
+	From   string
+	To     string
+	Amount float64
+}
+
+// ParseShareSpec parses "name", "name:60%" or "name:300".
+// It reports whether the value was a percentage.
+func ParseShareSpec(s string) (ShareSpec, bool, error) {
+	name, value, found := strings.Cut(s, ":")
+	spec := ShareSpec{Participant: strings.TrimSpace(name)}
+	if spec.Participant == "" {
+		return spec, false, fmt.Errorf("%w: invalid participant %q", ErrInvalidSplit, s)
+	}
+	if !found {
+		return spec, false, nil
+	}
+
+	value = strings.TrimSpace(value)
+	percent := strings.HasSuffix(value, "%")
+	v, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || v < 0 {
+		return spec, false, fmt.Errorf("%w: invalid share %q", ErrInvalidSplit, s)
+	}
+	spec.Value = v
+	spec.HasValue = true
+	return spec, percent, nil
+}
+
+// Shares divides amount between the participants. The payer is included in
+// equal splits. For percentage and exact splits whatever the specs leave over
+// is assigned to the payer. Amounts are rounded to cents and always add up to
+// the total; rounding leftovers go to the first participants.
+func Shares(amount float64, splitType models.SplitType, payer string, specs []ShareSpec) ([]*models.TransactionShare, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidSplit)
+	}
+	total := toCents(amount)
+
+	var names []string
+	var cents []int64
+
+	switch splitType {
+	case models.SplitEqual:
+		names = uniqueNames(append([]ShareSpec{{Participant: payer}}, specs...))
+		if len(names) < 2 {
+			return nil, fmt.Errorf("%w: at least one other participant is required", ErrInvalidSplit)
+		}
+		each := total / int64(len(names))
+		cents = make([]int64, len(names))
+		for i := range cents {
+			cents[i] = each
+		}
+		for i := int64(0); i < total-each*int64(len(names)); i++ {
+			cents[i]++
+		}
+
+	case models.SplitPercentage, models.SplitExact:
+		var assigned int64
+		var percentSum float64
+		for _, spec := range specs {
+			if !spec.HasValue {
+				return nil, fmt.Errorf("%w: missing share for %s", ErrInvalidSplit, spec.Participant)
+			}
+			var c int64
+			if splitType == models.SplitPercentage {
+				percentSum += spec.Value
+				c = int64(math.Round(float64(total) * spec.Value / 100))
+			} else {
+				c = toCents(spec.Value)
+			}
+			names = append(names, spec.Participant)
+			cents = append(cents, c)
+			assigned += c
+		}
+		if splitType == models.SplitPercentage && percentSum > 100.0001 {
+			return nil, fmt.Errorf("%w: percentages add up to %.1f%%", ErrInvalidSplit, percentSum)
+		}
+		if assigned > total {
+			// Percentage rounding may overshoot by a cent
+			if splitType == models.SplitPercentage && assigned-total <= int64(len(cents)) {
+				for i := 0; assigned > total; i++ {
+					cents[i]--
+					assigned--
+				}
+			} else {
+				return nil, fmt.Errorf("%w: shares add up to %.2f, more than %.2f", ErrInvalidSplit, fromCents(assigned), amount)
+			}
+		}
+		if rest := total - assigned; rest > 0 {
+			if splitType == models.SplitPercentage && percentSum > 99.9999 {
+				// Rounding leftover of a full 100%
+				cents[0] += rest
+			} else {
+				names = append(names, payer)
+				cents = append(cents, rest)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown split type %q", ErrInvalidSplit, splitType)
+	}
+
+	merged := make(map[string]int64)
+	var order []string
+	for i, name := range names {
+		if _, ok := merged[name]; !ok {
+			order = append(order, name)
+		}
+		merged[name] += cents[i]
+	}
+
+	shares := make([]*models.TransactionShare, 0, len(order))
+	for _, name := range order {
+		shares = append(shares, &models.TransactionShare{Participant: name, Amount: fromCents(merged[name])})
+	}
+	return shares, nil
+}
+
+// Simplify returns transfers that settle the balances, matching the largest
+// debtor with the largest creditor, so at most n-1 payments are needed for n
+// people. Positive balances are owed money, negative balances owe money.
+func Simplify(balances map[string]float64) []Transfer {
+	type entry struct {
+		name  string
+		cents int64
+	}
+	var creditors, debtors []entry
+	for name, b := range balances {
+		c := toCents(b)
+		switch {
+		case c > 0:
+			creditors = append(creditors, entry{name, c})
+		case c < 0:
+			debtors = append(debtors, entry{name, -c})
+		}
+	}
+
+	// Largest first gives few transfers; names break ties for stable output
+	byAmount := func(s []entry) func(i, j int) bool {
+		return func(i, j int) bool {
+			if s[i].cents != s[j].cents {
+				return s[i].cents > s[j].cents
+			}
+			return s[i].name < s[j].name
+		}
+	}
+	sort.Slice(creditors, byAmount(creditors))
+	sort.Slice(debtors, byAmount(debtors))
+
+	var transfers []Transfer
+	i, j := 0, 0
+	for i < len(debtors) && j < len(creditors) {
+		c := min(debtors[i].cents, creditors[j].cents)
+		transfers = append(transfers, Transfer{From: debtors[i].name, To: creditors[j].name, Amount: fromCents(c)})
+		debtors[i].cents -= c
+		creditors[j].cents -= c
+		if debtors[i].cents == 0 {
+			i++
+		}
+		if creditors[j].cents == 0 {
+			j++
+		}
+	}
+	return transfers
+}
+
+func uniqueNames(specs []ShareSpec) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, spec := range specs {
+		if !seen[spec.Participant] {
+			seen[spec.Participant] = true
+			names = append(names, spec.Participant)
+		}
+	}
+	return names
+}
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+func fromCents(c int64) float64 {
+	return float64(c) / 100
+}
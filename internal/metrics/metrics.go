@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Notification kinds used as the "kind" label of the notification counters
+const (
+	NotificationReminder     = "reminder"
+	NotificationEvent        = "event"
+	NotificationTodo         = "todo"
+	NotificationDailySummary = "daily_summary"
+)
+
+var (
+	// UpdatesHandled counts Telegram updates by type (command, callback, message, action) and name
+	UpdatesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lifeline",
+		Name:      "updates_handled_total",
+		Help:      "Telegram updates handled, by type and command/action name.",
+	}, []string{"type", "name"})
+
+	// UpdateDuration measures how long handling an update took, by type
+	UpdateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lifeline",
+		Name:      "update_duration_seconds",
+		Help:      "Time spent handling a Telegram update.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"type"})
+
+	// AIRequestDuration measures AI API latency, by call
+	AIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lifeline",
+		Name:      "ai_request_duration_seconds",
+		Help:      "Latency of AI API calls.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"call"})
+
+	// AITokens counts tokens used by AI calls, by call and kind (prompt, completion)
+	AITokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lifeline",
+		Name:      "ai_tokens_total",
+		Help:      "Tokens used by AI API calls.",
+	}, []string{"call", "kind"})
+
+	// AIRequestFailures counts failed AI calls, by call and reason (api, empty, parse, quota)
+	AIRequestFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lifeline",
+		Name:      "ai_request_failures_total",
+		Help:      "Failed AI API calls.",
+	}, []string{"call", "reason"})
+
+	// SchedulerCheckDuration measures each scheduler check, by check name
+	SchedulerCheckDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lifeline",
+		Name:      "scheduler_check_duration_seconds",
+		Help:      "Duration of scheduler checks; check=\"all\" is the whole loop iteration.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"check"})
+
+	// NotificationsSent counts notifications delivered to Telegram, by kind
+	NotificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lifeline",
+		Name:      "notifications_sent_total",
+		Help:      "Notifications sent to users.",
+	}, []string{"kind"})
+
+	// NotificationsFailed counts notifications Telegram rejected, by kind
+	NotificationsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lifeline",
+		Name:      "notifications_failed_total",
+		Help:      "Notifications that failed to send.",
+	}, []string{"kind"})
+
+	// DBQueryDuration measures database query latency, by SQL verb and status
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lifeline",
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"operation", "status"})
+)
+
+// Since observes the time elapsed since start on a histogram, for use with defer
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// Notification records the outcome of sending a notification of the given kind
+func Notification(kind string, err error) {
+	if err != nil {
+		NotificationsFailed.WithLabelValues(kind).Inc()
+		return
+	}
+	NotificationsSent.WithLabelValues(kind).Inc()
+}
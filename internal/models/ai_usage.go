@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AIUsage is the token usage of a single AI call
+type AIUsage struct {
+	UsageID          int64     `json:"usage_id"`
+	UserID           *int64    `json:"user_id"`
+	Call             string    `json:"call"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost"` // USD, estimated
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AIUsageTotal sums AI usage over a period, optionally for one call type
+type AIUsageTotal struct {
+	Call             string  `json:"call,omitempty"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (t *AIUsageTotal) Tokens() int64 {
+	return t.PromptTokens + t.CompletionTokens
+}
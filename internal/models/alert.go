@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxAlerts is how many alerts an event can have
+	MaxAlerts = 5
+	// MaxAlertMinutes is the earliest an alert can go off, four weeks before
+	MaxAlertMinutes = 4 * 7 * MinutesPerDay
+)
+
+// DefaultEventAlerts are the alerts of events of users who haven't set their own
+var DefaultEventAlerts = []int{30}
+
+// ParseAlerts parses minutes before an event separated by commas or
+// spaces, e.g. "1440,30", into normalized alerts; "" gives no alerts
+func ParseAlerts(s string) ([]int, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || unicode.IsSpace(r)
+	})
+
+	alerts := []int{}
+	for _, f := range fields {
+		minutes, err := strconv.Atoi(f)
+		if err != nil || minutes < 0 || minutes > MaxAlertMinutes {
+			return nil, fmt.Errorf("invalid alert %q", f)
+		}
+		alerts = append(alerts, minutes)
+	}
+	alerts = NormalizeAlerts(alerts)
+	if len(alerts) > MaxAlerts {
+		return nil, errors.New("too many alerts")
+	}
+	return alerts, nil
+}
+
+// NormalizeAlerts sorts alerts from the earliest, dropping duplicates
+func NormalizeAlerts(alerts []int) []int {
+	alerts = slices.Clone(alerts)
+	slices.Sort(alerts)
+	alerts = slices.Compact(alerts)
+	slices.Reverse(alerts)
+	return alerts
+}
+
+// AlertLabel describes when an alert goes off, e.g. "1 天前" or "準時"
+func AlertLabel(minutes int) string {
+	switch {
+	case minutes == 0:
+		return "準時"
+	case minutes%MinutesPerDay == 0:
+		return fmt.Sprintf("%d 天前", minutes/MinutesPerDay)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d 小時前", minutes/60)
+	case minutes > 60:
+		return fmt.Sprintf("%d 小時 %d 分鐘前", minutes/60, minutes%60)
+	}
+	return fmt.Sprintf("%d 分鐘前", minutes)
+}
+
+// AlertsLabel describes the alerts of an event, or that it has none
+func AlertsLabel(alerts []int) string {
+	if len(alerts) == 0 {
+		return "不提醒"
+	}
+	labels := make([]string, len(alerts))
+	for i, minutes := range alerts {
+		labels[i] = AlertLabel(minutes)
+	}
+	return strings.Join(labels, "、")
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAlerts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"", []int{}},
+		{"0", []int{0}},
+		{"30", []int{30}},
+		{"30,1440", []int{1440, 30}},
+		{"1440 30 30", []int{1440, 30}},
+		{"60，10、0", []int{60, 10, 0}},
+		{"40320", []int{40320}},
+	}
+	for _, tt := range tests {
+		got, err := ParseAlerts(tt.in)
+		if err != nil {
+			t.Errorf("ParseAlerts(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAlerts(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"-5", "soon", "40321", "1,2,3,4,5,6"} {
+		if _, err := ParseAlerts(in); err == nil {
+			t.Errorf("ParseAlerts(%q) should fail", in)
+		}
+	}
+}
+
+func TestAlertLabel(t *testing.T) {
+	tests := map[int]string{
+		0:    "準時",
+		10:   "10 分鐘前",
+		60:   "1 小時前",
+		90:   "1 小時 30 分鐘前",
+		1440: "1 天前",
+	}
+	for minutes, want := range tests {
+		if got := AlertLabel(minutes); got != want {
+			t.Errorf("AlertLabel(%d) = %q, want %q", minutes, got, want)
+		}
+	}
+	if got := AlertsLabel(nil); got != "不提醒" {
+		t.Errorf("AlertsLabel(nil) = %q", got)
+	}
+}
+
+func TestDueAlerts(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	event := &Event{NextOccurrence: &start, Alerts: []int{1440, 30, 0}}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		sent []int
+		want []int
+	}{
+		{"none yet", start.Add(-25 * time.Hour), nil, nil},
+		{"day before", start.Add(-24 * time.Hour), nil, []int{1440}},
+		{"day before sent", start.Add(-time.Hour), []int{1440}, nil},
+		{"half an hour before", start.Add(-30 * time.Minute), []int{1440}, []int{30}},
+		{"on time", start, []int{1440, 30}, []int{0}},
+		{"just after start", start.Add(30 * time.Second), []int{1440, 30}, []int{0}},
+		{"missed together", start.Add(-10 * time.Minute), nil, []int{1440, 30}},
+		{"all sent", start, []int{1440, 30, 0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event.AlertsSent = tt.sent
+			if got := event.DueAlerts(tt.now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&Event{Alerts: []int{0}}).DueAlerts(start); got != nil {
+		t.Errorf("event without next occurrence has due alerts %v", got)
+	}
+}
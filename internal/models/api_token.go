@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// APIToken is a credential for the HTTP API. Only the SHA-256 hash of the token is stored.
+type APIToken struct {
+	TokenID     int        `json:"token_id"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenHash   string     `json:"-"`
+	TokenPrefix string     `json:"token_prefix"` // First characters of the token, for display only
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsActive returns true if the token is neither revoked nor expired
+func (t *APIToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || t.ExpiresAt.After(now)
+}
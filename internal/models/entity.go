@@ -0,0 +1,21 @@
+package models
+
+// EntityKind names an entity type that can be searched and tagged
+type EntityKind string
+
+const (
+	EntityMemo        EntityKind = "memo"
+	EntityTodo        EntityKind = "todo"
+	EntityReminder    EntityKind = "reminder"
+	EntityEvent       EntityKind = "event"
+	EntityTransaction EntityKind = "transaction"
+)
+
+// EntityKinds lists the kinds in the order they are listed together, e.g. in search results
+var EntityKinds = []EntityKind{
+	EntityTodo,
+	EntityEvent,
+	EntityReminder,
+	EntityMemo,
+	EntityTransaction,
+}
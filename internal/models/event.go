@@ -1,20 +1,36 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// MinutesPerDay is the duration of each day of an all-day event
+const MinutesPerDay = 24 * 60
 
 type Event struct {
-	EventID             int        `json:"event_id"`
-	UserID              int64      `json:"user_id"`
-	Title               string     `json:"title"`
-	Description         string     `json:"description"`
-	Dtstart             *time.Time `json:"dtstart"`              // First occurrence (for RRULE calculation)
-	Duration            int        `json:"duration"`             // Duration in minutes
-	NextOccurrence      *time.Time `json:"next_occurrence"`      // Next scheduled occurrence
-	NotificationMinutes int        `json:"notification_minutes"` // Minutes before to notify
-	RecurrenceRule      string     `json:"recurrence_rule"`      // RFC 5545 RRULE
-	Tags                string     `json:"tags"`
-	NotifiedAt          *time.Time `json:"notified_at"` // Last notification time for this occurrence
-	CreatedAt           time.Time  `json:"created_at"`
+	EventID        int        `json:"event_id"`
+	UserID         int64      `json:"user_id"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Dtstart        *time.Time `json:"dtstart"`         // First occurrence (for RRULE calculation)
+	Duration       int        `json:"duration"`        // Duration in minutes
+	NextOccurrence *time.Time `json:"next_occurrence"` // Next scheduled occurrence
+	Alerts         []int      `json:"alerts"`          // Minutes before each occurrence to notify, earliest first
+	RecurrenceRule string     `json:"recurrence_rule"` // RFC 5545 RRULE
+	Tags           string     `json:"tags"`
+	AlertsSent     []int      `json:"alerts_sent"` // Alerts already sent for the next occurrence
+	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      *int64     `json:"created_by"`        // Member who created the event in a shared space
+	TodoID         *int       `json:"todo_id,omitempty"` // Todo a time block reserves time for
+	AllDay         bool       `json:"all_day"`           // Takes up whole days from the date of Dtstart
+	Transparent    bool       `json:"transparent"`       // Leaves its time free, like TRANSP:TRANSPARENT
+
+	// Exceptions cancel or change single occurrences of a recurring event
+	Exceptions []*RecurrenceException `json:"exceptions,omitempty"`
+	// RecurrenceID is the original start of the occurrence an expanded copy stands for
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"`
 }
 
 // IsRecurring returns true if this event has a recurrence rule
@@ -22,9 +38,57 @@ func (e *Event) IsRecurring() bool {
 	return e.RecurrenceRule != ""
 }
 
-// GetEndTime calculates end time based on dtstart and duration
+// DueAlerts returns the alerts of the next occurrence not sent yet whose
+// time has come by now
+func (e *Event) DueAlerts(now time.Time) []int {
+	if e.NextOccurrence == nil {
+		return nil
+	}
+	var due []int
+	for _, minutes := range e.Alerts {
+		if slices.Contains(e.AlertsSent, minutes) {
+			continue
+		}
+		if !e.NextOccurrence.Add(-time.Duration(minutes) * time.Minute).After(now) {
+			due = append(due, minutes)
+		}
+	}
+	return due
+}
+
+// UnmarshalJSON also reads the single notification_minutes of events
+// archived before they had several alerts
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type event Event
+	aux := struct {
+		*event
+		NotificationMinutes *int `json:"notification_minutes"`
+	}{event: (*event)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if e.Alerts == nil && aux.NotificationMinutes != nil {
+		e.Alerts = []int{*aux.NotificationMinutes}
+	}
+	return nil
+}
+
+// Days returns how many days an all-day event takes up, at least one
+func (e *Event) Days() int {
+	return max((e.Duration+MinutesPerDay-1)/MinutesPerDay, 1)
+}
+
+// GetEndTime calculates end time based on dtstart and duration; for an
+// all-day event it is midnight after the last day, like DTEND;VALUE=DATE
 func (e *Event) GetEndTime() *time.Time {
-	if e.Dtstart == nil || e.Duration == 0 {
+	if e.Dtstart == nil {
+		return nil
+	}
+	if e.AllDay {
+		endTime := e.Dtstart.AddDate(0, 0, e.Days())
+		return &endTime
+	}
+	if e.Duration == 0 {
 		return nil
 	}
 	endTime := e.Dtstart.Add(time.Duration(e.Duration) * time.Minute)
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// InviteCode admits new users when the bot runs in invite mode
+type InviteCode struct {
+	Code      string     `json:"code"`
+	CreatedBy *int64     `json:"created_by"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -8,4 +8,5 @@ type Memo struct {
 	Content   string    `json:"content"`
 	Tags      string    `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
+	CreatedBy *int64    `json:"created_by"` // Member who wrote the memo in a shared space
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Project groups todos, e.g. a trip or a work stream
+type Project struct {
+	ProjectID   int       `json:"project_id"`
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`        // one of ProjectColors, or empty
+	DefaultTags string    `json:"default_tags"` // added to every todo created in the project
+	CreatedBy   *int64    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Todo counts, filled in by lists
+	OpenTodos int `json:"-"`
+	DoneTodos int `json:"-"`
+}
+
+// ProjectColors maps the supported colors to the emoji shown for them
+var ProjectColors = map[string]string{
+	"red":    "🔴",
+	"orange": "🟠",
+	"yellow": "🟡",
+	"green":  "🟢",
+	"blue":   "🔵",
+	"purple": "🟣",
+	"brown":  "🟤",
+	"black":  "⚫",
+	"white":  "⚪",
+}
+
+// Emoji returns the project's color emoji, or a folder when it has no color
+func (p *Project) Emoji() string {
+	if emoji, ok := ProjectColors[p.Color]; ok {
+		return emoji
+	}
+	return "📁"
+}
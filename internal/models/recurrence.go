@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// RecurrenceException cancels or replaces one occurrence of a recurring
+// event or reminder, like an EXDATE or an overridden RECURRENCE-ID in iCalendar
+type RecurrenceException struct {
+	ExceptionID   int        `json:"exception_id"`
+	ParentID      int        `json:"parent_id"`      // event or reminder of the series
+	OriginalStart time.Time  `json:"original_start"` // when the series would have the occurrence
+	Cancelled     bool       `json:"cancelled"`
+	Start         *time.Time `json:"start,omitempty"`    // moved start, nil keeps the original
+	Duration      *int       `json:"duration,omitempty"` // changed duration of an event occurrence
+	Title         string     `json:"title,omitempty"`    // changed event title or reminder message
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// StartTime returns when the occurrence takes place
+func (x *RecurrenceException) StartTime() time.Time {
+	if x.Start != nil {
+		return *x.Start
+	}
+	return x.OriginalStart
+}
+
+// RecurrenceScope is which occurrences of a series a change applies to
+type RecurrenceScope string
+
+const (
+	ScopeThis      RecurrenceScope = "this"      // only the chosen occurrence
+	ScopeFollowing RecurrenceScope = "following" // the chosen occurrence and all after it
+	ScopeAll       RecurrenceScope = "all"       // the whole series
+)
+
+// RecurrenceScopes lists the scopes in the order they are offered
+var RecurrenceScopes = []RecurrenceScope{ScopeThis, ScopeFollowing, ScopeAll}
+
+// Label returns the Chinese name of the scope
+func (s RecurrenceScope) Label() string {
+	switch s {
+	case ScopeThis:
+		return "只有這次"
+	case ScopeFollowing:
+		return "這次及之後"
+	case ScopeAll:
+		return "全部"
+	}
+	return string(s)
+}
+
+// IsValid reports whether s is a known scope
+func (s RecurrenceScope) IsValid() bool {
+	switch s {
+	case ScopeThis, ScopeFollowing, ScopeAll:
+		return true
+	}
+	return false
+}
@@ -16,6 +16,10 @@ type Reminder struct {
 	AcknowledgedAt *time.Time `json:"acknowledged_at"` // When user confirmed the reminder
 	LastMessageID  *int       `json:"last_message_id"` // Last sent message ID for deletion before resend
 	CreatedAt      time.Time  `json:"created_at"`
+	CreatedBy      *int64     `json:"created_by"` // Member who set the reminder in a shared space
+
+	// Exceptions cancel or change single occurrences of a recurring reminder
+	Exceptions []*RecurrenceException `json:"exceptions,omitempty"`
 }
 
 // IsRecurring returns true if this reminder has a recurrence rule
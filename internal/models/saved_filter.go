@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SavedFilter is a named filter of a list command, recalled as a smart list
+type SavedFilter struct {
+	FilterID  int       `json:"filter_id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Target    string    `json:"target"` // list command without the slash: todos, events, reminders, memos or transactions
+	Query     string    `json:"query"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
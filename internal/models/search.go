@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SearchResult is one match of a search across all entities
+type SearchResult struct {
+	Kind   EntityKind `json:"kind"`
+	ID     int        `json:"id"`
+	Title  string     `json:"title"`            // memo content, todo/event title, reminder message or transaction description
+	Amount float64    `json:"amount,omitempty"` // transactions only
+	Date   *time.Time `json:"date,omitempty"`   // due time, next occurrence, reminder time, transaction or creation date
+	Done   bool       `json:"done,omitempty"`   // completed todo or disabled reminder
+	Rank   float64    `json:"rank"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type ShoppingItem struct {
+	ItemID    int        `json:"item_id"`
+	UserID    int64      `json:"user_id"`
+	Name      string     `json:"name"`
+	Quantity  string     `json:"quantity"`
+	CreatedBy *int64     `json:"created_by"`
+	BoughtBy  *int64     `json:"bought_by"`
+	BoughtAt  *time.Time `json:"bought_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (i *ShoppingItem) IsBought() bool {
+	return i.BoughtAt != nil
+}
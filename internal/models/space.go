@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Space is a shared workspace owned by a Telegram group chat. SpaceID is the
+// chat ID, which is also registered as a user so group data is stored under it.
+type Space struct {
+	SpaceID   int64     `json:"space_id"`
+	ChatType  string    `json:"chat_type"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SpaceMember struct {
+	SpaceID  int64     `json:"space_id"`
+	UserID   int64     `json:"user_id"`
+	UserName string    `json:"user_name"`
+	JoinedAt time.Time `json:"joined_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// UsageStats is an instance-wide overview for admins
+type UsageStats struct {
+	Users        int `json:"users"`
+	Groups       int `json:"groups"`
+	Approved     int `json:"approved"`
+	Banned       int `json:"banned"`
+	Memos        int `json:"memos"`
+	Todos        int `json:"todos"`
+	OpenTodos    int `json:"open_todos"`
+	Reminders    int `json:"reminders"`
+	Events       int `json:"events"`
+	Transactions int `json:"transactions"`
+}
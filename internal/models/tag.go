@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the longest tag name in characters
+const MaxTagLength = 50
+
+// Tag is a label shared by memos, todos, reminders, events and transactions
+// of a user or space
+type Tag struct {
+	TagID     int       `json:"tag_id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagCount is a tag with the number of items of each kind using it
+type TagCount struct {
+	Tag
+	Counts map[EntityKind]int `json:"counts"`
+}
+
+// Total is the number of items using the tag
+func (c *TagCount) Total() int {
+	total := 0
+	for _, n := range c.Counts {
+		total += n
+	}
+	return total
+}
+
+// ParseTags splits a tag string at commas and '#', trimming and collapsing
+// spaces so a tag may have several words. Tags longer than MaxTagLength are
+// truncated; empty and duplicate (case-insensitive) tags are dropped.
+func ParseTags(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == '#'
+	})
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		tag := truncateTag(strings.Join(strings.Fields(f), " "))
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// truncateTag cuts a tag to MaxTagLength characters
+func truncateTag(tag string) string {
+	if utf8.RuneCountInString(tag) <= MaxTagLength {
+		return tag
+	}
+	return strings.TrimSpace(string([]rune(tag)[:MaxTagLength]))
+}
+
+// JoinTags is the canonical form of a tag string stored with an item
+func JoinTags(tags []string) string {
+	return strings.Join(tags, ", ")
+}
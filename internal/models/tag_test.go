@@ -0,0 +1,44 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	long := strings.Repeat("長", MaxTagLength+5)
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"work", []string{"work"}},
+		{"work, home", []string{"work", "home"}},
+		{"#work#home", []string{"work", "home"}},
+		{"工作，家裡、學校", []string{"工作", "家裡", "學校"}},
+		{"side project, work", []string{"side project", "work"}},
+		{"  side   project  ", []string{"side project"}},
+		{"Work, work, WORK", []string{"Work"}},
+		{", ,#", nil},
+		{long, []string{strings.Repeat("長", MaxTagLength)}},
+		{long + ", work", []string{strings.Repeat("長", MaxTagLength), "work"}},
+	}
+	for _, tt := range tests {
+		if got := ParseTags(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJoinTagsRoundTrip(t *testing.T) {
+	tags := []string{"side project", "work", "家裡"}
+	joined := JoinTags(tags)
+	if joined != "side project, work, 家裡" {
+		t.Errorf("JoinTags = %q", joined)
+	}
+	if got := ParseTags(joined); !reflect.DeepEqual(got, tags) {
+		t.Errorf("ParseTags(JoinTags(%q)) = %q", tags, got)
+	}
+}
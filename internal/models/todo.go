@@ -9,12 +9,101 @@ type Todo struct {
 	Priority       int        `json:"priority"`
 	Description    string     `json:"description"`
 	DueTime        *time.Time `json:"due_time"`
-	CompletedAt    *time.Time `json:"completed_at"`
+	CompletedAt    *time.Time `json:"completed_at"` // Set for done and cancelled todos
 	Tags           string     `json:"tags"`
 	CreatedAt      time.Time  `json:"created_at"`
 	LastNotifiedAt *time.Time `json:"last_notified_at"`
+	CreatedBy      *int64     `json:"created_by"` // Member who created the todo in a shared space
+	ProjectID      *int       `json:"project_id,omitempty"`
+	ParentID       *int       `json:"parent_id,omitempty"` // Parent todo of a subtask
+
+	EstimatedMinutes int `json:"estimated_minutes,omitempty"` // Time the todo takes, 0 when unknown
+
+	Status          TodoStatus `json:"status"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+
+	// Subtask roll-up, counted when the todo is loaded
+	SubtaskCount int `json:"-"`
+	SubtaskDone  int `json:"-"`
+
+	// Open todos this todo waits for, loaded with the todo
+	BlockedBy []int `json:"blocked_by,omitempty"`
+
+	// Assignment to another user; the todo stays owned by UserID
+	AssigneeID       *int64           `json:"assignee_id,omitempty"`
+	AssignedBy       *int64           `json:"assigned_by,omitempty"`
+	AssignmentStatus AssignmentStatus `json:"assignment_status,omitempty"`
+	AssignedAt       *time.Time       `json:"assigned_at,omitempty"`
+}
+
+type AssignmentStatus string
+
+const (
+	AssignmentPending  AssignmentStatus = "pending"
+	AssignmentAccepted AssignmentStatus = "accepted"
+	AssignmentDeclined AssignmentStatus = "declined"
+)
+
+// TodoStatus is the step of a todo in the board workflow
+type TodoStatus string
+
+const (
+	TodoInbox     TodoStatus = "inbox"
+	TodoNext      TodoStatus = "next"
+	TodoDoing     TodoStatus = "doing"
+	TodoWaiting   TodoStatus = "waiting"
+	TodoDone      TodoStatus = "done"
+	TodoCancelled TodoStatus = "cancelled"
+)
+
+// TodoStatuses are the statuses in board column order
+var TodoStatuses = []TodoStatus{TodoInbox, TodoNext, TodoDoing, TodoWaiting, TodoDone, TodoCancelled}
+
+var todoStatusLabels = map[TodoStatus]string{
+	TodoInbox:     "📥 收件匣",
+	TodoNext:      "⏭ 下一步",
+	TodoDoing:     "🔄 進行中",
+	TodoWaiting:   "⏳ 等待中",
+	TodoDone:      "✅ 已完成",
+	TodoCancelled: "🚫 已取消",
+}
+
+// Label is the status with its emoji, for display
+func (s TodoStatus) Label() string {
+	if label, ok := todoStatusLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// IsClosed reports whether a todo with the status is finished
+func (s TodoStatus) IsClosed() bool {
+	return s == TodoDone || s == TodoCancelled
+}
+
+// IsValid reports whether s is one of TodoStatuses
+func (s TodoStatus) IsValid() bool {
+	_, ok := todoStatusLabels[s]
+	return ok
+}
+
+// TodoStatusChange is one transition of a todo's status
+type TodoStatusChange struct {
+	Status    TodoStatus `json:"status"`
+	ChangedBy *int64     `json:"changed_by,omitempty"`
+	ChangedAt time.Time  `json:"changed_at"`
 }
 
 func (t *Todo) IsCompleted() bool {
 	return t.CompletedAt != nil
 }
+
+// IsBlocked reports whether the todo waits for open todos
+func (t *Todo) IsBlocked() bool {
+	return len(t.BlockedBy) > 0
+}
+
+// IsAssignedTo reports whether userID has accepted the todo
+func (t *Todo) IsAssignedTo(userID int64) bool {
+	return t.AssigneeID != nil && *t.AssigneeID == userID && t.AssignmentStatus == AssignmentAccepted
+}
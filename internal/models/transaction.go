@@ -7,21 +7,52 @@ type TransactionType string
 const (
 	TransactionTypeIncome  TransactionType = "income"
 	TransactionTypeExpense TransactionType = "expense"
+	// TransactionTypeSettlement is a repayment between participants of split
+	// expenses; it is neither income nor expense of the ledger owner
+	TransactionTypeSettlement TransactionType = "settlement"
+)
+
+// SplitType is how a shared expense is divided between participants
+type SplitType string
+
+const (
+	SplitEqual      SplitType = "equal"
+	SplitPercentage SplitType = "percentage"
+	SplitExact      SplitType = "exact"
 )
 
 type Transaction struct {
-	TransactionID   int             `json:"transaction_id"`
-	UserID          int64           `json:"user_id"`
-	CategoryID      *int            `json:"category_id"`
-	Type            TransactionType `json:"type"`
-	Amount          float64         `json:"amount"`
-	Description     string          `json:"description"`
-	TransactionDate *time.Time      `json:"transaction_date"`
-	Tags            string          `json:"tags"`
-	RecurrenceRule  string          `json:"recurrence_rule"`
-	Frequency       string          `json:"frequency"`
-	Interval        int             `json:"interval"`
-	ByDay           string          `json:"by_day"`
-	Until           *time.Time      `json:"until"`
-	CreatedAt       time.Time       `json:"created_at"`
+	TransactionID   int                 `json:"transaction_id"`
+	UserID          int64               `json:"user_id"`
+	CategoryID      *int                `json:"category_id"`
+	Type            TransactionType     `json:"type"`
+	Amount          float64             `json:"amount"`
+	Description     string              `json:"description"`
+	TransactionDate *time.Time          `json:"transaction_date"`
+	Tags            string              `json:"tags"`
+	RecurrenceRule  string              `json:"recurrence_rule"`
+	Frequency       string              `json:"frequency"`
+	Interval        int                 `json:"interval"`
+	ByDay           string              `json:"by_day"`
+	Until           *time.Time          `json:"until"`
+	CreatedAt       time.Time           `json:"created_at"`
+	CreatedBy       *int64              `json:"created_by"`           // Member who recorded the transaction in a shared space
+	PaidBy          string              `json:"paid_by,omitempty"`    // Participant who paid a split expense or settlement
+	SplitType       SplitType           `json:"split_type,omitempty"` // Empty for transactions that are not split
+	Shares          []*TransactionShare `json:"shares,omitempty"`
+}
+
+// IsSplit returns true if the transaction is shared between participants
+func (t *Transaction) IsSplit() bool {
+	return t.PaidBy != ""
+}
+
+// TransactionShare is the part of a split transaction a participant owes.
+// Participants are labels such as "@username" so people without a Telegram
+// account can take part too.
+type TransactionShare struct {
+	ShareID       int     `json:"share_id"`
+	TransactionID int     `json:"transaction_id"`
+	Participant   string  `json:"participant"`
+	Amount        float64 `json:"amount"`
 }
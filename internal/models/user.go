@@ -1,6 +1,14 @@
 package models
 
+import "time"
+
 type User struct {
-	UserID   int64  `json:"user_id"`
-	UserName string `json:"user_name"`
+	UserID     int64      `json:"user_id"`
+	UserName   string     `json:"user_name"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty"` // Admitted by an admin or invite code
+	BannedAt   *time.Time `json:"banned_at,omitempty"`
+}
+
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
 }
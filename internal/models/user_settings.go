@@ -36,6 +36,10 @@ type UserSettings struct {
 	DailySummaryEnabled  bool              `json:"daily_summary_enabled"`
 	DailySummaryTime     string            `json:"daily_summary_time"` // HH:MM format
 	LastDailySummaryDate *time.Time        `json:"last_daily_summary_date"`
+	WorkStart            string            `json:"work_start"`     // HH:MM format
+	WorkEnd              string            `json:"work_end"`       // HH:MM format
+	MeetingBuffer        int               `json:"meeting_buffer"` // minutes kept free around events
+	EventAlerts          []int             `json:"event_alerts"`   // alerts of new events, minutes before
 	UpdatedAt            time.Time         `json:"updated_at"`
 }
 
@@ -53,10 +57,23 @@ func NewDefaultUserSettings(userID int64) *UserSettings {
 		DailySummaryEnabled:  true,
 		DailySummaryTime:     "08:00",
 		LastDailySummaryDate: nil,
+		WorkStart:            "08:00",
+		WorkEnd:              "22:00",
+		MeetingBuffer:        0,
+		EventAlerts:          DefaultEventAlerts,
 		UpdatedAt:            time.Now(),
 	}
 }
 
+// Location returns the user's time zone, falling back to the server's
+func (s *UserSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
 // ShouldSendDailySummary checks if it's time to send the daily summary
 func (s *UserSettings) ShouldSendDailySummary(now time.Time) bool {
 	if !s.DailySummaryEnabled {
@@ -112,6 +129,37 @@ func (s *UserSettings) IsQuietHours(t time.Time) bool {
 	return currentMinutes >= startMinutes && currentMinutes < endMinutes
 }
 
+// WorkingHours returns the start and end of the working hours on the day
+// of the given time, in its location
+func (s *UserSettings) WorkingHours(day time.Time) (start, end time.Time) {
+	startHour, startMin := parseTimeString(s.WorkStart)
+	endHour, endMin := parseTimeString(s.WorkEnd)
+	start = time.Date(day.Year(), day.Month(), day.Day(), startHour, startMin, 0, 0, day.Location())
+	end = time.Date(day.Year(), day.Month(), day.Day(), endHour, endMin, 0, 0, day.Location())
+	if !end.After(start) {
+		// Working hours can't span midnight, so treat them as ending at midnight
+		end = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	}
+	return start, end
+}
+
+// QuietHoursFrom returns the quiet hours starting on the day of the given
+// time, in its location; ok is false when quiet hours are disabled
+func (s *UserSettings) QuietHoursFrom(day time.Time) (start, end time.Time, ok bool) {
+	startHour, startMin := parseTimeString(s.QuietStart)
+	endHour, endMin := parseTimeString(s.QuietEnd)
+	if startHour == endHour && startMin == endMin {
+		return time.Time{}, time.Time{}, false
+	}
+	start = time.Date(day.Year(), day.Month(), day.Day(), startHour, startMin, 0, 0, day.Location())
+	end = time.Date(day.Year(), day.Month(), day.Day(), endHour, endMin, 0, 0, day.Location())
+	if !end.After(start) {
+		// Quiet hours span midnight
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
+
 // parseTimeString parses "HH:MM" format to hours and minutes
 func parseTimeString(timeStr string) (hour, min int) {
 	t, err := time.Parse("15:04", timeStr)
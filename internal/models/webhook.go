@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Webhook event names
+const (
+	WebhookEventTodoCompleted      = "todo.completed"
+	WebhookEventTransactionCreated = "transaction.created"
+	WebhookEventReminderFired      = "reminder.fired"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventTodoCompleted,
+	WebhookEventTransactionCreated,
+	WebhookEventReminderFired,
+}
+
+// Webhook is a user registered URL that receives signed JSON payloads
+type Webhook struct {
+	WebhookID int       `json:"webhook_id"`
+	UserID    int64     `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    string    `json:"events"` // Comma separated event names, "*" for all
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes returns true if the webhook wants to receive the given event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	WebhookDeliverySuccess WebhookDeliveryStatus = "success"
+	WebhookDeliveryFailed  WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one attempt series of sending an event to a webhook
+type WebhookDelivery struct {
+	DeliveryID     int                   `json:"delivery_id"`
+	WebhookID      int                   `json:"webhook_id"`
+	Event          string                `json:"event"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus *int                  `json:"response_status"`
+	LastError      string                `json:"last_error"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at"` // When the next retry is due, nil once finished
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
@@ -0,0 +1,156 @@
+// Package quota records AI token usage per user and enforces daily and
+// monthly token limits. Periods follow the user's time zone.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/ai"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits are token limits per user; zero means unlimited
+type Limits struct {
+	DailyTokens   int64
+	MonthlyTokens int64
+}
+
+// Period names the quota period that was exceeded
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodMonthly Period = "monthly"
+)
+
+// ExceededError reports which limit a user hit. It wraps ai.ErrQuotaExceeded.
+type ExceededError struct {
+	Period  Period
+	Used    int64
+	Limit   int64
+	ResetAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s AI quota exceeded: %d of %d tokens used", e.Period, e.Used, e.Limit)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ai.ErrQuotaExceeded
+}
+
+// Summary is a user's usage for the current day and month
+type Summary struct {
+	Today  *models.AIUsageTotal
+	Month  *models.AIUsageTotal
+	Limits Limits
+	Exempt bool
+}
+
+// Tracker implements ai.UsageTracker on top of the ai_usage table
+type Tracker struct {
+	usage    *repository.AIUsageRepository
+	settings *repository.UserSettingsRepository
+	limits   Limits
+	exempt   func(userID int64) bool
+}
+
+func New(usage *repository.AIUsageRepository, settings *repository.UserSettingsRepository, limits Limits) *Tracker {
+	return &Tracker{usage: usage, settings: settings, limits: limits}
+}
+
+// SetExempt sets a function reporting users without limits, e.g. admins
+func (t *Tracker) SetExempt(fn func(userID int64) bool) {
+	t.exempt = fn
+}
+
+// CheckQuota returns an *ExceededError when the user has used up a limit
+func (t *Tracker) CheckQuota(ctx context.Context, userID int64) error {
+	if t.limits.DailyTokens == 0 && t.limits.MonthlyTokens == 0 {
+		return nil
+	}
+	if t.isExempt(userID) {
+		return nil
+	}
+
+	dayStart, monthStart := t.periodStarts(ctx, userID, time.Now())
+
+	if t.limits.DailyTokens > 0 {
+		today, err := t.usage.GetTotal(ctx, userID, dayStart.Local())
+		if err != nil {
+			return err
+		}
+		if today.Tokens() >= t.limits.DailyTokens {
+			return &ExceededError{Period: PeriodDaily, Used: today.Tokens(), Limit: t.limits.DailyTokens, ResetAt: dayStart.AddDate(0, 0, 1)}
+		}
+	}
+	if t.limits.MonthlyTokens > 0 {
+		month, err := t.usage.GetTotal(ctx, userID, monthStart.Local())
+		if err != nil {
+			return err
+		}
+		if month.Tokens() >= t.limits.MonthlyTokens {
+			return &ExceededError{Period: PeriodMonthly, Used: month.Tokens(), Limit: t.limits.MonthlyTokens, ResetAt: monthStart.AddDate(0, 1, 0)}
+		}
+	}
+	return nil
+}
+
+// RecordUsage stores the usage of one call; failures are only logged
+func (t *Tracker) RecordUsage(ctx context.Context, u ai.Usage) {
+	usage := &models.AIUsage{
+		Call:             u.Call,
+		Model:            u.Model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Cost:             u.Cost,
+	}
+	if u.UserID != 0 {
+		usage.UserID = &u.UserID
+	}
+	if err := t.usage.Create(ctx, usage); err != nil {
+		log.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+// Summary returns the user's usage for the current day and month
+func (t *Tracker) Summary(ctx context.Context, userID int64) (*Summary, error) {
+	dayStart, monthStart := t.periodStarts(ctx, userID, time.Now())
+
+	today, err := t.usage.GetTotal(ctx, userID, dayStart.Local())
+	if err != nil {
+		return nil, err
+	}
+	month, err := t.usage.GetTotal(ctx, userID, monthStart.Local())
+	if err != nil {
+		return nil, err
+	}
+	return &Summary{Today: today, Month: month, Limits: t.limits, Exempt: t.isExempt(userID)}, nil
+}
+
+func (t *Tracker) isExempt(userID int64) bool {
+	return t.exempt != nil && t.exempt(userID)
+}
+
+// periodStarts returns the start of the current day and month in the user's
+// time zone. Stored timestamps are server local time, so queries use .Local().
+func (t *Tracker) periodStarts(ctx context.Context, userID int64, now time.Time) (time.Time, time.Time) {
+	settings, err := t.settings.GetByUserID(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to get settings for quota of %d: %v", userID, err)
+		}
+		settings = models.NewDefaultUserSettings(userID)
+	}
+
+	local := now.In(settings.Location())
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location())
+	return dayStart, monthStart
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type AIUsageRepository struct {
+	db *database.DB
+}
+
+func NewAIUsageRepository(db *database.DB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+func (r *AIUsageRepository) Create(ctx context.Context, usage *models.AIUsage) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO ai_usage (user_id, call, model, prompt_tokens, completion_tokens, cost)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING usage_id, created_at`,
+		usage.UserID, usage.Call, usage.Model, usage.PromptTokens, usage.CompletionTokens, usage.Cost,
+	).Scan(&usage.UsageID, &usage.CreatedAt)
+}
+
+// GetTotal sums a user's usage since the given time
+func (r *AIUsageRepository) GetTotal(ctx context.Context, userID int64, since time.Time) (*models.AIUsageTotal, error) {
+	total := &models.AIUsageTotal{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost), 0)::float8
+		 FROM ai_usage WHERE user_id = $1 AND created_at >= $2`,
+		userID, since,
+	).Scan(&total.Calls, &total.PromptTokens, &total.CompletionTokens, &total.Cost)
+	if err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// GetTotalsByCall sums a user's usage since the given time per call type, most tokens first
+func (r *AIUsageRepository) GetTotalsByCall(ctx context.Context, userID int64, since time.Time) ([]*models.AIUsageTotal, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT call, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(cost)::float8
+		 FROM ai_usage WHERE user_id = $1 AND created_at >= $2
+		 GROUP BY call
+		 ORDER BY SUM(prompt_tokens + completion_tokens) DESC`,
+		userID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*models.AIUsageTotal
+	for rows.Next() {
+		total := &models.AIUsageTotal{}
+		if err := rows.Scan(&total.Call, &total.Calls, &total.PromptTokens, &total.CompletionTokens, &total.Cost); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	return totals, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type APITokenRepository struct {
+	db *database.DB
+}
+
+func NewAPITokenRepository(db *database.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO api_token (user_id, name, token_hash, token_prefix, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING token_id, created_at`,
+		token.UserID, token.Name, token.TokenHash, token.TokenPrefix, token.ExpiresAt,
+	).Scan(&token.TokenID, &token.CreatedAt)
+}
+
+// GetActiveByHash returns the token with the given hash if it is not revoked or expired
+func (r *APITokenRepository) GetActiveByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT token_id, user_id, name, token_hash, token_prefix, last_used_at, expires_at, revoked_at, created_at
+		 FROM api_token
+		 WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		tokenHash,
+	).Scan(&token.TokenID, &token.UserID, &token.Name, &token.TokenHash, &token.TokenPrefix,
+		&token.LastUsedAt, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *APITokenRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.APIToken, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT token_id, user_id, name, token_hash, token_prefix, last_used_at, expires_at, revoked_at, created_at
+		 FROM api_token WHERE user_id = $1 AND revoked_at IS NULL
+		 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token := &models.APIToken{}
+		if err := rows.Scan(&token.TokenID, &token.UserID, &token.Name, &token.TokenHash, &token.TokenPrefix,
+			&token.LastUsedAt, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// Revoke marks a token as revoked. It returns false if no active token matched.
+func (r *APITokenRepository) Revoke(ctx context.Context, tokenID int, userID int64) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE api_token SET revoked_at = $1 WHERE token_id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		time.Now(), tokenID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAllForUser revokes every active token of a user and returns how many were revoked
+func (r *APITokenRepository) RevokeAllForUser(ctx context.Context, userID int64) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE api_token SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(), userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *APITokenRepository) SetLastUsedAt(ctx context.Context, tokenID int, t time.Time) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE api_token SET last_used_at = $1 WHERE token_id = $2`,
+		t, tokenID,
+	)
+	return err
+}
+
+// PurgeInactive deletes revoked tokens and tokens that expired before now
+func (r *APITokenRepository) PurgeInactive(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM api_token WHERE revoked_at IS NOT NULL OR expires_at <= $1`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -105,3 +105,27 @@ func (r *CategoryRepository) GetOrCreateByName(ctx context.Context, userID int64
 	}
 	return cat, nil
 }
+
+// GetSubcategoriesByUserID returns all subcategories under the user's categories
+func (r *CategoryRepository) GetSubcategoriesByUserID(ctx context.Context, userID int64) ([]*models.Subcategory, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT s.subcategory_id, s.category_id, s.subcategory_name, s.usage_count
+		 FROM subcategory s JOIN category c ON c.category_id = s.category_id
+		 WHERE c.user_id = $1 ORDER BY s.category_id, s.subcategory_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subcategories []*models.Subcategory
+	for rows.Next() {
+		sub := &models.Subcategory{}
+		if err := rows.Scan(&sub.SubcategoryID, &sub.CategoryID, &sub.SubcategoryName, &sub.UsageCount); err != nil {
+			return nil, err
+		}
+		subcategories = append(subcategories, sub)
+	}
+	return subcategories, nil
+}
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 type EventRepository struct {
@@ -16,21 +18,37 @@ func NewEventRepository(db *database.DB) *EventRepository {
 	return &EventRepository{db: db}
 }
 
+// eventColumns is the column list matching scanEvent
+const eventColumns = `event_id, user_id, title, description, dtstart, duration, next_occurrence,
+		 alerts, recurrence_rule, tags, alerts_sent, created_at, created_by, todo_id, all_day, transparent`
+
 func (r *EventRepository) Create(ctx context.Context, event *models.Event) error {
-	return r.db.Pool.QueryRow(ctx,
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		return insertEvent(ctx, tx, event)
+	})
+}
+
+// insertEvent inserts the event with its tags
+func insertEvent(ctx context.Context, q querier, event *models.Event) error {
+	tags := models.ParseTags(event.Tags)
+	event.Tags = models.JoinTags(tags)
+	if err := q.QueryRow(ctx,
 		`INSERT INTO event (user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 alerts, recurrence_rule, tags, alerts_sent, created_by, todo_id, all_day, transparent)
+		 VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::integer[], '{}'), $8, $9, COALESCE($10::integer[], '{}'), $11, $12, $13, $14)
 		 RETURNING event_id, created_at`,
 		event.UserID, event.Title, event.Description, event.Dtstart, event.Duration,
-		event.NextOccurrence, event.NotificationMinutes, event.RecurrenceRule, event.Tags, event.NotifiedAt,
-	).Scan(&event.EventID, &event.CreatedAt)
+		event.NextOccurrence, event.Alerts, event.RecurrenceRule, event.Tags, event.AlertsSent,
+		event.CreatedBy, event.TodoID, event.AllDay, event.Transparent,
+	).Scan(&event.EventID, &event.CreatedAt); err != nil {
+		return err
+	}
+	return setTags(ctx, q, models.EntityEvent, event.UserID, event.EventID, tags)
 }
 
 func (r *EventRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.Event, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+		`SELECT `+eventColumns+`
 		 FROM event WHERE user_id = $1
 		 ORDER BY next_occurrence ASC NULLS LAST, dtstart ASC NULLS LAST`,
 		userID,
@@ -44,27 +62,24 @@ func (r *EventRepository) GetByUserID(ctx context.Context, userID int64) ([]*mod
 }
 
 func (r *EventRepository) GetByID(ctx context.Context, eventID int, userID int64) (*models.Event, error) {
-	event := &models.Event{}
-	err := r.db.Pool.QueryRow(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+eventColumns+`
 		 FROM event WHERE event_id = $1 AND user_id = $2`,
 		eventID, userID,
-	).Scan(&event.EventID, &event.UserID, &event.Title, &event.Description, &event.Dtstart,
-		&event.Duration, &event.NextOccurrence, &event.NotificationMinutes, &event.RecurrenceRule,
-		&event.Tags, &event.NotifiedAt, &event.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return event, nil
+	)
+	return r.scanEvent(row)
 }
 
-func (r *EventRepository) GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*models.Event, error) {
+// GetInRange returns the events of userID that may take up time within
+// [start, end): one-time events overlapping it and every recurring event
+// starting before its end, whose occurrences the caller expands
+func (r *EventRepository) GetInRange(ctx context.Context, userID int64, start, end time.Time) ([]*models.Event, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
-		 FROM event WHERE user_id = $1 AND next_occurrence >= $2 AND next_occurrence <= $3
-		 ORDER BY next_occurrence ASC`,
+		`SELECT `+eventColumns+`
+		 FROM event WHERE user_id = $1 AND dtstart < $3
+		 AND (COALESCE(recurrence_rule, '') <> ''
+		      OR dtstart + make_interval(mins => CASE WHEN duration > 0 THEN duration ELSE 60 END) > $2)
+		 ORDER BY dtstart ASC`,
 		userID, start, end,
 	)
 	if err != nil {
@@ -79,8 +94,7 @@ func (r *EventRepository) GetUpcoming(ctx context.Context, userID int64, within
 	now := time.Now()
 	deadline := now.Add(within)
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+		`SELECT `+eventColumns+`
 		 FROM event WHERE user_id = $1 AND next_occurrence >= $2 AND next_occurrence <= $3
 		 ORDER BY next_occurrence ASC`,
 		userID, now, deadline,
@@ -94,38 +108,106 @@ func (r *EventRepository) GetUpcoming(ctx context.Context, userID int64, within
 }
 
 func (r *EventRepository) Update(ctx context.Context, event *models.Event) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE event SET title = $1, description = $2, dtstart = $3, duration = $4,
-		 next_occurrence = $5, notification_minutes = $6, recurrence_rule = $7, tags = $8, notified_at = $9
-		 WHERE event_id = $10 AND user_id = $11`,
-		event.Title, event.Description, event.Dtstart, event.Duration, event.NextOccurrence,
-		event.NotificationMinutes, event.RecurrenceRule, event.Tags, event.NotifiedAt,
-		event.EventID, event.UserID,
-	)
-	return err
+	tags := models.ParseTags(event.Tags)
+	event.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE event SET title = $1, description = $2, dtstart = $3, duration = $4,
+			 next_occurrence = $5, alerts = COALESCE($6::integer[], '{}'), recurrence_rule = $7, tags = $8,
+			 alerts_sent = COALESCE($9::integer[], '{}'),
+			 all_day = $10, transparent = $11
+			 WHERE event_id = $12 AND user_id = $13`,
+			event.Title, event.Description, event.Dtstart, event.Duration, event.NextOccurrence,
+			event.Alerts, event.RecurrenceRule, event.Tags, event.AlertsSent,
+			event.AllDay, event.Transparent, event.EventID, event.UserID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityEvent, event.UserID, event.EventID, tags)
+	})
 }
 
 func (r *EventRepository) UpdateNextOccurrence(ctx context.Context, eventID int, nextOccurrence *time.Time) error {
-	// Clear notified_at when updating next_occurrence to allow notification for the new occurrence
+	// Clear alerts_sent when updating next_occurrence to send the alerts of the new occurrence
 	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE event SET next_occurrence = $1, notified_at = NULL WHERE event_id = $2`,
+		`UPDATE event SET next_occurrence = $1, alerts_sent = '{}' WHERE event_id = $2`,
 		nextOccurrence, eventID,
 	)
 	return err
 }
 
-func (r *EventRepository) SetNotifiedAt(ctx context.Context, eventID int, notifiedAt *time.Time) error {
+// GetExceptions returns the exceptions of the events by event ID
+func (r *EventRepository) GetExceptions(ctx context.Context, eventIDs []int) (map[int][]*models.RecurrenceException, error) {
+	return eventExceptions.get(ctx, r.db.Pool, eventIDs)
+}
+
+// LoadExceptions sets the exceptions of the recurring events
+func (r *EventRepository) LoadExceptions(ctx context.Context, events ...*models.Event) error {
+	var ids []int
+	for _, e := range events {
+		if e.IsRecurring() {
+			ids = append(ids, e.EventID)
+		}
+	}
+	exceptions, err := r.GetExceptions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		e.Exceptions = exceptions[e.EventID]
+	}
+	return nil
+}
+
+// SaveException cancels or overrides one occurrence of an event of userID,
+// replacing an earlier exception of that occurrence
+func (r *EventRepository) SaveException(ctx context.Context, userID int64, x *models.RecurrenceException) error {
+	return eventExceptions.save(ctx, r.db.Pool, userID, x)
+}
+
+// Split ends the series of old with its recurrence rule and creates next
+// continuing it when given, handing over its exceptions from the split on.
+// Without next those exceptions are dropped along with the occurrences.
+func (r *EventRepository) Split(ctx context.Context, old, next *models.Event, from time.Time) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE event SET recurrence_rule = $1, next_occurrence = $2, alerts_sent = '{}'
+			 WHERE event_id = $3 AND user_id = $4`,
+			old.RecurrenceRule, old.NextOccurrence, old.EventID, old.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if next == nil {
+			return eventExceptions.moveFrom(ctx, tx, old.EventID, from, 0, 0)
+		}
+		if err := insertEvent(ctx, tx, next); err != nil {
+			return err
+		}
+		var shift time.Duration
+		if next.Dtstart != nil {
+			shift = next.Dtstart.Sub(from)
+		}
+		return eventExceptions.moveFrom(ctx, tx, old.EventID, from, next.EventID, shift)
+	})
+}
+
+// MarkAlertsSent records alerts of the next occurrence of an event as sent
+func (r *EventRepository) MarkAlertsSent(ctx context.Context, eventID int, alerts []int) error {
 	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE event SET notified_at = $1 WHERE event_id = $2`,
-		notifiedAt, eventID,
+		`UPDATE event SET alerts_sent = alerts_sent || $1::integer[] WHERE event_id = $2`,
+		alerts, eventID,
 	)
 	return err
 }
 
 func (r *EventRepository) GetPassedEvents(ctx context.Context, before time.Time) ([]*models.Event, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+		`SELECT `+eventColumns+`
 		 FROM event
 		 WHERE next_occurrence IS NOT NULL AND next_occurrence <= $1
 		 ORDER BY next_occurrence ASC`,
@@ -147,16 +229,20 @@ func (r *EventRepository) Delete(ctx context.Context, eventID int, userID int64)
 	return err
 }
 
-func (r *EventRepository) GetPendingNotifications(ctx context.Context) ([]*models.Event, error) {
+// GetPendingNotifications returns the events with an alert due by now that
+// wasn't sent yet. Occurrences that started after since are included so that
+// on-time alerts still fire on the first check after the event starts.
+func (r *EventRepository) GetPendingNotifications(ctx context.Context, now, since time.Time) ([]*models.Event, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+		`SELECT `+eventColumns+`
 		 FROM event
 		 WHERE next_occurrence IS NOT NULL
-		 AND next_occurrence - (notification_minutes || ' minutes')::interval <= NOW()
-		 AND next_occurrence > NOW()
-		 AND notified_at IS NULL
+		 AND next_occurrence > $2
+		 AND EXISTS (SELECT 1 FROM unnest(alerts) AS alert
+		             WHERE alert <> ALL(alerts_sent)
+		             AND next_occurrence - make_interval(mins => alert) <= $1)
 		 ORDER BY next_occurrence ASC`,
+		now, since,
 	)
 	if err != nil {
 		return nil, err
@@ -168,8 +254,7 @@ func (r *EventRepository) GetPendingNotifications(ctx context.Context) ([]*model
 
 func (r *EventRepository) Search(ctx context.Context, userID int64, keyword string) ([]*models.Event, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
+		`SELECT `+eventColumns+`
 		 FROM event WHERE user_id = $1 AND (title ILIKE $2 OR description ILIKE $2 OR tags ILIKE $2)
 		 ORDER BY next_occurrence ASC NULLS LAST, dtstart ASC NULLS LAST`,
 		userID, "%"+keyword+"%",
@@ -182,21 +267,17 @@ func (r *EventRepository) Search(ctx context.Context, userID int64, keyword stri
 	return r.scanEvents(rows)
 }
 
-func (r *EventRepository) GetTodayEvents(ctx context.Context, userID int64) ([]*models.Event, error) {
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
+// Filter returns the events matching f
+func (r *EventRepository) Filter(ctx context.Context, userID int64, f *filter.Filter) ([]*models.Event, error) {
+	where, args, err := eventFilterColumns.where(f, []any{userID})
+	if err != nil {
+		return nil, err
+	}
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT event_id, user_id, title, description, dtstart, duration, next_occurrence,
-		 notification_minutes, recurrence_rule, tags, notified_at, created_at
-		 FROM event WHERE user_id = $1
-		 AND (
-		   (next_occurrence >= $2 AND next_occurrence < $3)
-		   OR (next_occurrence IS NULL AND dtstart >= $2 AND dtstart < $3)
-		 )
-		 ORDER BY COALESCE(next_occurrence, dtstart) ASC`,
-		userID, startOfDay, endOfDay,
+		`SELECT `+eventColumns+`
+		 FROM event WHERE user_id = $1`+where+`
+		 ORDER BY next_occurrence ASC NULLS LAST, dtstart ASC NULLS LAST`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
@@ -206,16 +287,67 @@ func (r *EventRepository) GetTodayEvents(ctx context.Context, userID int64) ([]*
 	return r.scanEvents(rows)
 }
 
+// LinkTodo makes an event of userID a time block of the todo
+func (r *EventRepository) LinkTodo(ctx context.Context, eventID int, userID int64, todoID int) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE event SET todo_id = $1
+		 WHERE event_id = $2 AND user_id = $3 AND EXISTS(SELECT 1 FROM todo WHERE todo_id = $1 AND user_id = $3)`,
+		todoID, eventID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetScheduledTodoIDs returns the todos of userID that have a time block
+// starting at or after from
+func (r *EventRepository) GetScheduledTodoIDs(ctx context.Context, userID int64, from time.Time) ([]int, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT DISTINCT todo_id FROM event
+		 WHERE user_id = $1 AND todo_id IS NOT NULL AND COALESCE(next_occurrence, dtstart) >= $2`,
+		userID, from,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *EventRepository) scanEvent(row interface {
+	Scan(dest ...any) error
+}) (*models.Event, error) {
+	event := &models.Event{}
+	if err := row.Scan(&event.EventID, &event.UserID, &event.Title, &event.Description,
+		&event.Dtstart, &event.Duration, &event.NextOccurrence, &event.Alerts,
+		&event.RecurrenceRule, &event.Tags, &event.AlertsSent, &event.CreatedAt, &event.CreatedBy, &event.TodoID,
+		&event.AllDay, &event.Transparent); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
 func (r *EventRepository) scanEvents(rows interface {
 	Next() bool
 	Scan(dest ...any) error
 }) ([]*models.Event, error) {
 	var events []*models.Event
 	for rows.Next() {
-		event := &models.Event{}
-		if err := rows.Scan(&event.EventID, &event.UserID, &event.Title, &event.Description,
-			&event.Dtstart, &event.Duration, &event.NextOccurrence, &event.NotificationMinutes,
-			&event.RecurrenceRule, &event.Tags, &event.NotifiedAt, &event.CreatedAt); err != nil {
+		event, err := r.scanEvent(rows)
+		if err != nil {
 			return nil, err
 		}
 		events = append(events, event)
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// exceptionTable is the table of recurrence exceptions of events or reminders
+type exceptionTable struct {
+	table    string
+	parentID string // column referencing the series
+	parents  string // table of the series
+	ownerID  string // primary key column of the series table
+}
+
+var (
+	eventExceptions    = exceptionTable{table: "event_exception", parentID: "event_id", parents: "event", ownerID: "event_id"}
+	reminderExceptions = exceptionTable{table: "reminder_exception", parentID: "reminder_id", parents: "reminders", ownerID: "reminders_id"}
+)
+
+// get returns the exceptions of the series by series ID, ordered by original start
+func (t exceptionTable) get(ctx context.Context, q querier, parentIDs []int) (map[int][]*models.RecurrenceException, error) {
+	exceptions := make(map[int][]*models.RecurrenceException)
+	if len(parentIDs) == 0 {
+		return exceptions, nil
+	}
+	rows, err := q.Query(ctx,
+		`SELECT exception_id, `+t.parentID+`, original_start, cancelled, start_time, duration, title, created_at
+		 FROM `+t.table+` WHERE `+t.parentID+` = ANY($1)
+		 ORDER BY original_start ASC`,
+		parentIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		x := &models.RecurrenceException{}
+		if err := rows.Scan(&x.ExceptionID, &x.ParentID, &x.OriginalStart, &x.Cancelled, &x.Start,
+			&x.Duration, &x.Title, &x.CreatedAt); err != nil {
+			return nil, err
+		}
+		exceptions[x.ParentID] = append(exceptions[x.ParentID], x)
+	}
+	return exceptions, rows.Err()
+}
+
+// save inserts the exception of a series of userID, replacing an earlier
+// exception of the same occurrence; it returns pgx.ErrNoRows when the
+// series doesn't belong to userID
+func (t exceptionTable) save(ctx context.Context, q querier, userID int64, x *models.RecurrenceException) error {
+	return q.QueryRow(ctx,
+		`INSERT INTO `+t.table+` (`+t.parentID+`, original_start, cancelled, start_time, duration, title)
+		 SELECT $1, $2, $3, $4, $5, $6
+		 WHERE EXISTS(SELECT 1 FROM `+t.parents+` WHERE `+t.ownerID+` = $1 AND user_id = $7)
+		 ON CONFLICT (`+t.parentID+`, original_start) DO UPDATE SET
+		     cancelled = EXCLUDED.cancelled, start_time = EXCLUDED.start_time,
+		     duration = EXCLUDED.duration, title = EXCLUDED.title
+		 RETURNING exception_id, created_at`,
+		x.ParentID, x.OriginalStart, x.Cancelled, x.Start, x.Duration, x.Title, userID,
+	).Scan(&x.ExceptionID, &x.CreatedAt)
+}
+
+// moveFrom hands the exceptions of the series' occurrences starting at or
+// after from over to the series toID that continues it, shifting their
+// original starts by shift when the new series starts at another time. With
+// toID 0 the series ends at from and the exceptions are deleted.
+func (t exceptionTable) moveFrom(ctx context.Context, q querier, parentID int, from time.Time, toID int, shift time.Duration) error {
+	if toID == 0 {
+		_, err := q.Exec(ctx,
+			`DELETE FROM `+t.table+` WHERE `+t.parentID+` = $1 AND original_start >= $2`,
+			parentID, from,
+		)
+		return err
+	}
+	_, err := q.Exec(ctx,
+		`UPDATE `+t.table+` SET `+t.parentID+` = $3,
+		     original_start = original_start + make_interval(secs => $4)
+		 WHERE `+t.parentID+` = $1 AND original_start >= $2`,
+		parentID, from, toID, shift.Seconds(),
+	)
+	return err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hray3182/LifeLine/internal/filter"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// filterColumns maps the filter fields a table supports to SQL expressions.
+// The done and blocked fields map to boolean expressions, the tag field to a condition
+// with a %s placeholder for the tag name, every other field to a value.
+type filterColumns map[string]string
+
+var (
+	todoFilterColumns = filterColumns{
+		filter.FieldText:     todoDocument,
+		filter.FieldTag:      hasTag(models.EntityTodo),
+		filter.FieldDone:     "completed_at IS NOT NULL",
+		filter.FieldPriority: "priority",
+		filter.FieldDate:     "due_time",
+		filter.FieldProject:  "(SELECT name FROM project p WHERE p.project_id = todo.project_id)",
+		filter.FieldBlocked:  "cardinality(" + openBlockers + ") > 0",
+		filter.FieldStatus:   "status",
+	}
+	eventFilterColumns = filterColumns{
+		filter.FieldText: eventDocument,
+		filter.FieldTag:  hasTag(models.EntityEvent),
+		filter.FieldDate: "COALESCE(next_occurrence, dtstart)",
+	}
+	memoFilterColumns = filterColumns{
+		filter.FieldText: memoDocument,
+		filter.FieldTag:  hasTag(models.EntityMemo),
+		filter.FieldDate: "created_at",
+	}
+	reminderFilterColumns = filterColumns{
+		filter.FieldText: reminderDocument,
+		filter.FieldTag:  hasTag(models.EntityReminder),
+		filter.FieldDone: "NOT enabled",
+		filter.FieldDate: "remind_at",
+	}
+	transactionFilterColumns = filterColumns{
+		filter.FieldText:     transactionDocument,
+		filter.FieldTag:      hasTag(models.EntityTransaction),
+		filter.FieldDate:     "transaction_date",
+		filter.FieldAmount:   "amount",
+		filter.FieldCategory: "(SELECT category_name FROM category c WHERE c.category_id = transaction.category_id)",
+		filter.FieldType:     "type",
+	}
+)
+
+// where turns the filter into " AND ..." conditions, numbering placeholders
+// after the given args
+func (c filterColumns) where(f *filter.Filter, args []any) (string, []any, error) {
+	if f == nil {
+		return "", args, nil
+	}
+
+	var sb strings.Builder
+	for _, cond := range f.Conditions {
+		expr, ok := c[cond.Field]
+		if !ok {
+			return "", nil, &filter.UnsupportedError{Field: cond.Field}
+		}
+
+		next := func(v any) string {
+			args = append(args, v)
+			return fmt.Sprintf("$%d", len(args))
+		}
+
+		var clause string
+		switch cond.Field {
+		case filter.FieldDone, filter.FieldBlocked:
+			clause = expr
+		case filter.FieldText:
+			clause = expr + " ILIKE " + next(likePattern(cond.Text))
+		case filter.FieldTag:
+			// The tag column is a condition on the tag name, with or without #
+			clause = fmt.Sprintf(expr, next(strings.TrimPrefix(cond.Text, "#")))
+		case filter.FieldCategory, filter.FieldType, filter.FieldProject, filter.FieldStatus:
+			clause = expr + " ILIKE " + next(escapeLike(cond.Text))
+		case filter.FieldPriority, filter.FieldAmount:
+			clause = fmt.Sprintf("%s %s %s", expr, cond.Op, next(cond.Number))
+		case filter.FieldDate:
+			// A date stands for the whole day: "<" is before it, "<=" until its end
+			start, end := cond.Date.Local(), cond.DayEnd().Local()
+			switch cond.Op {
+			case filter.OpLt:
+				clause = expr + " < " + next(start)
+			case filter.OpLte:
+				clause = expr + " < " + next(end)
+			case filter.OpGt:
+				clause = expr + " >= " + next(end)
+			case filter.OpGte:
+				clause = expr + " >= " + next(start)
+			default:
+				clause = expr + " >= " + next(start) + " AND " + expr + " < " + next(end)
+			}
+		}
+
+		if cond.Negate {
+			// Rows without a value match a negated condition
+			sb.WriteString(" AND NOT COALESCE((" + clause + "), FALSE)")
+		} else {
+			sb.WriteString(" AND (" + clause + ")")
+		}
+	}
+	return sb.String(), args, nil
+}
+
+// escapeLike escapes the ILIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type InviteRepository struct {
+	db *database.DB
+}
+
+func NewInviteRepository(db *database.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) Create(ctx context.Context, invite *models.InviteCode) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO invite_code (code, created_by, max_uses, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING created_at`,
+		invite.Code, invite.CreatedBy, invite.MaxUses, invite.ExpiresAt,
+	).Scan(&invite.CreatedAt)
+}
+
+// Redeem uses up one use of the code and approves the user. It returns
+// pgx.ErrNoRows when the code does not exist, is used up or has expired.
+func (r *InviteRepository) Redeem(ctx context.Context, code string, userID int64, userName string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE invite_code SET uses = uses + 1
+		 WHERE code = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > NOW())`,
+		code,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO "user" (user_id, user_name, approved_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (user_id) DO UPDATE SET approved_at = COALESCE("user".approved_at, NOW())`,
+		userID, userName,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
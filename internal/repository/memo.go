@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 type MemoRepository struct {
@@ -16,16 +19,23 @@ func NewMemoRepository(db *database.DB) *MemoRepository {
 }
 
 func (r *MemoRepository) Create(ctx context.Context, memo *models.Memo) error {
-	return r.db.Pool.QueryRow(ctx,
-		`INSERT INTO memo (user_id, content, tags) VALUES ($1, $2, $3)
-		 RETURNING memo_id, created_at`,
-		memo.UserID, memo.Content, memo.Tags,
-	).Scan(&memo.MemoID, &memo.CreatedAt)
+	tags := models.ParseTags(memo.Tags)
+	memo.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO memo (user_id, content, tags, created_by) VALUES ($1, $2, $3, $4)
+			 RETURNING memo_id, created_at`,
+			memo.UserID, memo.Content, memo.Tags, memo.CreatedBy,
+		).Scan(&memo.MemoID, &memo.CreatedAt); err != nil {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityMemo, memo.UserID, memo.MemoID, tags)
+	})
 }
 
 func (r *MemoRepository) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]*models.Memo, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT memo_id, user_id, content, tags, created_at
+		`SELECT memo_id, user_id, content, tags, created_at, created_by
 		 FROM memo WHERE user_id = $1
 		 ORDER BY created_at DESC LIMIT $2 OFFSET $3`,
 		userID, limit, offset,
@@ -38,7 +48,60 @@ func (r *MemoRepository) GetByUserID(ctx context.Context, userID int64, limit, o
 	var memos []*models.Memo
 	for rows.Next() {
 		memo := &models.Memo{}
-		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt); err != nil {
+		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt, &memo.CreatedBy); err != nil {
+			return nil, err
+		}
+		memos = append(memos, memo)
+	}
+	return memos, nil
+}
+
+// GetAllByUserID returns every memo of the user without pagination
+func (r *MemoRepository) GetAllByUserID(ctx context.Context, userID int64) ([]*models.Memo, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT memo_id, user_id, content, tags, created_at, created_by
+		 FROM memo WHERE user_id = $1
+		 ORDER BY created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memos []*models.Memo
+	for rows.Next() {
+		memo := &models.Memo{}
+		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt, &memo.CreatedBy); err != nil {
+			return nil, err
+		}
+		memos = append(memos, memo)
+	}
+	return memos, nil
+}
+
+// Filter returns the latest memos matching f
+func (r *MemoRepository) Filter(ctx context.Context, userID int64, f *filter.Filter, limit int) ([]*models.Memo, error) {
+	where, args, err := memoFilterColumns.where(f, []any{userID})
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, limit)
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT memo_id, user_id, content, tags, created_at, created_by
+		 FROM memo WHERE user_id = $1`+where+`
+		 ORDER BY created_at DESC LIMIT $`+strconv.Itoa(len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memos []*models.Memo
+	for rows.Next() {
+		memo := &models.Memo{}
+		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt, &memo.CreatedBy); err != nil {
 			return nil, err
 		}
 		memos = append(memos, memo)
@@ -49,10 +112,10 @@ func (r *MemoRepository) GetByUserID(ctx context.Context, userID int64, limit, o
 func (r *MemoRepository) GetByID(ctx context.Context, memoID int, userID int64) (*models.Memo, error) {
 	memo := &models.Memo{}
 	err := r.db.Pool.QueryRow(ctx,
-		`SELECT memo_id, user_id, content, tags, created_at
+		`SELECT memo_id, user_id, content, tags, created_at, created_by
 		 FROM memo WHERE memo_id = $1 AND user_id = $2`,
 		memoID, userID,
-	).Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt)
+	).Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt, &memo.CreatedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -60,11 +123,18 @@ func (r *MemoRepository) GetByID(ctx context.Context, memoID int, userID int64)
 }
 
 func (r *MemoRepository) Update(ctx context.Context, memo *models.Memo) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE memo SET content = $1, tags = $2 WHERE memo_id = $3 AND user_id = $4`,
-		memo.Content, memo.Tags, memo.MemoID, memo.UserID,
-	)
-	return err
+	tags := models.ParseTags(memo.Tags)
+	memo.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE memo SET content = $1, tags = $2 WHERE memo_id = $3 AND user_id = $4`,
+			memo.Content, memo.Tags, memo.MemoID, memo.UserID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityMemo, memo.UserID, memo.MemoID, tags)
+	})
 }
 
 func (r *MemoRepository) Delete(ctx context.Context, memoID int, userID int64) error {
@@ -77,7 +147,7 @@ func (r *MemoRepository) Delete(ctx context.Context, memoID int, userID int64) e
 
 func (r *MemoRepository) Search(ctx context.Context, userID int64, keyword string) ([]*models.Memo, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT memo_id, user_id, content, tags, created_at
+		`SELECT memo_id, user_id, content, tags, created_at, created_by
 		 FROM memo WHERE user_id = $1 AND (content ILIKE $2 OR tags ILIKE $2)
 		 ORDER BY created_at DESC`,
 		userID, "%"+keyword+"%",
@@ -90,7 +160,7 @@ func (r *MemoRepository) Search(ctx context.Context, userID int64, keyword strin
 	var memos []*models.Memo
 	for rows.Next() {
 		memo := &models.Memo{}
-		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt); err != nil {
+		if err := rows.Scan(&memo.MemoID, &memo.UserID, &memo.Content, &memo.Tags, &memo.CreatedAt, &memo.CreatedBy); err != nil {
 			return nil, err
 		}
 		memos = append(memos, memo)
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type ProjectRepository struct {
+	db *database.DB
+}
+
+func NewProjectRepository(db *database.DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+func (r *ProjectRepository) Create(ctx context.Context, p *models.Project) error {
+	p.DefaultTags = models.JoinTags(models.ParseTags(p.DefaultTags))
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO project (user_id, name, color, default_tags, created_by)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING project_id, created_at`,
+		p.UserID, p.Name, p.Color, p.DefaultTags, p.CreatedBy,
+	).Scan(&p.ProjectID, &p.CreatedAt)
+}
+
+func (r *ProjectRepository) GetByID(ctx context.Context, projectID int, userID int64) (*models.Project, error) {
+	p := &models.Project{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT project_id, user_id, name, color, default_tags, created_by, created_at
+		 FROM project WHERE project_id = $1 AND user_id = $2`,
+		projectID, userID,
+	).Scan(&p.ProjectID, &p.UserID, &p.Name, &p.Color, &p.DefaultTags, &p.CreatedBy, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetByName looks a project up case-insensitively
+func (r *ProjectRepository) GetByName(ctx context.Context, userID int64, name string) (*models.Project, error) {
+	p := &models.Project{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT project_id, user_id, name, color, default_tags, created_by, created_at
+		 FROM project WHERE user_id = $1 AND LOWER(name) = LOWER($2)`,
+		userID, name,
+	).Scan(&p.ProjectID, &p.UserID, &p.Name, &p.Color, &p.DefaultTags, &p.CreatedBy, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetByUserID returns the projects with their open and completed todo counts
+func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.Project, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT p.project_id, p.user_id, p.name, p.color, p.default_tags, p.created_by, p.created_at,
+		        COUNT(t.todo_id) FILTER (WHERE t.completed_at IS NULL),
+		        COUNT(t.todo_id) FILTER (WHERE t.completed_at IS NOT NULL)
+		 FROM project p LEFT JOIN todo t ON t.project_id = p.project_id
+		 WHERE p.user_id = $1
+		 GROUP BY p.project_id
+		 ORDER BY LOWER(p.name) ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*models.Project
+	for rows.Next() {
+		p := &models.Project{}
+		if err := rows.Scan(&p.ProjectID, &p.UserID, &p.Name, &p.Color, &p.DefaultTags, &p.CreatedBy, &p.CreatedAt,
+			&p.OpenTodos, &p.DoneTodos); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// Update saves the name, color and default tags
+func (r *ProjectRepository) Update(ctx context.Context, p *models.Project) error {
+	p.DefaultTags = models.JoinTags(models.ParseTags(p.DefaultTags))
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE project SET name = $1, color = $2, default_tags = $3 WHERE project_id = $4 AND user_id = $5`,
+		p.Name, p.Color, p.DefaultTags, p.ProjectID, p.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes the project; its todos are kept without a project
+func (r *ProjectRepository) Delete(ctx context.Context, projectID int, userID int64) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM project WHERE project_id = $1 AND user_id = $2`,
+		projectID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
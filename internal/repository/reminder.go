@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 type ReminderRepository struct {
@@ -17,18 +19,31 @@ func NewReminderRepository(db *database.DB) *ReminderRepository {
 }
 
 func (r *ReminderRepository) Create(ctx context.Context, reminder *models.Reminder) error {
-	return r.db.Pool.QueryRow(ctx,
-		`INSERT INTO reminders (user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		return insertReminder(ctx, tx, reminder)
+	})
+}
+
+// insertReminder inserts the reminder with its tags
+func insertReminder(ctx context.Context, q querier, reminder *models.Reminder) error {
+	tags := models.ParseTags(reminder.Tags)
+	reminder.Tags = models.JoinTags(tags)
+	if err := q.QueryRow(ctx,
+		`INSERT INTO reminders (user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		 RETURNING reminders_id, created_at`,
 		reminder.UserID, reminder.Enabled, reminder.RecurrenceRule, reminder.Dtstart, reminder.Messages,
 		reminder.RemindAt, reminder.Description, reminder.Tags, reminder.NotifiedAt, reminder.AcknowledgedAt, reminder.LastMessageID,
-	).Scan(&reminder.ReminderID, &reminder.CreatedAt)
+		reminder.CreatedBy,
+	).Scan(&reminder.ReminderID, &reminder.CreatedAt); err != nil {
+		return err
+	}
+	return setTags(ctx, q, models.EntityReminder, reminder.UserID, reminder.ReminderID, tags)
 }
 
 func (r *ReminderRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.Reminder, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
 		 FROM reminders WHERE user_id = $1 ORDER BY remind_at ASC NULLS LAST`,
 		userID,
 	)
@@ -41,7 +56,7 @@ func (r *ReminderRepository) GetByUserID(ctx context.Context, userID int64) ([]*
 	for rows.Next() {
 		reminder := &models.Reminder{}
 		if err := rows.Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
-			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt); err != nil {
+			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy); err != nil {
 			return nil, err
 		}
 		reminders = append(reminders, reminder)
@@ -52,11 +67,11 @@ func (r *ReminderRepository) GetByUserID(ctx context.Context, userID int64) ([]*
 func (r *ReminderRepository) GetByID(ctx context.Context, reminderID int, userID int64) (*models.Reminder, error) {
 	reminder := &models.Reminder{}
 	err := r.db.Pool.QueryRow(ctx,
-		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
 		 FROM reminders WHERE reminders_id = $1 AND user_id = $2`,
 		reminderID, userID,
 	).Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
-		&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt)
+		&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -66,11 +81,11 @@ func (r *ReminderRepository) GetByID(ctx context.Context, reminderID int, userID
 func (r *ReminderRepository) GetByIDOnly(ctx context.Context, reminderID int) (*models.Reminder, error) {
 	reminder := &models.Reminder{}
 	err := r.db.Pool.QueryRow(ctx,
-		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
 		 FROM reminders WHERE reminders_id = $1`,
 		reminderID,
 	).Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
-		&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt)
+		&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -78,13 +93,20 @@ func (r *ReminderRepository) GetByIDOnly(ctx context.Context, reminderID int) (*
 }
 
 func (r *ReminderRepository) Update(ctx context.Context, reminder *models.Reminder) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE reminders SET enabled = $1, recurrence_rule = $2, dtstart = $3, messages = $4, remind_at = $5, description = $6, tags = $7, notified_at = $8, acknowledged_at = $9, last_message_id = $10
-		 WHERE reminders_id = $11 AND user_id = $12`,
-		reminder.Enabled, reminder.RecurrenceRule, reminder.Dtstart, reminder.Messages, reminder.RemindAt,
-		reminder.Description, reminder.Tags, reminder.NotifiedAt, reminder.AcknowledgedAt, reminder.LastMessageID, reminder.ReminderID, reminder.UserID,
-	)
-	return err
+	tags := models.ParseTags(reminder.Tags)
+	reminder.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE reminders SET enabled = $1, recurrence_rule = $2, dtstart = $3, messages = $4, remind_at = $5, description = $6, tags = $7, notified_at = $8, acknowledged_at = $9, last_message_id = $10
+			 WHERE reminders_id = $11 AND user_id = $12`,
+			reminder.Enabled, reminder.RecurrenceRule, reminder.Dtstart, reminder.Messages, reminder.RemindAt,
+			reminder.Description, reminder.Tags, reminder.NotifiedAt, reminder.AcknowledgedAt, reminder.LastMessageID, reminder.ReminderID, reminder.UserID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityReminder, reminder.UserID, reminder.ReminderID, tags)
+	})
 }
 
 func (r *ReminderRepository) UpdateRemindAt(ctx context.Context, reminderID int, remindAt *time.Time) error {
@@ -120,6 +142,65 @@ func (r *ReminderRepository) SetAcknowledgedAt(ctx context.Context, reminderID i
 	return err
 }
 
+// GetExceptions returns the exceptions of the reminders by reminder ID
+func (r *ReminderRepository) GetExceptions(ctx context.Context, reminderIDs []int) (map[int][]*models.RecurrenceException, error) {
+	return reminderExceptions.get(ctx, r.db.Pool, reminderIDs)
+}
+
+// LoadExceptions sets the exceptions of the recurring reminders
+func (r *ReminderRepository) LoadExceptions(ctx context.Context, reminders ...*models.Reminder) error {
+	var ids []int
+	for _, reminder := range reminders {
+		if reminder.IsRecurring() {
+			ids = append(ids, reminder.ReminderID)
+		}
+	}
+	exceptions, err := r.GetExceptions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		reminder.Exceptions = exceptions[reminder.ReminderID]
+	}
+	return nil
+}
+
+// SaveException cancels or overrides one occurrence of a reminder of
+// userID, replacing an earlier exception of that occurrence
+func (r *ReminderRepository) SaveException(ctx context.Context, userID int64, x *models.RecurrenceException) error {
+	return reminderExceptions.save(ctx, r.db.Pool, userID, x)
+}
+
+// Split ends the series of old with its recurrence rule and creates next
+// continuing it when given, handing over its exceptions from the split on.
+// Without next those exceptions are dropped along with the occurrences.
+func (r *ReminderRepository) Split(ctx context.Context, old, next *models.Reminder, from time.Time) error {
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE reminders SET recurrence_rule = $1, remind_at = $2, enabled = $3, notified_at = NULL, acknowledged_at = NULL
+			 WHERE reminders_id = $4 AND user_id = $5`,
+			old.RecurrenceRule, old.RemindAt, old.Enabled, old.ReminderID, old.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if next == nil {
+			return reminderExceptions.moveFrom(ctx, tx, old.ReminderID, from, 0, 0)
+		}
+		if err := insertReminder(ctx, tx, next); err != nil {
+			return err
+		}
+		var shift time.Duration
+		if next.Dtstart != nil {
+			shift = next.Dtstart.Sub(from)
+		}
+		return reminderExceptions.moveFrom(ctx, tx, old.ReminderID, from, next.ReminderID, shift)
+	})
+}
+
 func (r *ReminderRepository) Delete(ctx context.Context, reminderID int, userID int64) error {
 	_, err := r.db.Pool.Exec(ctx,
 		`DELETE FROM reminders WHERE reminders_id = $1 AND user_id = $2`,
@@ -135,7 +216,7 @@ func (r *ReminderRepository) GetPendingReminders(ctx context.Context, until time
 	// 3. Are NOT acknowledged yet
 	// 4. Either never notified OR notified more than 1 minute ago (cooldown)
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
 		 FROM reminders
 		 WHERE enabled = true
 		 AND remind_at IS NOT NULL
@@ -154,7 +235,7 @@ func (r *ReminderRepository) GetPendingReminders(ctx context.Context, until time
 	for rows.Next() {
 		reminder := &models.Reminder{}
 		if err := rows.Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
-			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt); err != nil {
+			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy); err != nil {
 			return nil, err
 		}
 		reminders = append(reminders, reminder)
@@ -172,7 +253,7 @@ func (r *ReminderRepository) SetEnabled(ctx context.Context, reminderID int, use
 
 func (r *ReminderRepository) Search(ctx context.Context, userID int64, keyword string) ([]*models.Reminder, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
 		 FROM reminders WHERE user_id = $1 AND (messages ILIKE $2 OR description ILIKE $2 OR tags ILIKE $2)
 		 ORDER BY remind_at ASC NULLS LAST`,
 		userID, "%"+keyword+"%",
@@ -186,7 +267,36 @@ func (r *ReminderRepository) Search(ctx context.Context, userID int64, keyword s
 	for rows.Next() {
 		reminder := &models.Reminder{}
 		if err := rows.Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
-			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt); err != nil {
+			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+// Filter returns the reminders matching f; done matches disabled reminders
+func (r *ReminderRepository) Filter(ctx context.Context, userID int64, f *filter.Filter) ([]*models.Reminder, error) {
+	where, args, err := reminderFilterColumns.where(f, []any{userID})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT reminders_id, user_id, enabled, recurrence_rule, dtstart, messages, remind_at, description, tags, notified_at, acknowledged_at, last_message_id, created_at, created_by
+		 FROM reminders WHERE user_id = $1`+where+`
+		 ORDER BY remind_at ASC NULLS LAST`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*models.Reminder
+	for rows.Next() {
+		reminder := &models.Reminder{}
+		if err := rows.Scan(&reminder.ReminderID, &reminder.UserID, &reminder.Enabled, &reminder.RecurrenceRule,
+			&reminder.Dtstart, &reminder.Messages, &reminder.RemindAt, &reminder.Description, &reminder.Tags, &reminder.NotifiedAt, &reminder.AcknowledgedAt, &reminder.LastMessageID, &reminder.CreatedAt, &reminder.CreatedBy); err != nil {
 			return nil, err
 		}
 		reminders = append(reminders, reminder)
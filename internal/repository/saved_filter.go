@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type SavedFilterRepository struct {
+	db *database.DB
+}
+
+func NewSavedFilterRepository(db *database.DB) *SavedFilterRepository {
+	return &SavedFilterRepository{db: db}
+}
+
+// Save creates the filter or replaces the one with the same name
+func (r *SavedFilterRepository) Save(ctx context.Context, f *models.SavedFilter) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO saved_filter (user_id, name, target, query, created_by)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (user_id, name) DO UPDATE
+		 SET target = EXCLUDED.target, query = EXCLUDED.query, created_by = EXCLUDED.created_by
+		 RETURNING filter_id, created_at`,
+		f.UserID, f.Name, f.Target, f.Query, f.CreatedBy,
+	).Scan(&f.FilterID, &f.CreatedAt)
+}
+
+// GetByName looks a filter up case-insensitively
+func (r *SavedFilterRepository) GetByName(ctx context.Context, userID int64, name string) (*models.SavedFilter, error) {
+	f := &models.SavedFilter{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT filter_id, user_id, name, target, query, created_by, created_at
+		 FROM saved_filter WHERE user_id = $1 AND LOWER(name) = LOWER($2)`,
+		userID, name,
+	).Scan(&f.FilterID, &f.UserID, &f.Name, &f.Target, &f.Query, &f.CreatedBy, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *SavedFilterRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.SavedFilter, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT filter_id, user_id, name, target, query, created_by, created_at
+		 FROM saved_filter WHERE user_id = $1
+		 ORDER BY name ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []*models.SavedFilter
+	for rows.Next() {
+		f := &models.SavedFilter{}
+		if err := rows.Scan(&f.FilterID, &f.UserID, &f.Name, &f.Target, &f.Query, &f.CreatedBy, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// Delete returns pgx.ErrNoRows when there is no filter with that name
+func (r *SavedFilterRepository) Delete(ctx context.Context, userID int64, name string) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM saved_filter WHERE user_id = $1 AND LOWER(name) = LOWER($2)`,
+		userID, name,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
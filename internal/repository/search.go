@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+// Searchable text of each entity. These must stay identical to the
+// expressions of the trigram indexes in migration 014, or the indexes are not used.
+const (
+	memoDocument        = `(COALESCE(content, '') || ' ' || COALESCE(tags, ''))`
+	todoDocument        = `(COALESCE(title, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, ''))`
+	reminderDocument    = `(COALESCE(messages, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, ''))`
+	eventDocument       = `(COALESCE(title, '') || ' ' || COALESCE(description, '') || ' ' || COALESCE(tags, ''))`
+	transactionDocument = `(COALESCE(description, '') || ' ' || COALESCE(tags, ''))`
+)
+
+type SearchRepository struct {
+	db *database.DB
+}
+
+func NewSearchRepository(db *database.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// Search finds memos, todos, reminders, events and transactions of the user
+// that contain the query or closely resemble it, best matches first. A
+// substring match ranks above a fuzzy one, and a match in the title above a
+// match in the description or tags.
+func (r *SearchRepository) Search(ctx context.Context, userID int64, query string, limit int) ([]*models.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT kind, id, title, amount, date, done, rank FROM (
+		   SELECT 'memo' AS kind, memo_id AS id, COALESCE(content, '') AS title, 0::float8 AS amount,
+		          created_at AS date, FALSE AS done,
+		          `+searchRank(memoDocument, "content")+` AS rank
+		   FROM memo WHERE user_id = $1 AND `+searchMatch(memoDocument)+`
+		   UNION ALL
+		   SELECT 'todo', todo_id, COALESCE(title, ''), 0,
+		          due_time, completed_at IS NOT NULL,
+		          `+searchRank(todoDocument, "title")+`
+		   FROM todo WHERE user_id = $1 AND `+searchMatch(todoDocument)+`
+		   UNION ALL
+		   SELECT 'reminder', reminders_id, COALESCE(messages, ''), 0,
+		          remind_at, NOT COALESCE(enabled, TRUE),
+		          `+searchRank(reminderDocument, "messages")+`
+		   FROM reminders WHERE user_id = $1 AND `+searchMatch(reminderDocument)+`
+		   UNION ALL
+		   SELECT 'event', event_id, COALESCE(title, ''), 0,
+		          COALESCE(next_occurrence, dtstart), FALSE,
+		          `+searchRank(eventDocument, "title")+`
+		   FROM event WHERE user_id = $1 AND `+searchMatch(eventDocument)+`
+		   UNION ALL
+		   SELECT 'transaction', transaction_id, COALESCE(description, ''), COALESCE(amount, 0)::float8,
+		          transaction_date::timestamp, FALSE,
+		          `+searchRank(transactionDocument, "description")+`
+		   FROM transaction WHERE user_id = $1 AND `+searchMatch(transactionDocument)+`
+		 ) results
+		 ORDER BY rank DESC, date DESC NULLS LAST
+		 LIMIT $4`,
+		userID, query, likePattern(query), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.SearchResult
+	for rows.Next() {
+		result := &models.SearchResult{}
+		if err := rows.Scan(&result.Kind, &result.ID, &result.Title, &result.Amount, &result.Date, &result.Done, &result.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ByTag returns the items of the user with the tag, most recent first
+func (r *SearchRepository) ByTag(ctx context.Context, userID int64, tagID int, limit int) ([]*models.SearchResult, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT kind, id, title, amount, date, done FROM (
+		   SELECT 'memo' AS kind, memo_id AS id, COALESCE(content, '') AS title, 0::float8 AS amount,
+		          created_at AS date, FALSE AS done
+		   FROM memo JOIN memo_tag USING (memo_id) WHERE user_id = $1 AND tag_id = $2
+		   UNION ALL
+		   SELECT 'todo', todo_id, COALESCE(title, ''), 0, due_time, completed_at IS NOT NULL
+		   FROM todo JOIN todo_tag USING (todo_id) WHERE user_id = $1 AND tag_id = $2
+		   UNION ALL
+		   SELECT 'reminder', reminders_id, COALESCE(messages, ''), 0, remind_at, NOT COALESCE(enabled, TRUE)
+		   FROM reminders JOIN reminder_tag USING (reminders_id) WHERE user_id = $1 AND tag_id = $2
+		   UNION ALL
+		   SELECT 'event', event_id, COALESCE(title, ''), 0, COALESCE(next_occurrence, dtstart), FALSE
+		   FROM event JOIN event_tag USING (event_id) WHERE user_id = $1 AND tag_id = $2
+		   UNION ALL
+		   SELECT 'transaction', transaction_id, COALESCE(description, ''), COALESCE(amount, 0)::float8,
+		          transaction_date::timestamp, FALSE
+		   FROM transaction JOIN transaction_tag USING (transaction_id) WHERE user_id = $1 AND tag_id = $2
+		 ) results
+		 ORDER BY done ASC, date DESC NULLS LAST
+		 LIMIT $3`,
+		userID, tagID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.SearchResult
+	for rows.Next() {
+		result := &models.SearchResult{}
+		if err := rows.Scan(&result.Kind, &result.ID, &result.Title, &result.Amount, &result.Date, &result.Done); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// searchMatch matches rows whose document contains the query ($3) or has a
+// word similar to it ($2)
+func searchMatch(document string) string {
+	return `(` + document + ` ILIKE $3 OR $2 <% ` + document + `)`
+}
+
+// searchRank scores a row: 0..1 for word similarity, +1 for a substring
+// match and +0.5 when the title itself contains the query
+func searchRank(document, title string) string {
+	return `(word_similarity($2, ` + document + `)` +
+		` + CASE WHEN ` + document + ` ILIKE $3 THEN 1 ELSE 0 END` +
+		` + CASE WHEN COALESCE(` + title + `, '') ILIKE $3 THEN 0.5 ELSE 0 END)::float8`
+}
+
+// likePattern turns the query into an ILIKE substring pattern, escaping the
+// wildcards it may contain
+func likePattern(query string) string {
+	return "%" + escapeLike(query) + "%"
+}
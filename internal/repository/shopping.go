@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type ShoppingRepository struct {
+	db *database.DB
+}
+
+func NewShoppingRepository(db *database.DB) *ShoppingRepository {
+	return &ShoppingRepository{db: db}
+}
+
+func (r *ShoppingRepository) Create(ctx context.Context, item *models.ShoppingItem) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO shopping_item (user_id, name, quantity, created_by) VALUES ($1, $2, $3, $4)
+		 RETURNING item_id, created_at`,
+		item.UserID, item.Name, item.Quantity, item.CreatedBy,
+	).Scan(&item.ItemID, &item.CreatedAt)
+}
+
+// GetByUserID returns the list, unbought items first
+func (r *ShoppingRepository) GetByUserID(ctx context.Context, userID int64, includeBought bool) ([]*models.ShoppingItem, error) {
+	query := `SELECT item_id, user_id, name, COALESCE(quantity, ''), created_by, bought_by, bought_at, created_at
+		 FROM shopping_item WHERE user_id = $1`
+	if !includeBought {
+		query += ` AND bought_at IS NULL`
+	}
+	query += ` ORDER BY bought_at IS NOT NULL, created_at ASC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.ShoppingItem
+	for rows.Next() {
+		item := &models.ShoppingItem{}
+		if err := rows.Scan(&item.ItemID, &item.UserID, &item.Name, &item.Quantity, &item.CreatedBy,
+			&item.BoughtBy, &item.BoughtAt, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *ShoppingRepository) GetByID(ctx context.Context, itemID int, userID int64) (*models.ShoppingItem, error) {
+	item := &models.ShoppingItem{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT item_id, user_id, name, COALESCE(quantity, ''), created_by, bought_by, bought_at, created_at
+		 FROM shopping_item WHERE item_id = $1 AND user_id = $2`,
+		itemID, userID,
+	).Scan(&item.ItemID, &item.UserID, &item.Name, &item.Quantity, &item.CreatedBy,
+		&item.BoughtBy, &item.BoughtAt, &item.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// SetBought marks the item as bought by the member, or clears it when boughtBy is nil
+func (r *ShoppingRepository) SetBought(ctx context.Context, itemID int, userID int64, boughtBy *int64) error {
+	var boughtAt *time.Time
+	if boughtBy != nil {
+		now := time.Now()
+		boughtAt = &now
+	}
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE shopping_item SET bought_by = $1, bought_at = $2 WHERE item_id = $3 AND user_id = $4`,
+		boughtBy, boughtAt, itemID, userID,
+	)
+	return err
+}
+
+func (r *ShoppingRepository) Delete(ctx context.Context, itemID int, userID int64) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM shopping_item WHERE item_id = $1 AND user_id = $2`,
+		itemID, userID,
+	)
+	return err
+}
+
+// ClearBought removes all bought items from the list
+func (r *ShoppingRepository) ClearBought(ctx context.Context, userID int64) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM shopping_item WHERE user_id = $1 AND bought_at IS NOT NULL`,
+		userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type SpaceRepository struct {
+	db *database.DB
+}
+
+func NewSpaceRepository(db *database.DB) *SpaceRepository {
+	return &SpaceRepository{db: db}
+}
+
+// Ensure registers the group chat as a user and a space, and adds the member.
+// It is safe to call on every update from the group.
+func (r *SpaceRepository) Ensure(ctx context.Context, space *models.Space, memberID int64) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO "user" (user_id, user_name) VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE SET user_name = EXCLUDED.user_name`,
+		space.SpaceID, space.Title,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO space (space_id, chat_type, title) VALUES ($1, $2, $3)
+		 ON CONFLICT (space_id) DO UPDATE SET chat_type = EXCLUDED.chat_type, title = EXCLUDED.title
+		 RETURNING created_at`,
+		space.SpaceID, space.ChatType, space.Title,
+	).Scan(&space.CreatedAt); err != nil {
+		return err
+	}
+
+	// Group reminders and summaries use the same settings as personal chats
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO user_settings (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`,
+		space.SpaceID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO space_member (space_id, user_id) VALUES ($1, $2)
+		 ON CONFLICT (space_id, user_id) DO NOTHING`,
+		space.SpaceID, memberID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SpaceRepository) GetByID(ctx context.Context, spaceID int64) (*models.Space, error) {
+	space := &models.Space{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT space_id, chat_type, COALESCE(title, ''), created_at FROM space WHERE space_id = $1`,
+		spaceID,
+	).Scan(&space.SpaceID, &space.ChatType, &space.Title, &space.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return space, nil
+}
+
+func (r *SpaceRepository) IsMember(ctx context.Context, spaceID, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM space_member WHERE space_id = $1 AND user_id = $2)`,
+		spaceID, userID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *SpaceRepository) RemoveMember(ctx context.Context, spaceID, userID int64) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM space_member WHERE space_id = $1 AND user_id = $2`,
+		spaceID, userID,
+	)
+	return err
+}
+
+// GetMembers returns the members of a space with their user names
+func (r *SpaceRepository) GetMembers(ctx context.Context, spaceID int64) ([]*models.SpaceMember, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT m.space_id, m.user_id, COALESCE(u.user_name, ''), m.joined_at
+		 FROM space_member m JOIN "user" u ON u.user_id = m.user_id
+		 WHERE m.space_id = $1
+		 ORDER BY m.joined_at ASC`,
+		spaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*models.SpaceMember
+	for rows.Next() {
+		member := &models.SpaceMember{}
+		if err := rows.Scan(&member.SpaceID, &member.UserID, &member.UserName, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// GetByMember returns the spaces the user belongs to
+func (r *SpaceRepository) GetByMember(ctx context.Context, userID int64) ([]*models.Space, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT s.space_id, s.chat_type, COALESCE(s.title, ''), s.created_at
+		 FROM space s JOIN space_member m ON m.space_id = s.space_id
+		 WHERE m.user_id = $1
+		 ORDER BY s.created_at ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spaces []*models.Space
+	for rows.Next() {
+		space := &models.Space{}
+		if err := rows.Scan(&space.SpaceID, &space.ChatType, &space.Title, &space.CreatedAt); err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, space)
+	}
+	return spaces, nil
+}
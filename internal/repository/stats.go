@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type StatsRepository struct {
+	db *database.DB
+}
+
+func NewStatsRepository(db *database.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// Get counts users and rows across all users
+func (r *StatsRepository) Get(ctx context.Context) (*models.UsageStats, error) {
+	stats := &models.UsageStats{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT
+		   (SELECT COUNT(*) FROM "user" WHERE user_id > 0),
+		   (SELECT COUNT(*) FROM space),
+		   (SELECT COUNT(*) FROM "user" WHERE approved_at IS NOT NULL),
+		   (SELECT COUNT(*) FROM "user" WHERE banned_at IS NOT NULL),
+		   (SELECT COUNT(*) FROM memo),
+		   (SELECT COUNT(*) FROM todo),
+		   (SELECT COUNT(*) FROM todo WHERE completed_at IS NULL),
+		   (SELECT COUNT(*) FROM reminders),
+		   (SELECT COUNT(*) FROM event),
+		   (SELECT COUNT(*) FROM transaction)`,
+	).Scan(&stats.Users, &stats.Groups, &stats.Approved, &stats.Banned, &stats.Memos,
+		&stats.Todos, &stats.OpenTodos, &stats.Reminders, &stats.Events, &stats.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
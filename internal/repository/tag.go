@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is implemented by both the pool and a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// withTx runs fn in a transaction that is committed when fn succeeds
+func withTx(ctx context.Context, db *database.DB, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// tagLink describes the link table between tags and one kind of entity
+type tagLink struct {
+	table    string // link table
+	idColumn string // entity ID column, named the same in the link and entity tables
+	entity   string // entity table
+}
+
+var tagLinks = map[models.EntityKind]tagLink{
+	models.EntityMemo:        {table: "memo_tag", idColumn: "memo_id", entity: "memo"},
+	models.EntityTodo:        {table: "todo_tag", idColumn: "todo_id", entity: "todo"},
+	models.EntityReminder:    {table: "reminder_tag", idColumn: "reminders_id", entity: "reminders"},
+	models.EntityEvent:       {table: "event_tag", idColumn: "event_id", entity: "event"},
+	models.EntityTransaction: {table: "transaction_tag", idColumn: "transaction_id", entity: "transaction"},
+}
+
+// tagUsage counts the items using tag t
+var tagUsage = func() string {
+	var parts []string
+	for _, kind := range models.EntityKinds {
+		parts = append(parts, `(SELECT COUNT(*) FROM `+tagLinks[kind].table+` l WHERE l.tag_id = t.tag_id)`)
+	}
+	return `(` + strings.Join(parts, ` + `) + `)`
+}()
+
+// hasTag is a filter condition matching entities of kind with the tag named
+// by the placeholder %s
+func hasTag(kind models.EntityKind) string {
+	link := tagLinks[kind]
+	return `EXISTS (SELECT 1 FROM ` + link.table + ` l JOIN tag t ON t.tag_id = l.tag_id
+		 WHERE l.` + link.idColumn + ` = ` + link.entity + `.` + link.idColumn + ` AND LOWER(t.name) = LOWER(%s))`
+}
+
+// setTags replaces the tags of an entity, creating the tags userID does not have yet
+func setTags(ctx context.Context, q querier, kind models.EntityKind, userID int64, entityID int, tags []string) error {
+	link := tagLinks[kind]
+	if _, err := q.Exec(ctx, `DELETE FROM `+link.table+` WHERE `+link.idColumn+` = $1`, entityID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	lowered := make([]string, len(tags))
+	for i, tag := range tags {
+		lowered[i] = strings.ToLower(tag)
+	}
+	if _, err := q.Exec(ctx,
+		`INSERT INTO tag (user_id, name) SELECT $1, unnest($2::text[])
+		 ON CONFLICT (user_id, LOWER(name)) DO NOTHING`,
+		userID, tags,
+	); err != nil {
+		return err
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO `+link.table+` (`+link.idColumn+`, tag_id)
+		 SELECT $1, tag_id FROM tag WHERE user_id = $2 AND LOWER(name) = ANY($3::text[])
+		 ON CONFLICT DO NOTHING`,
+		entityID, userID, lowered,
+	)
+	return err
+}
+
+// RebuildTags recreates the tag links of every item of userID from their
+// tags columns, e.g. after restoring a backup
+func RebuildTags(ctx context.Context, q querier, userID int64) error {
+	for _, kind := range models.EntityKinds {
+		link := tagLinks[kind]
+		rows, err := q.Query(ctx,
+			`SELECT `+link.idColumn+`, COALESCE(tags, '') FROM `+link.entity+` WHERE user_id = $1 AND tags <> ''`,
+			userID,
+		)
+		if err != nil {
+			return err
+		}
+		items := make(map[int]string)
+		for rows.Next() {
+			var id int
+			var tags string
+			if err := rows.Scan(&id, &tags); err != nil {
+				rows.Close()
+				return err
+			}
+			items[id] = tags
+		}
+		rows.Close()
+
+		for id, tags := range items {
+			if err := setTags(ctx, q, kind, userID, id, models.ParseTags(tags)); err != nil {
+				return fmt.Errorf("failed to link tags of %s %d: %w", kind, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+type TagRepository struct {
+	db *database.DB
+}
+
+func NewTagRepository(db *database.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// GetCounts returns the tags in use with the number of items of each kind,
+// most used first
+func (r *TagRepository) GetCounts(ctx context.Context, userID int64) ([]*models.TagCount, error) {
+	// Every link is read once; its kind picks the count it adds to
+	var links, counts []string
+	for _, kind := range models.EntityKinds {
+		links = append(links, `SELECT '`+string(kind)+`' AS kind, tag_id FROM `+tagLinks[kind].table)
+		counts = append(counts, `COUNT(*) FILTER (WHERE l.kind = '`+string(kind)+`')`)
+	}
+
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT t.tag_id, t.user_id, t.name, t.created_at, `+strings.Join(counts, ", ")+`
+		 FROM tag t JOIN (`+strings.Join(links, " UNION ALL ")+`) l ON l.tag_id = t.tag_id
+		 WHERE t.user_id = $1
+		 GROUP BY t.tag_id
+		 ORDER BY COUNT(*) DESC, LOWER(t.name) ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.TagCount
+	for rows.Next() {
+		tc := &models.TagCount{Counts: make(map[models.EntityKind]int)}
+		n := make([]int, len(models.EntityKinds))
+		dest := []any{&tc.TagID, &tc.UserID, &tc.Name, &tc.CreatedAt}
+		for i := range n {
+			dest = append(dest, &n[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, kind := range models.EntityKinds {
+			tc.Counts[kind] = n[i]
+		}
+		tags = append(tags, tc)
+	}
+	return tags, nil
+}
+
+// Suggest returns tags for an item with the given text: tags whose name
+// appears in the text first, then the most used ones. A non-empty prefix
+// limits the result to tags starting with it, for autocompletion.
+func (r *TagRepository) Suggest(ctx context.Context, userID int64, text, prefix string, limit int) ([]*models.Tag, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT tag_id, user_id, name, created_at FROM tag t
+		 WHERE user_id = $1 AND name ILIKE $3 AND `+tagUsage+` > 0
+		 ORDER BY (POSITION(LOWER(name) IN LOWER($2)) > 0) DESC, `+tagUsage+` DESC, LOWER(name) ASC
+		 LIMIT $4`,
+		userID, text, escapeLike(prefix)+"%", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		tag := &models.Tag{}
+		if err := rows.Scan(&tag.TagID, &tag.UserID, &tag.Name, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) GetByID(ctx context.Context, tagID int, userID int64) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT tag_id, user_id, name, created_at FROM tag WHERE tag_id = $1 AND user_id = $2`,
+		tagID, userID,
+	).Scan(&tag.TagID, &tag.UserID, &tag.Name, &tag.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// GetTagIDs returns the IDs of the tags of an entity
+func (r *TagRepository) GetTagIDs(ctx context.Context, kind models.EntityKind, entityID int) ([]int, error) {
+	link := tagLinks[kind]
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT tag_id FROM `+link.table+` WHERE `+link.idColumn+` = $1`,
+		entityID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Toggle adds the tag to an entity of userID or removes it when present, and
+// rewrites the entity's tags column. It reports whether the tag was added and
+// returns pgx.ErrNoRows when the entity or tag does not belong to userID.
+func (r *TagRepository) Toggle(ctx context.Context, userID int64, kind models.EntityKind, entityID, tagID int) (bool, error) {
+	link := tagLinks[kind]
+	added := false
+	err := withTx(ctx, r.db, func(tx pgx.Tx) error {
+		var owned bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM `+link.entity+` WHERE `+link.idColumn+` = $1 AND user_id = $3)
+			   AND EXISTS(SELECT 1 FROM tag WHERE tag_id = $2 AND user_id = $3)`,
+			entityID, tagID, userID,
+		).Scan(&owned); err != nil {
+			return err
+		}
+		if !owned {
+			return pgx.ErrNoRows
+		}
+
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM `+link.table+` WHERE `+link.idColumn+` = $1 AND tag_id = $2`,
+			entityID, tagID,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			added = true
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO `+link.table+` (`+link.idColumn+`, tag_id) VALUES ($1, $2)`,
+				entityID, tagID,
+			); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE `+link.entity+` e SET tags = COALESCE((
+			   SELECT string_agg(t.name, ', ' ORDER BY LOWER(t.name))
+			   FROM `+link.table+` l JOIN tag t ON t.tag_id = l.tag_id
+			   WHERE l.`+link.idColumn+` = e.`+link.idColumn+`), '')
+			 WHERE `+link.idColumn+` = $1`,
+			entityID,
+		)
+		return err
+	})
+	return added, err
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 type TodoRepository struct {
@@ -16,17 +19,44 @@ func NewTodoRepository(db *database.DB) *TodoRepository {
 	return &TodoRepository{db: db}
 }
 
+// todoColumns is the column list matching scanTodo; it must select FROM todo
+// without an alias for the subtask counts and open blockers
+const todoColumns = `todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at, created_by,
+	assignee_id, assigned_by, COALESCE(assignment_status, ''), assigned_at, project_id, parent_id, status, status_changed_at, estimated_minutes,
+	(SELECT COUNT(*) FROM todo s WHERE s.parent_id = todo.todo_id),
+	(SELECT COUNT(*) FROM todo s WHERE s.parent_id = todo.todo_id AND s.completed_at IS NOT NULL),
+	` + openBlockers
+
+// openBlockers selects the IDs of the open todos todo waits for
+const openBlockers = `ARRAY(SELECT d.blocked_by_id FROM todo_dependency d JOIN todo b ON b.todo_id = d.blocked_by_id
+	  WHERE d.todo_id = todo.todo_id AND b.completed_at IS NULL ORDER BY d.blocked_by_id)`
+
 func (r *TodoRepository) Create(ctx context.Context, todo *models.Todo) error {
-	return r.db.Pool.QueryRow(ctx,
-		`INSERT INTO todo (user_id, title, priority, description, due_time, tags)
-		 VALUES ($1, $2, $3, $4, $5, $6)
-		 RETURNING todo_id, created_at`,
-		todo.UserID, todo.Title, todo.Priority, todo.Description, todo.DueTime, todo.Tags,
-	).Scan(&todo.TodoID, &todo.CreatedAt)
+	tags := models.ParseTags(todo.Tags)
+	todo.Tags = models.JoinTags(tags)
+	if !todo.Status.IsValid() || todo.Status.IsClosed() {
+		todo.Status = models.TodoInbox
+	}
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO todo (user_id, title, priority, description, due_time, tags, created_by, project_id, parent_id, status, status_changed_at,
+			 estimated_minutes)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP, $11)
+			 RETURNING todo_id, created_at, status_changed_at`,
+			todo.UserID, todo.Title, todo.Priority, todo.Description, todo.DueTime, todo.Tags, todo.CreatedBy,
+			todo.ProjectID, todo.ParentID, todo.Status, todo.EstimatedMinutes,
+		).Scan(&todo.TodoID, &todo.CreatedAt, &todo.StatusChangedAt); err != nil {
+			return err
+		}
+		if err := recordStatus(ctx, tx, todo.TodoID, todo.Status, todo.CreatedBy); err != nil {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityTodo, todo.UserID, todo.TodoID, tags)
+	})
 }
 
 func (r *TodoRepository) GetByUserID(ctx context.Context, userID int64, includeCompleted bool) ([]*models.Todo, error) {
-	query := `SELECT todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at
+	query := `SELECT ` + todoColumns + `
 		 FROM todo WHERE user_id = $1`
 	if !includeCompleted {
 		query += ` AND completed_at IS NULL`
@@ -39,54 +69,134 @@ func (r *TodoRepository) GetByUserID(ctx context.Context, userID int64, includeC
 	}
 	defer rows.Close()
 
-	var todos []*models.Todo
-	for rows.Next() {
-		todo := &models.Todo{}
-		if err := rows.Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
-			&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt); err != nil {
-			return nil, err
-		}
-		todos = append(todos, todo)
-	}
-	return todos, nil
+	return r.scanTodos(rows)
 }
 
 func (r *TodoRepository) GetByID(ctx context.Context, todoID int, userID int64) (*models.Todo, error) {
-	todo := &models.Todo{}
-	err := r.db.Pool.QueryRow(ctx,
-		`SELECT todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+todoColumns+`
 		 FROM todo WHERE todo_id = $1 AND user_id = $2`,
 		todoID, userID,
-	).Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
-		&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt)
-	if err != nil {
-		return nil, err
-	}
-	return todo, nil
+	)
+	return r.scanTodo(row)
 }
 
 func (r *TodoRepository) Update(ctx context.Context, todo *models.Todo) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE todo SET title = $1, priority = $2, description = $3, due_time = $4, tags = $5
-		 WHERE todo_id = $6 AND user_id = $7`,
-		todo.Title, todo.Priority, todo.Description, todo.DueTime, todo.Tags, todo.TodoID, todo.UserID,
-	)
-	return err
+	tags := models.ParseTags(todo.Tags)
+	todo.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE todo SET title = $1, priority = $2, description = $3, due_time = $4, tags = $5, project_id = $6, parent_id = $7,
+			 estimated_minutes = $8
+			 WHERE todo_id = $9 AND user_id = $10`,
+			todo.Title, todo.Priority, todo.Description, todo.DueTime, todo.Tags, todo.ProjectID, todo.ParentID,
+			todo.EstimatedMinutes, todo.TodoID, todo.UserID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return setTags(ctx, tx, models.EntityTodo, todo.UserID, todo.TodoID, tags)
+	})
 }
 
-func (r *TodoRepository) Complete(ctx context.Context, todoID int, userID int64) error {
-	now := time.Now()
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE todo SET completed_at = $1 WHERE todo_id = $2 AND user_id = $3`,
-		now, todoID, userID,
+// GetAccessible returns a todo owned by userID or assigned to and accepted by userID
+func (r *TodoRepository) GetAccessible(ctx context.Context, todoID int, userID int64) (*models.Todo, error) {
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+todoColumns+`
+		 FROM todo WHERE todo_id = $1
+		   AND (user_id = $2 OR (assignee_id = $2 AND assignment_status = 'accepted'))`,
+		todoID, userID,
 	)
-	return err
+	return r.scanTodo(row)
+}
+
+// Complete marks a todo as completed by its owner or its accepted assignee.
+// It reports whether the todo was open before; see SetStatus.
+func (r *TodoRepository) Complete(ctx context.Context, todoID int, userID int64) (bool, error) {
+	return r.SetStatus(ctx, todoID, userID, userID, models.TodoDone)
 }
 
+// Uncomplete reopens a todo of its owner as the next step
 func (r *TodoRepository) Uncomplete(ctx context.Context, todoID int, userID int64) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE todo SET completed_at = NULL WHERE todo_id = $1 AND user_id = $2`,
-		todoID, userID,
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE todo SET completed_at = NULL, status = $1, status_changed_at = CURRENT_TIMESTAMP
+			 WHERE todo_id = $2 AND user_id = $3 AND completed_at IS NOT NULL`,
+			models.TodoNext, todoID, userID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return recordStatus(ctx, tx, todoID, models.TodoNext, &userID)
+	})
+}
+
+// SetStatus moves a todo of its owner or accepted assignee userID to status
+// and records the transition by changedBy, the member acting in a shared
+// space; done and cancelled close the todo. It reports whether the status
+// changed, so a todo already in status is left untouched, and returns
+// pgx.ErrNoRows when userID may not change the todo.
+func (r *TodoRepository) SetStatus(ctx context.Context, todoID int, userID, changedBy int64, status models.TodoStatus) (bool, error) {
+	changed := false
+	err := withTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE todo SET status = $1, status_changed_at = CURRENT_TIMESTAMP,
+			        completed_at = CASE WHEN $4 THEN COALESCE(completed_at, CURRENT_TIMESTAMP) END
+			 WHERE todo_id = $2 AND (user_id = $3 OR (assignee_id = $3 AND assignment_status = 'accepted'))
+			   AND status <> $1`,
+			status, todoID, userID, status.IsClosed(),
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			var exists bool
+			if err := tx.QueryRow(ctx,
+				`SELECT EXISTS (SELECT 1 FROM todo WHERE todo_id = $1
+				   AND (user_id = $2 OR (assignee_id = $2 AND assignment_status = 'accepted')))`,
+				todoID, userID,
+			).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return pgx.ErrNoRows
+			}
+			return nil
+		}
+		changed = true
+		return recordStatus(ctx, tx, todoID, status, &changedBy)
+	})
+	return changed, err
+}
+
+// GetStatusHistory returns the status transitions of a todo, oldest first
+func (r *TodoRepository) GetStatusHistory(ctx context.Context, todoID int) ([]*models.TodoStatusChange, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT status, changed_by, changed_at FROM todo_status_history
+		 WHERE todo_id = $1 ORDER BY changed_at ASC, history_id ASC`,
+		todoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*models.TodoStatusChange
+	for rows.Next() {
+		c := &models.TodoStatusChange{}
+		if err := rows.Scan(&c.Status, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// recordStatus adds a transition to the status history of a todo
+func recordStatus(ctx context.Context, q querier, todoID int, status models.TodoStatus, changedBy *int64) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO todo_status_history (todo_id, status, changed_by) VALUES ($1, $2, $3)`,
+		todoID, status, changedBy,
 	)
 	return err
 }
@@ -102,7 +212,7 @@ func (r *TodoRepository) Delete(ctx context.Context, todoID int, userID int64) e
 func (r *TodoRepository) GetDueSoon(ctx context.Context, userID int64, within time.Duration) ([]*models.Todo, error) {
 	deadline := time.Now().Add(within)
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at
+		`SELECT `+todoColumns+`
 		 FROM todo WHERE user_id = $1 AND completed_at IS NULL AND due_time IS NOT NULL AND due_time <= $2
 		 ORDER BY due_time ASC`,
 		userID, deadline,
@@ -112,20 +222,11 @@ func (r *TodoRepository) GetDueSoon(ctx context.Context, userID int64, within ti
 	}
 	defer rows.Close()
 
-	var todos []*models.Todo
-	for rows.Next() {
-		todo := &models.Todo{}
-		if err := rows.Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
-			&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt); err != nil {
-			return nil, err
-		}
-		todos = append(todos, todo)
-	}
-	return todos, nil
+	return r.scanTodos(rows)
 }
 
 func (r *TodoRepository) Search(ctx context.Context, userID int64, keyword string, includeCompleted bool) ([]*models.Todo, error) {
-	query := `SELECT todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at
+	query := `SELECT ` + todoColumns + `
 		 FROM todo WHERE user_id = $1 AND (title ILIKE $2 OR description ILIKE $2 OR tags ILIKE $2)`
 	if !includeCompleted {
 		query += ` AND completed_at IS NULL`
@@ -138,50 +239,220 @@ func (r *TodoRepository) Search(ctx context.Context, userID int64, keyword strin
 	}
 	defer rows.Close()
 
-	var todos []*models.Todo
-	for rows.Next() {
-		todo := &models.Todo{}
-		if err := rows.Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
-			&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt); err != nil {
-			return nil, err
+	return r.scanTodos(rows)
+}
+
+// Filter returns the todos matching f. Completed todos are left out unless
+// the filter has a done condition.
+func (r *TodoRepository) Filter(ctx context.Context, userID int64, f *filter.Filter) ([]*models.Todo, error) {
+	where, args, err := todoFilterColumns.where(f, []any{userID})
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + todoColumns + `
+		 FROM todo WHERE user_id = $1` + where
+	if !f.Has(filter.FieldDone) && !f.Has(filter.FieldStatus) {
+		query += ` AND completed_at IS NULL`
+	}
+	query += ` ORDER BY priority DESC, due_time ASC NULLS LAST, created_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTodos(rows)
+}
+
+// GetByProject returns the todos of a project, subtasks included
+func (r *TodoRepository) GetByProject(ctx context.Context, userID int64, projectID int, includeCompleted bool) ([]*models.Todo, error) {
+	query := `SELECT ` + todoColumns + `
+		 FROM todo WHERE user_id = $1 AND project_id = $2`
+	if !includeCompleted {
+		query += ` AND completed_at IS NULL`
+	}
+	query += ` ORDER BY priority DESC, due_time ASC NULLS LAST, created_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTodos(rows)
+}
+
+// ErrDependencyCycle is returned when a dependency would make todos wait for each other
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// AddDependency makes todoID wait for blockerID. Both must belong to userID,
+// otherwise pgx.ErrNoRows is returned.
+func (r *TodoRepository) AddDependency(ctx context.Context, userID int64, todoID, blockerID int) error {
+	if todoID == blockerID {
+		return ErrDependencyCycle
+	}
+	return withTx(ctx, r.db, func(tx pgx.Tx) error {
+		var owned int
+		if err := tx.QueryRow(ctx,
+			`SELECT COUNT(*) FROM todo WHERE todo_id IN ($1, $2) AND user_id = $3`,
+			todoID, blockerID, userID,
+		).Scan(&owned); err != nil {
+			return err
 		}
-		todos = append(todos, todo)
+		if owned != 2 {
+			return pgx.ErrNoRows
+		}
+
+		// A cycle exists when the blocker already waits for the todo, directly or not
+		var cycle bool
+		if err := tx.QueryRow(ctx,
+			`WITH RECURSIVE waits(id) AS (
+			   SELECT blocked_by_id FROM todo_dependency WHERE todo_id = $1
+			   UNION
+			   SELECT d.blocked_by_id FROM todo_dependency d JOIN waits w ON d.todo_id = w.id
+			 )
+			 SELECT EXISTS(SELECT 1 FROM waits WHERE id = $2)`,
+			blockerID, todoID,
+		).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err := tx.Exec(ctx,
+			`INSERT INTO todo_dependency (todo_id, blocked_by_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			todoID, blockerID,
+		)
+		return err
+	})
+}
+
+// RemoveDependency stops todoID waiting for blockerID
+func (r *TodoRepository) RemoveDependency(ctx context.Context, userID int64, todoID, blockerID int) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM todo_dependency d USING todo t
+		 WHERE d.todo_id = $1 AND d.blocked_by_id = $2 AND t.todo_id = d.todo_id AND t.user_id = $3`,
+		todoID, blockerID, userID,
+	)
+	if err != nil {
+		return err
 	}
-	return todos, nil
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
 
-// GetTodosForNotification retrieves all incomplete todos with due_time within 7 days for a user
+// GetUnblockedBy returns the open todos that waited for blockerID and no
+// longer wait for anything, e.g. after blockerID was completed
+func (r *TodoRepository) GetUnblockedBy(ctx context.Context, blockerID int) ([]*models.Todo, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+todoColumns+`
+		 FROM todo
+		 WHERE todo_id IN (SELECT todo_id FROM todo_dependency WHERE blocked_by_id = $1)
+		   AND completed_at IS NULL
+		   AND cardinality(`+openBlockers+`) = 0
+		 ORDER BY todo_id`,
+		blockerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTodos(rows)
+}
+
+// Assign offers the todo to assigneeID; it stays pending until the assignee answers
+func (r *TodoRepository) Assign(ctx context.Context, todoID int, userID, assigneeID, assignedBy int64) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE todo SET assignee_id = $1, assigned_by = $2, assignment_status = 'pending', assigned_at = NOW()
+		 WHERE todo_id = $3 AND user_id = $4 AND completed_at IS NULL`,
+		assigneeID, assignedBy, todoID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetAssignment returns a todo that was assigned to assigneeID, whatever its status
+func (r *TodoRepository) GetAssignment(ctx context.Context, todoID int, assigneeID int64) (*models.Todo, error) {
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+todoColumns+`
+		 FROM todo WHERE todo_id = $1 AND assignee_id = $2`,
+		todoID, assigneeID,
+	)
+	return r.scanTodo(row)
+}
+
+// SetAssignmentStatus records the assignee's answer to a pending assignment
+func (r *TodoRepository) SetAssignmentStatus(ctx context.Context, todoID int, assigneeID int64, status models.AssignmentStatus) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE todo SET assignment_status = $1
+		 WHERE todo_id = $2 AND assignee_id = $3 AND assignment_status = 'pending'`,
+		status, todoID, assigneeID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// GetAssignedTo returns todos owned by others that userID has accepted
+func (r *TodoRepository) GetAssignedTo(ctx context.Context, userID int64, includeCompleted bool) ([]*models.Todo, error) {
+	query := `SELECT ` + todoColumns + `
+		 FROM todo WHERE assignee_id = $1 AND assignment_status = 'accepted' AND user_id <> $1`
+	if !includeCompleted {
+		query += ` AND completed_at IS NULL`
+	}
+	query += ` ORDER BY priority DESC, due_time ASC NULLS LAST, created_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTodos(rows)
+}
+
+// GetTodosForNotification retrieves all incomplete todos with due_time within 7 days for a user.
+// An accepted assignment moves the reminders from the owner to the assignee.
+// Waiting todos are not nagged about.
 func (r *TodoRepository) GetTodosForNotification(ctx context.Context, userID int64) ([]*models.Todo, error) {
 	// Get todos that are:
-	// 1. Not completed
+	// 0. Owned by the user and not handed to someone else, or accepted by the user
+	// 1. Not completed or cancelled, and not waiting on someone else
 	// 2. Have a due_time
 	// 3. Due within 7 days (or already overdue)
 	sevenDaysLater := time.Now().Add(7 * 24 * time.Hour)
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT todo_id, user_id, title, priority, description, due_time, completed_at, tags, created_at, last_notified_at
+		`SELECT `+todoColumns+`
 		 FROM todo
-		 WHERE user_id = $1
+		 WHERE ((user_id = $1 AND (assignee_id IS NULL OR assignee_id = $1 OR assignment_status IS DISTINCT FROM 'accepted'))
+		        OR (assignee_id = $1 AND assignment_status = 'accepted'))
 		   AND completed_at IS NULL
+		   AND status NOT IN ($3, $4)
 		   AND due_time IS NOT NULL
 		   AND due_time <= $2
 		 ORDER BY due_time ASC`,
-		userID, sevenDaysLater,
+		userID, sevenDaysLater, models.TodoWaiting, models.TodoCancelled,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var todos []*models.Todo
-	for rows.Next() {
-		todo := &models.Todo{}
-		if err := rows.Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
-			&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt); err != nil {
-			return nil, err
-		}
-		todos = append(todos, todo)
-	}
-	return todos, nil
+	return r.scanTodos(rows)
 }
 
 // SetLastNotifiedAt updates the last notification time for a todo
@@ -204,3 +475,31 @@ func (r *TodoRepository) BatchSetLastNotifiedAt(ctx context.Context, todoIDs []i
 	)
 	return err
 }
+
+func (r *TodoRepository) scanTodo(row interface {
+	Scan(dest ...any) error
+}) (*models.Todo, error) {
+	todo := &models.Todo{}
+	if err := row.Scan(&todo.TodoID, &todo.UserID, &todo.Title, &todo.Priority,
+		&todo.Description, &todo.DueTime, &todo.CompletedAt, &todo.Tags, &todo.CreatedAt, &todo.LastNotifiedAt,
+		&todo.CreatedBy, &todo.AssigneeID, &todo.AssignedBy, &todo.AssignmentStatus, &todo.AssignedAt,
+		&todo.ProjectID, &todo.ParentID, &todo.Status, &todo.StatusChangedAt, &todo.EstimatedMinutes, &todo.SubtaskCount, &todo.SubtaskDone, &todo.BlockedBy); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+func (r *TodoRepository) scanTodos(rows interface {
+	Next() bool
+	Scan(dest ...any) error
+}) ([]*models.Todo, error) {
+	var todos []*models.Todo
+	for rows.Next() {
+		todo, err := r.scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/filter"
 	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/jackc/pgx/v5"
 )
 
 type TransactionRepository struct {
@@ -16,21 +19,60 @@ func NewTransactionRepository(db *database.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
+// transactionColumns is the column list matching scanTransaction
+const transactionColumns = `transaction_id, user_id, category_id, type, amount, description, transaction_date, tags,
+		 recurrence_rule, frequency, interval, by_day, until, created_at, created_by,
+		 COALESCE(paid_by, ''), COALESCE(split_type, '')`
+
 func (r *TransactionRepository) Create(ctx context.Context, tx *models.Transaction) error {
-	return r.db.Pool.QueryRow(ctx,
+	return withTx(ctx, r.db, func(dbTx pgx.Tx) error {
+		return r.create(ctx, dbTx, tx)
+	})
+}
+
+// CreateSplit stores a split expense or settlement together with its shares
+func (r *TransactionRepository) CreateSplit(ctx context.Context, tx *models.Transaction) error {
+	dbTx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback(ctx)
+
+	if err := r.create(ctx, dbTx, tx); err != nil {
+		return err
+	}
+	for _, share := range tx.Shares {
+		share.TransactionID = tx.TransactionID
+		if err := dbTx.QueryRow(ctx,
+			`INSERT INTO transaction_share (transaction_id, participant, amount) VALUES ($1, $2, $3)
+			 RETURNING share_id`,
+			share.TransactionID, share.Participant, share.Amount,
+		).Scan(&share.ShareID); err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit(ctx)
+}
+
+func (r *TransactionRepository) create(ctx context.Context, q querier, tx *models.Transaction) error {
+	tags := models.ParseTags(tx.Tags)
+	tx.Tags = models.JoinTags(tags)
+	if err := q.QueryRow(ctx,
 		`INSERT INTO transaction (user_id, category_id, type, amount, description, transaction_date, tags,
-		 recurrence_rule, frequency, interval, by_day, until)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 recurrence_rule, frequency, interval, by_day, until, created_by, paid_by, split_type)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), NULLIF($15, ''))
 		 RETURNING transaction_id, created_at`,
 		tx.UserID, tx.CategoryID, tx.Type, tx.Amount, tx.Description, tx.TransactionDate, tx.Tags,
-		tx.RecurrenceRule, tx.Frequency, tx.Interval, tx.ByDay, tx.Until,
-	).Scan(&tx.TransactionID, &tx.CreatedAt)
+		tx.RecurrenceRule, tx.Frequency, tx.Interval, tx.ByDay, tx.Until, tx.CreatedBy, tx.PaidBy, string(tx.SplitType),
+	).Scan(&tx.TransactionID, &tx.CreatedAt); err != nil {
+		return err
+	}
+	return setTags(ctx, q, models.EntityTransaction, tx.UserID, tx.TransactionID, tags)
 }
 
 func (r *TransactionRepository) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]*models.Transaction, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT transaction_id, user_id, category_id, type, amount, description, transaction_date, tags,
-		 recurrence_rule, frequency, interval, by_day, until, created_at
+		`SELECT `+transactionColumns+`
 		 FROM transaction WHERE user_id = $1
 		 ORDER BY transaction_date DESC NULLS LAST, created_at DESC
 		 LIMIT $2 OFFSET $3`,
@@ -44,25 +86,34 @@ func (r *TransactionRepository) GetByUserID(ctx context.Context, userID int64, l
 	return r.scanTransactions(rows)
 }
 
-func (r *TransactionRepository) GetByID(ctx context.Context, transactionID int, userID int64) (*models.Transaction, error) {
-	tx := &models.Transaction{}
-	err := r.db.Pool.QueryRow(ctx,
-		`SELECT transaction_id, user_id, category_id, type, amount, description, transaction_date, tags,
-		 recurrence_rule, frequency, interval, by_day, until, created_at
-		 FROM transaction WHERE transaction_id = $1 AND user_id = $2`,
-		transactionID, userID,
-	).Scan(&tx.TransactionID, &tx.UserID, &tx.CategoryID, &tx.Type, &tx.Amount, &tx.Description,
-		&tx.TransactionDate, &tx.Tags, &tx.RecurrenceRule, &tx.Frequency, &tx.Interval, &tx.ByDay, &tx.Until, &tx.CreatedAt)
+// GetAllByUserID returns every transaction of the user without pagination
+func (r *TransactionRepository) GetAllByUserID(ctx context.Context, userID int64) ([]*models.Transaction, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+transactionColumns+`
+		 FROM transaction WHERE user_id = $1
+		 ORDER BY transaction_date ASC NULLS LAST, created_at ASC`,
+		userID,
+	)
 	if err != nil {
 		return nil, err
 	}
-	return tx, nil
+	defer rows.Close()
+
+	return r.scanTransactions(rows)
+}
+
+func (r *TransactionRepository) GetByID(ctx context.Context, transactionID int, userID int64) (*models.Transaction, error) {
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+transactionColumns+`
+		 FROM transaction WHERE transaction_id = $1 AND user_id = $2`,
+		transactionID, userID,
+	)
+	return r.scanTransaction(row)
 }
 
 func (r *TransactionRepository) GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*models.Transaction, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT transaction_id, user_id, category_id, type, amount, description, transaction_date, tags,
-		 recurrence_rule, frequency, interval, by_day, until, created_at
+		`SELECT `+transactionColumns+`
 		 FROM transaction WHERE user_id = $1 AND transaction_date >= $2 AND transaction_date <= $3
 		 ORDER BY transaction_date DESC`,
 		userID, start, end,
@@ -76,14 +127,21 @@ func (r *TransactionRepository) GetByDateRange(ctx context.Context, userID int64
 }
 
 func (r *TransactionRepository) Update(ctx context.Context, tx *models.Transaction) error {
-	_, err := r.db.Pool.Exec(ctx,
-		`UPDATE transaction SET category_id = $1, type = $2, amount = $3, description = $4,
-		 transaction_date = $5, tags = $6, recurrence_rule = $7, frequency = $8, interval = $9, by_day = $10, until = $11
-		 WHERE transaction_id = $12 AND user_id = $13`,
-		tx.CategoryID, tx.Type, tx.Amount, tx.Description, tx.TransactionDate, tx.Tags,
-		tx.RecurrenceRule, tx.Frequency, tx.Interval, tx.ByDay, tx.Until, tx.TransactionID, tx.UserID,
-	)
-	return err
+	tags := models.ParseTags(tx.Tags)
+	tx.Tags = models.JoinTags(tags)
+	return withTx(ctx, r.db, func(dbTx pgx.Tx) error {
+		tag, err := dbTx.Exec(ctx,
+			`UPDATE transaction SET category_id = $1, type = $2, amount = $3, description = $4,
+			 transaction_date = $5, tags = $6, recurrence_rule = $7, frequency = $8, interval = $9, by_day = $10, until = $11
+			 WHERE transaction_id = $12 AND user_id = $13`,
+			tx.CategoryID, tx.Type, tx.Amount, tx.Description, tx.TransactionDate, tx.Tags,
+			tx.RecurrenceRule, tx.Frequency, tx.Interval, tx.ByDay, tx.Until, tx.TransactionID, tx.UserID,
+		)
+		if err != nil || tag.RowsAffected() == 0 {
+			return err
+		}
+		return setTags(ctx, dbTx, models.EntityTransaction, tx.UserID, tx.TransactionID, tags)
+	})
 }
 
 func (r *TransactionRepository) Delete(ctx context.Context, transactionID int, userID int64) error {
@@ -134,8 +192,7 @@ func (r *TransactionRepository) GetTotalByType(ctx context.Context, userID int64
 
 func (r *TransactionRepository) Search(ctx context.Context, userID int64, keyword string) ([]*models.Transaction, error) {
 	rows, err := r.db.Pool.Query(ctx,
-		`SELECT transaction_id, user_id, category_id, type, amount, description, transaction_date, tags,
-		 recurrence_rule, frequency, interval, by_day, until, created_at
+		`SELECT `+transactionColumns+`
 		 FROM transaction WHERE user_id = $1 AND (description ILIKE $2 OR tags ILIKE $2)
 		 ORDER BY transaction_date DESC NULLS LAST, created_at DESC`,
 		userID, "%"+keyword+"%",
@@ -148,16 +205,156 @@ func (r *TransactionRepository) Search(ctx context.Context, userID int64, keywor
 	return r.scanTransactions(rows)
 }
 
+// Filter returns the latest transactions matching f
+func (r *TransactionRepository) Filter(ctx context.Context, userID int64, f *filter.Filter, limit int) ([]*models.Transaction, error) {
+	where, args, err := transactionFilterColumns.where(f, []any{userID})
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, limit)
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+transactionColumns+`
+		 FROM transaction WHERE user_id = $1`+where+`
+		 ORDER BY transaction_date DESC NULLS LAST, created_at DESC
+		 LIMIT $`+strconv.Itoa(len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTransactions(rows)
+}
+
+// GetShares returns the shares of a split transaction
+func (r *TransactionRepository) GetShares(ctx context.Context, transactionID int) ([]*models.TransactionShare, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT share_id, transaction_id, participant, amount
+		 FROM transaction_share WHERE transaction_id = $1
+		 ORDER BY share_id ASC`,
+		transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanShares(rows)
+}
+
+// GetSharesByUserID returns the shares of all split transactions of the user keyed by transaction ID
+func (r *TransactionRepository) GetSharesByUserID(ctx context.Context, userID int64) (map[int][]*models.TransactionShare, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT s.share_id, s.transaction_id, s.participant, s.amount
+		 FROM transaction_share s JOIN transaction t ON t.transaction_id = s.transaction_id
+		 WHERE t.user_id = $1
+		 ORDER BY s.share_id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares, err := r.scanShares(rows)
+	if err != nil {
+		return nil, err
+	}
+	byTransaction := make(map[int][]*models.TransactionShare)
+	for _, share := range shares {
+		byTransaction[share.TransactionID] = append(byTransaction[share.TransactionID], share)
+	}
+	return byTransaction, nil
+}
+
+// GetSplits returns the most recent split expenses and settlements
+func (r *TransactionRepository) GetSplits(ctx context.Context, userID int64, limit int) ([]*models.Transaction, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+transactionColumns+`
+		 FROM transaction WHERE user_id = $1 AND paid_by IS NOT NULL
+		 ORDER BY transaction_date DESC NULLS LAST, created_at DESC
+		 LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTransactions(rows)
+}
+
+// GetBalances returns the running balance of every participant of the user's
+// split transactions. Positive means the participant is owed money, negative
+// means they owe money. A payer's own share does not count.
+func (r *TransactionRepository) GetBalances(ctx context.Context, userID int64) (map[string]float64, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT participant, SUM(delta) FROM (
+		     SELECT t.paid_by AS participant, s.amount AS delta
+		     FROM transaction_share s JOIN transaction t ON t.transaction_id = s.transaction_id
+		     WHERE t.user_id = $1 AND s.participant <> t.paid_by
+		     UNION ALL
+		     SELECT s.participant, -s.amount
+		     FROM transaction_share s JOIN transaction t ON t.transaction_id = s.transaction_id
+		     WHERE t.user_id = $1 AND s.participant <> t.paid_by
+		 ) d
+		 GROUP BY participant
+		 HAVING SUM(delta) <> 0`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[string]float64)
+	for rows.Next() {
+		var participant string
+		var balance float64
+		if err := rows.Scan(&participant, &balance); err != nil {
+			return nil, err
+		}
+		balances[participant] = balance
+	}
+	return balances, nil
+}
+
+func (r *TransactionRepository) scanShares(rows interface {
+	Next() bool
+	Scan(dest ...any) error
+}) ([]*models.TransactionShare, error) {
+	var shares []*models.TransactionShare
+	for rows.Next() {
+		share := &models.TransactionShare{}
+		if err := rows.Scan(&share.ShareID, &share.TransactionID, &share.Participant, &share.Amount); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+func (r *TransactionRepository) scanTransaction(row interface {
+	Scan(dest ...any) error
+}) (*models.Transaction, error) {
+	tx := &models.Transaction{}
+	if err := row.Scan(&tx.TransactionID, &tx.UserID, &tx.CategoryID, &tx.Type, &tx.Amount,
+		&tx.Description, &tx.TransactionDate, &tx.Tags, &tx.RecurrenceRule, &tx.Frequency,
+		&tx.Interval, &tx.ByDay, &tx.Until, &tx.CreatedAt, &tx.CreatedBy, &tx.PaidBy, &tx.SplitType); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 func (r *TransactionRepository) scanTransactions(rows interface {
 	Next() bool
 	Scan(dest ...any) error
 }) ([]*models.Transaction, error) {
 	var transactions []*models.Transaction
 	for rows.Next() {
-		tx := &models.Transaction{}
-		if err := rows.Scan(&tx.TransactionID, &tx.UserID, &tx.CategoryID, &tx.Type, &tx.Amount,
-			&tx.Description, &tx.TransactionDate, &tx.Tags, &tx.RecurrenceRule, &tx.Frequency,
-			&tx.Interval, &tx.ByDay, &tx.Until, &tx.CreatedAt); err != nil {
+		tx, err := r.scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, tx)
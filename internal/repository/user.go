@@ -15,28 +15,126 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// userColumns is the column list matching scanUser
+const userColumns = `user_id, user_name, approved_at, banned_at`
+
 func (r *UserRepository) GetOrCreate(ctx context.Context, userID int64, userName string) (*models.User, error) {
-	user := &models.User{}
-	err := r.db.Pool.QueryRow(ctx,
+	row := r.db.Pool.QueryRow(ctx,
 		`INSERT INTO "user" (user_id, user_name) VALUES ($1, $2)
 		 ON CONFLICT (user_id) DO UPDATE SET user_name = EXCLUDED.user_name
-		 RETURNING user_id, user_name`,
+		 RETURNING `+userColumns,
 		userID, userName,
-	).Scan(&user.UserID, &user.UserName)
+	)
+	return r.scanUser(row)
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, userID int64) (*models.User, error) {
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+userColumns+` FROM "user" WHERE user_id = $1`,
+		userID,
+	)
+	return r.scanUser(row)
+}
+
+// GetByUserName finds a person (not a group space) by Telegram username, ignoring case
+func (r *UserRepository) GetByUserName(ctx context.Context, userName string) (*models.User, error) {
+	row := r.db.Pool.QueryRow(ctx,
+		`SELECT `+userColumns+` FROM "user" WHERE LOWER(user_name) = LOWER($1) AND user_id > 0`,
+		userName,
+	)
+	return r.scanUser(row)
+}
+
+// GetAll returns every user ordered by ID
+func (r *UserRepository) GetAll(ctx context.Context) ([]*models.User, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+userColumns+` FROM "user" ORDER BY user_id`,
+	)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
 }
 
-func (r *UserRepository) GetByID(ctx context.Context, userID int64) (*models.User, error) {
-	user := &models.User{}
-	err := r.db.Pool.QueryRow(ctx,
-		`SELECT user_id, user_name FROM "user" WHERE user_id = $1`,
-		userID,
-	).Scan(&user.UserID, &user.UserName)
+// GetPeople returns a page of users ordered by ID, leaving out group spaces
+func (r *UserRepository) GetPeople(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT `+userColumns+` FROM "user" WHERE user_id > 0 ORDER BY user_id LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// CountPeople returns the number of users, leaving out group spaces
+func (r *UserRepository) CountPeople(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM "user" WHERE user_id > 0`).Scan(&n)
+	return n, err
+}
+
+// SetApproved admits or un-admits a user for the allowlist and invite access
+// modes. The user row is created if they have not used the bot yet.
+func (r *UserRepository) SetApproved(ctx context.Context, userID int64, approved bool) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO "user" (user_id, approved_at) VALUES ($1, CASE WHEN $2 THEN NOW() END)
+		 ON CONFLICT (user_id) DO UPDATE SET approved_at = CASE WHEN $2 THEN COALESCE("user".approved_at, NOW()) END`,
+		userID, approved,
+	)
+	return err
+}
+
+// SetBanned bans or unbans a user. The user row is created if they have not used the bot yet.
+func (r *UserRepository) SetBanned(ctx context.Context, userID int64, banned bool) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO "user" (user_id, banned_at) VALUES ($1, CASE WHEN $2 THEN NOW() END)
+		 ON CONFLICT (user_id) DO UPDATE SET banned_at = CASE WHEN $2 THEN COALESCE("user".banned_at, NOW()) END`,
+		userID, banned,
+	)
+	return err
+}
+
+// Delete removes the user. All owned rows are removed through ON DELETE CASCADE.
+func (r *UserRepository) Delete(ctx context.Context, userID int64) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM "user" WHERE user_id = $1`,
+		userID,
+	)
+	return err
+}
+
+func (r *UserRepository) scanUser(row interface {
+	Scan(dest ...any) error
+}) (*models.User, error) {
+	user := &models.User{}
+	var userName *string
+	if err := row.Scan(&user.UserID, &userName, &user.ApprovedAt, &user.BannedAt); err != nil {
+		return nil, err
+	}
+	if userName != nil {
+		user.UserName = *userName
+	}
 	return user, nil
 }
@@ -27,7 +27,7 @@ func (r *UserSettingsRepository) GetOrCreate(ctx context.Context, userID int64)
 		 RETURNING user_id, max_daily_reminders, quiet_start::text, quiet_end::text,
 		           timezone, reminder_intervals, todo_reminders_enabled,
 		           last_todo_message_id, daily_summary_enabled, daily_summary_time::text,
-		           last_daily_summary_date, updated_at`,
+		           last_daily_summary_date, work_start::text, work_end::text, meeting_buffer, event_alerts, updated_at`,
 		userID,
 	).Scan(
 		&settings.UserID,
@@ -41,6 +41,10 @@ func (r *UserSettingsRepository) GetOrCreate(ctx context.Context, userID int64)
 		&settings.DailySummaryEnabled,
 		&settings.DailySummaryTime,
 		&settings.LastDailySummaryDate,
+		&settings.WorkStart,
+		&settings.WorkEnd,
+		&settings.MeetingBuffer,
+		&settings.EventAlerts,
 		&settings.UpdatedAt,
 	)
 	if err != nil {
@@ -64,7 +68,7 @@ func (r *UserSettingsRepository) GetByUserID(ctx context.Context, userID int64)
 		`SELECT user_id, max_daily_reminders, quiet_start::text, quiet_end::text,
 		        timezone, reminder_intervals, todo_reminders_enabled,
 		        last_todo_message_id, daily_summary_enabled, daily_summary_time::text,
-		        last_daily_summary_date, updated_at
+		        last_daily_summary_date, work_start::text, work_end::text, meeting_buffer, event_alerts, updated_at
 		 FROM user_settings WHERE user_id = $1`,
 		userID,
 	).Scan(
@@ -79,6 +83,10 @@ func (r *UserSettingsRepository) GetByUserID(ctx context.Context, userID int64)
 		&settings.DailySummaryEnabled,
 		&settings.DailySummaryTime,
 		&settings.LastDailySummaryDate,
+		&settings.WorkStart,
+		&settings.WorkEnd,
+		&settings.MeetingBuffer,
+		&settings.EventAlerts,
 		&settings.UpdatedAt,
 	)
 	if err != nil {
@@ -139,6 +147,33 @@ func (r *UserSettingsRepository) SetQuietHours(ctx context.Context, userID int64
 	return err
 }
 
+// SetWorkingHours updates the working hours searched for free time
+func (r *UserSettingsRepository) SetWorkingHours(ctx context.Context, userID int64, start, end string) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE user_settings SET work_start = $1::time, work_end = $2::time, updated_at = $3 WHERE user_id = $4`,
+		start, end, time.Now(), userID,
+	)
+	return err
+}
+
+// SetMeetingBuffer updates the minutes kept free around events
+func (r *UserSettingsRepository) SetMeetingBuffer(ctx context.Context, userID int64, minutes int) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE user_settings SET meeting_buffer = $1, updated_at = $2 WHERE user_id = $3`,
+		minutes, time.Now(), userID,
+	)
+	return err
+}
+
+// SetEventAlerts updates the alerts of new events
+func (r *UserSettingsRepository) SetEventAlerts(ctx context.Context, userID int64, alerts []int) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE user_settings SET event_alerts = COALESCE($1::integer[], '{}'), updated_at = $2 WHERE user_id = $3`,
+		alerts, time.Now(), userID,
+	)
+	return err
+}
+
 // SetMaxDailyReminders updates max daily reminders limit
 func (r *UserSettingsRepository) SetMaxDailyReminders(ctx context.Context, userID int64, max int) error {
 	_, err := r.db.Pool.Exec(ctx,
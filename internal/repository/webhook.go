@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+)
+
+type WebhookRepository struct {
+	db *database.DB
+}
+
+func NewWebhookRepository(db *database.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *models.Webhook) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO webhook (user_id, url, secret, events, enabled)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING webhook_id, created_at`,
+		hook.UserID, hook.URL, hook.Secret, hook.Events, hook.Enabled,
+	).Scan(&hook.WebhookID, &hook.CreatedAt)
+}
+
+func (r *WebhookRepository) GetByUserID(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT webhook_id, user_id, url, secret, events, enabled, created_at
+		 FROM webhook WHERE user_id = $1
+		 ORDER BY webhook_id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*models.Webhook
+	for rows.Next() {
+		hook := &models.Webhook{}
+		if err := rows.Scan(&hook.WebhookID, &hook.UserID, &hook.URL, &hook.Secret,
+			&hook.Events, &hook.Enabled, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, webhookID int, userID int64) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT webhook_id, user_id, url, secret, events, enabled, created_at
+		 FROM webhook WHERE webhook_id = $1 AND user_id = $2`,
+		webhookID, userID,
+	).Scan(&hook.WebhookID, &hook.UserID, &hook.URL, &hook.Secret, &hook.Events, &hook.Enabled, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// GetByIDOnly gets a webhook without user verification (for the delivery worker)
+func (r *WebhookRepository) GetByIDOnly(ctx context.Context, webhookID int) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT webhook_id, user_id, url, secret, events, enabled, created_at
+		 FROM webhook WHERE webhook_id = $1`,
+		webhookID,
+	).Scan(&hook.WebhookID, &hook.UserID, &hook.URL, &hook.Secret, &hook.Events, &hook.Enabled, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepository) SetEnabled(ctx context.Context, webhookID int, userID int64, enabled bool) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE webhook SET enabled = $1 WHERE webhook_id = $2 AND user_id = $3`,
+		enabled, webhookID, userID,
+	)
+	return err
+}
+
+// Delete removes a webhook and its delivery log. It returns false if nothing matched.
+func (r *WebhookRepository) Delete(ctx context.Context, webhookID int, userID int64) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`DELETE FROM webhook WHERE webhook_id = $1 AND user_id = $2`,
+		webhookID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// --- Deliveries ---
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	return r.db.Pool.QueryRow(ctx,
+		`INSERT INTO webhook_delivery (webhook_id, event, payload, status, next_attempt_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING delivery_id, created_at`,
+		d.WebhookID, d.Event, d.Payload, d.Status, d.NextAttemptAt,
+	).Scan(&d.DeliveryID, &d.CreatedAt)
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due
+func (r *WebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT delivery_id, webhook_id, event, payload, status, attempts, response_status,
+		 COALESCE(last_error, ''), next_attempt_at, delivered_at, created_at
+		 FROM webhook_delivery
+		 WHERE status = 'pending' AND next_attempt_at <= $1
+		 ORDER BY next_attempt_at ASC
+		 LIMIT $2`,
+		now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanDeliveries(rows)
+}
+
+// GetDeliveriesByWebhookID returns the most recent deliveries of a webhook
+func (r *WebhookRepository) GetDeliveriesByWebhookID(ctx context.Context, webhookID int, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT delivery_id, webhook_id, event, payload, status, attempts, response_status,
+		 COALESCE(last_error, ''), next_attempt_at, delivered_at, created_at
+		 FROM webhook_delivery
+		 WHERE webhook_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanDeliveries(rows)
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE webhook_delivery SET status = $1, attempts = $2, response_status = $3, last_error = $4,
+		 next_attempt_at = $5, delivered_at = $6
+		 WHERE delivery_id = $7`,
+		d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.DeliveryID,
+	)
+	return err
+}
+
+func (r *WebhookRepository) scanDeliveries(rows interface {
+	Next() bool
+	Scan(dest ...any) error
+}) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		if err := rows.Scan(&d.DeliveryID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.ResponseStatus, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
@@ -18,15 +18,20 @@ func ParseRRule(ruleStr string, dtstart time.Time) (*rrule.RRule, error) {
 		return nil, fmt.Errorf("failed to parse RRULE: %w", err)
 	}
 
-	// Database stores TIMESTAMP without timezone, but pgx reads it as UTC.
-	// The actual values are local time, so we need to reinterpret them.
-	// Create a new time with the same clock values but in local timezone.
-	opt.Dtstart = time.Date(
-		dtstart.Year(), dtstart.Month(), dtstart.Day(),
-		dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(),
+	opt.Dtstart = WallClock(dtstart)
+	return rrule.NewRRule(*opt)
+}
+
+// WallClock reinterprets a time read from the database as local time.
+// Database stores TIMESTAMP without timezone, but pgx reads it as UTC.
+// The actual values are local time, so we keep the clock values and
+// move them into the local timezone.
+func WallClock(t time.Time) time.Time {
+	return time.Date(
+		t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
 		time.Local,
 	)
-	return rrule.NewRRule(*opt)
 }
 
 // NextOccurrence returns the next occurrence after the given time
@@ -99,6 +104,57 @@ func NextOccurrences(ruleStr string, dtstart time.Time, after time.Time, count i
 	return results, nil
 }
 
+// Between returns the occurrences starting within [from, to)
+func Between(ruleStr string, dtstart time.Time, from, to time.Time) ([]time.Time, error) {
+	rule, err := ParseRRule(ruleStr, dtstart)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []time.Time
+	for _, t := range rule.Between(from, to, true) {
+		if t.Before(to) {
+			results = append(results, t)
+		}
+	}
+	return results, nil
+}
+
+// EndBefore returns the rule ending with its last occurrence before t, its
+// COUNT or UNTIL replaced by an UNTIL just before t
+func EndBefore(ruleStr string, t time.Time) (string, error) {
+	opt, err := rrule.StrToROption(strings.TrimPrefix(ruleStr, "RRULE:"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse RRULE: %w", err)
+	}
+	opt.Count = 0
+	opt.Until = t.Add(-time.Second)
+	return opt.RRuleString(), nil
+}
+
+// ContinueFrom returns the rule of a series that takes over the given one
+// from its occurrence t. A COUNT is reduced by the occurrences before t so
+// the two series together repeat as often as the original; UNTIL is kept.
+func ContinueFrom(ruleStr string, dtstart time.Time, t time.Time) (string, error) {
+	opt, err := rrule.StrToROption(strings.TrimPrefix(ruleStr, "RRULE:"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse RRULE: %w", err)
+	}
+	if opt.Count == 0 {
+		return opt.RRuleString(), nil
+	}
+
+	before, err := Between(ruleStr, dtstart, dtstart, t)
+	if err != nil {
+		return "", err
+	}
+	if len(before) >= opt.Count {
+		return "", fmt.Errorf("no occurrences left from %s", t.Format(time.RFC3339))
+	}
+	opt.Count -= len(before)
+	return opt.RRuleString(), nil
+}
+
 // BuildRRule creates an RRULE string from components
 type RRuleBuilder struct {
 	Freq       rrule.Frequency
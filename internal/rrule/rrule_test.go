@@ -0,0 +1,161 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.Local)
+}
+
+func TestBetween(t *testing.T) {
+	dtstart := date(2026, 1, 5, 9, 0)
+	tests := []struct {
+		name     string
+		rule     string
+		from, to time.Time
+		want     int
+	}{
+		{"daily week", "FREQ=DAILY", date(2026, 1, 5, 0, 0), date(2026, 1, 12, 0, 0), 7},
+		{"end is exclusive", "FREQ=DAILY", date(2026, 1, 5, 0, 0), date(2026, 1, 7, 9, 0), 2},
+		{"start is inclusive", "FREQ=DAILY", date(2026, 1, 6, 9, 0), date(2026, 1, 7, 0, 0), 1},
+		{"count limits", "FREQ=DAILY;COUNT=3", date(2026, 1, 1, 0, 0), date(2026, 2, 1, 0, 0), 3},
+		{"weekly by day", "FREQ=WEEKLY;BYDAY=MO,WE", date(2026, 1, 5, 0, 0), date(2026, 1, 19, 0, 0), 4},
+		{"before dtstart", "FREQ=DAILY", date(2025, 12, 1, 0, 0), date(2026, 1, 5, 0, 0), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.rule, dtstart, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Between: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d occurrences %v, want %d", len(got), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceStrict(t *testing.T) {
+	dtstart := date(2026, 1, 5, 9, 0)
+
+	next, err := NextOccurrenceStrict("FREQ=DAILY", dtstart, dtstart)
+	if err != nil {
+		t.Fatalf("NextOccurrenceStrict: %v", err)
+	}
+	if want := date(2026, 1, 6, 9, 0); next == nil || !next.Equal(want) {
+		t.Errorf("got %v, want %v", next, want)
+	}
+
+	next, err = NextOccurrenceStrict("FREQ=DAILY;COUNT=2", dtstart, date(2026, 1, 6, 9, 0))
+	if err != nil {
+		t.Fatalf("NextOccurrenceStrict: %v", err)
+	}
+	if next != nil {
+		t.Errorf("got %v after the last occurrence, want nil", next)
+	}
+}
+
+func TestEndBefore(t *testing.T) {
+	dtstart := date(2026, 1, 5, 9, 0)
+	split := date(2026, 1, 8, 9, 0)
+
+	for _, rule := range []string{"FREQ=DAILY", "FREQ=DAILY;COUNT=10", "RRULE:FREQ=DAILY;UNTIL=20260131T090000Z"} {
+		t.Run(rule, func(t *testing.T) {
+			ended, err := EndBefore(rule, split)
+			if err != nil {
+				t.Fatalf("EndBefore: %v", err)
+			}
+			got, err := Between(ended, dtstart, dtstart, date(2027, 1, 1, 0, 0))
+			if err != nil {
+				t.Fatalf("Between: %v", err)
+			}
+			if len(got) != 3 {
+				t.Errorf("got %d occurrences %v, want 3", len(got), got)
+			}
+		})
+	}
+}
+
+func TestContinueFrom(t *testing.T) {
+	dtstart := date(2026, 1, 5, 9, 0)
+	split := date(2026, 1, 8, 9, 0)
+	end := date(2027, 1, 1, 0, 0)
+
+	tests := []struct {
+		name string
+		rule string
+		want int // occurrences of the continued series
+	}{
+		{"count", "FREQ=DAILY;COUNT=10", 7},
+		{"count with prefix", "RRULE:FREQ=DAILY;COUNT=10", 7},
+		{"count on last", "FREQ=DAILY;COUNT=4", 1},
+		{"weekly count", "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=6", 5},
+		{"until", "FREQ=DAILY;UNTIL=20260114T235959Z", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			continued, err := ContinueFrom(tt.rule, dtstart, split)
+			if err != nil {
+				t.Fatalf("ContinueFrom: %v", err)
+			}
+			got, err := Between(continued, split, split, end)
+			if err != nil {
+				t.Fatalf("Between: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("%s: got %d occurrences, want %d", continued, len(got), tt.want)
+			}
+
+			// Together the two parts repeat as often as the original series
+			ended, err := EndBefore(tt.rule, split)
+			if err != nil {
+				t.Fatalf("EndBefore: %v", err)
+			}
+			before, _ := Between(ended, dtstart, dtstart, end)
+			original, _ := Between(tt.rule, dtstart, dtstart, end)
+			if len(before)+len(got) != len(original) {
+				t.Errorf("split into %d + %d occurrences, original has %d", len(before), len(got), len(original))
+			}
+		})
+	}
+
+	if _, err := ContinueFrom("FREQ=DAILY;COUNT=2", dtstart, split); err == nil {
+		t.Error("expected an error continuing after the last occurrence")
+	}
+}
+
+func TestContinueFromRepeatedSplits(t *testing.T) {
+	rule := "FREQ=DAILY;COUNT=10"
+	dtstart := date(2026, 1, 5, 9, 0)
+	for i := 0; i < 3; i++ {
+		split := dtstart.AddDate(0, 0, 2)
+		next, err := ContinueFrom(rule, dtstart, split)
+		if err != nil {
+			t.Fatalf("split %d: %v", i, err)
+		}
+		rule, dtstart = next, split
+	}
+
+	got, err := Between(rule, dtstart, dtstart, date(2027, 1, 1, 0, 0))
+	if err != nil {
+		t.Fatalf("Between: %v", err)
+	}
+	if len(got) != 4 {
+		t.Errorf("got %d occurrences after three splits, want 4", len(got))
+	}
+}
+
+func TestIsRecurring(t *testing.T) {
+	tests := map[string]bool{
+		"":             false,
+		"FREQ=DAILY":   true,
+		"RRULE:FREQ=x": true,
+	}
+	for rule, want := range tests {
+		if got := IsRecurring(rule); got != want {
+			t.Errorf("IsRecurring(%q) = %v, want %v", rule, got, want)
+		}
+	}
+}
@@ -2,25 +2,33 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hray3182/LifeLine/internal/calendar"
 	"github.com/hray3182/LifeLine/internal/format"
+	"github.com/hray3182/LifeLine/internal/metrics"
 	"github.com/hray3182/LifeLine/internal/models"
 	"github.com/hray3182/LifeLine/internal/repository"
 	"github.com/hray3182/LifeLine/internal/rrule"
+	"github.com/hray3182/LifeLine/internal/webhook"
+	"github.com/jackc/pgx/v5"
 )
 
 type Scheduler struct {
 	api              *tgbotapi.BotAPI
 	reminderRepo     *repository.ReminderRepository
 	eventRepo        *repository.EventRepository
+	calendar         *calendar.Service
 	todoRepo         *repository.TodoRepository
 	userSettingsRepo *repository.UserSettingsRepository
+	projectRepo      *repository.ProjectRepository
 	checkInterval    time.Duration
 	notifyCh         chan struct{}
+	webhooks         *webhook.Dispatcher
 }
 
 func New(
@@ -34,6 +42,7 @@ func New(
 		api:              api,
 		reminderRepo:     reminderRepo,
 		eventRepo:        eventRepo,
+		calendar:         calendar.New(eventRepo),
 		todoRepo:         todoRepo,
 		userSettingsRepo: userSettingsRepo,
 		checkInterval:    1 * time.Minute,
@@ -41,6 +50,16 @@ func New(
 	}
 }
 
+// SetWebhooks sets the dispatcher used to emit webhook events
+func (s *Scheduler) SetWebhooks(d *webhook.Dispatcher) {
+	s.webhooks = d
+}
+
+// SetProjects sets the repository used to show project names in the daily summary
+func (s *Scheduler) SetProjects(r *repository.ProjectRepository) {
+	s.projectRepo = r
+}
+
 // Notify triggers an immediate check. Non-blocking if a check is already pending.
 func (s *Scheduler) Notify() {
 	select {
@@ -50,11 +69,15 @@ func (s *Scheduler) Notify() {
 	}
 }
 
+// Start runs checks until ctx is cancelled. A check in progress is finished
+// before Start returns, so no notification is cut off halfway.
 func (s *Scheduler) Start(ctx context.Context) {
 	log.Println("Scheduler started")
 	ticker := time.NewTicker(s.checkInterval)
 	defer ticker.Stop()
 
+	checkCtx := context.WithoutCancel(ctx)
+
 	// Wait a bit for migrations to complete before first check
 	select {
 	case <-ctx.Done():
@@ -63,7 +86,7 @@ func (s *Scheduler) Start(ctx context.Context) {
 	}
 
 	// Run first check
-	s.check(ctx)
+	s.check(checkCtx)
 
 	for {
 		select {
@@ -71,19 +94,27 @@ func (s *Scheduler) Start(ctx context.Context) {
 			log.Println("Scheduler stopped")
 			return
 		case <-ticker.C:
-			s.check(ctx)
+			s.check(checkCtx)
 		case <-s.notifyCh:
 			log.Println("Scheduler triggered by notification")
-			s.check(ctx)
+			s.check(checkCtx)
 		}
 	}
 }
 
 func (s *Scheduler) check(ctx context.Context) {
-	s.checkReminders(ctx)
-	s.checkEvents(ctx)
-	s.checkDueTodos(ctx)
-	s.checkDailySummary(ctx)
+	defer metrics.Since(metrics.SchedulerCheckDuration.WithLabelValues("all"), time.Now())
+
+	s.timed(ctx, "reminders", s.checkReminders)
+	s.timed(ctx, "events", s.checkEvents)
+	s.timed(ctx, "todos", s.checkDueTodos)
+	s.timed(ctx, "daily_summary", s.checkDailySummary)
+}
+
+// timed runs a single check and records its duration
+func (s *Scheduler) timed(ctx context.Context, name string, fn func(context.Context)) {
+	defer metrics.Since(metrics.SchedulerCheckDuration.WithLabelValues(name), time.Now())
+	fn(ctx)
 }
 
 func (s *Scheduler) checkReminders(ctx context.Context) {
@@ -95,62 +126,114 @@ func (s *Scheduler) checkReminders(ctx context.Context) {
 	}
 
 	for _, reminder := range reminders {
-		// Delete previous message if exists (to avoid flooding)
-		if reminder.LastMessageID != nil {
-			deleteMsg := tgbotapi.NewDeleteMessage(reminder.UserID, *reminder.LastMessageID)
-			if _, err := s.api.Request(deleteMsg); err != nil {
-				log.Printf("Failed to delete old reminder message %d: %v", *reminder.LastMessageID, err)
-				// Continue anyway, the old message might have been deleted by user
-			}
+		if err := s.sendReminder(ctx, reminder, now); err != nil {
+			log.Printf("Failed to send reminder notification: %v", err)
+		}
+	}
+}
+
+// ResendReminder immediately sends a reminder again, regardless of its schedule
+func (s *Scheduler) ResendReminder(ctx context.Context, reminderID int) error {
+	reminder, err := s.reminderRepo.GetByIDOnly(ctx, reminderID)
+	if err != nil {
+		return fmt.Errorf("failed to get reminder: %w", err)
+	}
+
+	// A resent reminder needs to be acknowledged again
+	if err := s.reminderRepo.SetAcknowledgedAt(ctx, reminderID, nil); err != nil {
+		return fmt.Errorf("failed to reset acknowledgement: %w", err)
+	}
+	return s.sendReminder(ctx, reminder, time.Now())
+}
+
+// sendReminder replaces the previous reminder message with a new one and marks the reminder as notified
+func (s *Scheduler) sendReminder(ctx context.Context, reminder *models.Reminder, now time.Time) error {
+	// Delete previous message if exists (to avoid flooding)
+	if reminder.LastMessageID != nil {
+		deleteMsg := tgbotapi.NewDeleteMessage(reminder.UserID, *reminder.LastMessageID)
+		if _, err := s.api.Request(deleteMsg); err != nil {
+			log.Printf("Failed to delete old reminder message %d: %v", *reminder.LastMessageID, err)
+			// Continue anyway, the old message might have been deleted by user
 		}
+	}
 
-		// Send notification
-		text := "⏰ **提醒**\n\n" + reminder.Messages
-		if reminder.Description != "" {
-			text += "\n\n" + reminder.Description
+	// An overridden occurrence replaces the message for that time only
+	message := reminder.Messages
+	if reminder.IsRecurring() && reminder.RemindAt != nil {
+		if err := s.reminderRepo.LoadExceptions(ctx, reminder); err != nil {
+			log.Printf("Failed to load exceptions for reminder %d: %v", reminder.ReminderID, err)
 		}
-		if reminder.IsRecurring() {
-			text += "\n\n🔄 " + rrule.HumanReadableChinese(reminder.RecurrenceRule)
+		if x := calendar.MovedTo(reminder.Exceptions, *reminder.RemindAt); x != nil && x.Title != "" {
+			message = x.Title
 		}
+	}
 
-		parsed := format.ParseMarkdown(text)
-		msg := tgbotapi.NewMessage(reminder.UserID, parsed.Text)
-		msg.Entities = parsed.Entities
+	// Send notification
+	text := "⏰ **提醒**\n\n" + message
+	if reminder.Description != "" {
+		text += "\n\n" + reminder.Description
+	}
+	if reminder.IsRecurring() {
+		text += "\n\n🔄 " + rrule.HumanReadableChinese(reminder.RecurrenceRule)
+	}
 
-		// Add confirm button
-		confirmButton := tgbotapi.NewInlineKeyboardButtonData(
-			"✅ 確認",
-			fmt.Sprintf("remind_ack:%d", reminder.ReminderID),
-		)
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(confirmButton),
-		)
+	parsed := format.ParseMarkdown(text)
+	msg := tgbotapi.NewMessage(reminder.UserID, parsed.Text)
+	msg.Entities = parsed.Entities
 
-		sentMsg, err := s.api.Send(msg)
-		if err != nil {
-			log.Printf("Failed to send reminder notification: %v", err)
-			continue
-		}
+	// Add confirm button
+	confirmButton := tgbotapi.NewInlineKeyboardButtonData(
+		"✅ 確認",
+		fmt.Sprintf("remind_ack:%d", reminder.ReminderID),
+	)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(confirmButton),
+	)
 
-		// Save message ID and mark as notified in database
-		s.reminderRepo.SetLastMessageID(ctx, reminder.ReminderID, sentMsg.MessageID)
-		s.reminderRepo.SetNotifiedAt(ctx, reminder.ReminderID, &now)
-		log.Printf("Sent reminder %d to user %d (msg_id=%d)", reminder.ReminderID, reminder.UserID, sentMsg.MessageID)
+	sentMsg, err := s.api.Send(msg)
+	metrics.Notification(metrics.NotificationReminder, err)
+	if err != nil {
+		return err
 	}
+
+	// Save message ID and mark as notified in database
+	s.reminderRepo.SetLastMessageID(ctx, reminder.ReminderID, sentMsg.MessageID)
+	s.reminderRepo.SetNotifiedAt(ctx, reminder.ReminderID, &now)
+	s.webhooks.Emit(ctx, reminder.UserID, models.WebhookEventReminderFired, reminder)
+	log.Printf("Sent reminder %d to user %d (msg_id=%d)", reminder.ReminderID, reminder.UserID, sentMsg.MessageID)
+	return nil
 }
 
 func (s *Scheduler) checkEvents(ctx context.Context) {
 	now := time.Now()
-	events, err := s.eventRepo.GetPendingNotifications(ctx)
+	// Events that started since the previous check are still considered;
+	// updateRecurringEvents moves them on only after this pass
+	events, err := s.eventRepo.GetPendingNotifications(ctx, now, now.Add(-s.checkInterval))
 	if err != nil {
 		log.Printf("Failed to get pending event notifications: %v", err)
 		return
 	}
 
+	if err := s.eventRepo.LoadExceptions(ctx, events...); err != nil {
+		log.Printf("Failed to load event exceptions: %v", err)
+	}
+
 	for _, event := range events {
 		if event.NextOccurrence == nil {
 			continue
 		}
+		next := rrule.WallClock(*event.NextOccurrence)
+		event.NextOccurrence = &next
+		// Alerts due together, e.g. of an event created shortly before it
+		// starts, are sent as a single notification
+		due := event.DueAlerts(now)
+		if len(due) == 0 {
+			continue
+		}
+		// An overridden occurrence notifies with its own title and duration
+		if x := calendar.MovedTo(event.Exceptions, *event.NextOccurrence); x != nil {
+			event = calendar.Override(event, x)
+		}
 
 		// Calculate time until event
 		timeUntil := time.Until(*event.NextOccurrence)
@@ -158,13 +241,17 @@ func (s *Scheduler) checkEvents(ctx context.Context) {
 
 		text := "📅 **即將開始的事件**\n\n"
 		text += "**" + event.Title + "**\n"
-		text += "⏰ " + event.NextOccurrence.Format("15:04")
+		if event.AllDay {
+			text += "📆 " + event.NextOccurrence.Format("01/02 ") + calendar.AllDayLabel(event)
+		} else {
+			text += "⏰ " + event.NextOccurrence.Format("15:04")
+		}
 
 		if minutesUntil > 0 {
 			text += " (約 " + formatDuration(timeUntil) + " 後)"
 		}
 
-		if event.Duration > 0 {
+		if event.Duration > 0 && !event.AllDay {
 			text += fmt.Sprintf("\n⏱ %d 分鐘", event.Duration)
 		}
 
@@ -179,13 +266,17 @@ func (s *Scheduler) checkEvents(ctx context.Context) {
 		parsed := format.ParseMarkdown(text)
 		msg := tgbotapi.NewMessage(event.UserID, parsed.Text)
 		msg.Entities = parsed.Entities
-		if _, err := s.api.Send(msg); err != nil {
+		_, err := s.api.Send(msg)
+		metrics.Notification(metrics.NotificationEvent, err)
+		if err != nil {
 			log.Printf("Failed to send event notification: %v", err)
 			continue
 		}
 
-		// Mark as notified in database
-		s.eventRepo.SetNotifiedAt(ctx, event.EventID, &now)
+		// Mark the alerts as sent for this occurrence
+		if err := s.eventRepo.MarkAlertsSent(ctx, event.EventID, due); err != nil {
+			log.Printf("Failed to mark event alerts sent: %v", err)
+		}
 		log.Printf("Sent event notification %d to user %d", event.EventID, event.UserID)
 	}
 
@@ -200,20 +291,23 @@ func (s *Scheduler) updateRecurringEvents(ctx context.Context, now time.Time) {
 		log.Printf("Failed to get passed events: %v", err)
 		return
 	}
+	if err := s.eventRepo.LoadExceptions(ctx, events...); err != nil {
+		log.Printf("Failed to load event exceptions: %v", err)
+	}
 
 	for _, event := range events {
 		// Event time has passed
 		if event.RecurrenceRule == "" || event.Dtstart == nil {
-			// One-time event, clear next_occurrence (this also clears notified_at)
+			// One-time event, clear next_occurrence (this also clears alerts_sent)
 			s.eventRepo.UpdateNextOccurrence(ctx, event.EventID, nil)
 		} else {
 			// Calculate next occurrence
-			next, err := rrule.NextOccurrence(event.RecurrenceRule, *event.Dtstart, now)
+			next, err := calendar.NextOccurrence(event.RecurrenceRule, rrule.WallClock(*event.Dtstart), event.Exceptions, now)
 			if err != nil {
 				log.Printf("Failed to calculate next occurrence for event %d: %v", event.EventID, err)
 				s.eventRepo.UpdateNextOccurrence(ctx, event.EventID, nil)
 			} else {
-				// Update next_occurrence (this also clears notified_at)
+				// Update next_occurrence (this also clears alerts_sent)
 				s.eventRepo.UpdateNextOccurrence(ctx, event.EventID, next)
 				if next != nil {
 					log.Printf("Scheduled next event %d at %s", event.EventID, next.Format("2006-01-02 15:04"))
@@ -304,6 +398,7 @@ func (s *Scheduler) checkUserTodos(ctx context.Context, userID int64, now time.T
 	msg.Entities = parsed.Entities
 
 	sentMsg, err := s.api.Send(msg)
+	metrics.Notification(metrics.NotificationTodo, err)
 	if err != nil {
 		log.Printf("Failed to send todo notification to %d: %v", userID, err)
 		return
@@ -333,7 +428,8 @@ func (s *Scheduler) checkUserTodos(ctx context.Context, userID int64, now time.T
 
 // shouldNotifyTodo determines if a todo should be notified based on time and priority
 func (s *Scheduler) shouldNotifyTodo(todo *models.Todo, settings *models.UserSettings, now time.Time) (bool, string) {
-	if todo.DueTime == nil {
+	// Blocked todos wait until their blockers are completed
+	if todo.DueTime == nil || todo.IsBlocked() {
 		return false, ""
 	}
 
@@ -511,30 +607,55 @@ func (s *Scheduler) sendDailySummaryIfNeeded(ctx context.Context, userID int64,
 		return
 	}
 
-	// Get today's events
-	todayEvents, err := s.eventRepo.GetTodayEvents(ctx, userID)
+	if err := s.sendDailySummary(ctx, settings, now); err != nil {
+		log.Printf("Failed to send daily summary to %d: %v", userID, err)
+	}
+}
+
+// SendDailySummary sends the daily summary to a user right away, ignoring the configured time
+func (s *Scheduler) SendDailySummary(ctx context.Context, userID int64) error {
+	settings, err := s.settingsOrDefault(ctx, userID)
 	if err != nil {
-		log.Printf("Failed to get today events for %d: %v", userID, err)
-		todayEvents = nil
+		return err
 	}
+	return s.sendDailySummary(ctx, settings, time.Now())
+}
 
-	// Get incomplete todos (with due_time within 7 days or no due_time)
-	todos, err := s.todoRepo.GetByUserID(ctx, userID, false)
+// BuildDailySummary returns the daily summary text a user would receive at the given time
+func (s *Scheduler) BuildDailySummary(ctx context.Context, userID int64, now time.Time) (string, error) {
+	settings, err := s.settingsOrDefault(ctx, userID)
 	if err != nil {
-		log.Printf("Failed to get todos for daily summary %d: %v", userID, err)
-		todos = nil
+		return "", err
 	}
+	return s.dailySummaryText(ctx, settings, now), nil
+}
+
+// settingsOrDefault returns the user's settings, or the defaults if none are stored yet
+func (s *Scheduler) settingsOrDefault(ctx context.Context, userID int64) (*models.UserSettings, error) {
+	settings, err := s.userSettingsRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.NewDefaultUserSettings(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (s *Scheduler) sendDailySummary(ctx context.Context, settings *models.UserSettings, now time.Time) error {
+	userID := settings.UserID
 
 	// Build and send daily summary message
-	text := s.buildDailySummaryText(todayEvents, todos, now, settings.Timezone)
+	text := s.dailySummaryText(ctx, settings, now)
 
 	parsed := format.ParseMarkdown(text)
 	msg := tgbotapi.NewMessage(userID, parsed.Text)
 	msg.Entities = parsed.Entities
 
-	if _, err := s.api.Send(msg); err != nil {
-		log.Printf("Failed to send daily summary to %d: %v", userID, err)
-		return
+	_, err := s.api.Send(msg)
+	metrics.Notification(metrics.NotificationDailySummary, err)
+	if err != nil {
+		return err
 	}
 
 	// Update last daily summary date
@@ -543,9 +664,42 @@ func (s *Scheduler) sendDailySummaryIfNeeded(ctx context.Context, userID int64,
 	}
 
 	log.Printf("Sent daily summary to user %d", userID)
+	return nil
 }
 
-func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*models.Todo, now time.Time, timezone string) string {
+// dailySummaryText loads today's events and open todos and renders the summary
+func (s *Scheduler) dailySummaryText(ctx context.Context, settings *models.UserSettings, now time.Time) string {
+	userID := settings.UserID
+
+	// Get today's events
+	todayEvents, err := s.calendar.Day(ctx, userID, now)
+	if err != nil {
+		log.Printf("Failed to get today events for %d: %v", userID, err)
+		todayEvents = nil
+	}
+
+	// Get incomplete todos (with due_time within 7 days or no due_time)
+	todos, err := s.todoRepo.GetByUserID(ctx, userID, false)
+	if err != nil {
+		log.Printf("Failed to get todos for daily summary %d: %v", userID, err)
+		todos = nil
+	}
+
+	projects := make(map[int]*models.Project)
+	if s.projectRepo != nil {
+		list, err := s.projectRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get projects for daily summary %d: %v", userID, err)
+		}
+		for _, p := range list {
+			projects[p.ProjectID] = p
+		}
+	}
+
+	return s.buildDailySummaryText(todayEvents, todos, projects, now, settings.Timezone)
+}
+
+func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*models.Todo, projects map[int]*models.Project, now time.Time, timezone string) string {
 	loc, err := time.LoadLocation(timezone)
 	if err != nil {
 		loc = time.Local
@@ -564,6 +718,16 @@ func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*model
 		text += "• 今天沒有行程安排\n"
 	} else {
 		for _, event := range events {
+			if event.AllDay {
+				text += fmt.Sprintf("• %s %s", calendar.AllDayLabel(event), event.Title)
+				if event.Days() > 1 && event.NextOccurrence != nil {
+					// Which day of a multi-day event today is
+					day := int(localNow.Sub(*event.NextOccurrence).Hours()/24) + 1
+					text += fmt.Sprintf(" (第 %d/%d 天)", day, event.Days())
+				}
+				text += "\n"
+				continue
+			}
 			timeStr := ""
 			if event.NextOccurrence != nil {
 				timeStr = event.NextOccurrence.In(loc).Format("15:04")
@@ -578,22 +742,52 @@ func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*model
 		}
 	}
 
+	// Overlapping events
+	if conflicts := calendar.Overlapping(events); len(conflicts) > 0 {
+		text += "\n**⚠️ 時間衝突**\n"
+		startText := func(e *models.Event) string {
+			if e.AllDay {
+				return "全天"
+			}
+			return e.NextOccurrence.In(loc).Format("15:04")
+		}
+		for _, pair := range conflicts {
+			text += fmt.Sprintf("• %s %s ↔ %s %s\n", startText(pair[0]), pair[0].Title, startText(pair[1]), pair[1].Title)
+		}
+	}
+
 	// Todo list
 	text += "\n**待辦事項**\n"
 	if len(todos) == 0 {
 		text += "• 沒有待辦事項\n"
 	} else {
-		// Show up to 10 todos
+		// Show up to 10 todos, subtasks below their parents
+		todos = nestSubtasks(todos)
 		count := len(todos)
 		if count > 10 {
 			count = 10
 		}
 		for i := 0; i < count; i++ {
 			todo := todos[i]
+			indent := "• "
+			if todo.ParentID != nil && i > 0 && containsTodo(todos[:i], *todo.ParentID) {
+				indent = "   ↳ "
+			}
+			project := ""
+			if p, ok := projects[derefInt(todo.ProjectID)]; ok && todo.ParentID == nil {
+				project = fmt.Sprintf(" [%s %s]", p.Emoji(), p.Name)
+			}
+			progress := ""
+			if todo.SubtaskCount > 0 {
+				progress = fmt.Sprintf(" (%d/%d)", todo.SubtaskDone, todo.SubtaskCount)
+			}
 			priority := ""
 			if todo.Priority >= 4 {
 				priority = " ⭐"
 			}
+			if todo.IsBlocked() {
+				priority += " 🔒"
+			}
 			dueStr := ""
 			if todo.DueTime != nil {
 				if todo.DueTime.Before(now) {
@@ -604,7 +798,7 @@ func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*model
 					dueStr = " (明天截止)"
 				}
 			}
-			text += fmt.Sprintf("• %s%s%s\n", todo.Title, priority, dueStr)
+			text += fmt.Sprintf("%s%s%s%s%s%s\n", indent, todo.Title, progress, project, priority, dueStr)
 		}
 		if len(todos) > 10 {
 			text += fmt.Sprintf("• ...還有 %d 項\n", len(todos)-10)
@@ -616,6 +810,54 @@ func (s *Scheduler) buildDailySummaryText(events []*models.Event, todos []*model
 	return text
 }
 
+// nestSubtasks orders todos so that subtasks follow their parent; subtasks
+// whose parent is not in the list stay where they are
+func nestSubtasks(todos []*models.Todo) []*models.Todo {
+	inList := make(map[int]bool, len(todos))
+	for _, t := range todos {
+		inList[t.TodoID] = true
+	}
+	children := make(map[int][]*models.Todo)
+	var roots []*models.Todo
+	for _, t := range todos {
+		if t.ParentID != nil && inList[*t.ParentID] {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+
+	ordered := make([]*models.Todo, 0, len(todos))
+	var add func(t *models.Todo)
+	add = func(t *models.Todo) {
+		ordered = append(ordered, t)
+		for _, child := range children[t.TodoID] {
+			add(child)
+		}
+	}
+	for _, t := range roots {
+		add(t)
+	}
+	return ordered
+}
+
+// containsTodo reports whether todoID is in todos
+func containsTodo(todos []*models.Todo, todoID int) bool {
+	for _, t := range todos {
+		if t.TodoID == todoID {
+			return true
+		}
+	}
+	return false
+}
+
+func derefInt(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
 func getGreeting(hour int) string {
 	switch {
 	case hour >= 5 && hour < 12:
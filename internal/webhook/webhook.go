@@ -0,0 +1,378 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hray3182/LifeLine/internal/database"
+	"github.com/hray3182/LifeLine/internal/models"
+	"github.com/hray3182/LifeLine/internal/repository"
+)
+
+// Signature headers sent with every delivery. The signature is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	HeaderEvent     = "X-LifeLine-Event"
+	HeaderDelivery  = "X-LifeLine-Delivery"
+	HeaderTimestamp = "X-LifeLine-Timestamp"
+	HeaderSignature = "X-LifeLine-Signature"
+)
+
+// retryBackoff is the wait before each retry; a delivery fails after len(retryBackoff)+1 attempts
+var retryBackoff = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	1 * time.Hour,
+	6 * time.Hour,
+}
+
+// Payload is the JSON body posted to webhook URLs
+type Payload struct {
+	Event     string    `json:"event"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher records events for subscribed webhooks and delivers them in the background.
+// A nil Dispatcher is valid and drops all events.
+type Dispatcher struct {
+	repo          *repository.WebhookRepository
+	client        *http.Client
+	checkInterval time.Duration
+	notifyCh      chan struct{}
+}
+
+func New(db *database.DB) *Dispatcher {
+	return &Dispatcher{
+		repo:          repository.NewWebhookRepository(db),
+		client:        newClient(),
+		checkInterval: 30 * time.Second,
+		notifyCh:      make(chan struct{}, 1),
+	}
+}
+
+// newClient returns an HTTP client that only connects to public addresses and
+// does not follow redirects. The address check runs on every dial, after DNS
+// resolution, so a hostname that later resolves to an internal address is
+// refused as well.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenHost, ip)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return errors.New("webhook redirects are not followed")
+		},
+	}
+}
+
+// Emit queues an event for every enabled webhook of the user that subscribes to it.
+// Errors are logged, never returned, so callers do not fail because of a webhook.
+func (d *Dispatcher) Emit(ctx context.Context, userID int64, event string, data any) {
+	if d == nil {
+		return
+	}
+
+	hooks, err := d.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get webhooks for user %d: %v", userID, err)
+		return
+	}
+
+	var body []byte
+	now := time.Now()
+	queued := 0
+	for _, hook := range hooks {
+		if !hook.Enabled || !hook.Subscribes(event) {
+			continue
+		}
+
+		if body == nil {
+			body, err = json.Marshal(Payload{Event: event, UserID: userID, CreatedAt: now, Data: data})
+			if err != nil {
+				log.Printf("Failed to marshal webhook payload for %s: %v", event, err)
+				return
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			WebhookID:     hook.WebhookID,
+			Event:         event,
+			Payload:       body,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+			log.Printf("Failed to queue webhook delivery for webhook %d: %v", hook.WebhookID, err)
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		d.notify()
+	}
+}
+
+// notify wakes up the delivery loop. Non-blocking if a run is already pending.
+func (d *Dispatcher) notify() {
+	select {
+	case d.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers pending events until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	log.Println("Webhook dispatcher started")
+	ticker := time.NewTicker(d.checkInterval)
+	defer ticker.Stop()
+
+	// A batch in progress is delivered before Start returns
+	deliverCtx := context.WithoutCancel(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			d.deliverDue(deliverCtx)
+		case <-d.notifyCh:
+			d.deliverDue(deliverCtx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.repo.GetDueDeliveries(ctx, time.Now(), 50)
+	if err != nil {
+		log.Printf("Failed to get due webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		hook, err := d.repo.GetByIDOnly(ctx, delivery.WebhookID)
+		if err != nil {
+			log.Printf("Failed to get webhook %d: %v", delivery.WebhookID, err)
+			continue
+		}
+		d.attempt(ctx, hook, delivery)
+	}
+}
+
+// attempt sends a delivery once and schedules a retry with backoff on failure
+func (d *Dispatcher) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+
+	var status int
+	var err error
+	if !hook.Enabled {
+		err = errors.New("webhook disabled")
+	} else {
+		status, err = d.send(ctx, hook, delivery, now)
+	}
+
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case !hook.Enabled || delivery.Attempts > len(retryBackoff):
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		log.Printf("Webhook delivery %d to webhook %d failed after %d attempts: %v",
+			delivery.DeliveryID, hook.WebhookID, delivery.Attempts, err)
+	default:
+		next := now.Add(retryBackoff[delivery.Attempts-1])
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", delivery.DeliveryID, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LifeLine-Webhook/1")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.DeliveryID))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ErrForbiddenHost is returned for webhook URLs pointing at loopback, private,
+// link-local or unspecified addresses
+var ErrForbiddenHost = errors.New("webhook URL must point to a public address")
+
+// ValidateURL checks that a webhook URL is an absolute http(s) URL whose host
+// resolves only to public addresses
+func ValidateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(ip) {
+			return ErrForbiddenHost
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return ErrForbiddenHost
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(lookupCtx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %q", host)
+	}
+	for _, ip := range addrs {
+		if !isPublicAddr(ip) {
+			return ErrForbiddenHost
+		}
+	}
+	return nil
+}
+
+// specialPrefixes are ranges outside the checks of netip.Addr that don't
+// reach the public internet or reach it by translating to an address
+// that may be internal
+var specialPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // documentation
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+}
+
+// isPublicAddr reports whether ip may be the target of a webhook delivery
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range specialPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// ParseEvents normalizes a comma separated event list, returning "*" for an empty list
+func ParseEvents(raw string) (string, error) {
+	var events []string
+	for _, e := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(e)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			return "*", nil
+		}
+		if !slices.Contains(models.WebhookEvents, name) {
+			return "", fmt.Errorf("unknown webhook event %q", name)
+		}
+		events = append(events, name)
+	}
+	if len(events) == 0 {
+		return "*", nil
+	}
+	return strings.Join(events, ","), nil
+}
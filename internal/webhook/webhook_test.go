@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"todo.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("secret", "1700000001", body) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		forbidden bool
+	}{
+		{"ftp://93.184.216.34/hook", false},
+		{"/relative", false},
+		{"http://127.0.0.1/hook", true},
+		{"http://[::1]:8080/hook", true},
+		{"http://10.0.0.5/hook", true},
+		{"http://172.16.0.1/hook", true},
+		{"http://192.168.1.1/hook", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://0.0.0.0/hook", true},
+		{"http://[fd00::1]/hook", true},
+		{"http://[::ffff:127.0.0.1]/hook", true},
+		{"http://localhost:3000/hook", true},
+		{"http://db.internal/hook", true},
+		{"http://printer.local/hook", true},
+	}
+	for _, tt := range tests {
+		err := ValidateURL(context.Background(), tt.url)
+		if err == nil {
+			t.Errorf("ValidateURL(%q) accepted the URL", tt.url)
+			continue
+		}
+		if got := errors.Is(err, ErrForbiddenHost); got != tt.forbidden {
+			t.Errorf("ValidateURL(%q) = %v, forbidden host %v, want %v", tt.url, err, got, tt.forbidden)
+		}
+	}
+
+	if err := ValidateURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("public address rejected: %v", err)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"169.254.1.1":     false,
+		"::":              false,
+		"fe80::1":         false,
+		"224.0.0.1":       false,
+		"0.1.2.3":         false,
+		"100.64.0.1":      false,
+		"100.127.255.254": false,
+		"100.128.0.1":     true,
+		"192.0.0.8":       false,
+		"192.0.2.10":      false,
+		"198.18.0.1":      false,
+		"198.19.255.1":    false,
+		"198.20.0.1":      true,
+		"198.51.100.7":    false,
+		"203.0.113.9":     false,
+		"255.255.255.255": false,
+		"64:ff9b::a00:1":  false,
+		"2001:db8::1":     false,
+		"2001::1":         false,
+		"2002:a00:1::1":   false,
+		"fec0::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+	for addr, want := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := newClient().Post(srv.URL, "application/json", nil)
+	if !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("got %v, want ErrForbiddenHost", err)
+	}
+}